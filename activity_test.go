@@ -0,0 +1,132 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// feedEntry is an entry of the activity feed of a group.
+type feedEntry struct {
+	Type    string
+	ActorID int64
+}
+
+func TestGroupActivityFeed(t *testing.T) {
+	owner, member, kicked, outsider := signUp(t), signUp(t), signUp(t), signUp(t)
+	g := createGroup(t, owner, nil)
+	joinGroup(t, member, g)
+	joinGroup(t, kicked, g)
+	expectStatus(t, apiRequest{
+		Method: http.MethodPost, Path: groupPath(g, "/kick"), Token: owner.Token,
+		Body: map[string]interface{}{"id": kicked.ID},
+	}.send(t), http.StatusOK)
+	expectStatus(t, apiRequest{
+		Method: http.MethodPost, Path: groupPath(g, "/close"), Token: owner.Token,
+	}.send(t), http.StatusOK)
+
+	page := func(t *testing.T, u testUser, n int) []feedEntry {
+		t.Helper()
+		w := apiRequest{
+			Method: http.MethodGet, Token: u.Token,
+			Path: groupPath(g, fmt.Sprintf("/activity?page=%v&page_size=2", n)),
+		}.send(t)
+		expectStatus(t, w, http.StatusOK)
+		var activities []struct {
+			Type  string `json:"type"`
+			Actor struct {
+				ID       int64  `json:"id"`
+				Username string `json:"username"`
+			} `json:"actor"`
+		}
+		decode(t, w, &activities)
+		entries := make([]feedEntry, len(activities))
+		for i, a := range activities {
+			if a.Actor.Username == "" {
+				t.Errorf("got no actor summary for %v", a.Type)
+			}
+			entries[i] = feedEntry{a.Type, a.Actor.ID}
+		}
+		return entries
+	}
+
+	// The feed is newest first.
+	want := [][]feedEntry{
+		{{"closed", owner.ID}, {"kicked", owner.ID}},
+		{{"joined", kicked.ID}, {"joined", member.ID}},
+		{{"created", owner.ID}},
+		{},
+	}
+	for i, w := range want {
+		got := page(t, member, i+1)
+		if fmt.Sprint(got) != fmt.Sprint(w) {
+			t.Errorf("got %v on page %v, want %v", got, i+1, w)
+		}
+	}
+
+	for _, u := range []testUser{outsider, kicked} {
+		expectStatus(t, apiRequest{
+			Method: http.MethodGet, Path: groupPath(g, "/activity"), Token: u.Token,
+		}.send(t), http.StatusForbidden)
+	}
+}
+
+func TestKickRecordsAnAuditEntry(t *testing.T) {
+	owner, spammer, quiet := signUp(t), signUp(t), signUp(t)
+	g := createGroup(t, owner, nil)
+	joinGroup(t, spammer, g)
+	joinGroup(t, quiet, g)
+	kick := func(u testUser, reason string) *httptest.ResponseRecorder {
+		body := map[string]interface{}{"id": u.ID}
+		if reason != "" {
+			body["reason"] = reason
+		}
+		return apiRequest{
+			Method: http.MethodPost, Path: groupPath(g, "/kick"), Token: owner.Token,
+			Body: body,
+		}.send(t)
+	}
+
+	w := kick(spammer, strings.Repeat("x", 201))
+	expectStatus(t, w, http.StatusBadRequest)
+	if ids := fieldErrorIDs(t, w)["reason"]; len(ids) != 1 {
+		t.Errorf("got reason errors %v for a long reason, want one", ids)
+	}
+	expectStatus(t, kick(spammer, "  Spamming the chat  "), http.StatusOK)
+	expectStatus(t, kick(quiet, ""), http.StatusOK)
+
+	w = apiRequest{
+		Method: http.MethodGet, Path: groupPath(g, "/activity"), Token: owner.Token,
+	}.send(t)
+	expectStatus(t, w, http.StatusOK)
+	var activities []struct {
+		Type  string `json:"type"`
+		Actor struct {
+			ID int64 `json:"id"`
+		} `json:"actor"`
+		Target struct {
+			ID int64 `json:"id"`
+		} `json:"target"`
+		Reason    string `json:"reason"`
+		CreatedAt string `json:"created_at"`
+	}
+	decode(t, w, &activities)
+	// The feed is newest first.
+	want := []struct {
+		target int64
+		reason string
+	}{{quiet.ID, ""}, {spammer.ID, "Spamming the chat"}}
+	if len(activities) < len(want) {
+		t.Fatalf("got activities %+v, want the two kicks first", activities)
+	}
+	for i, k := range want {
+		a := activities[i]
+		if a.Type != "kicked" || a.Actor.ID != owner.ID || a.Target.ID != k.target ||
+			a.Reason != k.reason || a.CreatedAt == "" {
+			t.Errorf("got activity %+v, want the kick of %v with reason %q",
+				a, k.target, k.reason)
+		}
+	}
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/damascopaul/lfg-backend/endpoints"
+	"github.com/damascopaul/lfg-backend/schemas"
+)
+
+// makeAdmin gives the user access to the admin endpoints.
+func makeAdmin(t *testing.T, u testUser) {
+	t.Helper()
+	su := schemas.User{ID: u.ID}
+	if err := su.InitDB(context.Background()); err != nil {
+		t.Fatalf("could not init the database: %v", err)
+	}
+	if r := su.DB.Model(&schemas.User{}).Where("id = ?", u.ID).Update(
+		"is_admin", true); r.Error != nil {
+		t.Fatalf("could not make the user an admin: %v", r.Error)
+	}
+}
+
+func TestRandomizeStatusesRequiresDemoModeAndAdmin(t *testing.T) {
+	defer func(demo bool, percent int) {
+		endpoints.DEMO_MODE, endpoints.DEMO_CLOSED_PERCENT = demo, percent
+	}(endpoints.DEMO_MODE, endpoints.DEMO_CLOSED_PERCENT)
+	// Every group is opened so the groups of the other tests keep working.
+	endpoints.DEMO_CLOSED_PERCENT = 0
+
+	admin, user := signUp(t), signUp(t)
+	makeAdmin(t, admin)
+	createGroup(t, user, nil)
+	randomize := func(u testUser) apiRequest {
+		return apiRequest{
+			Method: http.MethodPost, Path: "/admin/groups/randomize-status",
+			Token: u.Token,
+		}
+	}
+
+	endpoints.DEMO_MODE = false
+	expectStatus(t, randomize(admin).send(t), http.StatusNotFound)
+
+	endpoints.DEMO_MODE = true
+	expectStatus(t, randomize(user).send(t), http.StatusForbidden)
+	w := randomize(admin).send(t)
+	expectStatus(t, w, http.StatusOK)
+	var dist schemas.StatusDistribution
+	decode(t, w, &dist)
+	if dist.Closed != 0 || dist.Open == 0 {
+		t.Errorf("got %+v, want every group open", dist)
+	}
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/damascopaul/lfg-backend/endpoints"
+)
+
+func TestListGroupsByIDs(t *testing.T) {
+	owner, outsider := signUp(t), signUp(t)
+	first := createGroup(t, owner, nil)
+	second := createGroup(t, owner, nil)
+	private := createGroup(t, owner, map[string]interface{}{"password": "s3cret-pass"})
+
+	w := apiRequest{
+		Method: http.MethodGet,
+		Path: fmt.Sprintf("/groups?ids=%v,999999999,%v,%v,%v",
+			second["id"], first["id"], second["id"], private["id"]),
+		Token: outsider.Token,
+		// The password does not unlock the private groups in a batch.
+		Headers: map[string]string{"X-Group-Password": "s3cret-pass"},
+	}.send(t)
+	expectStatus(t, w, http.StatusOK)
+	var groups []map[string]interface{}
+	decode(t, w, &groups)
+
+	// The missing and the repeated IDs are skipped and the order of the IDs
+	// is kept.
+	want := []interface{}{second["id"], first["id"], private["id"]}
+	if len(groups) != len(want) {
+		t.Fatalf("got %v groups, want %v: %v", len(groups), len(want), groups)
+	}
+	for i, grp := range groups {
+		if grp["id"] != want[i] {
+			t.Errorf("got group %v at %v, want %v", grp["id"], i, want[i])
+		}
+		if _, ok := grp["password"]; ok {
+			t.Errorf("got the password of group %v", grp["id"])
+		}
+	}
+	if _, ok := groups[2]["description"]; ok {
+		t.Errorf("got the details of the private group: %v", groups[2])
+	}
+}
+
+func TestListGroupsByIDsRejectsInvalidIDs(t *testing.T) {
+	user := signUp(t)
+	tooMany := make([]string, endpoints.MAX_BATCH_GROUP_IDS+1)
+	for i := range tooMany {
+		tooMany[i] = fmt.Sprint(i + 1)
+	}
+	for _, tc := range []struct {
+		name   string
+		ids    string
+		wantID string
+	}{
+		{"not a number", "1,two", "unsupported_value"},
+		{"not positive", "0", "unsupported_value"},
+		{"too many", strings.Join(tooMany, ","), "too_many_values"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			w := apiRequest{
+				Method: http.MethodGet, Path: "/groups?ids=" + tc.ids, Token: user.Token,
+			}.send(t)
+			expectStatus(t, w, http.StatusBadRequest)
+			ids := fieldErrorIDs(t, w)["ids"]
+			if len(ids) != 1 || ids[0] != tc.wantID {
+				t.Errorf("got ids errors %v, want [%v]", ids, tc.wantID)
+			}
+		})
+	}
+}
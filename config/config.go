@@ -0,0 +1,560 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// devTokenSecret signs the tokens in dev mode if no secret is configured.
+const devTokenSecret = "1d62gCp6XcESjQh0oUwkHmoScQ14i4wmpyLgabxYwXb2EOllX4EJ1Ajs1pF5"
+
+// ErrTokenSecretRequired is returned when the token secret is not set
+// outside of dev mode.
+var ErrTokenSecretRequired = errors.New(
+	"LFG_TOKEN_SECRET is required unless LFG_DEV_MODE is set")
+
+// PageLimits are the default and the largest page size of a listing.
+type PageLimits struct {
+	Default int
+	Max     int
+}
+
+// defaultReservedUsernames are the usernames reserved if
+// `LFG_RESERVED_USERNAMES` is not set.
+var defaultReservedUsernames = []string{
+	"admin", "administrator", "api", "help", "lfg", "mod", "moderator",
+	"root", "staff", "support", "system",
+}
+
+// Config is the configuration of the server read once at startup.
+type Config struct {
+	// Addr is the address the server listens on. It is read from `LFG_ADDR`
+	// and defaults to `localhost:8080`.
+	Addr string
+	// DBPath is the path of the SQLite database file. It is read from
+	// `LFG_DB_PATH` and defaults to `./lfg.db`.
+	DBPath string
+	// DevMode enables the defaults meant for local development. It is read
+	// from `LFG_DEV_MODE`.
+	DevMode bool
+	// TokenSecret is the key used to sign and verify the user tokens. It is
+	// read from `LFG_TOKEN_SECRET` and is required outside of dev mode.
+	TokenSecret string
+	// LogLevel is the level of the logs. It is read from `LFG_LOG_LEVEL` and
+	// defaults to debug.
+	LogLevel log.Level
+	// RequireJSONAccept rejects the requests that do not accept JSON. It is
+	// read from `LFG_REQUIRE_JSON_ACCEPT`.
+	RequireJSONAccept bool
+
+	// RequestTimeout is how long a request can take before its database
+	// queries are canceled. It is read from `LFG_REQUEST_TIMEOUT_SECONDS`
+	// and defaults to 30 seconds. Zero means requests have no time limit.
+	RequestTimeout time.Duration
+	// MaxBodySize is the number of bytes allowed in a request body. It is
+	// read from `LFG_MAX_BODY_SIZE` and defaults to 1 MiB.
+	MaxBodySize int64
+	// MaxQueryLength is the number of bytes allowed in a query string. It is
+	// read from `LFG_MAX_QUERY_LENGTH` and defaults to 2048.
+	MaxQueryLength int
+	// MetricsToken is the bearer token required on the metrics endpoint. It
+	// is read from `LFG_METRICS_TOKEN`. The metrics are public if it is
+	// empty.
+	MetricsToken string
+
+	// CORSAllowedOrigins are the origins browser clients can call the API
+	// from. They are read from `LFG_CORS_ALLOWED_ORIGINS`. A "*" allows any
+	// origin. CORS is disabled if it is empty.
+	CORSAllowedOrigins []string
+	// CORSAllowedMethods are the methods allowed on cross-origin requests.
+	// They are read from `LFG_CORS_ALLOWED_METHODS`.
+	CORSAllowedMethods []string
+	// CORSAllowedHeaders are the headers allowed on cross-origin requests.
+	// They are read from `LFG_CORS_ALLOWED_HEADERS`.
+	CORSAllowedHeaders []string
+	// CORSAllowCredentials allows cross-origin requests to include
+	// credentials. It is read from `LFG_CORS_ALLOW_CREDENTIALS`.
+	CORSAllowCredentials bool
+
+	// NosniffHeader adds `X-Content-Type-Options: nosniff` to the responses.
+	// It is read from `LFG_NOSNIFF_HEADER` and defaults to true.
+	NosniffHeader bool
+	// FrameDenyHeader adds `X-Frame-Options: DENY` to the responses. It is
+	// read from `LFG_FRAME_DENY_HEADER` and defaults to true.
+	FrameDenyHeader bool
+	// HSTSMaxAge is how long browsers only use HTTPS for the API after a
+	// response. It is read from `LFG_HSTS_MAX_AGE_SECONDS` and defaults to a
+	// year. The `Strict-Transport-Security` header is left out if it is
+	// zero.
+	HSTSMaxAge time.Duration
+	// HSTSIncludeSubdomains applies the `Strict-Transport-Security` header
+	// to the subdomains too. It is read from `LFG_HSTS_INCLUDE_SUBDOMAINS`.
+	HSTSIncludeSubdomains bool
+
+	// StaleGroupTTL is how long a group stays open after it was created, or
+	// after it started if it has a start time. It is read from
+	// `LFG_STALE_GROUP_TTL_HOURS`. Zero, the default, means groups are never
+	// closed automatically.
+	StaleGroupTTL time.Duration
+	// StaleGroupSweepInterval is how often the stale groups are closed. It
+	// is read from `LFG_STALE_GROUP_SWEEP_MINUTES` and defaults to 10
+	// minutes.
+	StaleGroupSweepInterval time.Duration
+
+	// MaxOwnedGroups is the number of open groups a user can own. It is read
+	// from `LFG_MAX_OWNED_GROUPS`. Zero, the default, means there is no
+	// limit.
+	MaxOwnedGroups int
+	// MaxJoinedGroups is the number of open groups a user can be a member
+	// of. It is read from `LFG_MAX_JOINED_GROUPS`. Zero, the default, means
+	// there is no limit.
+	MaxJoinedGroups int
+	// Games are the games a group can be for. They are read from
+	// `LFG_GAMES`. Any game is allowed if it is empty.
+	Games []string
+	// DuplicateTitleMode is what happens when an owner creates an open group
+	// with the same title as one of their open groups. It is read from
+	// `LFG_DUPLICATE_TITLE_MODE` and is either "reject", "warn", the
+	// default, or "off".
+	DuplicateTitleMode string
+	// GroupPasswordsEnabled allows owners to protect their groups with a
+	// password. It is read from `LFG_GROUP_PASSWORDS_ENABLED` and defaults
+	// to true.
+	GroupPasswordsEnabled bool
+	// DemoMode enables the endpoints used to seed demo data. It is read from
+	// `LFG_DEMO_MODE`.
+	DemoMode bool
+	// DemoClosedPercent is the percentage of groups closed when the group
+	// statuses are randomized. It is read from `LFG_DEMO_CLOSED_PERCENT` and
+	// defaults to 50.
+	DemoClosedPercent int
+
+	// ErrorFormat is the format of the error responses. It is read from
+	// `LFG_ERROR_FORMAT` and is either "json", the default, or "problem".
+	ErrorFormat string
+	// ProblemTypeBaseURL is prefixed to the error code to make the type of
+	// the problem details. It is read from `LFG_PROBLEM_TYPE_BASE_URL`.
+	ProblemTypeBaseURL string
+
+	// GroupsPageLimits are the page sizes of the group listings. They are
+	// read from `LFG_GROUPS_PAGE_SIZE` and `LFG_GROUPS_MAX_PAGE_SIZE` and
+	// default to 20 and 100.
+	GroupsPageLimits PageLimits
+	// TrendingLimits are the numbers of trending groups. They are read from
+	// `LFG_TRENDING_SIZE` and `LFG_TRENDING_MAX_SIZE` and default to 10 and
+	// 50.
+	TrendingLimits PageLimits
+	// MembersPageLimits are the page sizes of the member listing. They are
+	// read from `LFG_MEMBERS_PAGE_SIZE` and `LFG_MEMBERS_MAX_PAGE_SIZE` and
+	// default to 20 and 50.
+	MembersPageLimits PageLimits
+	// ActivityPageLimits are the page sizes of the activity feed. They are
+	// read from `LFG_ACTIVITY_PAGE_SIZE` and `LFG_ACTIVITY_MAX_PAGE_SIZE` and
+	// default to 20 and 100.
+	ActivityPageLimits PageLimits
+	// EventPageLimits are the page sizes of the audit log. They are read
+	// from `LFG_EVENT_PAGE_SIZE` and `LFG_EVENT_MAX_PAGE_SIZE` and default to
+	// 20 and 100.
+	EventPageLimits PageLimits
+	// HistoryPageLimits are the page sizes of the settings history. They are
+	// read from `LFG_HISTORY_PAGE_SIZE` and `LFG_HISTORY_MAX_PAGE_SIZE` and
+	// default to 20 and 100.
+	HistoryPageLimits PageLimits
+	// MaxBatchGroupIDs is the most groups retrieved at once with
+	// `GET /groups?ids=`. It is read from `LFG_MAX_BATCH_GROUP_IDS` and
+	// defaults to 50.
+	MaxBatchGroupIDs int
+	// MaxQueryValues is the number of values allowed in a multi-value query
+	// parameter. It is read from `LFG_MAX_QUERY_VALUES` and defaults to 100.
+	MaxQueryValues int
+
+	// SignInMaxAttempts is the number of failed sign ins before the user is
+	// locked out. It is read from `LFG_SIGN_IN_MAX_ATTEMPTS` and defaults to
+	// 5. Zero means users are never locked out.
+	SignInMaxAttempts int
+	// SignInLockout is how long a user is locked out. It is read from
+	// `LFG_SIGN_IN_LOCKOUT_MINUTES` and defaults to 15 minutes.
+	SignInLockout time.Duration
+	// GroupPasswordMaxAttempts is the number of wrong group passwords before
+	// the user is locked out of the group. It is read from
+	// `LFG_GROUP_PASSWORD_MAX_ATTEMPTS` and defaults to 5. Zero means the
+	// attempts are not limited.
+	GroupPasswordMaxAttempts int
+	// GroupPasswordLockout is how long a user cannot unlock a group. It is
+	// read from `LFG_GROUP_PASSWORD_LOCKOUT_MINUTES` and defaults to 15
+	// minutes.
+	GroupPasswordLockout time.Duration
+	// ReservedUsernames are the usernames that cannot be claimed. They are
+	// read from `LFG_RESERVED_USERNAMES` and default to the names of the
+	// staff and the API. The names in the file at
+	// `LFG_RESERVED_USERNAMES_FILE`, one per line, are added to them.
+	ReservedUsernames []string
+	// BlockCommonPasswords rejects common passwords on sign up. It is read
+	// from `LFG_BLOCK_COMMON_PASSWORDS` and defaults to true.
+	BlockCommonPasswords bool
+}
+
+// Load reads the config from the environment.
+func Load() (Config, error) {
+	return Parse(os.Getenv)
+}
+
+// Parse reads the config with the getenv function.
+//
+// An error is returned if a value cannot be parsed or if a required value
+// is missing.
+func Parse(getenv func(string) string) (Config, error) {
+	cfg := Config{
+		Addr:        getenv("LFG_ADDR"),
+		DBPath:      getenv("LFG_DB_PATH"),
+		TokenSecret: getenv("LFG_TOKEN_SECRET"),
+		LogLevel:    log.DebugLevel,
+	}
+	if cfg.Addr == "" {
+		cfg.Addr = "localhost:8080"
+	}
+	if cfg.DBPath == "" {
+		cfg.DBPath = "./lfg.db"
+	}
+
+	var err error
+	if cfg.DevMode, err = parseBool(getenv, "LFG_DEV_MODE", false); err != nil {
+		return cfg, err
+	}
+	if cfg.RequireJSONAccept, err = parseBool(
+		getenv, "LFG_REQUIRE_JSON_ACCEPT", false); err != nil {
+		return cfg, err
+	}
+	if err := parseServerLimits(getenv, &cfg); err != nil {
+		return cfg, err
+	}
+	if err := parseHeaders(getenv, &cfg); err != nil {
+		return cfg, err
+	}
+	if cfg.StaleGroupTTL, err = parseDuration(
+		getenv, "LFG_STALE_GROUP_TTL_HOURS", 0, time.Hour); err != nil {
+		return cfg, err
+	}
+	if cfg.StaleGroupSweepInterval, err = parseDuration(
+		getenv, "LFG_STALE_GROUP_SWEEP_MINUTES", 10, time.Minute); err != nil {
+		return cfg, err
+	}
+	if err := parseGroupSettings(getenv, &cfg); err != nil {
+		return cfg, err
+	}
+	if err := parseErrorFormat(getenv, &cfg); err != nil {
+		return cfg, err
+	}
+	if err := parsePageLimits(getenv, &cfg); err != nil {
+		return cfg, err
+	}
+	if err := parseSignInAndSignUp(getenv, &cfg); err != nil {
+		return cfg, err
+	}
+	if v := getenv("LFG_LOG_LEVEL"); v != "" {
+		if cfg.LogLevel, err = log.ParseLevel(v); err != nil {
+			return cfg, fmt.Errorf("could not parse LFG_LOG_LEVEL: %w", err)
+		}
+	}
+
+	if cfg.TokenSecret == "" && !cfg.DevMode {
+		return cfg, ErrTokenSecretRequired
+	} else if cfg.TokenSecret == "" {
+		log.Warn("LFG_TOKEN_SECRET is not set. Using the dev token secret")
+		cfg.TokenSecret = devTokenSecret
+	}
+	return cfg, nil
+}
+
+// parseServerLimits reads the limits of the requests.
+func parseServerLimits(getenv func(string) string, cfg *Config) error {
+	var err error
+	if cfg.RequestTimeout, err = parseDuration(
+		getenv, "LFG_REQUEST_TIMEOUT_SECONDS", 30, time.Second); err != nil {
+		return err
+	}
+	maxBodySize, err := parseInt(getenv, "LFG_MAX_BODY_SIZE", 1<<20)
+	if err != nil {
+		return err
+	}
+	cfg.MaxBodySize = int64(maxBodySize)
+	if cfg.MaxQueryLength, err = parseInt(
+		getenv, "LFG_MAX_QUERY_LENGTH", 2048); err != nil {
+		return err
+	}
+	cfg.MetricsToken = getenv("LFG_METRICS_TOKEN")
+	return nil
+}
+
+// parseHeaders reads the CORS and the security headers of the responses.
+func parseHeaders(getenv func(string) string, cfg *Config) error {
+	cfg.CORSAllowedOrigins = parseList(getenv, "LFG_CORS_ALLOWED_ORIGINS", nil)
+	cfg.CORSAllowedMethods = parseList(
+		getenv, "LFG_CORS_ALLOWED_METHODS",
+		[]string{"GET", "POST", "PATCH", "DELETE", "OPTIONS"})
+	cfg.CORSAllowedHeaders = parseList(
+		getenv, "LFG_CORS_ALLOWED_HEADERS",
+		[]string{"Authorization", "Content-Type", "Accept", "X-Group-Password"})
+
+	var err error
+	if cfg.CORSAllowCredentials, err = parseBool(
+		getenv, "LFG_CORS_ALLOW_CREDENTIALS", false); err != nil {
+		return err
+	}
+	if cfg.NosniffHeader, err = parseBool(
+		getenv, "LFG_NOSNIFF_HEADER", true); err != nil {
+		return err
+	}
+	if cfg.FrameDenyHeader, err = parseBool(
+		getenv, "LFG_FRAME_DENY_HEADER", true); err != nil {
+		return err
+	}
+	if cfg.HSTSMaxAge, err = parseDuration(
+		getenv, "LFG_HSTS_MAX_AGE_SECONDS", 31536000, time.Second); err != nil {
+		return err
+	}
+	cfg.HSTSIncludeSubdomains, err = parseBool(
+		getenv, "LFG_HSTS_INCLUDE_SUBDOMAINS", false)
+	return err
+}
+
+// parseGroupSettings reads the limits and the features of the groups.
+func parseGroupSettings(getenv func(string) string, cfg *Config) error {
+	var err error
+	if cfg.MaxOwnedGroups, err = parseCount(
+		getenv, "LFG_MAX_OWNED_GROUPS", 0); err != nil {
+		return err
+	}
+	if cfg.MaxJoinedGroups, err = parseCount(
+		getenv, "LFG_MAX_JOINED_GROUPS", 0); err != nil {
+		return err
+	}
+	cfg.Games = parseList(getenv, "LFG_GAMES", nil)
+	if cfg.DuplicateTitleMode, err = parseChoice(
+		getenv, "LFG_DUPLICATE_TITLE_MODE", "warn", "reject", "off"); err != nil {
+		return err
+	}
+	if cfg.GroupPasswordsEnabled, err = parseBool(
+		getenv, "LFG_GROUP_PASSWORDS_ENABLED", true); err != nil {
+		return err
+	}
+	if cfg.DemoMode, err = parseBool(getenv, "LFG_DEMO_MODE", false); err != nil {
+		return err
+	}
+	if cfg.DemoClosedPercent, err = parseCount(
+		getenv, "LFG_DEMO_CLOSED_PERCENT", 50); err != nil {
+		return err
+	}
+	if cfg.DemoClosedPercent > 100 {
+		return errors.New("LFG_DEMO_CLOSED_PERCENT cannot be more than 100")
+	}
+	return nil
+}
+
+// parseErrorFormat reads the format of the error responses.
+func parseErrorFormat(getenv func(string) string, cfg *Config) error {
+	var err error
+	cfg.ErrorFormat, err = parseChoice(
+		getenv, "LFG_ERROR_FORMAT", "json", "problem")
+	cfg.ProblemTypeBaseURL = getenv("LFG_PROBLEM_TYPE_BASE_URL")
+	return err
+}
+
+// parsePageLimits reads the page sizes of the listings.
+func parsePageLimits(getenv func(string) string, cfg *Config) error {
+	for _, l := range []struct {
+		limits *PageLimits
+		prefix string
+		def    PageLimits
+	}{
+		{&cfg.GroupsPageLimits, "LFG_GROUPS", PageLimits{20, 100}},
+		{&cfg.MembersPageLimits, "LFG_MEMBERS", PageLimits{20, 50}},
+		{&cfg.ActivityPageLimits, "LFG_ACTIVITY", PageLimits{20, 100}},
+		{&cfg.EventPageLimits, "LFG_EVENT", PageLimits{20, 100}},
+		{&cfg.HistoryPageLimits, "LFG_HISTORY", PageLimits{20, 100}},
+	} {
+		var err error
+		if *l.limits, err = parseLimits(
+			getenv, l.prefix+"_PAGE_SIZE", l.prefix+"_MAX_PAGE_SIZE",
+			l.def); err != nil {
+			return err
+		}
+	}
+
+	var err error
+	if cfg.TrendingLimits, err = parseLimits(
+		getenv, "LFG_TRENDING_SIZE", "LFG_TRENDING_MAX_SIZE",
+		PageLimits{10, 50}); err != nil {
+		return err
+	}
+	if cfg.MaxBatchGroupIDs, err = parseCount(
+		getenv, "LFG_MAX_BATCH_GROUP_IDS", 50); err != nil {
+		return err
+	}
+	cfg.MaxQueryValues, err = parseCount(getenv, "LFG_MAX_QUERY_VALUES", 100)
+	return err
+}
+
+// parseSignInAndSignUp reads the lockouts and the checks of the sign ups.
+func parseSignInAndSignUp(getenv func(string) string, cfg *Config) error {
+	var err error
+	if cfg.SignInMaxAttempts, err = parseCount(
+		getenv, "LFG_SIGN_IN_MAX_ATTEMPTS", 5); err != nil {
+		return err
+	}
+	if cfg.SignInLockout, err = parseDuration(
+		getenv, "LFG_SIGN_IN_LOCKOUT_MINUTES", 15, time.Minute); err != nil {
+		return err
+	}
+	if cfg.GroupPasswordMaxAttempts, err = parseCount(
+		getenv, "LFG_GROUP_PASSWORD_MAX_ATTEMPTS", 5); err != nil {
+		return err
+	}
+	if cfg.GroupPasswordLockout, err = parseDuration(
+		getenv, "LFG_GROUP_PASSWORD_LOCKOUT_MINUTES", 15, time.Minute); err != nil {
+		return err
+	}
+
+	cfg.ReservedUsernames = parseList(
+		getenv, "LFG_RESERVED_USERNAMES", defaultReservedUsernames)
+	reserved, err := parseFileList(getenv, "LFG_RESERVED_USERNAMES_FILE")
+	if err != nil {
+		return err
+	}
+	// The defaults are copied so they are not changed by the append.
+	cfg.ReservedUsernames = append(
+		append([]string{}, cfg.ReservedUsernames...), reserved...)
+	cfg.BlockCommonPasswords, err = parseBool(
+		getenv, "LFG_BLOCK_COMMON_PASSWORDS", true)
+	return err
+}
+
+// parseBool reads a boolean that has the default value if it is not set.
+func parseBool(getenv func(string) string, key string, def bool) (bool, error) {
+	v := getenv(key)
+	if v == "" {
+		return def, nil
+	}
+	b, err := strconv.ParseBool(v)
+	if err != nil {
+		return false, fmt.Errorf("could not parse %v: %w", key, err)
+	}
+	return b, nil
+}
+
+// parseInt reads an integer that has the default value if it is not set.
+func parseInt(getenv func(string) string, key string, def int) (int, error) {
+	v := getenv(key)
+	if v == "" {
+		return def, nil
+	}
+	i, err := strconv.Atoi(v)
+	if err != nil {
+		return 0, fmt.Errorf("could not parse %v: %w", key, err)
+	}
+	return i, nil
+}
+
+// parseCount reads an integer that cannot be negative and has the default
+// value if it is not set.
+func parseCount(getenv func(string) string, key string, def int) (int, error) {
+	i, err := parseInt(getenv, key, def)
+	if err == nil && i < 0 {
+		return 0, fmt.Errorf("%v cannot be negative", key)
+	}
+	return i, err
+}
+
+// parseLimits reads the default and the largest page size of a listing.
+//
+// The default page size is at least 1 and at most the largest page size.
+func parseLimits(
+	getenv func(string) string, defKey, maxKey string, def PageLimits,
+) (PageLimits, error) {
+	var l PageLimits
+	var err error
+	if l.Default, err = parseInt(getenv, defKey, def.Default); err != nil {
+		return l, err
+	}
+	if l.Max, err = parseInt(getenv, maxKey, def.Max); err != nil {
+		return l, err
+	}
+	if l.Default < 1 {
+		return l, fmt.Errorf("%v must be at least 1", defKey)
+	}
+	if l.Default > l.Max {
+		return l, fmt.Errorf("%v cannot be more than %v", defKey, maxKey)
+	}
+	return l, nil
+}
+
+// parseChoice reads a value that is one of the choices and has the default
+// value if it is not set.
+func parseChoice(
+	getenv func(string) string, key string, def string, choices ...string,
+) (string, error) {
+	v := getenv(key)
+	if v == "" || v == def {
+		return def, nil
+	}
+	for _, c := range choices {
+		if v == c {
+			return v, nil
+		}
+	}
+	return "", fmt.Errorf(
+		"%v should be one of: %v", key, strings.Join(append([]string{def}, choices...), ", "))
+}
+
+// parseDuration reads a number of units that has the default number if it
+// is not set.
+func parseDuration(
+	getenv func(string) string, key string, def int, unit time.Duration,
+) (time.Duration, error) {
+	n, err := parseCount(getenv, key, def)
+	return time.Duration(n) * unit, err
+}
+
+// parseList reads a comma separated list that has the default value if it
+// is not set.
+func parseList(getenv func(string) string, key string, def []string) []string {
+	v := getenv(key)
+	if v == "" {
+		return def
+	}
+	var l []string
+	for _, item := range strings.Split(v, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			l = append(l, item)
+		}
+	}
+	return l
+}
+
+// parseFileList reads a list from the file at the path in the variable.
+//
+// The file has one item per line. Blank lines and lines starting with "#"
+// are skipped. The list is empty if the variable is not set.
+func parseFileList(getenv func(string) string, key string) ([]string, error) {
+	path := getenv(key)
+	if path == "" {
+		return nil, nil
+	}
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("could not read %v: %w", key, err)
+	}
+	var l []string
+	for _, line := range strings.Split(string(b), "\n") {
+		if line = strings.TrimSpace(line); line != "" && !strings.HasPrefix(line, "#") {
+			l = append(l, line)
+		}
+	}
+	return l, nil
+}
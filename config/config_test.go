@@ -0,0 +1,203 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// env returns a getenv function reading the variables.
+func env(vars map[string]string) func(string) string {
+	return func(key string) string { return vars[key] }
+}
+
+func TestParseDefaults(t *testing.T) {
+	cfg, err := Parse(env(map[string]string{"LFG_TOKEN_SECRET": "secret"}))
+	if err != nil {
+		t.Fatalf("could not parse the config: %v", err)
+	}
+	want := Config{
+		Addr:                     "localhost:8080",
+		DBPath:                   "./lfg.db",
+		TokenSecret:              "secret",
+		LogLevel:                 cfg.LogLevel,
+		RequestTimeout:           30 * time.Second,
+		MaxBodySize:              1 << 20,
+		MaxQueryLength:           2048,
+		CORSAllowedMethods:       []string{"GET", "POST", "PATCH", "DELETE", "OPTIONS"},
+		CORSAllowedHeaders:       []string{"Authorization", "Content-Type", "Accept", "X-Group-Password"},
+		NosniffHeader:            true,
+		FrameDenyHeader:          true,
+		HSTSMaxAge:               365 * 24 * time.Hour,
+		StaleGroupSweepInterval:  10 * time.Minute,
+		DuplicateTitleMode:       "warn",
+		GroupPasswordsEnabled:    true,
+		DemoClosedPercent:        50,
+		ErrorFormat:              "json",
+		GroupsPageLimits:         PageLimits{20, 100},
+		TrendingLimits:           PageLimits{10, 50},
+		MembersPageLimits:        PageLimits{20, 50},
+		ActivityPageLimits:       PageLimits{20, 100},
+		EventPageLimits:          PageLimits{20, 100},
+		HistoryPageLimits:        PageLimits{20, 100},
+		MaxBatchGroupIDs:         50,
+		MaxQueryValues:           100,
+		SignInMaxAttempts:        5,
+		SignInLockout:            15 * time.Minute,
+		GroupPasswordMaxAttempts: 5,
+		GroupPasswordLockout:     15 * time.Minute,
+		ReservedUsernames:        defaultReservedUsernames,
+		BlockCommonPasswords:     true,
+	}
+	if !reflect.DeepEqual(cfg, want) {
+		t.Errorf("got %+v, want %+v", cfg, want)
+	}
+}
+
+func TestParseValues(t *testing.T) {
+	cfg, err := Parse(env(map[string]string{
+		"LFG_TOKEN_SECRET":          "secret",
+		"LFG_ADDR":                  ":9000",
+		"LFG_LOG_LEVEL":             "warn",
+		"LFG_CORS_ALLOWED_ORIGINS":  " https://a.example , https://b.example ",
+		"LFG_HSTS_MAX_AGE_SECONDS":  "0",
+		"LFG_STALE_GROUP_TTL_HOURS": "48",
+		"LFG_NOSNIFF_HEADER":        "false",
+	}))
+	if err != nil {
+		t.Fatalf("could not parse the config: %v", err)
+	}
+	if cfg.Addr != ":9000" {
+		t.Errorf("got addr %q", cfg.Addr)
+	}
+	if cfg.LogLevel.String() != "warning" {
+		t.Errorf("got log level %v", cfg.LogLevel)
+	}
+	origins := []string{"https://a.example", "https://b.example"}
+	if !reflect.DeepEqual(cfg.CORSAllowedOrigins, origins) {
+		t.Errorf("got origins %q, want %q", cfg.CORSAllowedOrigins, origins)
+	}
+	if cfg.HSTSMaxAge != 0 || cfg.NosniffHeader {
+		t.Errorf("got HSTS max age %v and nosniff %v", cfg.HSTSMaxAge, cfg.NosniffHeader)
+	}
+	if cfg.StaleGroupTTL != 48*time.Hour {
+		t.Errorf("got stale group TTL %v", cfg.StaleGroupTTL)
+	}
+}
+
+func TestParseInvalidValues(t *testing.T) {
+	for _, key := range []string{
+		"LFG_DEV_MODE", "LFG_LOG_LEVEL", "LFG_MAX_BODY_SIZE",
+		"LFG_HSTS_INCLUDE_SUBDOMAINS", "LFG_STALE_GROUP_TTL_HOURS",
+		"LFG_MAX_OWNED_GROUPS", "LFG_DUPLICATE_TITLE_MODE",
+		"LFG_GROUP_PASSWORDS_ENABLED", "LFG_ERROR_FORMAT",
+		"LFG_GROUPS_PAGE_SIZE", "LFG_TRENDING_MAX_SIZE",
+		"LFG_SIGN_IN_LOCKOUT_MINUTES", "LFG_BLOCK_COMMON_PASSWORDS",
+	} {
+		_, err := Parse(env(map[string]string{
+			"LFG_TOKEN_SECRET": "secret", key: "not-a-value"}))
+		if err == nil {
+			t.Errorf("got no error for an invalid %v", key)
+		}
+	}
+}
+
+func TestParseTokenSecret(t *testing.T) {
+	if _, err := Parse(env(nil)); !errors.Is(err, ErrTokenSecretRequired) {
+		t.Errorf("got %v without a secret, want ErrTokenSecretRequired", err)
+	}
+
+	cfg, err := Parse(env(map[string]string{"LFG_DEV_MODE": "true"}))
+	if err != nil {
+		t.Fatalf("could not parse the config in dev mode: %v", err)
+	}
+	if cfg.TokenSecret != devTokenSecret {
+		t.Error("got no dev token secret in dev mode")
+	}
+}
+
+func TestParseAddrAndLogLevel(t *testing.T) {
+	tests := []struct {
+		vars  map[string]string
+		addr  string
+		level log.Level
+	}{
+		{map[string]string{}, "localhost:8080", log.DebugLevel},
+		{map[string]string{"LFG_ADDR": "0.0.0.0:9000"}, "0.0.0.0:9000", log.DebugLevel},
+		{map[string]string{"LFG_ADDR": ":8081", "LFG_LOG_LEVEL": "info"}, ":8081", log.InfoLevel},
+		{map[string]string{"LFG_LOG_LEVEL": "error"}, "localhost:8080", log.ErrorLevel},
+	}
+	for _, tt := range tests {
+		tt.vars["LFG_TOKEN_SECRET"] = "secret"
+		cfg, err := Parse(env(tt.vars))
+		if err != nil {
+			t.Fatalf("could not parse %v: %v", tt.vars, err)
+		}
+		if cfg.Addr != tt.addr {
+			t.Errorf("got addr %q for %v, want %q", cfg.Addr, tt.vars, tt.addr)
+		}
+		if cfg.LogLevel != tt.level {
+			t.Errorf("got log level %v for %v, want %v", cfg.LogLevel, tt.vars, tt.level)
+		}
+	}
+}
+
+func TestParseFeatureSettings(t *testing.T) {
+	reserved := filepath.Join(t.TempDir(), "reserved.txt")
+	if err := os.WriteFile(
+		reserved, []byte("# Staff\nalice\n\n bob \n"), 0o600); err != nil {
+		t.Fatalf("could not write the reserved usernames: %v", err)
+	}
+	cfg, err := Parse(env(map[string]string{
+		"LFG_TOKEN_SECRET":            "secret",
+		"LFG_MAX_JOINED_GROUPS":       "3",
+		"LFG_GAMES":                   "Chess, Go",
+		"LFG_DUPLICATE_TITLE_MODE":    "reject",
+		"LFG_ERROR_FORMAT":            "problem",
+		"LFG_MEMBERS_PAGE_SIZE":       "50",
+		"LFG_MEMBERS_MAX_PAGE_SIZE":   "200",
+		"LFG_GROUP_PASSWORDS_ENABLED": "false",
+		"LFG_RESERVED_USERNAMES":      "root",
+		"LFG_RESERVED_USERNAMES_FILE": reserved,
+	}))
+	if err != nil {
+		t.Fatalf("could not parse the config: %v", err)
+	}
+	if cfg.MaxJoinedGroups != 3 || !reflect.DeepEqual(cfg.Games, []string{"Chess", "Go"}) {
+		t.Errorf("got max joined groups %v and games %q", cfg.MaxJoinedGroups, cfg.Games)
+	}
+	if cfg.DuplicateTitleMode != "reject" || cfg.ErrorFormat != "problem" {
+		t.Errorf("got duplicate title mode %q and error format %q",
+			cfg.DuplicateTitleMode, cfg.ErrorFormat)
+	}
+	if cfg.MembersPageLimits != (PageLimits{50, 200}) || cfg.GroupPasswordsEnabled {
+		t.Errorf("got member page limits %v and group passwords %v",
+			cfg.MembersPageLimits, cfg.GroupPasswordsEnabled)
+	}
+	if want := []string{"root", "alice", "bob"}; !reflect.DeepEqual(cfg.ReservedUsernames, want) {
+		t.Errorf("got reserved usernames %q, want %q", cfg.ReservedUsernames, want)
+	}
+}
+
+func TestParseRejectsOutOfRangeSettings(t *testing.T) {
+	for _, vars := range []map[string]string{
+		{"LFG_MAX_OWNED_GROUPS": "-1"},
+		{"LFG_SIGN_IN_MAX_ATTEMPTS": "-5"},
+		{"LFG_GROUP_PASSWORD_LOCKOUT_MINUTES": "-1"},
+		{"LFG_DEMO_CLOSED_PERCENT": "101"},
+		{"LFG_GROUPS_PAGE_SIZE": "0"},
+		{"LFG_EVENT_PAGE_SIZE": "200"},
+		{"LFG_HISTORY_PAGE_SIZE": "30", "LFG_HISTORY_MAX_PAGE_SIZE": "10"},
+		{"LFG_RESERVED_USERNAMES_FILE": filepath.Join(t.TempDir(), "missing.txt")},
+	} {
+		vars["LFG_TOKEN_SECRET"] = "secret"
+		if _, err := Parse(env(vars)); err == nil {
+			t.Errorf("got no error for %v", vars)
+		}
+	}
+}
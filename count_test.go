@@ -0,0 +1,110 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/damascopaul/lfg-backend/endpoints"
+)
+
+// countGroups counts the groups with the query string.
+func countGroups(t *testing.T, u testUser, query string) int64 {
+	t.Helper()
+	w := apiRequest{
+		Method: http.MethodGet, Path: "/groups/count?" + query, Token: u.Token,
+	}.send(t)
+	expectStatus(t, w, http.StatusOK)
+	var resp struct {
+		Count int64 `json:"count"`
+	}
+	decode(t, w, &resp)
+	return resp.Count
+}
+
+func TestCountGroupsMatchesListing(t *testing.T) {
+	owner := signUp(t)
+	createGroup(t, owner, map[string]interface{}{
+		"title": "Hyacinth raid", "category": "Raids",
+	})
+	full := createGroup(t, owner, map[string]interface{}{
+		"title": "Hyacinth full raid", "category": "raids",
+	})
+	for i := 0; i < 4; i++ {
+		joinGroup(t, signUp(t), full)
+	}
+	closed := createGroup(t, owner, map[string]interface{}{
+		"title": "Hyacinth casual", "category": "casual",
+	})
+	expectStatus(t, apiRequest{
+		Method: http.MethodPost, Path: groupPath(closed, "/close"),
+		Token: owner.Token,
+	}.send(t), http.StatusOK)
+
+	for _, tc := range []struct {
+		query string
+		want  int64
+	}{
+		{"q=hyacinth", 3},
+		{"q=hyacinth&category=raids", 2},
+		{"q=hyacinth&category=RAIDS&joinable=true", 1},
+		{"q=hyacinth&joinable=true", 1},
+		{"q=hyacinth&joinable=false", 2},
+		{"q=hyacinth&status=0", 2},
+		{"q=hyacinth&status=-100&category=casual", 1},
+		{"q=hyacinth&category=pvp", 0},
+	} {
+		t.Run(tc.query, func(t *testing.T) {
+			count := countGroups(t, owner, tc.query)
+			listed := listGroupIDs(t, owner, tc.query+"&page_size=100")
+			if count != tc.want || int64(len(listed)) != count {
+				t.Errorf("got count %v and %v listed, want %v",
+					count, len(listed), tc.want)
+			}
+		})
+	}
+}
+
+func TestCountGroupsUsesTheListingFilters(t *testing.T) {
+	defer func(games []string) { endpoints.GAMES = games }(endpoints.GAMES)
+	endpoints.GAMES = nil
+
+	owner := signUp(t)
+	createGroup(t, owner, map[string]interface{}{
+		"title": "Snapdragon ranked", "game": "Valheim", "tags": []string{"ranked"}})
+	createGroup(t, owner, map[string]interface{}{
+		"title": "Snapdragon casual", "game": "Valheim", "tags": []string{"casual"}})
+	createGroup(t, owner, map[string]interface{}{
+		"title": "Snapdragon other", "game": "Dota 2", "tags": []string{"ranked"}})
+	// Hidden groups are not counted, like they are not listed.
+	createGroup(t, owner, map[string]interface{}{
+		"title": "Snapdragon unlisted", "visibility": "unlisted"})
+	deleted := createGroup(t, owner, map[string]interface{}{"title": "Snapdragon deleted"})
+	expectStatus(t, apiRequest{
+		Method: http.MethodDelete, Path: groupPath(deleted, ""), Token: owner.Token,
+	}.send(t), http.StatusNoContent)
+
+	for _, tc := range []struct {
+		query string
+		want  int64
+	}{
+		{"q=snapdragon", 3},
+		{"q=snapdragon&game=valheim", 2},
+		{"q=snapdragon&tags=ranked", 2},
+		{"q=snapdragon&game=valheim&tags=ranked", 1},
+		{"q=snapdragon&status=0&tags=casual", 1},
+	} {
+		t.Run(tc.query, func(t *testing.T) {
+			count := countGroups(t, owner, tc.query)
+			listed := listGroupIDs(t, owner, tc.query+"&page_size=100")
+			if count != tc.want || int64(len(listed)) != count {
+				t.Errorf("got count %v and %v listed, want %v",
+					count, len(listed), tc.want)
+			}
+		})
+	}
+
+	// The filters are checked like on the listing.
+	expectStatus(t, apiRequest{
+		Method: http.MethodGet, Path: "/groups/count?status=open", Token: owner.Token,
+	}.send(t), http.StatusBadRequest)
+}
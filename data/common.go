@@ -2,6 +2,10 @@ package data
 
 import (
 	"fmt"
+	"sync"
+	"time"
+
+	"github.com/damascopaul/lfg-backend/config"
 
 	log "github.com/sirupsen/logrus"
 	"gorm.io/driver/sqlite"
@@ -10,14 +14,82 @@ import (
 
 const databaseName = "lfg"
 
-// CreateConnection creates the database connection object.
+// memoryPath is the database path of an in-memory database.
+const memoryPath = ":memory:"
+
+var (
+	sharedDB   *gorm.DB
+	sharedDBMu sync.Mutex
+)
+
+// databaseFile is the path of the SQLite database file.
+var databaseFile = fmt.Sprintf("./%s.db", databaseName)
+
+// Configure applies the config of the server to the database connections.
+//
+// It is called before the first connection is created.
+func Configure(cfg config.Config) {
+	databaseFile = cfg.DBPath
+}
+
+// gormConfig returns the config of the database connections.
+//
+// The times set by GORM, like the creation times, are stored in UTC so they
+// compare the same way as the UTC times of the queries.
+func gormConfig() *gorm.Config {
+	return &gorm.Config{NowFunc: func() time.Time { return time.Now().UTC() }}
+}
+
+// CreateConnection returns the database connection object.
+//
+// A single connection object, and so a single connection pool, is shared by
+// all the callers until Close is called. An in-memory database only lives as
+// long as its connections, so it is also kept for the whole process.
 func CreateConnection() (*gorm.DB, error) {
-	databaseFile := fmt.Sprintf("./%s.db", databaseName)
-	db, err := gorm.Open(sqlite.Open(databaseFile), &gorm.Config{})
+	sharedDBMu.Lock()
+	defer sharedDBMu.Unlock()
+	if sharedDB != nil {
+		return sharedDB, nil
+	}
+
+	dsn := databaseFile
+	if dsn == memoryPath {
+		dsn = "file::memory:?cache=shared"
+	}
+	db, err := gorm.Open(sqlite.Open(dsn), gormConfig())
 	if err != nil {
-		log.Fatalf("Could not open SQL database. Error: %v", err)
+		log.Errorf("Could not open SQL database. Error: %v", err)
 		return nil, err
 	}
+	sharedDB = db
 	log.Info("Created database connection sucessfully")
 	return db, nil
 }
+
+// Ping checks if the database of the connection object is reachable.
+func Ping(db *gorm.DB) error {
+	sqlDB, err := db.DB()
+	if err != nil {
+		return err
+	}
+	return sqlDB.Ping()
+}
+
+// Close closes the shared connection object.
+//
+// It is called once the server stopped handling requests. The next call to
+// CreateConnection opens a new connection object.
+func Close() error {
+	sharedDBMu.Lock()
+	defer sharedDBMu.Unlock()
+	if sharedDB == nil {
+		return nil
+	}
+
+	sqlDB, err := sharedDB.DB()
+	if err != nil {
+		return err
+	}
+	sharedDB = nil
+	return sqlDB.Close()
+}
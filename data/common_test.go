@@ -0,0 +1,90 @@
+package data
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/damascopaul/lfg-backend/config"
+)
+
+func TestCreateConnectionUsesDBPath(t *testing.T) {
+	defer func(path string) { databaseFile = path }(databaseFile)
+	path := filepath.Join(t.TempDir(), "test.db")
+	cfg, err := config.Parse(func(key string) string {
+		return map[string]string{
+			"LFG_DB_PATH":      path,
+			"LFG_TOKEN_SECRET": "secret",
+		}[key]
+	})
+	if err != nil {
+		t.Fatalf("could not parse the config: %v", err)
+	}
+	Configure(cfg)
+	defer Close()
+
+	db, err := CreateConnection()
+	if err != nil {
+		t.Fatalf("could not create the connection: %v", err)
+	}
+	if err := db.Exec("CREATE TABLE things (id INTEGER)").Error; err != nil {
+		t.Fatalf("could not create a table: %v", err)
+	}
+	if _, err := os.Stat(path); err != nil {
+		t.Errorf("got no database file at %v: %v", path, err)
+	}
+}
+
+func TestCreateConnectionSharesMemoryDB(t *testing.T) {
+	defer func(path string) { databaseFile = path }(databaseFile)
+	Configure(config.Config{DBPath: memoryPath})
+	defer Close()
+
+	first, err := CreateConnection()
+	if err != nil {
+		t.Fatalf("could not create the connection: %v", err)
+	}
+	if err := first.Exec("CREATE TABLE things (id INTEGER)").Error; err != nil {
+		t.Fatalf("could not create a table: %v", err)
+	}
+	second, err := CreateConnection()
+	if err != nil {
+		t.Fatalf("could not create the connection: %v", err)
+	}
+	// The table is seen by the second caller since the database is shared.
+	if err := second.Exec("INSERT INTO things (id) VALUES (1)").Error; err != nil {
+		t.Errorf("could not use the table from another caller: %v", err)
+	}
+}
+
+func TestCloseClosesSharedConnection(t *testing.T) {
+	defer func(path string) { databaseFile = path }(databaseFile)
+	Configure(config.Config{DBPath: filepath.Join(t.TempDir(), "test.db")})
+	defer Close()
+
+	first, err := CreateConnection()
+	if err != nil {
+		t.Fatalf("could not create the connection: %v", err)
+	}
+	second, err := CreateConnection()
+	if err != nil {
+		t.Fatalf("could not create the connection: %v", err)
+	}
+	if first != second {
+		t.Error("got a new connection object for a database file")
+	}
+
+	if err := Close(); err != nil {
+		t.Fatalf("could not close the connection: %v", err)
+	}
+	if err := Ping(first); err == nil {
+		t.Error("got no error pinging a closed connection")
+	}
+	third, err := CreateConnection()
+	if err != nil {
+		t.Fatalf("could not create the connection: %v", err)
+	}
+	if third == first || Ping(third) != nil {
+		t.Error("got no new usable connection after closing")
+	}
+}
@@ -0,0 +1,180 @@
+package endpoints
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/damascopaul/lfg-backend/schemas"
+
+	"github.com/gin-gonic/gin"
+	log "github.com/sirupsen/logrus"
+)
+
+// recordActivity adds an entry to the activity feed of the group and pushes
+// it to the clients watching the group. The clients of a user who left or was
+// kicked stop watching the group.
+//
+// A failure is only logged since the activity itself already happened.
+// targetID is zero if the activity does not affect another user.
+func recordActivity(g schemas.Group, kind string, actorID int64, targetID int64) {
+	recordActivityWithReason(g, kind, actorID, targetID, "")
+}
+
+// recordActivityWithReason records the activity like recordActivity together
+// with the reason given by the actor.
+func recordActivityWithReason(
+	g schemas.Group, kind string, actorID int64, targetID int64, reason string,
+) {
+	a := schemas.GroupActivity{
+		GroupID: g.ID, Type: kind, ActorID: actorID, Reason: reason, DB: g.DB}
+	if targetID != 0 {
+		a.TargetID = &targetID
+	}
+	if err := a.Create(); err != nil {
+		log.WithFields(log.Fields{
+			"group_id": g.ID,
+			"type":     kind,
+		}).Warn("Could not record group activity")
+	}
+	recordEvent(g, kind, actorID)
+	watchers.publish(schemas.GroupUpdate{
+		Type: kind, GroupID: g.ID, ActorID: actorID, TargetID: a.TargetID,
+		At: time.Now().UTC()})
+	switch kind {
+	case schemas.ActivityLeft:
+		watchers.drop(g.ID, actorID)
+	case schemas.ActivityKicked:
+		watchers.drop(g.ID, targetID)
+	}
+}
+
+// activityEvents are the events of the audit log recorded for the
+// activities. A join request that is approved is a join by the owner.
+var activityEvents = map[string]string{
+	schemas.ActivityCreated:  schemas.EventCreated,
+	schemas.ActivityUpdated:  schemas.EventUpdated,
+	schemas.ActivityJoined:   schemas.EventJoined,
+	schemas.ActivityApproved: schemas.EventJoined,
+	schemas.ActivityLeft:     schemas.EventLeft,
+	schemas.ActivityKicked:   schemas.EventKicked,
+	schemas.ActivityClosed:   schemas.EventClosed,
+	schemas.ActivityReopened: schemas.EventReopened,
+}
+
+// recordEvent adds the event of the activity to the audit log of the group.
+//
+// Like the activity, the action already succeeded so a failure is only
+// logged.
+func recordEvent(g schemas.Group, kind string, actorID int64) {
+	eventType, ok := activityEvents[kind]
+	if !ok {
+		return
+	}
+	e := schemas.GroupEvent{
+		GroupID: g.ID, Type: eventType, ActorID: actorID, DB: g.DB}
+	if err := e.Create(); err != nil {
+		log.WithFields(log.Fields{
+			"group_id": g.ID,
+			"type":     eventType,
+		}).Warn("Could not record group event")
+	}
+}
+
+// ListGroupEvents returns a page of the audit log of a group.
+func ListGroupEvents(c *gin.Context) {
+	g, _ := c.Keys["obj"].(schemas.Group)
+
+	var p schemas.Pagination
+	if err := c.ShouldBindQuery(&p); err != nil {
+		// Return a 400 error if the query parameters are not valid.
+		requestLog(c).WithFields(log.Fields{
+			"endpoint": "ListGroupEvents",
+			"error":    err.Error(),
+		}).Warn("Request failed")
+		AbortWithBodyError(
+			c, http.StatusBadRequest,
+			schemas.BodyError{
+				Code:    "invalid_query",
+				Message: "Query parameters are invalid",
+			})
+		return
+	}
+
+	p.Limits = EVENT_PAGE_LIMITS
+	events, err := g.ListEvents(p)
+	if err != nil {
+		AbortWithBodyError(
+			c, http.StatusInternalServerError, BodyInternalServerError)
+		return
+	}
+
+	c.JSON(http.StatusOK, events)
+	requestLog(c).WithFields(
+		log.Fields{"endpoint": "ListGroupEvents"}).Info("Request successful")
+}
+
+// ListGroupActivities returns a page of the activity feed of a group.
+func ListGroupActivities(c *gin.Context) {
+	g, _ := c.Keys["obj"].(schemas.Group)
+
+	var p schemas.Pagination
+	if err := c.ShouldBindQuery(&p); err != nil {
+		// Return a 400 error if the query parameters are not valid.
+		requestLog(c).WithFields(log.Fields{
+			"endpoint": "ListGroupActivities",
+			"error":    err.Error(),
+		}).Warn("Request failed")
+		AbortWithBodyError(
+			c, http.StatusBadRequest,
+			schemas.BodyError{
+				Code:    "invalid_query",
+				Message: "Query parameters are invalid",
+			})
+		return
+	}
+
+	p.Limits = ACTIVITY_PAGE_LIMITS
+	activities, err := g.ListActivities(p)
+	if err != nil {
+		AbortWithBodyError(
+			c, http.StatusInternalServerError, BodyInternalServerError)
+		return
+	}
+
+	c.JSON(http.StatusOK, activities)
+	requestLog(c).WithFields(
+		log.Fields{"endpoint": "ListGroupActivities"}).Info("Request successful")
+}
+
+// ListGroupSettingsHistory returns a page of the settings history of a group.
+func ListGroupSettingsHistory(c *gin.Context) {
+	g, _ := c.Keys["obj"].(schemas.Group)
+
+	var p schemas.Pagination
+	if err := c.ShouldBindQuery(&p); err != nil {
+		// Return a 400 error if the query parameters are not valid.
+		requestLog(c).WithFields(log.Fields{
+			"endpoint": "ListGroupSettingsHistory",
+			"error":    err.Error(),
+		}).Warn("Request failed")
+		AbortWithBodyError(
+			c, http.StatusBadRequest,
+			schemas.BodyError{
+				Code:    "invalid_query",
+				Message: "Query parameters are invalid",
+			})
+		return
+	}
+
+	p.Limits = HISTORY_PAGE_LIMITS
+	history, err := g.ListSettingsHistory(p)
+	if err != nil {
+		AbortWithBodyError(
+			c, http.StatusInternalServerError, BodyInternalServerError)
+		return
+	}
+
+	c.JSON(http.StatusOK, history)
+	requestLog(c).WithFields(
+		log.Fields{"endpoint": "ListGroupSettingsHistory"}).Info("Request successful")
+}
@@ -0,0 +1,32 @@
+package endpoints
+
+import (
+	"net/http"
+
+	"github.com/damascopaul/lfg-backend/schemas"
+
+	"github.com/gin-gonic/gin"
+	log "github.com/sirupsen/logrus"
+)
+
+// RandomizeGroupStatuses opens and closes the groups at random to produce
+// varied demo data.
+func RandomizeGroupStatuses(c *gin.Context) {
+	g := schemas.Group{}
+	if err := g.InitDB(c.Request.Context()); err != nil {
+		AbortWithBodyError(
+			c, http.StatusInternalServerError, BodyInternalServerError)
+		return
+	}
+
+	dist, err := g.RandomizeStatuses(DEMO_CLOSED_PERCENT)
+	if err != nil {
+		AbortWithBodyError(
+			c, http.StatusInternalServerError, BodyInternalServerError)
+		return
+	}
+
+	c.JSON(http.StatusOK, dist)
+	requestLog(c).WithFields(
+		log.Fields{"endpoint": "RandomizeGroupStatuses"}).Info("Request successful")
+}
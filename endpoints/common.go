@@ -1,10 +1,211 @@
 package endpoints
 
-import "github.com/damascopaul/lfg-backend/schemas"
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/damascopaul/lfg-backend/logging"
+	"github.com/damascopaul/lfg-backend/schemas"
+
+	"github.com/gin-gonic/gin"
+	log "github.com/sirupsen/logrus"
+)
 
 var (
 	BodyInternalServerError = schemas.BodyError{
+		Code:    "internal_error",
 		Message: "An internal error occurred in the server"}
 	BodyNotFound = schemas.BodyError{
+		Code:    "not_found",
 		Message: "The requested resource could not be found"}
 )
+
+// requestLog returns the logger of the request. Its logs carry the ID of the
+// request.
+func requestLog(c *gin.Context) *log.Entry {
+	return logging.FromContext(c.Request.Context())
+}
+
+// parseIDs parses a comma-separated list of database IDs.
+func parseIDs(s string) ([]int64, error) {
+	ids := []int64{}
+	for _, v := range strings.Split(s, ",") {
+		if v = strings.TrimSpace(v); v == "" {
+			continue
+		}
+		id, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+// problemJSONType is the media type of RFC 7807 problem details.
+const problemJSONType = "application/problem+json"
+
+// accepts checks if the client asked for the media type in the Accept
+// header.
+func accepts(c *gin.Context, mediaType string) bool {
+	for _, r := range strings.Split(c.GetHeader("Accept"), ",") {
+		mt, _, err := mime.ParseMediaType(strings.TrimSpace(r))
+		if err == nil && mt == mediaType {
+			return true
+		}
+	}
+	return false
+}
+
+// language returns the supported language the client prefers in the
+// Accept-Language header.
+//
+// Only the primary language of the tags is used. English is returned if the
+// client prefers no supported language.
+func language(c *gin.Context) string {
+	lang, best := schemas.DefaultLanguage, 0.0
+	for _, r := range strings.Split(c.GetHeader("Accept-Language"), ",") {
+		tag, params, _ := strings.Cut(strings.TrimSpace(r), ";")
+		tag, _, _ = strings.Cut(strings.ToLower(tag), "-")
+		q := 1.0
+		if v := strings.TrimSpace(params); strings.HasPrefix(v, "q=") {
+			if f, err := strconv.ParseFloat(v[len("q="):], 64); err == nil {
+				q = f
+			}
+		}
+		if q > best && schemas.IsSupportedLanguage(tag) {
+			lang, best = tag, q
+		}
+	}
+	return lang
+}
+
+// ErrorBody returns the body of an error response in the format and the
+// language used by the client.
+//
+// Errors are returned as problem details if ERROR_FORMAT is "problem" or if
+// the client accepts `application/problem+json`. The content type is set on
+// the response in that case.
+func ErrorBody(c *gin.Context, status int, body schemas.BodyError) interface{} {
+	lang := language(c)
+	body = body.Localize(lang)
+	c.Header("Content-Language", lang)
+
+	if ERROR_FORMAT != "problem" && !accepts(c, problemJSONType) {
+		return body
+	}
+
+	problemType := "about:blank"
+	if PROBLEM_TYPE_BASE_URL != "" && body.Code != "" {
+		problemType = PROBLEM_TYPE_BASE_URL + body.Code
+	}
+	c.Header("Content-Type", problemJSONType)
+	return schemas.Problem{
+		Type:        problemType,
+		Title:       http.StatusText(status),
+		Status:      status,
+		Detail:      body.Message,
+		Instance:    c.Request.URL.Path,
+		Code:        body.Code,
+		FieldErrors: body.FieldErrors,
+	}
+}
+
+// AbortWithBodyError aborts the request with an error response.
+//
+// All the error responses go through it so they share the same format.
+func AbortWithBodyError(c *gin.Context, status int, body schemas.BodyError) {
+	c.AbortWithStatusJSON(status, ErrorBody(c, status, body))
+}
+
+// AbortBodyTooLarge returns the response for a request body over
+// MAX_BODY_SIZE.
+func AbortBodyTooLarge(c *gin.Context) {
+	requestLog(c).WithFields(log.Fields{
+		"details": "Request denied because the body is too large",
+		"limit":   MAX_BODY_SIZE,
+	}).Info("Request too large")
+	AbortWithBodyError(
+		c, http.StatusRequestEntityTooLarge,
+		schemas.BodyError{
+			Code:    "body_too_large",
+			Message: "Request body is too large",
+		})
+}
+
+// jsonTypeName returns the name of the JSON type a Go type is read from.
+func jsonTypeName(t reflect.Type) string {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t == reflect.TypeOf(time.Time{}) {
+		return "string"
+	}
+	switch t.Kind() {
+	case reflect.Bool:
+		return "boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32,
+		reflect.Uint64, reflect.Float32, reflect.Float64:
+		return "number"
+	case reflect.String:
+		return "string"
+	case reflect.Slice, reflect.Array:
+		return "list"
+	default:
+		return "object"
+	}
+}
+
+// AbortWithBindError returns the response matching why the JSON request body
+// could not be read.
+//
+// Bodies that are empty, not valid JSON, or have values of the wrong type are
+// client errors. Anything else is an internal error.
+func AbortWithBindError(c *gin.Context, err error) {
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	var timeErr *time.ParseError
+	var sizeErr *http.MaxBytesError
+	body := schemas.BodyError{}
+	switch {
+	case errors.As(err, &sizeErr):
+		AbortBodyTooLarge(c)
+		return
+	case errors.Is(err, io.EOF):
+		body.Code, body.Message = "empty_body", "The request body is empty"
+	case errors.As(err, &syntaxErr), errors.Is(err, io.ErrUnexpectedEOF):
+		body.Code, body.Message = "malformed_json", "The request body is not valid JSON"
+	case errors.As(err, &typeErr):
+		body.Code, body.Message = "invalid_type", "The request body contains errors"
+		body.FieldErrors = []schemas.FieldError{{
+			Name:   typeErr.Field,
+			ID:     "invalid_type",
+			Params: []interface{}{jsonTypeName(typeErr.Type)},
+			Error:  "This field should be a " + jsonTypeName(typeErr.Type),
+		}}
+	case errors.As(err, &timeErr):
+		body.Code, body.Message = "invalid_time",
+			"Times in the request body should be in the RFC 3339 format"
+	default:
+		requestLog(c).WithFields(log.Fields{
+			"error": err.Error(),
+		}).Error("Failed to bind JSON request body")
+		AbortWithBodyError(
+			c, http.StatusInternalServerError, BodyInternalServerError)
+		return
+	}
+
+	requestLog(c).WithFields(log.Fields{
+		"code":  body.Code,
+		"error": err.Error(),
+	}).Warn("Request body is invalid")
+	AbortWithBodyError(c, http.StatusBadRequest, body)
+}
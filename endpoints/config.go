@@ -1,3 +1,225 @@
 package endpoints
 
-var TOKEN_SECRET = "1d62gCp6XcESjQh0oUwkHmoScQ14i4wmpyLgabxYwXb2EOllX4EJ1Ajs1pF5"
+import (
+	"time"
+
+	"github.com/damascopaul/lfg-backend/config"
+	"github.com/damascopaul/lfg-backend/schemas"
+)
+
+// TOKEN_ALGORITHMS are the only JWT signing algorithms accepted on requests.
+var TOKEN_ALGORITHMS = []string{"HS256"}
+
+// TOKEN_SECRET is the key used to sign and verify the user tokens.
+//
+// It is set from the config of the server by Configure.
+var TOKEN_SECRET string
+
+// DEV_MODE enables the shortcuts meant for local development, like logging
+// the password reset tokens since there is no mailer.
+//
+// It is set from the config of the server by Configure.
+var DEV_MODE bool
+
+// Configure applies the config of the server to the endpoints.
+//
+// The settings below are read from the config and are left at their zero
+// value until it is called.
+func Configure(cfg config.Config) {
+	TOKEN_SECRET = cfg.TokenSecret
+	DEV_MODE = cfg.DevMode
+	REQUEST_TIMEOUT = cfg.RequestTimeout
+	MAX_BODY_SIZE = cfg.MaxBodySize
+	MAX_QUERY_LENGTH = cfg.MaxQueryLength
+	METRICS_TOKEN = cfg.MetricsToken
+	CORS_ALLOWED_ORIGINS = cfg.CORSAllowedOrigins
+	CORS_ALLOWED_METHODS = cfg.CORSAllowedMethods
+	CORS_ALLOWED_HEADERS = cfg.CORSAllowedHeaders
+	CORS_ALLOW_CREDENTIALS = cfg.CORSAllowCredentials
+	NOSNIFF_HEADER = cfg.NosniffHeader
+	FRAME_DENY_HEADER = cfg.FrameDenyHeader
+	HSTS_MAX_AGE = cfg.HSTSMaxAge
+	HSTS_INCLUDE_SUBDOMAINS = cfg.HSTSIncludeSubdomains
+
+	MAX_OWNED_GROUPS = cfg.MaxOwnedGroups
+	MAX_JOINED_GROUPS = cfg.MaxJoinedGroups
+	GAMES = cfg.Games
+	DUPLICATE_TITLE_MODE = cfg.DuplicateTitleMode
+	GROUP_PASSWORDS_ENABLED = cfg.GroupPasswordsEnabled
+	DEMO_MODE = cfg.DemoMode
+	DEMO_CLOSED_PERCENT = cfg.DemoClosedPercent
+	ERROR_FORMAT = cfg.ErrorFormat
+	PROBLEM_TYPE_BASE_URL = cfg.ProblemTypeBaseURL
+
+	GROUPS_PAGE_LIMITS = schemas.PageLimits(cfg.GroupsPageLimits)
+	TRENDING_LIMITS = schemas.PageLimits(cfg.TrendingLimits)
+	MEMBERS_PAGE_LIMITS = schemas.PageLimits(cfg.MembersPageLimits)
+	ACTIVITY_PAGE_LIMITS = schemas.PageLimits(cfg.ActivityPageLimits)
+	EVENT_PAGE_LIMITS = schemas.PageLimits(cfg.EventPageLimits)
+	HISTORY_PAGE_LIMITS = schemas.PageLimits(cfg.HistoryPageLimits)
+	MAX_BATCH_GROUP_IDS = cfg.MaxBatchGroupIDs
+	MAX_QUERY_VALUES = cfg.MaxQueryValues
+
+	SIGN_IN_MAX_ATTEMPTS = cfg.SignInMaxAttempts
+	SIGN_IN_LOCKOUT = cfg.SignInLockout
+	GROUP_PASSWORD_MAX_ATTEMPTS = cfg.GroupPasswordMaxAttempts
+	GROUP_PASSWORD_LOCKOUT = cfg.GroupPasswordLockout
+	SIGN_UP_RULES = schemas.SignUpRules{
+		ReservedUsernames:    cfg.ReservedUsernames,
+		BlockCommonPasswords: cfg.BlockCommonPasswords,
+	}
+}
+
+// MAX_OWNED_GROUPS is the number of open groups a user can own.
+//
+// Zero means there is no limit. Admins are not limited.
+var MAX_OWNED_GROUPS int
+
+// GAMES are the games a group can be for.
+//
+// Any game is allowed if it is empty.
+var GAMES []string
+
+// MAX_JOINED_GROUPS is the number of open groups a user can be a member of.
+//
+// Zero means there is no limit. The groups owned by the user do not count.
+var MAX_JOINED_GROUPS int
+
+// DUPLICATE_TITLE_MODE is what happens when an owner creates an open group
+// with the same title as one of their open groups.
+//
+// It is either "reject", "warn", or "off".
+var DUPLICATE_TITLE_MODE string
+
+// ERROR_FORMAT is the format of the error responses.
+//
+// It is either "json" for the usual error body or "problem" for RFC 7807
+// problem details. Clients accepting `application/problem+json` get problem
+// details either way.
+var ERROR_FORMAT string
+
+// PROBLEM_TYPE_BASE_URL is prefixed to the error code to make the type of
+// the problem details.
+//
+// The type is "about:blank" if it is empty.
+var PROBLEM_TYPE_BASE_URL string
+
+// METRICS_TOKEN is the bearer token required on the metrics endpoint.
+//
+// The metrics endpoint is public if it is empty. It is set from the config
+// of the server by Configure.
+var METRICS_TOKEN string
+
+// DEMO_MODE enables the endpoints used to seed staging and demo data.
+var DEMO_MODE bool
+
+// DEMO_CLOSED_PERCENT is the percentage of groups closed when the group
+// statuses are randomized in demo mode.
+var DEMO_CLOSED_PERCENT int
+
+// GROUPS_PAGE_LIMITS are the page sizes allowed on the group listings.
+var GROUPS_PAGE_LIMITS schemas.PageLimits
+
+// TRENDING_LIMITS are the numbers of groups allowed on the trending groups.
+var TRENDING_LIMITS schemas.PageLimits
+
+// MAX_BATCH_GROUP_IDS is the most groups retrieved at once with
+// GET /groups?ids=.
+var MAX_BATCH_GROUP_IDS int
+
+// MEMBERS_PAGE_LIMITS are the page sizes allowed on the member listing.
+var MEMBERS_PAGE_LIMITS schemas.PageLimits
+
+// ACTIVITY_PAGE_LIMITS are the page sizes allowed on the activity feed.
+var ACTIVITY_PAGE_LIMITS schemas.PageLimits
+
+// EVENT_PAGE_LIMITS are the page sizes allowed on the audit log.
+var EVENT_PAGE_LIMITS schemas.PageLimits
+
+// HISTORY_PAGE_LIMITS are the page sizes allowed on the settings history.
+var HISTORY_PAGE_LIMITS schemas.PageLimits
+
+// MAX_QUERY_LENGTH is the number of bytes allowed in a query string.
+//
+// It is set from the config of the server by Configure.
+var MAX_QUERY_LENGTH int
+
+// REQUEST_TIMEOUT is how long a request can take before its database
+// queries are canceled.
+//
+// Zero means requests have no time limit. It is set from the config of the
+// server by Configure.
+var REQUEST_TIMEOUT time.Duration
+
+// MAX_BODY_SIZE is the number of bytes allowed in a request body.
+//
+// It is set from the config of the server by Configure.
+var MAX_BODY_SIZE int64
+
+// MAX_QUERY_VALUES is the number of values allowed in a multi-value query
+// parameter like `ids`.
+var MAX_QUERY_VALUES int
+
+// GROUP_PASSWORDS_ENABLED allows owners to protect their groups with a
+// shared password.
+//
+// If it is disabled, existing password protected groups require the approval
+// of the owner to join instead.
+var GROUP_PASSWORDS_ENABLED bool
+
+// SIGN_IN_MAX_ATTEMPTS is the number of consecutive failed sign ins before
+// the user is locked out.
+//
+// Zero means users are never locked out.
+var SIGN_IN_MAX_ATTEMPTS int
+
+// GROUP_PASSWORD_MAX_ATTEMPTS is the number of wrong passwords a user can
+// send in the X-Group-Password header of a group before being locked out.
+//
+// Zero means the attempts are not limited.
+var GROUP_PASSWORD_MAX_ATTEMPTS int
+
+// GROUP_PASSWORD_LOCKOUT is how long a user cannot unlock a group with its
+// password after too many wrong passwords.
+var GROUP_PASSWORD_LOCKOUT time.Duration
+
+// SIGN_IN_LOCKOUT is how long a user is locked out after too many failed
+// sign ins.
+var SIGN_IN_LOCKOUT time.Duration
+
+// SIGN_UP_RULES are the checks of the details used to sign up, like the
+// reserved usernames and the common passwords.
+var SIGN_UP_RULES schemas.SignUpRules
+
+// CORS_ALLOWED_ORIGINS are the origins browser clients can call the API from.
+//
+// A "*" allows any origin. CORS is disabled if it is empty. The CORS and the
+// security header settings are set from the config of the server by
+// Configure.
+var CORS_ALLOWED_ORIGINS []string
+
+// CORS_ALLOWED_METHODS are the methods allowed on cross-origin requests.
+var CORS_ALLOWED_METHODS []string
+
+// CORS_ALLOWED_HEADERS are the headers allowed on cross-origin requests.
+var CORS_ALLOWED_HEADERS []string
+
+// CORS_ALLOW_CREDENTIALS allows cross-origin requests to include credentials.
+var CORS_ALLOW_CREDENTIALS bool
+
+// NOSNIFF_HEADER adds `X-Content-Type-Options: nosniff` to the responses so
+// browsers do not guess their content type.
+var NOSNIFF_HEADER bool
+
+// FRAME_DENY_HEADER adds `X-Frame-Options: DENY` to the responses so they
+// cannot be shown in a frame.
+var FRAME_DENY_HEADER bool
+
+// HSTS_MAX_AGE is how long browsers only use HTTPS for the API after a
+// response. The `Strict-Transport-Security` header is left out if it is
+// zero, which is useful when serving over plain HTTP locally.
+var HSTS_MAX_AGE time.Duration
+
+// HSTS_INCLUDE_SUBDOMAINS applies the `Strict-Transport-Security` header to
+// the subdomains too.
+var HSTS_INCLUDE_SUBDOMAINS bool
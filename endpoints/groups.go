@@ -1,29 +1,512 @@
 package endpoints
 
 import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
 	"net/http"
+	"strconv"
 	"strings"
 
 	"github.com/damascopaul/lfg-backend/schemas"
 
 	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
 	log "github.com/sirupsen/logrus"
+	"golang.org/x/exp/slices"
 )
 
+// requiresApproval checks if users need the approval of the owner to join
+// the group.
+func requiresApproval(g schemas.Group) bool {
+	return g.RequiresApproval() || (!GROUP_PASSWORDS_ENABLED && g.IsPrivate())
+}
+
+// groupPasswordHeader is the header carrying the password of a private group
+// when retrieving it.
+const groupPasswordHeader = "X-Group-Password"
+
+// groupPasswordAttempts counts the wrong passwords sent in the
+// X-Group-Password header by each user for each group.
+var groupPasswordAttempts = newAttemptLimiter()
+
+// canSeeGroupDetails checks if the user can see the details of the group.
+//
+// Private groups are only shown in full to the owner and the members. The
+// X-Group-Password header is only checked when retrieving a single group.
+//
+// The membership is checked in the database since the member list of listed
+// groups is only loaded when it is expanded.
+func canSeeGroupDetails(c *gin.Context, g schemas.Group) (bool, error) {
+	uid := c.GetInt64("user_id")
+	if !(g.Private || g.IsPrivate()) || g.IsOwner(uid) {
+		return true, nil
+	}
+	m := schemas.Group{ID: g.ID}
+	if err := m.InitDB(c.Request.Context()); err != nil {
+		return false, err
+	}
+	return m.HasMember(uid)
+}
+
+// unlockGroupDetails checks if the password in the X-Group-Password header
+// unlocks the details of the private group.
+//
+// The wrong passwords of a user are limited for each group. The request is
+// aborted with a 429 error when the user is locked out, in which case ok is
+// false.
+func unlockGroupDetails(c *gin.Context, g schemas.Group) (unlocked, ok bool) {
+	pw := c.GetHeader(groupPasswordHeader)
+	if !GROUP_PASSWORDS_ENABLED || pw == "" {
+		return false, true
+	}
+
+	key := fmt.Sprintf("%v:%v", c.GetInt64("user_id"), g.ID)
+	if wait := groupPasswordAttempts.lockedFor(
+		key, GROUP_PASSWORD_MAX_ATTEMPTS); wait > 0 {
+		// Return a 429 error if the user is locked out. The password is not
+		// checked so it cannot be guessed during the lockout.
+		c.Header("Retry-After", strconv.Itoa(int(wait.Seconds())+1))
+		AbortWithBodyError(c, http.StatusTooManyRequests, schemas.BodyError{
+			Code:    "too_many_attempts",
+			Message: "Too many wrong passwords. Try again later.",
+		})
+		return false, false
+	}
+
+	if g.ValidatePassword(pw) != nil {
+		groupPasswordAttempts.fail(
+			key, GROUP_PASSWORD_MAX_ATTEMPTS, GROUP_PASSWORD_LOCKOUT)
+		return false, true
+	}
+	groupPasswordAttempts.reset(key)
+	return true, true
+}
+
+// joinViewer is what is known about the authenticated user, besides the
+// group itself, to tell why they cannot join a group.
+type joinViewer struct {
+	ID int64
+	// Banned are the groups the user is banned from.
+	Banned []int64
+	// Joined are the groups the user is a member of.
+	Joined []int64
+	// AtJoinedGroupLimit is true if the user cannot join more open groups.
+	AtJoinedGroupLimit bool
+}
+
+// loadJoinViewer loads the bans and the joined groups of the authenticated
+// user.
+//
+// They are loaded once per request so listing groups does not query them
+// for each group or load the member lists.
+func loadJoinViewer(c *gin.Context) (joinViewer, error) {
+	if v, ok := c.Keys["join_viewer"].(joinViewer); ok {
+		return v, nil
+	}
+
+	v := joinViewer{ID: c.GetInt64("user_id")}
+	if v.ID == 0 {
+		return v, nil
+	}
+	u := schemas.User{ID: v.ID}
+	if err := u.InitDB(c.Request.Context()); err != nil {
+		return v, err
+	}
+	banned, err := u.ListBannedGroupIDs()
+	if err != nil {
+		return v, err
+	}
+	v.Banned = banned
+	if v.Joined, err = u.ListJoinedGroupIDs(); err != nil {
+		return v, err
+	}
+	if MAX_JOINED_GROUPS > 0 {
+		joined, err := u.CountOpenJoinedGroups()
+		if err != nil {
+			return v, err
+		}
+		v.AtJoinedGroupLimit = joined >= int64(MAX_JOINED_GROUPS)
+	}
+	c.Set("join_viewer", v)
+	return v, nil
+}
+
+// setJoinBlockedReason sets why the user cannot join the group.
+//
+// The checks follow the join route: its middlewares first, then the
+// branches of JoinGroup. A full group is only blocked if joining does not
+// go through a join request or the waitlist. It is reported before the
+// password since the right password would not let the user in.
+func setJoinBlockedReason(g *schemas.Group, v joinViewer) {
+	private := g.Private || g.IsPrivate()
+	// The password may not be loaded so requiresApproval cannot be used.
+	approval := g.RequiresApproval() || (!GROUP_PASSWORDS_ENABLED && private)
+	switch {
+	case g.IsOwner(v.ID):
+		g.JoinBlockedReason = schemas.JoinBlockedIsOwner
+	case !g.IsOpen():
+		g.JoinBlockedReason = schemas.JoinBlockedClosed
+	case slices.Contains(v.Banned, g.ID):
+		g.JoinBlockedReason = schemas.JoinBlockedBanned
+	case slices.Contains(v.Joined, g.ID):
+		g.JoinBlockedReason = schemas.JoinBlockedAlreadyMember
+	case v.AtJoinedGroupLimit:
+		g.JoinBlockedReason = schemas.JoinBlockedJoinedGroupLimit
+	case approval && GROUP_PASSWORDS_ENABLED && private:
+		g.JoinBlockedReason = schemas.JoinBlockedPasswordRequired
+	case approval:
+		g.JoinBlockedReason = ""
+	case g.IsFull() && !g.HasWaitlist():
+		g.JoinBlockedReason = schemas.JoinBlockedFull
+	case private:
+		g.JoinBlockedReason = schemas.JoinBlockedPasswordRequired
+	default:
+		g.JoinBlockedReason = ""
+	}
+}
+
+// abortIfGroupPassword rejects setting a group password if group passwords
+// are disabled.
+func abortIfGroupPassword(c *gin.Context, g schemas.Group) bool {
+	if GROUP_PASSWORDS_ENABLED || g.Password == "" {
+		return false
+	}
+	// Return a 400 error since the password cannot be used.
+	AbortWithBodyError(c, http.StatusBadRequest, schemas.BodyError{
+		Code:    "group_passwords_disabled",
+		Message: "The request body contains errors",
+		FieldErrors: []schemas.FieldError{{
+			Name:  "password",
+			ID:    "group_passwords_disabled",
+			Error: "Group passwords are disabled",
+		}},
+	})
+	return true
+}
+
+// abortIfUnknownGame rejects a game that is not one of the allowed games.
+//
+// The game is replaced with the spelling of the allowed game since games are
+// matched regardless of case.
+func abortIfUnknownGame(c *gin.Context, game *string) bool {
+	if len(GAMES) == 0 || *game == "" {
+		return false
+	}
+	i := slices.IndexFunc(GAMES, func(allowed string) bool {
+		return strings.EqualFold(allowed, *game)
+	})
+	if i >= 0 {
+		*game = GAMES[i]
+		return false
+	}
+	// Return a 400 error since the game is not allowed.
+	AbortWithBodyError(c, http.StatusBadRequest, schemas.BodyError{
+		Code:    "unknown_game",
+		Message: "The request body contains errors",
+		FieldErrors: []schemas.FieldError{{
+			Name:   "game",
+			ID:     "one_of",
+			Params: []interface{}{strings.Join(GAMES, ", ")},
+			Error:  "The value should be one of: " + strings.Join(GAMES, ", "),
+		}},
+	})
+	return true
+}
+
+// abortIfOwnedGroupLimit rejects creating a group if the user already owns
+// the maximum number of open groups.
+func abortIfOwnedGroupLimit(c *gin.Context, uid int64) bool {
+	if MAX_OWNED_GROUPS <= 0 {
+		return false
+	}
+
+	u := schemas.User{ID: uid}
+	if err := u.InitDB(c.Request.Context()); err != nil {
+		AbortWithBodyError(
+			c, http.StatusInternalServerError, BodyInternalServerError)
+		return true
+	}
+	if err := u.Retrieve(); err != nil {
+		AbortWithBodyError(
+			c, http.StatusInternalServerError, BodyInternalServerError)
+		return true
+	}
+	caps, err := u.Capabilities(MAX_OWNED_GROUPS)
+	if err != nil {
+		AbortWithBodyError(
+			c, http.StatusInternalServerError, BodyInternalServerError)
+		return true
+	}
+	if caps.CanCreateGroup {
+		return false
+	}
+
+	// Return a 400 error since the user cannot own more open groups.
+	requestLog(c).WithFields(log.Fields{
+		"endpoint": "CreateGroup",
+		"user_id":  uid,
+	}).Warning("Request failed")
+	AbortWithBodyError(c, http.StatusBadRequest, schemas.BodyError{
+		Code:   "owned_group_limit",
+		Params: []interface{}{MAX_OWNED_GROUPS},
+		Message: fmt.Sprintf(
+			"User cannot own more than %v open groups", MAX_OWNED_GROUPS),
+	})
+	return true
+}
+
+// abortIfJoinedGroupLimit rejects joining a group if the user is already a
+// member of the maximum number of open groups.
+//
+// Members are let through since joining again does not add a membership.
+func abortIfJoinedGroupLimit(c *gin.Context, g schemas.Group, uid int64) bool {
+	if MAX_JOINED_GROUPS <= 0 || g.IsMember(uid) {
+		return false
+	}
+
+	u := schemas.User{ID: uid}
+	if err := u.InitDB(c.Request.Context()); err != nil {
+		AbortWithBodyError(
+			c, http.StatusInternalServerError, BodyInternalServerError)
+		return true
+	}
+	joined, err := u.CountOpenJoinedGroups()
+	if err != nil {
+		AbortWithBodyError(
+			c, http.StatusInternalServerError, BodyInternalServerError)
+		return true
+	}
+	if joined < int64(MAX_JOINED_GROUPS) {
+		return false
+	}
+
+	// Return a 400 error since the user cannot join more open groups.
+	requestLog(c).WithFields(log.Fields{
+		"endpoint": "JoinGroup",
+		"group_id": g.ID,
+		"user_id":  uid,
+	}).Warning("Request failed")
+	AbortWithBodyError(c, http.StatusBadRequest, schemas.BodyError{
+		Code:   "joined_group_limit",
+		Params: []interface{}{MAX_JOINED_GROUPS},
+		Message: fmt.Sprintf(
+			"User cannot be a member of more than %v open groups",
+			MAX_JOINED_GROUPS),
+	})
+	return true
+}
+
+// respondWithGroup returns the group to the authenticated user.
+//
+// The password is removed and the fields computed for the user are set.
+func respondWithGroup(c *gin.Context, status int, g schemas.Group) {
+	v, err := loadJoinViewer(c)
+	if err != nil {
+		AbortWithBodyError(
+			c, http.StatusInternalServerError, BodyInternalServerError)
+		return
+	}
+	presentGroup(&g, v, expandsMembers(c))
+	c.JSON(status, g)
+}
+
+// expandsMembers checks if the member list of the groups is asked for with
+// ?expand=members or ?include=members. Several values are separated by
+// commas.
+func expandsMembers(c *gin.Context) bool {
+	for _, param := range []string{"expand", "include"} {
+		for _, v := range strings.Split(c.Query(param), ",") {
+			if strings.TrimSpace(v) == "members" {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// presentGroup prepares the group to be returned to the user.
+//
+// The password is removed and the member list is null unless it is
+// expanded. The member count is returned either way.
+func presentGroup(g *schemas.Group, v joinViewer, members bool) {
+	setJoinBlockedReason(g, v)
+	g.Password = "" // Makes sure the password is not included in the response.
+	if !members {
+		g.Members = nil
+	} else if g.Members == nil {
+		// An expanded group without members has an empty member list.
+		g.Members = []schemas.User{}
+	}
+}
+
+// presentListedGroup prepares a listed group to be returned to the user.
+//
+// Only the summary of the private groups the user cannot see is returned.
+func presentListedGroup(
+	c *gin.Context, g schemas.Group, v joinViewer, members bool,
+) (interface{}, error) {
+	visible, err := canSeeGroupDetails(c, g)
+	if err != nil {
+		return nil, err
+	}
+	if !visible {
+		return g.Summary(), nil
+	}
+	presentGroup(&g, v, members)
+	return g, nil
+}
+
+// presentGroups prepares the listed groups to be returned to the user.
+func presentGroups(
+	c *gin.Context, groups []schemas.Group,
+) ([]interface{}, error) {
+	v, err := loadJoinViewer(c)
+	if err != nil {
+		return nil, err
+	}
+	members := expandsMembers(c)
+	resp := make([]interface{}, len(groups))
+	for i := range groups {
+		if resp[i], err = presentListedGroup(c, groups[i], v, members); err != nil {
+			return nil, err
+		}
+	}
+	return resp, nil
+}
+
+// respondWithGroups returns the groups to the authenticated user.
+//
+// The passwords are removed and the fields computed for the user are set.
+// The private groups the user cannot see are summarized.
+func respondWithGroups(c *gin.Context, status int, groups []schemas.Group) {
+	resp, err := presentGroups(c, groups)
+	if err != nil {
+		AbortWithBodyError(
+			c, http.StatusInternalServerError, BodyInternalServerError)
+		return
+	}
+	c.JSON(status, resp)
+}
+
+// respondWithGroupPage returns the page of groups after the cursor with the
+// cursor of the next page.
+func respondWithGroupPage(
+	c *gin.Context, endpoint string, g schemas.Group, f schemas.GroupFilters,
+) {
+	groups, next, err := g.ListPage(f)
+	if err != nil {
+		AbortWithBodyError(
+			c, http.StatusInternalServerError, BodyInternalServerError)
+		return
+	}
+
+	resp, err := presentGroups(c, groups)
+	if err != nil {
+		AbortWithBodyError(
+			c, http.StatusInternalServerError, BodyInternalServerError)
+		return
+	}
+	c.JSON(http.StatusOK, schemas.GroupPage{Groups: resp, NextCursor: next})
+	requestLog(c).WithFields(
+		log.Fields{"endpoint": endpoint}).Info("Request successful")
+}
+
+// ndjsonType is the media type of newline delimited JSON responses.
+const ndjsonType = "application/x-ndjson"
+
+// wantsNDJSON checks if the client asked for newline delimited JSON.
+func wantsNDJSON(c *gin.Context) bool {
+	return accepts(c, ndjsonType)
+}
+
+// streamGroups writes the groups that match the filters as newline
+// delimited JSON, one group per line.
+func streamGroups(c *gin.Context, g schemas.Group, f schemas.GroupFilters) {
+	v, err := loadJoinViewer(c)
+	if err != nil {
+		AbortWithBodyError(
+			c, http.StatusInternalServerError, BodyInternalServerError)
+		return
+	}
+	members := expandsMembers(c)
+	enc := json.NewEncoder(c.Writer)
+
+	c.Header("Content-Type", ndjsonType)
+	c.Status(http.StatusOK)
+	err = g.Stream(f, func(grp schemas.Group) error {
+		resp, err := presentListedGroup(c, grp, v, members)
+		if err != nil {
+			return err
+		}
+		if err := enc.Encode(resp); err != nil {
+			return err
+		}
+		c.Writer.Flush()
+		return nil
+	})
+	if err != nil {
+		// The status is already sent so the stream is only cut short.
+		requestLog(c).WithFields(log.Fields{
+			"endpoint": "ListGroups",
+			"error":    err.Error(),
+		}).Error("Could not stream groups")
+		c.Abort()
+		return
+	}
+	requestLog(c).WithFields(log.Fields{"endpoint": "ListGroups"}).Info("Request successful")
+}
+
+// bindGroupFilters parses and validates the group filters in the query.
+//
+// The request is aborted with a 400 error if the filters are not valid.
+func bindGroupFilters(c *gin.Context, endpoint string) (schemas.GroupFilters, bool) {
+	var f schemas.GroupFilters
+	if err := c.ShouldBindQuery(&f); err != nil {
+		// Return a 400 error if the query parameters are not valid.
+		requestLog(c).WithFields(log.Fields{
+			"endpoint": endpoint,
+			"error":    err.Error(),
+		}).Warn("Request failed")
+		AbortWithBodyError(
+			c, http.StatusBadRequest,
+			schemas.BodyError{
+				Code:    "invalid_query",
+				Message: "Query parameters are invalid",
+			})
+		return f, false
+	}
+	// The cursor pagination is used once the parameter is set, even empty.
+	_, f.UseCursor = c.GetQuery("cursor")
+	if err := f.Validate(); err != nil {
+		// Return a 400 error if there are validation errors
+		validationError, _ := err.(*schemas.ValidationError)
+		AbortWithBodyError(c, http.StatusBadRequest, schemas.BodyError{
+			Code:        "validation_error",
+			Message:     err.Error(),
+			FieldErrors: validationError.Errors,
+		})
+		return f, false
+	}
+	f.Pagination.Limits = GROUPS_PAGE_LIMITS
+	f.ExpandMembers = expandsMembers(c)
+	return f, true
+}
+
 // CloseGroup allows the user to mark a group as closed.
 func CloseGroup(c *gin.Context) {
 	g, _ := c.Keys["obj"].(schemas.Group)
 
-	g.Status = -100 // Update the group status to closed.
+	g.Status = schemas.GroupStatusClosed
 	if err := g.Update(); err != nil {
-		c.AbortWithStatusJSON(
-			http.StatusInternalServerError, BodyInternalServerError)
+		abortUpdate(c, "CloseGroup", err)
 		return
 	}
 
-	g.Password = "" // Makes sure the password is not included in the response.
-	c.JSON(http.StatusOK, g)
-	log.WithFields(
+	recordActivity(g, schemas.ActivityClosed, c.GetInt64("user_id"), 0)
+	respondWithGroup(c, http.StatusOK, g)
+	requestLog(c).WithFields(
 		log.Fields{"endpoint": "CloseGroup"}).Info("Request successful")
 }
 
@@ -35,57 +518,238 @@ func CreateGroup(c *gin.Context) {
 	if err := req.ValidateForCreate(); err != nil {
 		// Return a 404 error if there are validation errors
 		validationError, _ := err.(*schemas.ValidationError)
-		c.AbortWithStatusJSON(http.StatusBadRequest, schemas.BodyError{
+		AbortWithBodyError(c, http.StatusBadRequest, schemas.BodyError{
+			Code:        "validation_error",
 			Message:     err.Error(),
 			FieldErrors: validationError.Errors,
 		})
 		return
 	}
 
-	if err := req.InitDB(); err != nil {
-		c.AbortWithStatusJSON(
-			http.StatusInternalServerError, BodyInternalServerError)
+	if abortIfGroupPassword(c, req) || abortIfUnknownGame(c, &req.Game) {
+		return
+	}
+
+	if err := req.InitDB(c.Request.Context()); err != nil {
+		AbortWithBodyError(
+			c, http.StatusInternalServerError, BodyInternalServerError)
 		return
 	}
 
 	req.OwnerID = c.GetInt64("user_id") // Set the ID of the user as owner.
+	if abortIfOwnedGroupLimit(c, req.OwnerID) {
+		return
+	}
+	if DUPLICATE_TITLE_MODE == "reject" || DUPLICATE_TITLE_MODE == "warn" {
+		dup, err := req.HasOpenDuplicate()
+		if err != nil {
+			AbortWithBodyError(
+				c, http.StatusInternalServerError, BodyInternalServerError)
+			return
+		}
+		const dupMsg = "You already have an open group with this title"
+		if dup && DUPLICATE_TITLE_MODE == "reject" {
+			// Return a 400 error if the owner has an open group with the title.
+			AbortWithBodyError(c, http.StatusBadRequest, schemas.BodyError{
+				Code:    "duplicate_title",
+				Message: "The new group is not valid",
+				FieldErrors: []schemas.FieldError{{
+					Name:  "title",
+					ID:    "duplicate_title",
+					Error: dupMsg,
+				}},
+			})
+			return
+		} else if dup {
+			req.Warnings = append(req.Warnings, dupMsg)
+		}
+	}
+
 	if err := req.Create(); err != nil {
-		c.AbortWithStatusJSON(
-			http.StatusInternalServerError, BodyInternalServerError)
+		AbortWithBodyError(
+			c, http.StatusInternalServerError, BodyInternalServerError)
 		return
 	}
 
-	req.Password = ""
-	c.JSON(http.StatusCreated, req)
-	log.WithFields(
+	recordActivity(req, schemas.ActivityCreated, req.OwnerID, 0)
+	respondWithGroup(c, http.StatusCreated, req)
+	requestLog(c).WithFields(
 		log.Fields{"endpoint": "CreateGroup"}).Info("Request successful")
 }
 
+// DemoteMember allows the owner to make a moderator a regular member.
+func DemoteMember(c *gin.Context) {
+	setMemberRole(c, "DemoteMember", schemas.RoleMember)
+}
+
+// DeleteGroup allows the owner to delete a group.
+//
+// The group is soft deleted so it can still be seen by the admins.
+func DeleteGroup(c *gin.Context) {
+	g, _ := c.Keys["obj"].(schemas.Group)
+
+	if err := g.Delete(); err != nil {
+		AbortWithBodyError(
+			c, http.StatusInternalServerError, BodyInternalServerError)
+		return
+	}
+
+	recordActivity(g, schemas.ActivityDeleted, c.GetInt64("user_id"), 0)
+
+	c.Status(http.StatusNoContent)
+	requestLog(c).WithFields(
+		log.Fields{"endpoint": "DeleteGroup"}).Info("Request successful")
+}
+
 // JoinGroup allows a user to join a group
+//
+// The group password is checked together with the insert of the member so
+// the join fails if the group changed after it was retrieved.
 func JoinGroup(c *gin.Context) {
 	g, _ := c.Keys["obj"].(schemas.Group)
 
-	// Add the user as a member of the group.
-	g.Members = append(g.Members, schemas.User{ID: c.GetInt64("user_id")})
-	if err := g.Update(); err != nil {
-		c.AbortWithStatusJSON(
-			http.StatusInternalServerError, BodyInternalServerError)
+	var req schemas.Group
+	// The body is optional since it only carries the group password.
+	if err := c.ShouldBindWith(&req, binding.JSON); err != nil && !errors.Is(err, io.EOF) {
+		AbortWithBindError(c, err)
 		return
 	}
 
-	g.Password = "" // Makes sure the password is not included in the response.
-	c.JSON(http.StatusOK, g)
-	log.WithFields(log.Fields{"endpoint": "JoinGroup"}).Info("Request successful")
+	uid := c.GetInt64("user_id")
+	if g.IsMember(uid) {
+		// Joining again is not an error so a replayed request succeeds.
+		respondWithGroup(c, http.StatusOK, g)
+		requestLog(c).WithFields(log.Fields{
+			"details":  "The user is already a member",
+			"endpoint": "JoinGroup",
+		}).Info("Request successful")
+		return
+	}
+	if abortIfJoinedGroupLimit(c, g, uid) {
+		return
+	}
+
+	if requiresApproval(g) {
+		checkPassword := GROUP_PASSWORDS_ENABLED && g.IsPrivate()
+		if checkPassword && req.Password == "" {
+			abortJoin(c, g, schemas.ErrGroupPasswordRequired)
+			return
+		} else if checkPassword && g.ValidatePassword(req.Password) != nil {
+			abortJoin(c, g, schemas.ErrIncorrectGroupPassword)
+			return
+		}
+		requestJoin(c, g)
+		return
+	}
+
+	if err := g.Join(uid, req.Password); err != nil {
+		if errors.Is(err, schemas.ErrGroupFull) && g.HasWaitlist() {
+			joinWaitlist(c, g)
+			return
+		}
+		if errors.Is(err, schemas.ErrAlreadyMember) {
+			// The same user joined in a concurrent request.
+			if err := g.Retrieve(); err != nil {
+				AbortWithBodyError(
+					c, http.StatusInternalServerError, BodyInternalServerError)
+				return
+			}
+			respondWithGroup(c, http.StatusOK, g)
+			return
+		}
+		abortJoin(c, g, err)
+		return
+	}
+
+	recordActivity(g, schemas.ActivityJoined, uid, 0)
+
+	// Retrieve the group again to include the new member.
+	if err := g.Retrieve(); err != nil {
+		AbortWithBodyError(
+			c, http.StatusInternalServerError, BodyInternalServerError)
+		return
+	}
+
+	respondWithGroup(c, http.StatusOK, g)
+	requestLog(c).WithFields(log.Fields{"endpoint": "JoinGroup"}).Info("Request successful")
+}
+
+// abortJoin returns the response matching the reason the join failed.
+func abortJoin(c *gin.Context, g schemas.Group, err error) {
+	var status int
+	var code, msg string
+	switch {
+	case errors.Is(err, schemas.ErrGroupNotOpen):
+		status, code, msg = http.StatusBadRequest, "group_not_open", "Group is not open"
+	case errors.Is(err, schemas.ErrGroupFull):
+		status, code, msg = http.StatusBadRequest, "group_full", "Group is full"
+	case errors.Is(err, schemas.ErrAlreadyMember):
+		status, code, msg = http.StatusBadRequest, "already_member",
+			"User is a member of the group"
+	case errors.Is(err, schemas.ErrGroupPasswordRequired):
+		status, code, msg = http.StatusBadRequest, "password_required",
+			"Group password is required"
+	case errors.Is(err, schemas.ErrIncorrectGroupPassword):
+		status, code, msg = http.StatusForbidden, "incorrect_password",
+			"Incorrect password"
+	default:
+		AbortWithBodyError(
+			c, http.StatusInternalServerError, BodyInternalServerError)
+		return
+	}
+
+	requestLog(c).WithFields(log.Fields{
+		"details":  err.Error(),
+		"endpoint": "JoinGroup",
+		"group_id": g.ID,
+		"user_id":  c.GetInt64("user_id"),
+	}).Warning("Request failed")
+	AbortWithBodyError(c, status, schemas.BodyError{Code: code, Message: msg})
 }
 
-// KickFromGroup allows the owner to remove a member.
+// abortUpdate aborts the request after the group could not be updated.
+//
+// A 409 error is returned if the group was updated by another request so the
+// client can retrieve the group again and retry.
+func abortUpdate(c *gin.Context, endpoint string, err error) {
+	if !errors.Is(err, schemas.ErrGroupConflict) {
+		AbortWithBodyError(
+			c, http.StatusInternalServerError, BodyInternalServerError)
+		return
+	}
+	requestLog(c).WithFields(log.Fields{
+		"details":  err.Error(),
+		"endpoint": endpoint,
+	}).Warning("Request failed")
+	AbortWithBodyError(c, http.StatusConflict, schemas.BodyError{
+		Code:    "conflict",
+		Message: "Group was changed by another request",
+	})
+}
+
+// KickFromGroup allows the owner or a moderator to remove a member.
+//
+// The kick is added to the activity feed of the group with the optional
+// reason given in the request.
 func KickFromGroup(c *gin.Context) {
-	req, _ := c.Keys["req"].(schemas.User)
+	kick, _ := c.Keys["req"].(schemas.Kick)
 	g, _ := c.Keys["obj"].(schemas.Group)
 
-	if err := req.InitDB(); err != nil {
-		c.AbortWithStatusJSON(
-			http.StatusInternalServerError, BodyInternalServerError)
+	if err := kick.Validate(); err != nil {
+		// Return a 400 error if there are validation errors
+		validationError, _ := err.(*schemas.ValidationError)
+		AbortWithBodyError(c, http.StatusBadRequest, schemas.BodyError{
+			Code:        "validation_error",
+			Message:     err.Error(),
+			FieldErrors: validationError.Errors,
+		})
+		return
+	}
+
+	req := schemas.User{ID: kick.UserID}
+	if err := req.InitDB(c.Request.Context()); err != nil {
+		AbortWithBodyError(
+			c, http.StatusInternalServerError, BodyInternalServerError)
 		return
 	}
 
@@ -93,146 +757,787 @@ func KickFromGroup(c *gin.Context) {
 	if err := req.Retrieve(); err != nil {
 		if strings.Contains(err.Error(), "record not found") {
 			// Return a 404 error if the group does not exist in the database
-			c.AbortWithStatusJSON(http.StatusNotFound, BodyNotFound)
+			AbortWithBodyError(c, http.StatusNotFound, BodyNotFound)
 			return
 		}
 		// Return a 500 error for any other error other than "record not found"
-		c.AbortWithStatusJSON(
-			http.StatusInternalServerError, BodyInternalServerError)
+		AbortWithBodyError(
+			c, http.StatusInternalServerError, BodyInternalServerError)
 		return
 	}
 
 	if !g.IsMember(req.ID) {
 		// Return a 400 error if the user to kick is not a member of the group.
-		log.WithFields(log.Fields{
+		requestLog(c).WithFields(log.Fields{
 			"details":  "The user to kick is not a member",
 			"endpoint": "KickFromGroup",
 			"group_id": g.ID,
 			"user_id":  req.ID,
 		}).Warning("Request failed")
-		c.AbortWithStatusJSON(
-			http.StatusBadRequest,
-			schemas.BodyError{Message: "The user to kick is not a member"})
+		AbortWithBodyError(
+			c, http.StatusBadRequest,
+			schemas.BodyError{
+				Code:    "not_member",
+				Message: "The user to kick is not a member",
+			})
+		return
+	}
+
+	if !g.IsOwner(c.GetInt64("user_id")) && g.IsModerator(req.ID) {
+		// Return a 403 error if a moderator tries to kick another moderator.
+		requestLog(c).WithFields(log.Fields{
+			"details":  "Moderators can only kick regular members",
+			"endpoint": "KickFromGroup",
+			"group_id": g.ID,
+			"user_id":  req.ID,
+		}).Warning("Request failed")
+		AbortWithBodyError(
+			c, http.StatusForbidden,
+			schemas.BodyError{
+				Code:    "target_is_moderator",
+				Message: "Moderators can only kick regular members",
+			})
 		return
 	}
 
 	if err := g.RemoveMember(req); err != nil {
-		c.AbortWithStatusJSON(
-			http.StatusInternalServerError, BodyInternalServerError)
+		if errors.Is(err, schemas.ErrNotMember) {
+			// Return a 400 error if the user left in the meantime.
+			AbortWithBodyError(
+				c, http.StatusBadRequest,
+				schemas.BodyError{
+					Code:    "not_member",
+					Message: "User is not a member of the group",
+				})
+			return
+		}
+		AbortWithBodyError(
+			c, http.StatusInternalServerError, BodyInternalServerError)
 		return
 	}
 
-	g.Password = "" // Makes sure the password is not included in the response.
-	c.JSON(http.StatusOK, g)
-	log.WithFields(
+	if kick.Ban {
+		if err := g.Ban(req.ID); err != nil {
+			AbortWithBodyError(
+				c, http.StatusInternalServerError, BodyInternalServerError)
+			return
+		}
+	}
+
+	recordActivityWithReason(
+		g, schemas.ActivityKicked, c.GetInt64("user_id"), req.ID, kick.Reason)
+	if promoteFromWaitlist(g) {
+		// Retrieve the group again to include the promoted member.
+		if err := g.Retrieve(); err != nil {
+			AbortWithBodyError(
+				c, http.StatusInternalServerError, BodyInternalServerError)
+			return
+		}
+	}
+
+	respondWithGroup(c, http.StatusOK, g)
+	requestLog(c).WithFields(
 		log.Fields{"endpoint": "KickFromGroup"}).Info("Request successful")
 }
 
-// LeaveGroup allows a user to leave a group the user is a member of.
+// ownerLeaveGroup removes the owner from the group.
+//
+// The group is handed to the member who joined first so it is not left
+// without an owner, or closed if nobody else is in it.
+func ownerLeaveGroup(c *gin.Context, g schemas.Group) {
+	uid := c.GetInt64("user_id")
+	newOwnerID, err := g.RemoveOwner()
+	if err != nil {
+		AbortWithBodyError(
+			c, http.StatusInternalServerError, BodyInternalServerError)
+		return
+	}
+
+	recordActivity(g, schemas.ActivityLeft, uid, 0)
+	if newOwnerID == 0 {
+		recordActivity(g, schemas.ActivityClosed, uid, 0)
+	} else {
+		recordActivity(g, schemas.ActivityTransferred, uid, newOwnerID)
+		promoteFromWaitlist(g)
+	}
+
+	// Retrieve the group again to include the new owner and members.
+	if err := g.Retrieve(); err != nil {
+		AbortWithBodyError(
+			c, http.StatusInternalServerError, BodyInternalServerError)
+		return
+	}
+
+	respondWithGroup(c, http.StatusOK, g)
+	requestLog(c).WithFields(log.Fields{
+		"endpoint":     "LeaveGroup",
+		"details":      "Owner left the group",
+		"new_owner_id": newOwnerID,
+	}).Info("Request successful")
+}
+
+// LeaveGroup allows a user to leave a group the user is a member or the owner
+// of.
 func LeaveGroup(c *gin.Context) {
 	g, _ := c.Keys["obj"].(schemas.Group)
 	u := schemas.User{ID: c.GetInt64("user_id")}
 
-	if err := u.InitDB(); err != nil {
-		c.AbortWithStatusJSON(
-			http.StatusInternalServerError, BodyInternalServerError)
+	if err := u.InitDB(c.Request.Context()); err != nil {
+		AbortWithBodyError(
+			c, http.StatusInternalServerError, BodyInternalServerError)
 		return
 	}
 
 	if err := u.Retrieve(); err != nil {
-		c.AbortWithStatusJSON(
-			http.StatusInternalServerError, BodyInternalServerError)
+		AbortWithBodyError(
+			c, http.StatusInternalServerError, BodyInternalServerError)
+		return
+	}
+
+	if g.IsOwner(u.ID) {
+		ownerLeaveGroup(c, g)
 		return
 	}
 
 	if err := g.RemoveMember(u); err != nil {
-		c.AbortWithStatusJSON(
-			http.StatusInternalServerError, BodyInternalServerError)
+		if errors.Is(err, schemas.ErrNotMember) {
+			// Return a 400 error if the user left in the meantime.
+			AbortWithBodyError(
+				c, http.StatusBadRequest,
+				schemas.BodyError{
+					Code:    "not_member",
+					Message: "User is not a member of the group",
+				})
+			return
+		}
+		AbortWithBodyError(
+			c, http.StatusInternalServerError, BodyInternalServerError)
 		return
 	}
 
-	g.Password = "" // Makes sure the password is not included in the response.
-	c.JSON(http.StatusOK, g)
-	log.WithFields(
+	recordActivity(g, schemas.ActivityLeft, u.ID, 0)
+	if promoteFromWaitlist(g) {
+		// Retrieve the group again to include the promoted member.
+		if err := g.Retrieve(); err != nil {
+			AbortWithBodyError(
+				c, http.StatusInternalServerError, BodyInternalServerError)
+			return
+		}
+	}
+
+	respondWithGroup(c, http.StatusOK, g)
+	requestLog(c).WithFields(
 		log.Fields{"endpoint": "LeaveGroup"}).Info("Request successful")
 }
 
-// ListGroups returns all the groups
+// ListGroups returns the groups that match the query parameters.
+//
+// The groups with the IDs in ?ids= are returned instead if it is set.
 func ListGroups(c *gin.Context) {
-	g := schemas.Group{}
+	if ids, ok := c.GetQuery("ids"); ok {
+		listGroupsByIDs(c, ids)
+		return
+	}
+	listGroups(c, "ListGroups", false)
+}
 
-	if err := g.InitDB(); err != nil {
-		c.AbortWithStatusJSON(
-			http.StatusInternalServerError, BodyInternalServerError)
+// listGroupsByIDs returns the groups with the comma separated IDs.
+//
+// The missing groups are skipped. Only the summary of the private groups is
+// returned to the users who cannot see their details. The X-Group-Password
+// header is ignored since the groups are loaded without their passwords, so
+// a private group can only be unlocked with GET /groups/:id. The other
+// filters are ignored.
+func listGroupsByIDs(c *gin.Context, raw string) {
+	ids, err := schemas.ParseGroupIDs(raw, MAX_BATCH_GROUP_IDS)
+	if err != nil {
+		// Return a 400 error if there are validation errors
+		validationError, _ := err.(*schemas.ValidationError)
+		AbortWithBodyError(c, http.StatusBadRequest, schemas.BodyError{
+			Code:        "validation_error",
+			Message:     err.Error(),
+			FieldErrors: validationError.Errors,
+		})
 		return
 	}
 
-	groups, err := g.List()
+	g := schemas.Group{}
+	if err := g.InitDB(c.Request.Context()); err != nil {
+		AbortWithBodyError(
+			c, http.StatusInternalServerError, BodyInternalServerError)
+		return
+	}
+	groups, err := g.ListByIDs(ids, expandsMembers(c))
 	if err != nil {
-		c.AbortWithStatusJSON(
-			http.StatusInternalServerError, BodyInternalServerError)
+		AbortWithBodyError(
+			c, http.StatusInternalServerError, BodyInternalServerError)
 		return
 	}
 
-	c.JSON(http.StatusOK, groups)
-	log.WithFields(
+	respondWithGroups(c, http.StatusOK, groups)
+	requestLog(c).WithFields(
 		log.Fields{"endpoint": "ListGroups"}).Info("Request successful")
 }
 
+// ListAllGroups returns the list of groups including the deleted and the
+// unlisted groups.
+func ListAllGroups(c *gin.Context) {
+	listGroups(c, "ListAllGroups", true)
+}
+
+// listGroups returns the groups that match the query parameters.
+//
+// Admins list the deleted groups and the groups of any visibility too.
+func listGroups(c *gin.Context, endpoint string, admin bool) {
+	g := schemas.Group{}
+
+	f, ok := bindGroupFilters(c, endpoint)
+	if !ok {
+		return
+	}
+	f.IncludeDeleted = admin
+	f.AnyVisibility = admin
+
+	if err := g.InitDB(c.Request.Context()); err != nil {
+		AbortWithBodyError(
+			c, http.StatusInternalServerError, BodyInternalServerError)
+		return
+	}
+
+	if wantsNDJSON(c) {
+		streamGroups(c, g, f)
+		return
+	}
+
+	if f.UseCursor {
+		respondWithGroupPage(c, endpoint, g, f)
+		return
+	}
+
+	groups, err := g.List(f)
+	if err != nil {
+		AbortWithBodyError(
+			c, http.StatusInternalServerError, BodyInternalServerError)
+		return
+	}
+
+	respondWithGroups(c, http.StatusOK, groups)
+	requestLog(c).WithFields(
+		log.Fields{"endpoint": endpoint}).Info("Request successful")
+}
+
+// CountGroups returns the number of groups that match the query parameters.
+func CountGroups(c *gin.Context) {
+	g := schemas.Group{}
+
+	f, ok := bindGroupFilters(c, "CountGroups")
+	if !ok {
+		return
+	}
+
+	if err := g.InitDB(c.Request.Context()); err != nil {
+		AbortWithBodyError(
+			c, http.StatusInternalServerError, BodyInternalServerError)
+		return
+	}
+
+	count, err := g.Count(f)
+	if err != nil {
+		AbortWithBodyError(
+			c, http.StatusInternalServerError, BodyInternalServerError)
+		return
+	}
+
+	c.JSON(http.StatusOK, schemas.CountResponse{Count: count})
+	requestLog(c).WithFields(
+		log.Fields{"endpoint": "CountGroups"}).Info("Request successful")
+}
+
+// ListTrendingGroups returns the open groups with the most recent activity.
+func ListTrendingGroups(c *gin.Context) {
+	var q schemas.TrendingQuery
+	if err := c.ShouldBindQuery(&q); err != nil {
+		// Return a 400 error if the query parameters are not valid.
+		requestLog(c).WithFields(log.Fields{
+			"endpoint": "ListTrendingGroups",
+			"error":    err.Error(),
+		}).Warn("Request failed")
+		AbortWithBodyError(
+			c, http.StatusBadRequest,
+			schemas.BodyError{
+				Code:    "invalid_query",
+				Message: "Query parameters are invalid",
+			})
+		return
+	}
+	q.Limits = TRENDING_LIMITS
+	q.ExpandMembers = expandsMembers(c)
+
+	g := schemas.Group{}
+	if err := g.InitDB(c.Request.Context()); err != nil {
+		AbortWithBodyError(
+			c, http.StatusInternalServerError, BodyInternalServerError)
+		return
+	}
+
+	groups, err := g.ListTrending(q)
+	if err != nil {
+		AbortWithBodyError(
+			c, http.StatusInternalServerError, BodyInternalServerError)
+		return
+	}
+
+	respondWithGroups(c, http.StatusOK, groups)
+	requestLog(c).WithFields(
+		log.Fields{"endpoint": "ListTrendingGroups"}).Info("Request successful")
+}
+
+// GroupCategoryStats returns the aggregates of the open groups per category.
+func GroupCategoryStats(c *gin.Context) {
+	g := schemas.Group{}
+	if err := g.InitDB(c.Request.Context()); err != nil {
+		AbortWithBodyError(
+			c, http.StatusInternalServerError, BodyInternalServerError)
+		return
+	}
+
+	stats, err := g.CategoryStats()
+	if err != nil {
+		AbortWithBodyError(
+			c, http.StatusInternalServerError, BodyInternalServerError)
+		return
+	}
+
+	c.JSON(http.StatusOK, stats)
+	requestLog(c).WithFields(
+		log.Fields{"endpoint": "GroupCategoryStats"}).Info("Request successful")
+}
+
+// GroupTimeseries returns the number of groups created per time bucket.
+func GroupTimeseries(c *gin.Context) {
+	g := schemas.Group{}
+
+	var q schemas.GroupTimeseriesQuery
+	if err := c.ShouldBindQuery(&q); err != nil {
+		// Return a 400 error if the query parameters are not valid.
+		requestLog(c).WithFields(log.Fields{
+			"endpoint": "GroupTimeseries",
+			"error":    err.Error(),
+		}).Warn("Request failed")
+		AbortWithBodyError(
+			c, http.StatusBadRequest,
+			schemas.BodyError{
+				Code:    "invalid_query",
+				Message: "Query parameters are invalid",
+			})
+		return
+	}
+	if err := q.Validate(); err != nil {
+		// Return a 400 error if there are validation errors
+		validationError, _ := err.(*schemas.ValidationError)
+		AbortWithBodyError(c, http.StatusBadRequest, schemas.BodyError{
+			Code:        "validation_error",
+			Message:     err.Error(),
+			FieldErrors: validationError.Errors,
+		})
+		return
+	}
+
+	if err := g.InitDB(c.Request.Context()); err != nil {
+		AbortWithBodyError(
+			c, http.StatusInternalServerError, BodyInternalServerError)
+		return
+	}
+
+	buckets, err := g.Timeseries(q)
+	if err != nil {
+		AbortWithBodyError(
+			c, http.StatusInternalServerError, BodyInternalServerError)
+		return
+	}
+
+	c.JSON(http.StatusOK, buckets)
+	requestLog(c).WithFields(
+		log.Fields{"endpoint": "GroupTimeseries"}).Info("Request successful")
+}
+
+// ListGroupMembers returns a page of the members of a group.
+func ListGroupMembers(c *gin.Context) {
+	g, _ := c.Keys["obj"].(schemas.Group)
+
+	var p schemas.Pagination
+	if err := c.ShouldBindQuery(&p); err != nil {
+		// Return a 400 error if the query parameters are not valid.
+		requestLog(c).WithFields(log.Fields{
+			"endpoint": "ListGroupMembers",
+			"error":    err.Error(),
+		}).Warn("Request failed")
+		AbortWithBodyError(
+			c, http.StatusBadRequest,
+			schemas.BodyError{
+				Code:    "invalid_query",
+				Message: "Query parameters are invalid",
+			})
+		return
+	}
+
+	p.Limits = MEMBERS_PAGE_LIMITS
+	members, err := g.ListMembers(p)
+	if err != nil {
+		AbortWithBodyError(
+			c, http.StatusInternalServerError, BodyInternalServerError)
+		return
+	}
+
+	c.JSON(http.StatusOK, members)
+	requestLog(c).WithFields(
+		log.Fields{"endpoint": "ListGroupMembers"}).Info("Request successful")
+}
+
+// ListOwnedGroups returns the groups owned by the authenticated user.
+func ListOwnedGroups(c *gin.Context) {
+	g := schemas.Group{}
+
+	f, ok := bindGroupFilters(c, "ListOwnedGroups")
+	if !ok {
+		return
+	}
+	f.OwnerID = c.GetInt64("user_id")
+
+	if err := g.InitDB(c.Request.Context()); err != nil {
+		AbortWithBodyError(
+			c, http.StatusInternalServerError, BodyInternalServerError)
+		return
+	}
+
+	groups, err := g.List(f)
+	if err != nil {
+		AbortWithBodyError(
+			c, http.StatusInternalServerError, BodyInternalServerError)
+		return
+	}
+
+	respondWithGroups(c, http.StatusOK, groups)
+	requestLog(c).WithFields(
+		log.Fields{"endpoint": "ListOwnedGroups"}).Info("Request successful")
+}
+
+// ListJoinedGroups returns the groups the authenticated user is a member of.
+func ListJoinedGroups(c *gin.Context) {
+	g := schemas.Group{}
+
+	f, ok := bindGroupFilters(c, "ListJoinedGroups")
+	if !ok {
+		return
+	}
+	f.MemberID = c.GetInt64("user_id")
+
+	if err := g.InitDB(c.Request.Context()); err != nil {
+		AbortWithBodyError(
+			c, http.StatusInternalServerError, BodyInternalServerError)
+		return
+	}
+
+	groups, err := g.List(f)
+	if err != nil {
+		AbortWithBodyError(
+			c, http.StatusInternalServerError, BodyInternalServerError)
+		return
+	}
+
+	respondWithGroups(c, http.StatusOK, groups)
+	requestLog(c).WithFields(
+		log.Fields{"endpoint": "ListJoinedGroups"}).Info("Request successful")
+}
+
+// PromoteMember allows the owner to make a member a moderator.
+func PromoteMember(c *gin.Context) {
+	setMemberRole(c, "PromoteMember", schemas.RoleModerator)
+}
+
+// ReopenGroup allows the user to mark a closed group as open again.
+func ReopenGroup(c *gin.Context) {
+	g, _ := c.Keys["obj"].(schemas.Group)
+
+	g.Status = schemas.GroupStatusOpen
+	if err := g.Update(); err != nil {
+		abortUpdate(c, "ReopenGroup", err)
+		return
+	}
+
+	recordActivity(g, schemas.ActivityReopened, c.GetInt64("user_id"), 0)
+	respondWithGroup(c, http.StatusOK, g)
+	requestLog(c).WithFields(
+		log.Fields{"endpoint": "ReopenGroup"}).Info("Request successful")
+}
+
 // RetrieveGroup returns the group details given its ID.
 func RetrieveGroup(c *gin.Context) {
-	// TODO: Add condition to show the group details if user is the owner
-	// or password is correct.
 	g, _ := c.Keys["obj"].(schemas.Group)
 
-	g.Password = "" //Omits the password from the response
-	c.JSON(http.StatusOK, g)
-	log.WithFields(
+	visible, err := canSeeGroupDetails(c, g)
+	if err != nil {
+		AbortWithBodyError(
+			c, http.StatusInternalServerError, BodyInternalServerError)
+		return
+	}
+	if !visible {
+		var ok bool
+		if visible, ok = unlockGroupDetails(c, g); !ok {
+			return
+		}
+	}
+
+	// The view is only counted once the response is decided. Wrong group
+	// passwords are not counted so guessing them does not add views. The
+	// owner's own views are not counted either so the count reflects
+	// interest from other users.
+	guessed := !visible && GROUP_PASSWORDS_ENABLED &&
+		c.GetHeader(groupPasswordHeader) != ""
+	if !guessed && !g.IsOwner(c.GetInt64("user_id")) {
+		if err := g.IncrementViews(); err != nil {
+			AbortWithBodyError(
+				c, http.StatusInternalServerError, BodyInternalServerError)
+			return
+		}
+	}
+	if !visible {
+		c.JSON(http.StatusOK, g.Summary())
+		requestLog(c).WithFields(log.Fields{
+			"details":  "Only the summary of the private group is shown",
+			"endpoint": "RetrieveGroup",
+		}).Info("Request successful")
+		return
+	}
+
+	respondWithGroup(c, http.StatusOK, g)
+	requestLog(c).WithFields(
 		log.Fields{"endpoint": "RetrieveGroup"}).Info("Request successful")
 }
 
+// TransferGroup allows the owner to hand the group over to a member.
+func TransferGroup(c *gin.Context) {
+	req, _ := c.Keys["req"].(schemas.User)
+	g, _ := c.Keys["obj"].(schemas.Group)
+
+	if g.IsOwner(req.ID) {
+		// Return a 400 error if the user is already the owner.
+		AbortWithBodyError(
+			c, http.StatusBadRequest,
+			schemas.BodyError{
+				Code:    "already_owner",
+				Message: "The user is already the owner",
+			})
+		return
+	}
+	if !g.IsMember(req.ID) {
+		// Return a 400 error if the new owner is not a member of the group.
+		requestLog(c).WithFields(log.Fields{
+			"details":  "The new owner is not a member",
+			"endpoint": "TransferGroup",
+			"group_id": g.ID,
+			"user_id":  req.ID,
+		}).Warning("Request failed")
+		AbortWithBodyError(
+			c, http.StatusBadRequest,
+			schemas.BodyError{
+				Code:    "not_member",
+				Message: "The new owner is not a member",
+			})
+		return
+	}
+
+	if err := g.TransferOwnership(req.ID); err != nil {
+		AbortWithBodyError(
+			c, http.StatusInternalServerError, BodyInternalServerError)
+		return
+	}
+
+	recordActivity(
+		g, schemas.ActivityTransferred, c.GetInt64("user_id"), req.ID)
+
+	// Retrieve the group again to include the updated members.
+	if err := g.Retrieve(); err != nil {
+		AbortWithBodyError(
+			c, http.StatusInternalServerError, BodyInternalServerError)
+		return
+	}
+
+	respondWithGroup(c, http.StatusOK, g)
+	requestLog(c).WithFields(
+		log.Fields{"endpoint": "TransferGroup"}).Info("Request successful")
+}
+
 // UpdateGroup allows the user to update the group details.
 func UpdateGroup(c *gin.Context) {
-	req, _ := c.Keys["req"].(schemas.Group)
+	req, _ := c.Keys["req"].(schemas.GroupChanges)
 	g, _ := c.Keys["obj"].(schemas.Group)
 
-	// Checks for changes
-	if req.Title != "" {
-		g.Title = req.Title
-	}
-	if req.Description != "" {
-		g.Description = req.Description
+	// Validate the request body
+	if err := req.Validate(&g); err != nil {
+		// Return a 400 error if there are validation errors
+		validationError, _ := err.(*schemas.ValidationError)
+		AbortWithBodyError(c, http.StatusBadRequest, schemas.BodyError{
+			Code:        "validation_error",
+			Message:     err.Error(),
+			FieldErrors: validationError.Errors,
+		})
+		return
 	}
-	if req.MaxSize != 0 {
-		g.MaxSize = req.MaxSize
+
+	if req.Game != nil && abortIfUnknownGame(c, req.Game) {
+		return
 	}
 
+	req.Apply(&g)
+
 	if err := g.Update(); err != nil {
-		c.AbortWithStatusJSON(
-			http.StatusInternalServerError, BodyInternalServerError)
+		abortUpdate(c, "UpdateGroup", err)
 		return
 	}
 
-	g.Password = "" // Makes sure the password is not included in the response.
-	c.JSON(http.StatusOK, g)
-	log.WithFields(
+	recordActivity(g, schemas.ActivityUpdated, c.GetInt64("user_id"), 0)
+
+	respondWithGroup(c, http.StatusOK, g)
+	requestLog(c).WithFields(
 		log.Fields{"endpoint": "UpdateGroup"}).Info("Request successful")
 }
 
+// UpdateMemberLabel allows the owner to set the label of a member.
+func UpdateMemberLabel(c *gin.Context) {
+	req, _ := c.Keys["req"].(schemas.GroupMember)
+	g, _ := c.Keys["obj"].(schemas.Group)
+
+	uid, err := strconv.ParseInt(c.Param("userId"), 10, 64)
+	if err != nil {
+		// Return a 404 error if the user ID in the URL is not valid.
+		requestLog(c).Errorf("Could not parse user ID parameter from URL. Error: %v", err)
+		AbortWithBodyError(c, http.StatusNotFound, BodyNotFound)
+		return
+	}
+
+	if !g.IsMember(uid) {
+		// Return a 400 error if the user to label is not a member of the group.
+		requestLog(c).WithFields(log.Fields{
+			"details":  "The user to label is not a member",
+			"endpoint": "UpdateMemberLabel",
+			"group_id": g.ID,
+			"user_id":  uid,
+		}).Warning("Request failed")
+		AbortWithBodyError(
+			c, http.StatusBadRequest,
+			schemas.BodyError{
+				Code:    "not_member",
+				Message: "The user to label is not a member",
+			})
+		return
+	}
+
+	// Validate the request body
+	if err := req.ValidateLabel(); err != nil {
+		// Return a 400 error if the label is not allowed
+		validationError, _ := err.(*schemas.ValidationError)
+		AbortWithBodyError(c, http.StatusBadRequest, schemas.BodyError{
+			Code:        "validation_error",
+			Message:     err.Error(),
+			FieldErrors: validationError.Errors,
+		})
+		return
+	}
+
+	if err := g.SetMemberLabel(uid, req.Label); err != nil {
+		AbortWithBodyError(
+			c, http.StatusInternalServerError, BodyInternalServerError)
+		return
+	}
+
+	respondWithGroup(c, http.StatusOK, g)
+	requestLog(c).WithFields(
+		log.Fields{"endpoint": "UpdateMemberLabel"}).Info("Request successful")
+}
+
+// setMemberRole sets the role of the member in the URL of the request.
+func setMemberRole(c *gin.Context, endpoint string, role string) {
+	g, _ := c.Keys["obj"].(schemas.Group)
+
+	uid, err := strconv.ParseInt(c.Param("userId"), 10, 64)
+	if err != nil {
+		// Return a 404 error if the user ID in the URL is not valid.
+		requestLog(c).Errorf("Could not parse user ID parameter from URL. Error: %v", err)
+		AbortWithBodyError(c, http.StatusNotFound, BodyNotFound)
+		return
+	}
+
+	if !g.IsMember(uid) {
+		// Return a 400 error if the user is not a member of the group.
+		requestLog(c).WithFields(log.Fields{
+			"details":  "The user is not a member",
+			"endpoint": endpoint,
+			"group_id": g.ID,
+			"user_id":  uid,
+		}).Warning("Request failed")
+		AbortWithBodyError(
+			c, http.StatusBadRequest,
+			schemas.BodyError{Code: "not_member", Message: "The user is not a member"})
+		return
+	}
+
+	if err := g.SetMemberRole(uid, role); err != nil {
+		AbortWithBodyError(
+			c, http.StatusInternalServerError, BodyInternalServerError)
+		return
+	}
+
+	respondWithGroup(c, http.StatusOK, g)
+	requestLog(c).WithFields(log.Fields{"endpoint": endpoint}).Info("Request successful")
+}
+
 // UpdateGroupPassword allows the user to update the group details.
 func UpdateGroupPassword(c *gin.Context) {
 	req, _ := c.Keys["req"].(schemas.Group)
 	g, _ := c.Keys["obj"].(schemas.Group)
 
-	g.Password = req.Password // Set the new password
+	if abortIfGroupPassword(c, req) {
+		return
+	}
+
+	g.SetPassword(req.Password)
 	if err := g.Update(); err != nil {
-		c.AbortWithStatusJSON(
-			http.StatusInternalServerError, BodyInternalServerError)
+		abortUpdate(c, "UpdateGroupPassword", err)
 		return
 	}
 
-	g.Password = "" // Makes sure the password is not included in the response.
-	log.WithFields(
+	recordActivity(g, schemas.ActivityUpdated, c.GetInt64("user_id"), 0)
+
+	respondWithGroup(c, http.StatusOK, g)
+	requestLog(c).WithFields(
 		log.Fields{"endpoint": "UpdateGroupPassword"}).Info("Request successful")
-	c.JSON(http.StatusOK, g)
+}
+
+// RemoveGroupPassword allows the owner to make a private group public.
+//
+// Removing the password of a public group is not an error.
+func RemoveGroupPassword(c *gin.Context) {
+	g, _ := c.Keys["obj"].(schemas.Group)
+
+	if !g.IsPrivate() {
+		respondWithGroup(c, http.StatusOK, g)
+		requestLog(c).WithFields(log.Fields{
+			"details":  "The group has no password",
+			"endpoint": "RemoveGroupPassword",
+		}).Info("Request successful")
+		return
+	}
+
+	g.SetPassword("")
+	if err := g.Update(); err != nil {
+		abortUpdate(c, "RemoveGroupPassword", err)
+		return
+	}
+
+	recordActivity(g, schemas.ActivityUpdated, c.GetInt64("user_id"), 0)
+
+	respondWithGroup(c, http.StatusOK, g)
+	requestLog(c).WithFields(
+		log.Fields{"endpoint": "RemoveGroupPassword"}).Info("Request successful")
 }
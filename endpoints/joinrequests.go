@@ -0,0 +1,148 @@
+package endpoints
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/damascopaul/lfg-backend/schemas"
+
+	"github.com/gin-gonic/gin"
+	log "github.com/sirupsen/logrus"
+)
+
+// requestJoin creates a pending join request for the user instead of
+// adding the user to the group.
+func requestJoin(c *gin.Context, g schemas.Group) {
+	jr := schemas.JoinRequest{
+		GroupID: g.ID, UserID: c.GetInt64("user_id"), DB: g.DB}
+
+	if err := jr.Create(); err != nil {
+		if errors.Is(err, schemas.ErrJoinRequestExists) {
+			// Return a 400 error if the user already asked to join.
+			AbortWithBodyError(
+				c, http.StatusBadRequest,
+				schemas.BodyError{
+					Code:    "join_request_exists",
+					Message: "User already has a pending join request",
+				})
+			return
+		}
+		AbortWithBodyError(
+			c, http.StatusInternalServerError, BodyInternalServerError)
+		return
+	}
+
+	c.JSON(http.StatusAccepted, jr)
+	requestLog(c).WithFields(log.Fields{
+		"endpoint": "JoinGroup",
+		"details":  "Join request is pending approval",
+	}).Info("Request successful")
+}
+
+// joinRequestOf returns the join request of the user in the URL of the request.
+func joinRequestOf(c *gin.Context, g schemas.Group) (schemas.JoinRequest, bool) {
+	uid, err := strconv.ParseInt(c.Param("uid"), 10, 64)
+	if err != nil {
+		// Return a 404 error if the user ID in the URL is not valid.
+		requestLog(c).Errorf("Could not parse user ID parameter from URL. Error: %v", err)
+		AbortWithBodyError(c, http.StatusNotFound, BodyNotFound)
+		return schemas.JoinRequest{}, false
+	}
+	return schemas.JoinRequest{GroupID: g.ID, UserID: uid, DB: g.DB}, true
+}
+
+// ListJoinRequests returns the pending join requests of a group.
+func ListJoinRequests(c *gin.Context) {
+	g, _ := c.Keys["obj"].(schemas.Group)
+
+	requests, err := g.ListJoinRequests()
+	if err != nil {
+		AbortWithBodyError(
+			c, http.StatusInternalServerError, BodyInternalServerError)
+		return
+	}
+
+	c.JSON(http.StatusOK, requests)
+	requestLog(c).WithFields(
+		log.Fields{"endpoint": "ListJoinRequests"}).Info("Request successful")
+}
+
+// ApproveJoinRequest allows the owner to add a user who asked to join.
+func ApproveJoinRequest(c *gin.Context) {
+	g, _ := c.Keys["obj"].(schemas.Group)
+	jr, ok := joinRequestOf(c, g)
+	if !ok {
+		return
+	}
+
+	if err := jr.Approve(); err != nil {
+		if errors.Is(err, schemas.ErrJoinRequestNotFound) {
+			// Return a 404 error if the user has no pending join request.
+			AbortWithBodyError(c, http.StatusNotFound, BodyNotFound)
+			return
+		}
+		AbortWithBodyError(
+			c, http.StatusInternalServerError, BodyInternalServerError)
+		return
+	}
+
+	recordActivity(g, schemas.ActivityApproved, c.GetInt64("user_id"), jr.UserID)
+
+	// Retrieve the group again to include the new member.
+	if err := g.Retrieve(); err != nil {
+		AbortWithBodyError(
+			c, http.StatusInternalServerError, BodyInternalServerError)
+		return
+	}
+
+	respondWithGroup(c, http.StatusOK, g)
+	requestLog(c).WithFields(
+		log.Fields{"endpoint": "ApproveJoinRequest"}).Info("Request successful")
+}
+
+// RejectJoinRequest allows the owner to discard a join request.
+func RejectJoinRequest(c *gin.Context) {
+	g, _ := c.Keys["obj"].(schemas.Group)
+	jr, ok := joinRequestOf(c, g)
+	if !ok {
+		return
+	}
+
+	if err := jr.Reject(); err != nil {
+		if errors.Is(err, schemas.ErrJoinRequestNotFound) {
+			// Return a 404 error if the user has no pending join request.
+			AbortWithBodyError(c, http.StatusNotFound, BodyNotFound)
+			return
+		}
+		AbortWithBodyError(
+			c, http.StatusInternalServerError, BodyInternalServerError)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+	requestLog(c).WithFields(
+		log.Fields{"endpoint": "RejectJoinRequest"}).Info("Request successful")
+}
+
+// RetrieveMyGroupStatus returns the relation of the user with a group.
+func RetrieveMyGroupStatus(c *gin.Context) {
+	g, _ := c.Keys["obj"].(schemas.Group)
+	uid := c.GetInt64("user_id")
+
+	jr := schemas.JoinRequest{GroupID: g.ID, UserID: uid, DB: g.DB}
+	pending, err := jr.Exists()
+	if err != nil {
+		AbortWithBodyError(
+			c, http.StatusInternalServerError, BodyInternalServerError)
+		return
+	}
+
+	c.JSON(http.StatusOK, schemas.MyGroupStatus{
+		HasPendingRequest: pending,
+		IsMember:          g.IsMember(uid),
+		IsOwner:           g.IsOwner(uid),
+	})
+	requestLog(c).WithFields(
+		log.Fields{"endpoint": "RetrieveMyGroupStatus"}).Info("Request successful")
+}
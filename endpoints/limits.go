@@ -0,0 +1,76 @@
+package endpoints
+
+import (
+	"sync"
+	"time"
+)
+
+// failedAttempts are the consecutive failed attempts of a key.
+type failedAttempts struct {
+	count       int
+	lockedUntil time.Time
+}
+
+// attemptLimiter locks a key out after too many consecutive failed attempts.
+//
+// The attempts are only counted within this process.
+type attemptLimiter struct {
+	mu       sync.Mutex
+	failures map[string]failedAttempts
+}
+
+// newAttemptLimiter returns a limiter with no failed attempts.
+func newAttemptLimiter() *attemptLimiter {
+	return &attemptLimiter{failures: map[string]failedAttempts{}}
+}
+
+// lockedFor returns how long the key is still locked out. Zero means the key
+// can be attempted.
+func (l *attemptLimiter) lockedFor(key string, max int) time.Duration {
+	if max <= 0 {
+		return 0
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	f := l.failures[key]
+	if f.count < max {
+		return 0
+	}
+	wait := time.Until(f.lockedUntil)
+	if wait <= 0 {
+		// The lockout is over so the attempts start over.
+		delete(l.failures, key)
+		return 0
+	}
+	return wait
+}
+
+// fail records a failed attempt of the key. The key is locked out for the
+// lockout once it reaches the max attempts.
+func (l *attemptLimiter) fail(key string, max int, lockout time.Duration) {
+	if max <= 0 {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := time.Now()
+	for k, f := range l.failures {
+		// Forget the lockouts that are over so the map does not grow.
+		if f.count >= max && now.After(f.lockedUntil) {
+			delete(l.failures, k)
+		}
+	}
+	f := l.failures[key]
+	f.count++
+	if f.count >= max {
+		f.lockedUntil = now.Add(lockout)
+	}
+	l.failures[key] = f
+}
+
+// reset forgets the failed attempts of the key.
+func (l *attemptLimiter) reset(key string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	delete(l.failures, key)
+}
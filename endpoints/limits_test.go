@@ -0,0 +1,39 @@
+package endpoints
+
+import (
+	"testing"
+	"time"
+)
+
+func TestAttemptLimiter(t *testing.T) {
+	l := newAttemptLimiter()
+	for i := 0; i < 2; i++ {
+		if wait := l.lockedFor("a", 3); wait != 0 {
+			t.Fatalf("got locked for %v after %v attempts", wait, i)
+		}
+		l.fail("a", 3, time.Minute)
+	}
+	l.fail("a", 3, time.Minute)
+	if wait := l.lockedFor("a", 3); wait <= 0 || wait > time.Minute {
+		t.Errorf("got locked for %v, want up to a minute", wait)
+	}
+	if wait := l.lockedFor("b", 3); wait != 0 {
+		t.Errorf("got other key locked for %v", wait)
+	}
+
+	l.reset("a")
+	if wait := l.lockedFor("a", 3); wait != 0 {
+		t.Errorf("got locked for %v after the reset", wait)
+	}
+}
+
+func TestAttemptLimiterLockoutEnds(t *testing.T) {
+	l := newAttemptLimiter()
+	l.fail("a", 1, -time.Second)
+	if wait := l.lockedFor("a", 1); wait != 0 {
+		t.Errorf("got locked for %v after the lockout", wait)
+	}
+	if wait := l.lockedFor("a", 0); wait != 0 {
+		t.Errorf("got locked for %v without a limit", wait)
+	}
+}
@@ -0,0 +1,102 @@
+package endpoints
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/damascopaul/lfg-backend/schemas"
+
+	"github.com/gin-gonic/gin"
+	log "github.com/sirupsen/logrus"
+)
+
+// ReserveSlot holds a slot in a group for the user before joining.
+func ReserveSlot(c *gin.Context) {
+	g, _ := c.Keys["obj"].(schemas.Group)
+	r := schemas.Reservation{
+		GroupID: g.ID, UserID: c.GetInt64("user_id"), DB: g.DB}
+
+	if requiresApproval(g) {
+		// Return a 400 error since the slot cannot be confirmed without
+		// the approval of the owner.
+		AbortWithBodyError(
+			c, http.StatusBadRequest,
+			schemas.BodyError{
+				Code:    "approval_required",
+				Message: "Group requires approval to join",
+			})
+		return
+	}
+
+	if err := r.Create(); err != nil {
+		if errors.Is(err, schemas.ErrReservationExists) {
+			// Return a 400 error if the user already holds a slot.
+			AbortWithBodyError(
+				c, http.StatusBadRequest,
+				schemas.BodyError{
+					Code:    "reservation_exists",
+					Message: "User already has a reservation",
+				})
+			return
+		}
+		AbortWithBodyError(
+			c, http.StatusInternalServerError, BodyInternalServerError)
+		return
+	}
+
+	c.JSON(http.StatusCreated, r)
+	requestLog(c).WithFields(log.Fields{"endpoint": "ReserveSlot"}).Info("Request successful")
+}
+
+// ConfirmReservation adds the user with an active reservation as a member.
+func ConfirmReservation(c *gin.Context) {
+	g, _ := c.Keys["obj"].(schemas.Group)
+	r := schemas.Reservation{
+		GroupID: g.ID, UserID: c.GetInt64("user_id"), DB: g.DB}
+
+	if err := r.Confirm(); err != nil {
+		if errors.Is(err, schemas.ErrReservationNotFound) {
+			// Return a 404 error if the reservation does not exist or expired.
+			AbortWithBodyError(c, http.StatusNotFound, BodyNotFound)
+			return
+		}
+		AbortWithBodyError(
+			c, http.StatusInternalServerError, BodyInternalServerError)
+		return
+	}
+
+	recordActivity(g, schemas.ActivityJoined, r.UserID, 0)
+
+	// Retrieve the group again to include the new member.
+	if err := g.Retrieve(); err != nil {
+		AbortWithBodyError(
+			c, http.StatusInternalServerError, BodyInternalServerError)
+		return
+	}
+
+	respondWithGroup(c, http.StatusOK, g)
+	requestLog(c).WithFields(
+		log.Fields{"endpoint": "ConfirmReservation"}).Info("Request successful")
+}
+
+// CancelReservation releases the slot held by the user.
+func CancelReservation(c *gin.Context) {
+	g, _ := c.Keys["obj"].(schemas.Group)
+	r := schemas.Reservation{
+		GroupID: g.ID, UserID: c.GetInt64("user_id"), DB: g.DB}
+
+	if err := r.Cancel(); err != nil {
+		if errors.Is(err, schemas.ErrReservationNotFound) {
+			// Return a 404 error if the reservation does not exist or expired.
+			AbortWithBodyError(c, http.StatusNotFound, BodyNotFound)
+			return
+		}
+		AbortWithBodyError(
+			c, http.StatusInternalServerError, BodyInternalServerError)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+	requestLog(c).WithFields(
+		log.Fields{"endpoint": "CancelReservation"}).Info("Request successful")
+}
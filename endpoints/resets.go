@@ -0,0 +1,112 @@
+package endpoints
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/damascopaul/lfg-backend/schemas"
+
+	"github.com/gin-gonic/gin"
+	log "github.com/sirupsen/logrus"
+)
+
+// bodyPasswordResetRequested is returned whether or not the username exists
+// so the endpoint cannot be used to find out which usernames are taken.
+var bodyPasswordResetRequested = schemas.MessageResponse{
+	Message: "If the user exists, a password reset token has been issued"}
+
+// RequestPasswordReset issues a password reset token for a username.
+func RequestPasswordReset(c *gin.Context) {
+	req, _ := c.Keys["req"].(schemas.PasswordReset)
+	u := schemas.User{Username: req.Username}
+
+	if err := u.InitDB(c.Request.Context()); err != nil {
+		AbortWithBodyError(
+			c, http.StatusInternalServerError, BodyInternalServerError)
+		return
+	}
+
+	if err := u.RetrieveByUsername(); err != nil {
+		if strings.Contains(err.Error(), "record not found") {
+			// Return a 200 response even if the user does not exist.
+			c.JSON(http.StatusOK, bodyPasswordResetRequested)
+			requestLog(c).WithFields(log.Fields{
+				"endpoint": "RequestPasswordReset",
+			}).Info("Request successful")
+			return
+		}
+		AbortWithBodyError(
+			c, http.StatusInternalServerError, BodyInternalServerError)
+		return
+	}
+
+	pr := schemas.PasswordReset{UserID: u.ID}
+	if err := pr.InitDB(c.Request.Context()); err != nil {
+		AbortWithBodyError(
+			c, http.StatusInternalServerError, BodyInternalServerError)
+		return
+	}
+	if err := pr.Create(); err != nil {
+		AbortWithBodyError(
+			c, http.StatusInternalServerError, BodyInternalServerError)
+		return
+	}
+
+	// TODO: Deliver the token to the user once a mailer is available.
+	fields := log.Fields{"user_id": u.ID}
+	if DEV_MODE {
+		// The token is only logged in dev mode since anyone reading the
+		// logs could reset the password with it.
+		fields["token"] = pr.Token
+	}
+	requestLog(c).WithFields(fields).Debug("Issued password reset token")
+
+	c.JSON(http.StatusOK, bodyPasswordResetRequested)
+	requestLog(c).WithFields(
+		log.Fields{"endpoint": "RequestPasswordReset"}).Info("Request successful")
+}
+
+// ConfirmPasswordReset sets a new password using a password reset token.
+func ConfirmPasswordReset(c *gin.Context) {
+	req, _ := c.Keys["req"].(schemas.PasswordReset)
+
+	if err := req.ValidateForConfirm(); err != nil {
+		// Return a 400 error if there are validation errors
+		validationError, _ := err.(*schemas.ValidationError)
+		AbortWithBodyError(c, http.StatusBadRequest, schemas.BodyError{
+			Code:        "validation_error",
+			Message:     err.Error(),
+			FieldErrors: validationError.Errors,
+		})
+		return
+	}
+
+	if err := req.InitDB(c.Request.Context()); err != nil {
+		AbortWithBodyError(
+			c, http.StatusInternalServerError, BodyInternalServerError)
+		return
+	}
+
+	if err := req.Confirm(); err != nil {
+		if errors.Is(err, schemas.ErrInvalidResetToken) {
+			// Return a 400 error if the token is unknown, expired, or used.
+			AbortWithBodyError(
+				c, http.StatusBadRequest,
+				schemas.BodyError{
+					Code:    "invalid_reset_token",
+					Message: "Reset token is invalid or expired",
+				})
+			return
+		}
+		AbortWithBodyError(
+			c, http.StatusInternalServerError, BodyInternalServerError)
+		return
+	}
+
+	c.JSON(
+		http.StatusOK,
+		schemas.MessageResponse{Message: "Password has been reset"})
+	requestLog(c).WithFields(
+		log.Fields{"endpoint": "ConfirmPasswordReset"}).Info("Request successful")
+}
@@ -0,0 +1,46 @@
+package endpoints
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/damascopaul/lfg-backend/data"
+	"github.com/damascopaul/lfg-backend/schemas"
+
+	"github.com/gin-gonic/gin"
+	log "github.com/sirupsen/logrus"
+)
+
+// ServerTime returns the current UTC time of the server.
+//
+// Clients can compare it with their own clock to compensate for the skew
+// when checking token timestamps.
+func ServerTime(c *gin.Context) {
+	now := time.Now().UTC()
+	c.JSON(http.StatusOK, schemas.TimeResponse{Time: now, Unix: now.Unix()})
+}
+
+// Healthz reports that the server is alive.
+func Healthz(c *gin.Context) {
+	c.JSON(http.StatusOK, schemas.StatusResponse{Status: "ok"})
+}
+
+// Readyz reports if the server can handle requests by pinging the database.
+func Readyz(c *gin.Context) {
+	db, err := data.CreateConnection()
+	if err == nil {
+		err = data.Ping(db)
+	}
+	if err != nil {
+		// Return a 503 error if the database is not reachable.
+		requestLog(c).WithFields(log.Fields{
+			"endpoint": "Readyz",
+			"error":    err.Error(),
+		}).Error("Database is not reachable")
+		c.JSON(
+			http.StatusServiceUnavailable,
+			schemas.StatusResponse{Status: "unavailable"})
+		return
+	}
+	c.JSON(http.StatusOK, schemas.StatusResponse{Status: "ok"})
+}
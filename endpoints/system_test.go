@@ -0,0 +1,61 @@
+package endpoints
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/damascopaul/lfg-backend/config"
+	"github.com/damascopaul/lfg-backend/data"
+	"github.com/damascopaul/lfg-backend/schemas"
+
+	"github.com/gin-gonic/gin"
+)
+
+// serveStatus calls the health check handler and decodes its response.
+func serveStatus(t *testing.T, h gin.HandlerFunc) (int, schemas.StatusResponse) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodGet, "/", nil)
+	h(c)
+	var resp schemas.StatusResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("could not decode the response %q: %v", w.Body.String(), err)
+	}
+	return w.Code, resp
+}
+
+func TestHealthz(t *testing.T) {
+	if code, resp := serveStatus(t, Healthz); code != http.StatusOK || resp.Status != "ok" {
+		t.Errorf("got %v %+v, want 200 ok", code, resp)
+	}
+}
+
+func TestReadyz(t *testing.T) {
+	defer data.Close()
+	for _, tc := range []struct {
+		name   string
+		path   string
+		code   int
+		status string
+	}{
+		{"healthy", filepath.Join(t.TempDir(), "lfg.db"), http.StatusOK, "ok"},
+		// The database cannot be opened in a directory that does not exist.
+		{"down", filepath.Join(t.TempDir(), "missing", "lfg.db"),
+			http.StatusServiceUnavailable, "unavailable"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			// The connection to the previous path is closed first.
+			data.Close()
+			data.Configure(config.Config{DBPath: tc.path})
+			code, resp := serveStatus(t, Readyz)
+			if code != tc.code || resp.Status != tc.status {
+				t.Errorf("got %v %+v, want %v %v", code, resp, tc.code, tc.status)
+			}
+		})
+	}
+}
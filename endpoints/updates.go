@@ -0,0 +1,181 @@
+package endpoints
+
+import (
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/damascopaul/lfg-backend/schemas"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+	log "github.com/sirupsen/logrus"
+	"golang.org/x/exp/slices"
+)
+
+// updatesPingInterval is how often the connections watching a group are
+// pinged to detect clients that went away.
+const updatesPingInterval = 30 * time.Second
+
+// updatesBufferSize is the number of updates kept for a slow client before
+// new updates are dropped.
+const updatesBufferSize = 16
+
+// groupWatchers keeps the channels of the clients watching each group,
+// together with the user watching on each channel.
+//
+// Updates are only shared within this process.
+type groupWatchers struct {
+	mu    sync.Mutex
+	chans map[int64]map[chan schemas.GroupUpdate]int64
+}
+
+var watchers = groupWatchers{
+	chans: map[int64]map[chan schemas.GroupUpdate]int64{}}
+
+// watch returns a channel receiving the updates of the group for the user.
+func (w *groupWatchers) watch(gid int64, uid int64) chan schemas.GroupUpdate {
+	ch := make(chan schemas.GroupUpdate, updatesBufferSize)
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.chans[gid] == nil {
+		w.chans[gid] = map[chan schemas.GroupUpdate]int64{}
+	}
+	w.chans[gid][ch] = uid
+	return ch
+}
+
+// unwatch stops sending the updates of the group to the channel.
+func (w *groupWatchers) unwatch(gid int64, ch chan schemas.GroupUpdate) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	delete(w.chans[gid], ch)
+	if len(w.chans[gid]) == 0 {
+		delete(w.chans, gid)
+	}
+}
+
+// drop closes the channels of the user watching the group, once the user is
+// no longer in it.
+//
+// The updates already sent to the channels are still delivered.
+func (w *groupWatchers) drop(gid int64, uid int64) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	for ch, watcher := range w.chans[gid] {
+		if watcher == uid {
+			delete(w.chans[gid], ch)
+			close(ch)
+		}
+	}
+	if len(w.chans[gid]) == 0 {
+		delete(w.chans, gid)
+	}
+}
+
+// publish sends the update to the clients watching the group.
+//
+// The update is dropped for clients that are too slow to keep up.
+func (w *groupWatchers) publish(u schemas.GroupUpdate) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	for ch := range w.chans[u.GroupID] {
+		select {
+		case ch <- u:
+		default:
+			log.WithFields(
+				log.Fields{"group_id": u.GroupID}).Warn("Dropped group update")
+		}
+	}
+}
+
+// checkUpdatesOrigin allows WebSocket connections from the same host or from
+// the origins allowed by CORS.
+func checkUpdatesOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	if u, err := url.Parse(origin); err == nil && u.Host == r.Host {
+		return true
+	}
+	return slices.Contains(CORS_ALLOWED_ORIGINS, "*") ||
+		slices.Contains(CORS_ALLOWED_ORIGINS, origin)
+}
+
+var updatesUpgrader = websocket.Upgrader{CheckOrigin: checkUpdatesOrigin}
+
+// WatchGroup pushes the updates of a group over a WebSocket connection.
+//
+// A message is sent when a member joins, leaves, or is kicked, and when the
+// status or the owner of the group changes. Only the owner and the members
+// can watch the group, and the connection is closed once the user leaves or
+// is kicked.
+func WatchGroup(c *gin.Context) {
+	g, _ := c.Keys["obj"].(schemas.Group)
+
+	// The client watches the group before the connection is upgraded so no
+	// update is missed once the client sees the connection open.
+	ch := watchers.watch(g.ID, c.GetInt64("user_id"))
+	defer watchers.unwatch(g.ID, ch)
+
+	conn, err := updatesUpgrader.Upgrade(c.Writer, c.Request, nil)
+	if err != nil {
+		// The upgrader already returned an error response.
+		requestLog(c).WithFields(log.Fields{
+			"endpoint": "WatchGroup",
+			"error":    err.Error(),
+		}).Warn("Request failed")
+		return
+	}
+	defer conn.Close()
+
+	requestLog(c).WithFields(log.Fields{
+		"endpoint": "WatchGroup",
+		"group_id": g.ID,
+	}).Info("Watching group")
+
+	// Messages from the client are discarded. Reading is still needed to
+	// notice when the connection is closed.
+	closed := make(chan struct{})
+	go func() {
+		defer close(closed)
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}()
+
+	ping := time.NewTicker(updatesPingInterval)
+	defer ping.Stop()
+	for {
+		select {
+		case u, ok := <-ch:
+			if !ok {
+				conn.WriteMessage(websocket.CloseMessage,
+					websocket.FormatCloseMessage(
+						websocket.ClosePolicyViolation, "not in the group"))
+				requestLog(c).WithFields(log.Fields{
+					"endpoint": "WatchGroup",
+					"group_id": g.ID,
+				}).Info("Stopped watching group after leaving it")
+				return
+			}
+			if err := conn.WriteJSON(u); err != nil {
+				return
+			}
+		case <-ping.C:
+			if err := conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+				return
+			}
+		case <-closed:
+			requestLog(c).WithFields(log.Fields{
+				"endpoint": "WatchGroup",
+				"group_id": g.ID,
+			}).Info("Stopped watching group")
+			return
+		}
+	}
+}
@@ -2,6 +2,7 @@ package endpoints
 
 import (
 	"net/http"
+	"strconv"
 	"strings"
 	"time"
 
@@ -24,6 +25,7 @@ func buildResponseWithToken(u schemas.User) (schemas.TokenResponse, error) {
 	}
 
 	u.Password = "" // Removes the password from the response
+	u.Identifier = ""
 	r := schemas.TokenResponse{
 		Token: jwt,
 		User:  u,
@@ -34,9 +36,10 @@ func buildResponseWithToken(u schemas.User) (schemas.TokenResponse, error) {
 
 func createJWTClaim(u schemas.User) jwt.MapClaims {
 	c := jwt.MapClaims{
-		"user_id":  u.ID,
-		"username": u.Username,
-		"iat":      time.Now().Unix(),
+		"user_id":       u.ID,
+		"username":      u.Username,
+		"token_version": u.TokenVersion,
+		"iat":           time.Now().Unix(),
 	}
 	return c
 }
@@ -55,49 +58,67 @@ func generateJWT(c jwt.MapClaims, secret []byte) (string, error) {
 func SignUp(c *gin.Context) {
 	u, _ := c.Keys["req"].(schemas.User)
 
-	if err := u.ValidateForSignUp(); err != nil {
-		log.WithFields(log.Fields{
+	if err := u.ValidateForSignUp(SIGN_UP_RULES); err != nil {
+		requestLog(c).WithFields(log.Fields{
 			"endpoint": "SignUp",
 			"error":    err.Error(),
 		}).Warn("Request failed")
 		validationError, _ := err.(*schemas.ValidationError)
-		c.AbortWithStatusJSON(http.StatusBadRequest, schemas.BodyError{
+		AbortWithBodyError(c, http.StatusBadRequest, schemas.BodyError{
+			Code:        "validation_error",
 			Message:     err.Error(),
 			FieldErrors: validationError.Errors,
 		})
 		return
 	}
 
-	if err := u.InitDB(); err != nil {
-		c.AbortWithStatusJSON(
-			http.StatusInternalServerError, BodyInternalServerError)
+	if err := u.InitDB(c.Request.Context()); err != nil {
+		AbortWithBodyError(
+			c, http.StatusInternalServerError, BodyInternalServerError)
 		return
 	}
 
 	err := u.Create()
 	if err != nil {
 		const usernameError = "UNIQUE constraint failed: users.username"
-		if err.Error() == usernameError {
+		// The index rejects the usernames that only differ in case from an
+		// older username stored before usernames were normalized.
+		const usernameCaseError = "UNIQUE constraint failed: index 'idx_users_username_lower'"
+		const emailError = "UNIQUE constraint failed: users.email"
+		if err.Error() == emailError {
+			// Return a 400 error if the email is used by another user.
+			AbortWithBodyError(
+				c, http.StatusBadRequest,
+				schemas.BodyError{
+					Code:    "email_taken",
+					Message: "Email is already used.",
+				})
+			return
+		}
+		if err.Error() == usernameError || err.Error() == usernameCaseError {
 			// Return a 404 error if the error is related to
 			// the uniqueness of the username.
-			c.AbortWithStatusJSON(
-				http.StatusBadRequest,
-				schemas.BodyError{Message: "User already exists."})
+			AbortWithBodyError(
+				c, http.StatusBadRequest,
+				schemas.BodyError{
+					Code:    "username_taken",
+					Message: "User already exists.",
+				})
 			return
 		}
-		c.AbortWithStatusJSON(
-			http.StatusInternalServerError, BodyInternalServerError)
+		AbortWithBodyError(
+			c, http.StatusInternalServerError, BodyInternalServerError)
 		return
 	}
 
 	resp, err := buildResponseWithToken(u)
 	if err != nil {
-		c.AbortWithStatusJSON(
-			http.StatusInternalServerError, BodyInternalServerError)
+		AbortWithBodyError(
+			c, http.StatusInternalServerError, BodyInternalServerError)
 		return
 	}
 	c.JSON(http.StatusCreated, resp)
-	log.WithFields(log.Fields{"endpoint": "SignUp"}).Info("Request successful")
+	requestLog(c).WithFields(log.Fields{"endpoint": "SignUp"}).Info("Request successful")
 }
 
 // SignIn allows existing users to sign in with their username and password.
@@ -106,42 +127,360 @@ func SignIn(c *gin.Context) {
 	reqPW := u.Password
 
 	bodyInvalidCredentials := schemas.BodyError{
+		Code:    "invalid_credentials",
 		Message: "username or password is invalid.",
 	}
 
-	if err := u.InitDB(); err != nil {
-		c.AbortWithStatusJSON(
-			http.StatusInternalServerError, BodyInternalServerError)
+	if err := u.InitDB(c.Request.Context()); err != nil {
+		AbortWithBodyError(
+			c, http.StatusInternalServerError, BodyInternalServerError)
 		return
 	}
 
-	err := u.RetrieveByUsername()
+	// The username is still accepted for clients that do not send an
+	// identifier.
+	if u.Identifier == "" {
+		u.Identifier = u.Username
+	}
+	err := u.RetrieveByIdentifier()
 	if err != nil {
 		if strings.Contains(err.Error(), "record not found") {
 			// Return a 403 error if there is
-			// no matching user given the username
-			c.AbortWithStatusJSON(
-				http.StatusUnauthorized, bodyInvalidCredentials)
+			// no matching user given the identifier
+			AbortWithBodyError(
+				c, http.StatusUnauthorized, bodyInvalidCredentials)
 			return
 		}
-		c.AbortWithStatusJSON(
-			http.StatusInternalServerError, BodyInternalServerError)
+		AbortWithBodyError(
+			c, http.StatusInternalServerError, BodyInternalServerError)
+		return
+	}
+
+	if u.IsLocked() {
+		// Return a 429 error if the user is locked out. The password is not
+		// checked so it cannot be guessed during the lockout.
+		c.Header("Retry-After", strconv.Itoa(
+			int(time.Until(*u.LockedUntil).Seconds())+1))
+		AbortWithBodyError(
+			c, http.StatusTooManyRequests,
+			schemas.BodyError{
+				Code:    "account_locked",
+				Message: "Too many failed sign ins. Try again later.",
+			})
 		return
 	}
 
 	if err := bcrypt.CompareHashAndPassword(
 		// Return a 403 error if the password does not match
 		[]byte(u.Password), []byte(reqPW)); err != nil {
-		c.AbortWithStatusJSON(http.StatusUnauthorized, bodyInvalidCredentials)
+		if SIGN_IN_MAX_ATTEMPTS > 0 {
+			u.RecordFailedSignIn(SIGN_IN_MAX_ATTEMPTS, SIGN_IN_LOCKOUT)
+		}
+		AbortWithBodyError(c, http.StatusUnauthorized, bodyInvalidCredentials)
+		return
+	}
+	if err := u.ResetFailedSignIns(); err != nil {
+		AbortWithBodyError(
+			c, http.StatusInternalServerError, BodyInternalServerError)
 		return
 	}
 
 	resp, err := buildResponseWithToken(u)
 	if err != nil {
-		c.AbortWithStatusJSON(
-			http.StatusInternalServerError, BodyInternalServerError)
+		AbortWithBodyError(
+			c, http.StatusInternalServerError, BodyInternalServerError)
 		return
 	}
 	c.JSON(http.StatusCreated, resp)
-	log.WithFields(log.Fields{"endpoint": "SignIn"}).Info("Request successful")
+	requestLog(c).WithFields(log.Fields{"endpoint": "SignIn"}).Info("Request successful")
+}
+
+// CheckUsernameAvailability returns whether a username can be used to sign up.
+func CheckUsernameAvailability(c *gin.Context) {
+	u := schemas.User{Username: c.Query("username")}
+
+	if err := u.ValidateUsername(SIGN_UP_RULES); err != nil {
+		// Return a 400 error if the username could never be used.
+		validationError, _ := err.(*schemas.ValidationError)
+		AbortWithBodyError(c, http.StatusBadRequest, schemas.BodyError{
+			Code:        "validation_error",
+			Message:     err.Error(),
+			FieldErrors: validationError.Errors,
+		})
+		return
+	}
+
+	if err := u.InitDB(c.Request.Context()); err != nil {
+		AbortWithBodyError(
+			c, http.StatusInternalServerError, BodyInternalServerError)
+		return
+	}
+
+	available, err := u.IsUsernameAvailable()
+	if err != nil {
+		AbortWithBodyError(
+			c, http.StatusInternalServerError, BodyInternalServerError)
+		return
+	}
+
+	c.JSON(http.StatusOK, schemas.AvailabilityResponse{
+		Username: u.Username, Available: available})
+	requestLog(c).WithFields(log.Fields{
+		"endpoint": "CheckUsernameAvailability"}).Info("Request successful")
+}
+
+// ListUsers returns the public details of the users given their IDs.
+func ListUsers(c *gin.Context) {
+	ids, err := parseIDs(c.Query("ids"))
+	if err != nil {
+		// Return a 400 error if any of the IDs is not a number.
+		requestLog(c).WithFields(log.Fields{
+			"endpoint": "ListUsers",
+			"error":    err.Error(),
+		}).Warn("Request failed")
+		AbortWithBodyError(
+			c, http.StatusBadRequest,
+			schemas.BodyError{
+				Code:    "invalid_query",
+				Message: "Query parameters are invalid",
+			})
+		return
+	}
+
+	u := schemas.User{}
+	if err := u.InitDB(c.Request.Context()); err != nil {
+		AbortWithBodyError(
+			c, http.StatusInternalServerError, BodyInternalServerError)
+		return
+	}
+
+	users, err := u.ListByIDs(ids)
+	if err != nil {
+		AbortWithBodyError(
+			c, http.StatusInternalServerError, BodyInternalServerError)
+		return
+	}
+
+	c.JSON(http.StatusOK, users)
+	requestLog(c).WithFields(log.Fields{"endpoint": "ListUsers"}).Info("Request successful")
+}
+
+// RetrieveCurrentUser returns the details of the authenticated user.
+func RetrieveCurrentUser(c *gin.Context) {
+	u := schemas.User{ID: c.GetInt64("user_id")}
+
+	if err := u.InitDB(c.Request.Context()); err != nil {
+		AbortWithBodyError(
+			c, http.StatusInternalServerError, BodyInternalServerError)
+		return
+	}
+
+	if err := u.Retrieve(); err != nil {
+		if strings.Contains(err.Error(), "record not found") {
+			// Return a 404 error if the user no longer exists in the database
+			AbortWithBodyError(c, http.StatusNotFound, BodyNotFound)
+			return
+		}
+		AbortWithBodyError(
+			c, http.StatusInternalServerError, BodyInternalServerError)
+		return
+	}
+
+	c.JSON(http.StatusOK, u)
+	requestLog(c).WithFields(
+		log.Fields{"endpoint": "RetrieveCurrentUser"}).Info("Request successful")
+}
+
+// UpdateCurrentUser updates the profile of the authenticated user.
+func UpdateCurrentUser(c *gin.Context) {
+	req, _ := c.Keys["req"].(schemas.ProfileChanges)
+	u := schemas.User{ID: c.GetInt64("user_id")}
+
+	if err := req.Validate(); err != nil {
+		// Return a 400 error if there are validation errors
+		validationError, _ := err.(*schemas.ValidationError)
+		AbortWithBodyError(c, http.StatusBadRequest, schemas.BodyError{
+			Code:        "validation_error",
+			Message:     err.Error(),
+			FieldErrors: validationError.Errors,
+		})
+		return
+	}
+
+	if err := u.InitDB(c.Request.Context()); err != nil {
+		AbortWithBodyError(
+			c, http.StatusInternalServerError, BodyInternalServerError)
+		return
+	}
+
+	if err := u.UpdateProfile(req); err != nil {
+		AbortWithBodyError(
+			c, http.StatusInternalServerError, BodyInternalServerError)
+		return
+	}
+
+	if err := u.Retrieve(); err != nil {
+		AbortWithBodyError(
+			c, http.StatusInternalServerError, BodyInternalServerError)
+		return
+	}
+
+	c.JSON(http.StatusOK, u)
+	requestLog(c).WithFields(
+		log.Fields{"endpoint": "UpdateCurrentUser"}).Info("Request successful")
+}
+
+// DeleteCurrentUser deletes the account of the authenticated user.
+//
+// The groups owned by the user are deleted along with the account.
+func DeleteCurrentUser(c *gin.Context) {
+	u := schemas.User{ID: c.GetInt64("user_id")}
+
+	if err := u.InitDB(c.Request.Context()); err != nil {
+		AbortWithBodyError(
+			c, http.StatusInternalServerError, BodyInternalServerError)
+		return
+	}
+
+	if err := u.Retrieve(); err != nil {
+		if strings.Contains(err.Error(), "record not found") {
+			// Return a 404 error if the user no longer exists in the database
+			AbortWithBodyError(c, http.StatusNotFound, BodyNotFound)
+			return
+		}
+		AbortWithBodyError(
+			c, http.StatusInternalServerError, BodyInternalServerError)
+		return
+	}
+
+	if err := u.Delete(); err != nil {
+		AbortWithBodyError(
+			c, http.StatusInternalServerError, BodyInternalServerError)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+	requestLog(c).WithFields(
+		log.Fields{"endpoint": "DeleteCurrentUser"}).Info("Request successful")
+}
+
+// ChangePassword allows the authenticated user to change the password.
+func ChangePassword(c *gin.Context) {
+	req, _ := c.Keys["req"].(schemas.PasswordChange)
+	u := schemas.User{ID: c.GetInt64("user_id")}
+
+	if err := req.Validate(); err != nil {
+		// Return a 400 error if there are validation errors
+		validationError, _ := err.(*schemas.ValidationError)
+		AbortWithBodyError(c, http.StatusBadRequest, schemas.BodyError{
+			Code:        "validation_error",
+			Message:     err.Error(),
+			FieldErrors: validationError.Errors,
+		})
+		return
+	}
+
+	if err := u.InitDB(c.Request.Context()); err != nil {
+		AbortWithBodyError(
+			c, http.StatusInternalServerError, BodyInternalServerError)
+		return
+	}
+
+	if err := u.RetrieveWithPassword(); err != nil {
+		if strings.Contains(err.Error(), "record not found") {
+			// Return a 404 error if the user no longer exists in the database
+			AbortWithBodyError(c, http.StatusNotFound, BodyNotFound)
+			return
+		}
+		AbortWithBodyError(
+			c, http.StatusInternalServerError, BodyInternalServerError)
+		return
+	}
+
+	if err := bcrypt.CompareHashAndPassword(
+		[]byte(u.Password), []byte(req.CurrentPassword)); err != nil {
+		// Return a 400 error if the current password does not match
+		requestLog(c).WithFields(log.Fields{
+			"details":  "The current password is incorrect",
+			"endpoint": "ChangePassword",
+			"user_id":  u.ID,
+		}).Warning("Request failed")
+		AbortWithBodyError(c, http.StatusBadRequest, schemas.BodyError{
+			Code:    "incorrect_password",
+			Message: "The request body contains errors",
+			FieldErrors: []schemas.FieldError{{
+				Name:  "current_password",
+				ID:    "incorrect_password",
+				Error: "The password is incorrect",
+			}},
+		})
+		return
+	}
+
+	if err := u.UpdatePassword(req.NewPassword); err != nil {
+		AbortWithBodyError(
+			c, http.StatusInternalServerError, BodyInternalServerError)
+		return
+	}
+
+	u.Password = "" // Removes the password from the response
+	u.Identifier = ""
+	c.JSON(http.StatusOK, u)
+	requestLog(c).WithFields(
+		log.Fields{"endpoint": "ChangePassword"}).Info("Request successful")
+}
+
+// RetrieveCapabilities returns what the authenticated user is allowed to do.
+func RetrieveCapabilities(c *gin.Context) {
+	u := schemas.User{ID: c.GetInt64("user_id")}
+
+	if err := u.InitDB(c.Request.Context()); err != nil {
+		AbortWithBodyError(
+			c, http.StatusInternalServerError, BodyInternalServerError)
+		return
+	}
+
+	if err := u.Retrieve(); err != nil {
+		if strings.Contains(err.Error(), "record not found") {
+			// Return a 404 error if the user no longer exists in the database
+			AbortWithBodyError(c, http.StatusNotFound, BodyNotFound)
+			return
+		}
+		AbortWithBodyError(
+			c, http.StatusInternalServerError, BodyInternalServerError)
+		return
+	}
+
+	caps, err := u.Capabilities(MAX_OWNED_GROUPS)
+	if err != nil {
+		AbortWithBodyError(
+			c, http.StatusInternalServerError, BodyInternalServerError)
+		return
+	}
+
+	c.JSON(http.StatusOK, caps)
+	requestLog(c).WithFields(
+		log.Fields{"endpoint": "RetrieveCapabilities"}).Info("Request successful")
+}
+
+// RevokeAllSessions invalidates all the tokens of the authenticated user,
+// including the one used on the request.
+func RevokeAllSessions(c *gin.Context) {
+	u := schemas.User{ID: c.GetInt64("user_id")}
+
+	if err := u.InitDB(c.Request.Context()); err != nil {
+		AbortWithBodyError(
+			c, http.StatusInternalServerError, BodyInternalServerError)
+		return
+	}
+
+	if err := u.RevokeTokens(); err != nil {
+		AbortWithBodyError(
+			c, http.StatusInternalServerError, BodyInternalServerError)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+	requestLog(c).WithFields(
+		log.Fields{"endpoint": "RevokeAllSessions"}).Info("Request successful")
 }
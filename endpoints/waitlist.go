@@ -0,0 +1,100 @@
+package endpoints
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/damascopaul/lfg-backend/schemas"
+
+	"github.com/gin-gonic/gin"
+	log "github.com/sirupsen/logrus"
+)
+
+// joinWaitlist adds the user to the waitlist of the full group.
+func joinWaitlist(c *gin.Context, g schemas.Group) {
+	w := schemas.WaitlistEntry{
+		GroupID: g.ID, UserID: c.GetInt64("user_id"), DB: g.DB}
+
+	if err := w.Create(); err != nil {
+		if errors.Is(err, schemas.ErrWaitlistEntryExists) {
+			// Return a 400 error if the user is already waitlisted.
+			AbortWithBodyError(
+				c, http.StatusBadRequest,
+				schemas.BodyError{
+					Code:    "already_waitlisted",
+					Message: "User is already on the waitlist",
+				})
+			return
+		}
+		AbortWithBodyError(
+			c, http.StatusInternalServerError, BodyInternalServerError)
+		return
+	}
+
+	c.JSON(http.StatusAccepted, w)
+	requestLog(c).WithFields(log.Fields{
+		"endpoint": "JoinGroup",
+		"details":  "User is waitlisted",
+	}).Info("Request successful")
+}
+
+// promoteFromWaitlist fills the slot freed in the group with the earliest
+// waitlisted user.
+//
+// It returns true if a user was promoted. A failure is only logged since the
+// slot was already freed.
+func promoteFromWaitlist(g schemas.Group) bool {
+	if !g.HasWaitlist() {
+		return false
+	}
+	uid, err := g.PromoteFromWaitlist()
+	if err != nil || uid == 0 {
+		return false
+	}
+	recordActivity(g, schemas.ActivityJoined, uid, 0)
+	return true
+}
+
+// RetrieveWaitlistPosition returns the position of the user in the waitlist.
+func RetrieveWaitlistPosition(c *gin.Context) {
+	g, _ := c.Keys["obj"].(schemas.Group)
+	w := schemas.WaitlistEntry{
+		GroupID: g.ID, UserID: c.GetInt64("user_id"), DB: g.DB}
+
+	if err := w.Retrieve(); err != nil {
+		if errors.Is(err, schemas.ErrWaitlistEntryNotFound) {
+			// Return a 404 error if the user is not waitlisted.
+			AbortWithBodyError(c, http.StatusNotFound, BodyNotFound)
+			return
+		}
+		AbortWithBodyError(
+			c, http.StatusInternalServerError, BodyInternalServerError)
+		return
+	}
+
+	c.JSON(http.StatusOK, w)
+	requestLog(c).WithFields(
+		log.Fields{"endpoint": "RetrieveWaitlistPosition"}).Info("Request successful")
+}
+
+// LeaveWaitlist removes the user from the waitlist of the group.
+func LeaveWaitlist(c *gin.Context) {
+	g, _ := c.Keys["obj"].(schemas.Group)
+	w := schemas.WaitlistEntry{
+		GroupID: g.ID, UserID: c.GetInt64("user_id"), DB: g.DB}
+
+	if err := w.Delete(); err != nil {
+		if errors.Is(err, schemas.ErrWaitlistEntryNotFound) {
+			// Return a 404 error if the user is not waitlisted.
+			AbortWithBodyError(c, http.StatusNotFound, BodyNotFound)
+			return
+		}
+		AbortWithBodyError(
+			c, http.StatusInternalServerError, BodyInternalServerError)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+	requestLog(c).WithFields(
+		log.Fields{"endpoint": "LeaveWaitlist"}).Info("Request successful")
+}
@@ -0,0 +1,221 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/damascopaul/lfg-backend/endpoints"
+)
+
+// problemDetails is an error response in the RFC 7807 format.
+type problemDetails struct {
+	Type        string `json:"type"`
+	Title       string `json:"title"`
+	Status      int    `json:"status"`
+	Detail      string `json:"detail"`
+	Instance    string `json:"instance"`
+	Code        string `json:"code"`
+	FieldErrors []struct {
+		Name string
+		ID   string
+	} `json:"field_errors"`
+}
+
+func TestProblemDetailsForNotFound(t *testing.T) {
+	user := signUp(t)
+	problem := map[string]string{"Accept": "application/problem+json"}
+
+	t.Run("default type", func(t *testing.T) {
+		w := apiRequest{
+			Method: http.MethodGet, Path: "/groups/999999999", Token: user.Token,
+			Headers: problem,
+		}.send(t)
+		expectStatus(t, w, http.StatusNotFound)
+		if ct := w.Header().Get("Content-Type"); ct != "application/problem+json" {
+			t.Errorf("got content type %q, want application/problem+json", ct)
+		}
+
+		var resp problemDetails
+		decode(t, w, &resp)
+		if resp.Type != "about:blank" || resp.Title != "Not Found" ||
+			resp.Status != http.StatusNotFound || resp.Detail == "" ||
+			resp.Instance != "/groups/999999999" || resp.Code != "not_found" {
+			t.Errorf("got problem %+v", resp)
+		}
+	})
+
+	t.Run("type base URL", func(t *testing.T) {
+		defer func(base string) { endpoints.PROBLEM_TYPE_BASE_URL = base }(
+			endpoints.PROBLEM_TYPE_BASE_URL)
+		endpoints.PROBLEM_TYPE_BASE_URL = "https://lfg.example/errors/"
+
+		w := apiRequest{
+			Method: http.MethodGet, Path: "/groups/999999999", Token: user.Token,
+			Headers: problem,
+		}.send(t)
+		expectStatus(t, w, http.StatusNotFound)
+		var resp problemDetails
+		decode(t, w, &resp)
+		if want := "https://lfg.example/errors/not_found"; resp.Type != want {
+			t.Errorf("got type %q, want %q", resp.Type, want)
+		}
+	})
+
+	t.Run("usual body", func(t *testing.T) {
+		w := apiRequest{
+			Method: http.MethodGet, Path: "/groups/999999999", Token: user.Token,
+		}.send(t)
+		expectStatus(t, w, http.StatusNotFound)
+		var resp map[string]interface{}
+		decode(t, w, &resp)
+		if _, ok := resp["type"]; ok || resp["code"] != "not_found" {
+			t.Errorf("got body %v, want the usual error body", resp)
+		}
+	})
+}
+
+func TestProblemDetailsForValidationError(t *testing.T) {
+	user := signUp(t)
+	w := apiRequest{
+		Method: http.MethodPost, Path: "/groups", Token: user.Token,
+		Body:    map[string]interface{}{"description": "No title", "max_size": 5},
+		Headers: map[string]string{"Accept": "application/problem+json"},
+	}.send(t)
+	expectStatus(t, w, http.StatusBadRequest)
+	if ct := w.Header().Get("Content-Type"); ct != "application/problem+json" {
+		t.Errorf("got content type %q, want application/problem+json", ct)
+	}
+
+	var resp problemDetails
+	decode(t, w, &resp)
+	if resp.Title != "Bad Request" || resp.Status != http.StatusBadRequest ||
+		resp.Instance != "/groups" {
+		t.Errorf("got problem %+v", resp)
+	}
+	found := false
+	for _, fe := range resp.FieldErrors {
+		found = found || (fe.Name == "title" && fe.ID == "required")
+	}
+	if !found {
+		t.Errorf("got field errors %+v, want a required title", resp.FieldErrors)
+	}
+}
+
+func TestValidationErrorLanguage(t *testing.T) {
+	for _, tc := range []struct {
+		acceptLanguage string
+		wantLanguage   string
+		wantMessage    string
+		wantFieldError string
+	}{
+		{"", "en", "The request body contains errors",
+			"This field has to be 3 to 50 characters long"},
+		{"en-US,en;q=0.9", "en", "The request body contains errors",
+			"This field has to be 3 to 50 characters long"},
+		{"es-MX,es;q=0.9,en;q=0.5", "es", "La solicitud contiene errores",
+			"Este campo debe tener entre 3 y 50 caracteres"},
+		{"fr", "en", "The request body contains errors",
+			"This field has to be 3 to 50 characters long"},
+	} {
+		t.Run(tc.acceptLanguage, func(t *testing.T) {
+			req := apiRequest{
+				Method: http.MethodPost,
+				Path:   "/sign-up",
+				Body:   map[string]string{"username": "ab", "password": testPassword},
+			}
+			if tc.acceptLanguage != "" {
+				req.Headers = map[string]string{"Accept-Language": tc.acceptLanguage}
+			}
+			w := req.send(t)
+			expectStatus(t, w, http.StatusBadRequest)
+			if lang := w.Header().Get("Content-Language"); lang != tc.wantLanguage {
+				t.Errorf("got language %q, want %q", lang, tc.wantLanguage)
+			}
+
+			var resp struct {
+				Code        string `json:"code"`
+				Message     string `json:"message"`
+				FieldErrors []struct {
+					Name  string
+					ID    string
+					Error string
+				} `json:"field_errors"`
+			}
+			decode(t, w, &resp)
+			if resp.Message != tc.wantMessage {
+				t.Errorf("got message %q, want %q", resp.Message, tc.wantMessage)
+			}
+			if len(resp.FieldErrors) != 1 {
+				t.Fatalf("got field errors %+v, want 1", resp.FieldErrors)
+			}
+			// The code and the ID stay the same in every language.
+			fe := resp.FieldErrors[0]
+			if resp.Code != "validation_error" || fe.ID != "length_range" {
+				t.Errorf("got code %q and ID %q", resp.Code, fe.ID)
+			}
+			if fe.Error != tc.wantFieldError {
+				t.Errorf("got field error %q, want %q", fe.Error, tc.wantFieldError)
+			}
+		})
+	}
+}
+
+func TestErrorCodes(t *testing.T) {
+	owner, other := signUp(t), signUp(t)
+	g := createGroup(t, owner, nil)
+	private := createGroup(t, owner, map[string]interface{}{"password": "s3cret-pass"})
+	closed := createGroup(t, owner, nil)
+	expectStatus(t, apiRequest{
+		Method: http.MethodPost, Path: groupPath(closed, "/close"), Token: owner.Token,
+	}.send(t), http.StatusOK)
+	full := createGroup(t, owner, nil)
+	for i := 0; i < 4; i++ {
+		joinGroup(t, signUp(t), full)
+	}
+
+	for _, tc := range []struct {
+		name   string
+		req    apiRequest
+		status int
+		code   string
+	}{
+		{"missing token", apiRequest{Method: http.MethodGet, Path: "/groups"},
+			http.StatusUnauthorized, "missing_token"},
+		{"not owner", apiRequest{
+			Method: http.MethodPost, Path: groupPath(g, "/close"), Token: other.Token},
+			http.StatusForbidden, "not_owner"},
+		{"password required", apiRequest{
+			Method: http.MethodPost, Path: groupPath(private, "/join"), Token: other.Token},
+			http.StatusBadRequest, "password_required"},
+		{"incorrect password", apiRequest{
+			Method: http.MethodPost, Path: groupPath(private, "/join"), Token: other.Token,
+			Body: map[string]string{"password": "wrong-pass"}},
+			http.StatusForbidden, "incorrect_password"},
+		{"group not open", apiRequest{
+			Method: http.MethodPost, Path: groupPath(closed, "/join"), Token: other.Token},
+			http.StatusBadRequest, "group_not_open"},
+		{"group full", apiRequest{
+			Method: http.MethodPost, Path: groupPath(full, "/join"), Token: other.Token},
+			http.StatusBadRequest, "group_full"},
+		{"invalid credentials", apiRequest{
+			Method: http.MethodPost, Path: "/sign-in",
+			Body: map[string]string{"username": other.Username, "password": "wrong-pass"}},
+			http.StatusUnauthorized, "invalid_credentials"},
+		{"username taken", apiRequest{
+			Method: http.MethodPost, Path: "/sign-up",
+			Body: map[string]string{"username": other.Username, "password": testPassword}},
+			http.StatusBadRequest, "username_taken"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			w := tc.req.send(t)
+			expectStatus(t, w, tc.status)
+			var resp struct {
+				Code string `json:"code"`
+			}
+			decode(t, w, &resp)
+			if resp.Code != tc.code {
+				t.Errorf("got code %q, want %q", resp.Code, tc.code)
+			}
+		})
+	}
+}
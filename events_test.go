@@ -0,0 +1,117 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+)
+
+// groupEvent is an entry in the audit log of a group.
+type groupEvent struct {
+	Type    string `json:"type"`
+	ActorID int64  `json:"actor_id"`
+}
+
+// lastEvent returns the newest event in the audit log of the group.
+func lastEvent(t *testing.T, owner testUser, g map[string]interface{}) groupEvent {
+	t.Helper()
+	w := apiRequest{
+		Method: http.MethodGet, Path: groupPath(g, "/events"), Token: owner.Token,
+	}.send(t)
+	expectStatus(t, w, http.StatusOK)
+
+	var events []groupEvent
+	decode(t, w, &events)
+	if len(events) == 0 {
+		t.Fatal("got no events")
+	}
+	return events[0]
+}
+
+func TestGroupActionsRecordEvents(t *testing.T) {
+	owner, member, kicked := signUp(t), signUp(t), signUp(t)
+	g := createGroup(t, owner, nil)
+	if got := lastEvent(t, owner, g); got != (groupEvent{"created", owner.ID}) {
+		t.Errorf("got %+v after creating the group", got)
+	}
+
+	expectStatus(t, apiRequest{
+		Method: http.MethodPost, Path: groupPath(g, "/join"), Token: kicked.Token,
+	}.send(t), http.StatusOK)
+
+	for _, tc := range []struct {
+		name string
+		req  apiRequest
+		want groupEvent
+	}{
+		{
+			"update",
+			apiRequest{
+				Method: http.MethodPatch, Path: groupPath(g, ""), Token: owner.Token,
+				Body: map[string]interface{}{"title": "Raid night again"},
+			},
+			groupEvent{"updated", owner.ID},
+		},
+		{
+			"join",
+			apiRequest{
+				Method: http.MethodPost, Path: groupPath(g, "/join"),
+				Token: member.Token,
+			},
+			groupEvent{"joined", member.ID},
+		},
+		{
+			"leave",
+			apiRequest{
+				Method: http.MethodPost, Path: groupPath(g, "/leave"),
+				Token: member.Token,
+			},
+			groupEvent{"left", member.ID},
+		},
+		{
+			"kick",
+			apiRequest{
+				Method: http.MethodPost, Path: groupPath(g, "/kick"),
+				Token: owner.Token, Body: map[string]interface{}{"id": kicked.ID},
+			},
+			groupEvent{"kicked", owner.ID},
+		},
+		{
+			"close",
+			apiRequest{
+				Method: http.MethodPost, Path: groupPath(g, "/close"),
+				Token: owner.Token,
+			},
+			groupEvent{"closed", owner.ID},
+		},
+		{
+			"reopen",
+			apiRequest{
+				Method: http.MethodPost, Path: groupPath(g, "/reopen"),
+				Token: owner.Token,
+			},
+			groupEvent{"reopened", owner.ID},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			w := tc.req.send(t)
+			if w.Code >= 300 {
+				t.Fatalf("got status %v: %s", w.Code, w.Body.String())
+			}
+			if got := lastEvent(t, owner, g); got != tc.want {
+				t.Errorf("got %+v, want %+v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestListGroupEventsIsOnlyForOwner(t *testing.T) {
+	owner, member := signUp(t), signUp(t)
+	g := createGroup(t, owner, nil)
+	expectStatus(t, apiRequest{
+		Method: http.MethodPost, Path: groupPath(g, "/join"), Token: member.Token,
+	}.send(t), http.StatusOK)
+
+	expectStatus(t, apiRequest{
+		Method: http.MethodGet, Path: groupPath(g, "/events"), Token: member.Token,
+	}.send(t), http.StatusForbidden)
+}
@@ -0,0 +1,1230 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/damascopaul/lfg-backend/endpoints"
+	"github.com/damascopaul/lfg-backend/schemas"
+
+	"golang.org/x/exp/slices"
+)
+
+func TestListGroupsSummarizesHiddenPrivateGroups(t *testing.T) {
+	owner, member, outsider := signUp(t), signUp(t), signUp(t)
+	g := createGroup(t, owner, map[string]interface{}{
+		"title": "Hidden raid", "password": "s3cret-pass"})
+	expectStatus(t, apiRequest{
+		Method: http.MethodPost, Path: groupPath(g, "/join"), Token: member.Token,
+		Body: map[string]string{"password": "s3cret-pass"},
+	}.send(t), http.StatusOK)
+
+	paths := map[string]string{
+		"list": "/groups?visibility=private",
+		"page": "/groups?visibility=private&cursor=",
+	}
+	for name, path := range paths {
+		t.Run(name, func(t *testing.T) {
+			for _, tc := range []struct {
+				user        testUser
+				wantDetails bool
+			}{
+				{owner, true}, {member, true}, {outsider, false},
+			} {
+				w := apiRequest{
+					Method: http.MethodGet, Path: path, Token: tc.user.Token,
+				}.send(t)
+				expectStatus(t, w, http.StatusOK)
+
+				var groups []map[string]interface{}
+				if name == "page" {
+					var page struct {
+						Groups []map[string]interface{} `json:"groups"`
+					}
+					decode(t, w, &page)
+					groups = page.Groups
+				} else {
+					decode(t, w, &groups)
+				}
+				checkListedGroup(t, groups, g["id"], tc.wantDetails)
+			}
+		})
+	}
+
+	t.Run("stream", func(t *testing.T) {
+		w := apiRequest{
+			Method: http.MethodGet, Path: "/groups?visibility=private",
+			Token:   outsider.Token,
+			Headers: map[string]string{"Accept": "application/x-ndjson"},
+		}.send(t)
+		expectStatus(t, w, http.StatusOK)
+
+		var groups []map[string]interface{}
+		scanner := bufio.NewScanner(w.Body)
+		for scanner.Scan() {
+			var grp map[string]interface{}
+			if err := json.Unmarshal(scanner.Bytes(), &grp); err != nil {
+				t.Fatalf("could not decode the line %q: %v", scanner.Text(), err)
+			}
+			groups = append(groups, grp)
+		}
+		checkListedGroup(t, groups, g["id"], false)
+	})
+}
+
+// checkListedGroup checks if the group is listed with or without its
+// details.
+func checkListedGroup(
+	t *testing.T, groups []map[string]interface{}, id interface{},
+	wantDetails bool,
+) {
+	t.Helper()
+	for _, grp := range groups {
+		if grp["id"] != id {
+			continue
+		}
+		if _, ok := grp["description"]; ok != wantDetails {
+			t.Errorf("got details %v, want %v: %v", ok, wantDetails, grp)
+		}
+		if grp["private"] != true {
+			t.Errorf("got private %v, want true", grp["private"])
+		}
+		return
+	}
+	t.Errorf("group %v is not listed in %v", id, groups)
+}
+
+func TestRetrievePrivateGroup(t *testing.T) {
+	owner, member, outsider := signUp(t), signUp(t), signUp(t)
+	g := createGroup(t, owner, map[string]interface{}{"password": "s3cret-pass"})
+	expectStatus(t, apiRequest{
+		Method: http.MethodPost, Path: groupPath(g, "/join"), Token: member.Token,
+		Body: map[string]string{"password": "s3cret-pass"},
+	}.send(t), http.StatusOK)
+
+	for _, tc := range []struct {
+		name        string
+		user        testUser
+		password    string
+		wantDetails bool
+	}{
+		{"owner", owner, "", true},
+		{"member", member, "", true},
+		{"outsider", outsider, "", false},
+		{"outsider with wrong password", outsider, "wrong-pass", false},
+		{"outsider with password", outsider, "s3cret-pass", true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			req := apiRequest{
+				Method: http.MethodGet, Path: groupPath(g, ""), Token: tc.user.Token,
+			}
+			if tc.password != "" {
+				req.Headers = map[string]string{"X-Group-Password": tc.password}
+			}
+			w := req.send(t)
+			expectStatus(t, w, http.StatusOK)
+
+			var resp map[string]interface{}
+			decode(t, w, &resp)
+			if _, ok := resp["description"]; ok != tc.wantDetails {
+				t.Errorf("got details %v, want %v: %v", ok, tc.wantDetails, resp)
+			}
+		})
+	}
+}
+
+func TestRetrievePrivateGroupLimitsWrongPasswords(t *testing.T) {
+	owner, outsider := signUp(t), signUp(t)
+	g := createGroup(t, owner, map[string]interface{}{"password": "s3cret-pass"})
+
+	retrieve := func(password string) *httptest.ResponseRecorder {
+		return apiRequest{
+			Method: http.MethodGet, Path: groupPath(g, ""), Token: outsider.Token,
+			Headers: map[string]string{"X-Group-Password": password},
+		}.send(t)
+	}
+	for i := 0; i < endpoints.GROUP_PASSWORD_MAX_ATTEMPTS; i++ {
+		expectStatus(t, retrieve("wrong-pass"), http.StatusOK)
+	}
+
+	// The right password is rejected too during the lockout.
+	w := retrieve("s3cret-pass")
+	expectStatus(t, w, http.StatusTooManyRequests)
+	if w.Header().Get("Retry-After") == "" {
+		t.Error("got no Retry-After header")
+	}
+}
+
+func TestRetrieveGroupCountsViews(t *testing.T) {
+	owner, viewer, guesser := signUp(t), signUp(t), signUp(t)
+	g := createGroup(t, owner, map[string]interface{}{"password": "s3cret-pass"})
+	retrieve := func(u testUser, password string) *httptest.ResponseRecorder {
+		req := apiRequest{Method: http.MethodGet, Path: groupPath(g, ""), Token: u.Token}
+		if password != "" {
+			req.Headers = map[string]string{"X-Group-Password": password}
+		}
+		return req.send(t)
+	}
+	views := func() interface{} {
+		w := retrieve(owner, "")
+		expectStatus(t, w, http.StatusOK)
+		var resp map[string]interface{}
+		decode(t, w, &resp)
+		return resp["views"]
+	}
+
+	// The summary and the unlocked group are both views.
+	expectStatus(t, retrieve(viewer, ""), http.StatusOK)
+	expectStatus(t, retrieve(viewer, "s3cret-pass"), http.StatusOK)
+	if got := views(); got != float64(2) {
+		t.Fatalf("got %v views, want 2", got)
+	}
+
+	// Wrong passwords and the retrieves during the lockout are not views.
+	for i := 0; i < endpoints.GROUP_PASSWORD_MAX_ATTEMPTS; i++ {
+		expectStatus(t, retrieve(guesser, "wrong-pass"), http.StatusOK)
+	}
+	expectStatus(t, retrieve(guesser, "wrong-pass"), http.StatusTooManyRequests)
+	if got := views(); got != float64(2) {
+		t.Errorf("got %v views after wrong passwords, want 2", got)
+	}
+}
+
+func TestListGroupMembersOfPrivateGroup(t *testing.T) {
+	owner, member, outsider := signUp(t), signUp(t), signUp(t)
+	g := createGroup(t, owner, map[string]interface{}{"password": "s3cret-pass"})
+	expectStatus(t, apiRequest{
+		Method: http.MethodPost, Path: groupPath(g, "/join"), Token: member.Token,
+		Body: map[string]string{"password": "s3cret-pass"},
+	}.send(t), http.StatusOK)
+
+	for _, tc := range []struct {
+		name   string
+		user   testUser
+		status int
+	}{
+		{"owner", owner, http.StatusOK},
+		{"member", member, http.StatusOK},
+		{"outsider", outsider, http.StatusForbidden},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			expectStatus(t, apiRequest{
+				Method: http.MethodGet, Path: groupPath(g, "/members"),
+				Token: tc.user.Token,
+			}.send(t), tc.status)
+		})
+	}
+}
+
+func TestUnlistedGroupIsHiddenFromListing(t *testing.T) {
+	owner, outsider := signUp(t), signUp(t)
+	public := createGroup(t, owner, nil)
+	unlisted := createGroup(t, owner, map[string]interface{}{"visibility": "unlisted"})
+
+	w := apiRequest{
+		Method: http.MethodGet, Path: "/groups?page_size=50", Token: outsider.Token,
+	}.send(t)
+	expectStatus(t, w, http.StatusOK)
+	var groups []map[string]interface{}
+	decode(t, w, &groups)
+	listed := map[interface{}]bool{}
+	for _, grp := range groups {
+		listed[grp["id"]] = true
+	}
+	if !listed[public["id"]] {
+		t.Errorf("public group %v is not listed", public["id"])
+	}
+	if listed[unlisted["id"]] {
+		t.Errorf("unlisted group %v is listed", unlisted["id"])
+	}
+
+	// The group can still be retrieved and joined with its link.
+	w = apiRequest{
+		Method: http.MethodGet, Path: groupPath(unlisted, ""), Token: outsider.Token,
+	}.send(t)
+	expectStatus(t, w, http.StatusOK)
+	var resp map[string]interface{}
+	decode(t, w, &resp)
+	if resp["visibility"] != "unlisted" {
+		t.Errorf("got visibility %v, want unlisted", resp["visibility"])
+	}
+	expectStatus(t, apiRequest{
+		Method: http.MethodPost, Path: groupPath(unlisted, "/join"),
+		Token: outsider.Token,
+	}.send(t), http.StatusOK)
+}
+
+func TestExpandMembersOfGroupWithoutMembers(t *testing.T) {
+	owner := signUp(t)
+	g := createGroup(t, owner, nil)
+
+	for _, tc := range []struct {
+		query    string
+		expanded bool
+	}{
+		{"", false},
+		{"?expand=members", true},
+		{"?include=tags,members", true},
+	} {
+		t.Run(tc.query, func(t *testing.T) {
+			w := apiRequest{
+				Method: http.MethodGet, Path: groupPath(g, tc.query), Token: owner.Token,
+			}.send(t)
+			expectStatus(t, w, http.StatusOK)
+			var resp map[string]interface{}
+			decode(t, w, &resp)
+			members, ok := resp["members"]
+			if !ok {
+				t.Fatalf("got no members key: %v", resp)
+			}
+			// An empty list tells an expanded group without members apart
+			// from a group that is not expanded.
+			list, isList := members.([]interface{})
+			if tc.expanded && (!isList || len(list) != 0) {
+				t.Errorf("got members %v, want []", members)
+			} else if !tc.expanded && members != nil {
+				t.Errorf("got members %v, want null", members)
+			}
+		})
+	}
+}
+
+func TestListGroupsExpandsMembersOnlyWhenAsked(t *testing.T) {
+	owner, member := signUp(t), signUp(t)
+	g := createGroup(t, owner, map[string]interface{}{"title": "Wombatwatch raid"})
+	joinGroup(t, member, g)
+
+	for _, tc := range []struct {
+		query    string
+		expanded bool
+	}{
+		{"", false},
+		{"&expand=members", true},
+	} {
+		t.Run(tc.query, func(t *testing.T) {
+			w := apiRequest{
+				Method: http.MethodGet, Path: "/groups?q=wombatwatch" + tc.query,
+				Token: member.Token,
+			}.send(t)
+			expectStatus(t, w, http.StatusOK)
+			var groups []map[string]interface{}
+			decode(t, w, &groups)
+			if len(groups) != 1 {
+				t.Fatalf("got groups %v, want only %v", groups, g["id"])
+			}
+			grp := groups[0]
+			if grp["member_count"] != float64(1) {
+				t.Errorf("got member count %v, want 1", grp["member_count"])
+			}
+			// The membership is known without the member list.
+			if grp["join_blocked_reason"] != schemas.JoinBlockedAlreadyMember {
+				t.Errorf("got join blocked reason %v, want %v",
+					grp["join_blocked_reason"], schemas.JoinBlockedAlreadyMember)
+			}
+			members, _ := grp["members"].([]interface{})
+			if tc.expanded && len(members) != 1 {
+				t.Errorf("got members %v, want the member", grp["members"])
+			} else if !tc.expanded && grp["members"] != nil {
+				t.Errorf("got members %v, want null", grp["members"])
+			}
+		})
+	}
+}
+
+func TestCreateGroupRejectsInvalidStatusAndVisibility(t *testing.T) {
+	owner := signUp(t)
+	for _, tc := range []struct {
+		name    string
+		fields  map[string]interface{}
+		field   string
+		wantMsg string
+	}{
+		{"closed", map[string]interface{}{"status": -100},
+			"status", "The value should be one of: 0"},
+		{"unknown status", map[string]interface{}{"status": 7},
+			"status", "The value should be one of: 0"},
+		{"unknown visibility", map[string]interface{}{"visibility": "hidden"},
+			"visibility", "The value should be one of: public, unlisted, private"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			body := map[string]interface{}{
+				"title": "Raid night", "description": "Weekly raid", "max_size": 5,
+			}
+			for k, v := range tc.fields {
+				body[k] = v
+			}
+			w := apiRequest{
+				Method: http.MethodPost, Path: "/groups", Token: owner.Token, Body: body,
+			}.send(t)
+			expectStatus(t, w, http.StatusBadRequest)
+
+			var resp struct {
+				FieldErrors []struct {
+					Name  string
+					ID    string
+					Error string
+				} `json:"field_errors"`
+			}
+			decode(t, w, &resp)
+			for _, fe := range resp.FieldErrors {
+				if fe.Name == tc.field {
+					if fe.ID != "one_of" || fe.Error != tc.wantMsg {
+						t.Errorf("got %v error %q (%v), want %q (one_of)",
+							fe.Name, fe.Error, fe.ID, tc.wantMsg)
+					}
+					return
+				}
+			}
+			t.Errorf("got no %v error: %+v", tc.field, resp.FieldErrors)
+		})
+	}
+}
+
+func TestReserveSlotDistinguishesMissingAndForbidden(t *testing.T) {
+	owner, outsider := signUp(t), signUp(t)
+	g := createGroup(t, owner, map[string]interface{}{"password": "s3cret-pass"})
+	reserve := func(path, password string) *httptest.ResponseRecorder {
+		return apiRequest{
+			Method: http.MethodPost, Path: path, Token: outsider.Token,
+			Body: map[string]string{"password": password},
+		}.send(t)
+	}
+
+	expectStatus(t, reserve("/groups/999999999/reserve", "s3cret-pass"),
+		http.StatusNotFound)
+	w := reserve(groupPath(g, "/reserve"), "wrong-pass")
+	expectStatus(t, w, http.StatusForbidden)
+	var resp struct {
+		Code string `json:"code"`
+	}
+	decode(t, w, &resp)
+	if resp.Code != "incorrect_password" {
+		t.Errorf("got code %q, want incorrect_password", resp.Code)
+	}
+	expectStatus(t, reserve(groupPath(g, "/reserve"), "s3cret-pass"),
+		http.StatusCreated)
+}
+
+func TestCreateGroupIgnoresServerFields(t *testing.T) {
+	owner, other := signUp(t), signUp(t)
+	g := createGroup(t, owner, map[string]interface{}{
+		"id":         123456789,
+		"views":      999,
+		"version":    42,
+		"created_at": "2001-02-03T04:05:06Z",
+		"deleted_at": "2001-02-03T04:05:06Z",
+		"members":    []map[string]interface{}{{"id": other.ID}},
+	})
+
+	if g["id"] == float64(123456789) {
+		t.Errorf("got the id %v of the request body", g["id"])
+	}
+	if g["views"] != float64(0) || g["version"] != float64(1) {
+		t.Errorf("got views %v and version %v, want 0 and 1", g["views"], g["version"])
+	}
+	if created, _ := g["created_at"].(string); strings.HasPrefix(created, "2001") {
+		t.Errorf("got the created_at %v of the request body", created)
+	}
+	if g["deleted_at"] != nil {
+		t.Errorf("got deleted_at %v, want none", g["deleted_at"])
+	}
+
+	w := apiRequest{
+		Method: http.MethodGet, Path: groupPath(g, "/members"), Token: owner.Token,
+	}.send(t)
+	expectStatus(t, w, http.StatusOK)
+	var members []map[string]interface{}
+	decode(t, w, &members)
+	if len(members) != 0 {
+		t.Errorf("got members %v, want none", members)
+	}
+}
+
+func TestListOwnedGroups(t *testing.T) {
+	owner, other := signUp(t), signUp(t)
+	var ids []int64
+	for _, fields := range []map[string]interface{}{
+		{"title": "Owned public"},
+		{"title": "Owned unlisted", "visibility": "unlisted"},
+		{"title": "Owned private", "password": "s3cret-pass"},
+	} {
+		ids = append(ids, groupID(createGroup(t, owner, fields)))
+	}
+	createGroup(t, other, nil)
+
+	// All the groups of the owner are listed whatever their visibility, the
+	// newest first.
+	if got := listIDs(t, owner, "/me/groups/owned"); !slices.Equal(
+		got, []int64{ids[2], ids[1], ids[0]}) {
+		t.Errorf("got groups %v, want %v", got, ids)
+	}
+	if got := listIDs(t, owner, "/me/groups/owned?page_size=2"); !slices.Equal(
+		got, []int64{ids[2], ids[1]}) {
+		t.Errorf("got first page %v, want the 2 newest of %v", got, ids)
+	}
+	if got := listIDs(t, owner, "/me/groups/owned?page_size=2&page=2"); !slices.Equal(
+		got, []int64{ids[0]}) {
+		t.Errorf("got second page %v, want the oldest of %v", got, ids)
+	}
+	if got := listIDs(t, signUp(t), "/me/groups/owned"); len(got) != 0 {
+		t.Errorf("got groups %v for a user without groups", got)
+	}
+}
+
+func TestListJoinedGroups(t *testing.T) {
+	u, owner := signUp(t), signUp(t)
+	first := createGroup(t, owner, nil)
+	second := createGroup(t, owner, map[string]interface{}{"password": "s3cret-pass"})
+	left := createGroup(t, owner, nil)
+	createGroup(t, u, nil) // Owned groups are not joined groups.
+	joinGroup(t, u, first)
+	expectStatus(t, apiRequest{
+		Method: http.MethodPost, Path: groupPath(second, "/join"), Token: u.Token,
+		Body: map[string]string{"password": "s3cret-pass"},
+	}.send(t), http.StatusOK)
+	joinGroup(t, u, left)
+	expectStatus(t, apiRequest{
+		Method: http.MethodPost, Path: groupPath(left, "/leave"), Token: u.Token,
+	}.send(t), http.StatusOK)
+
+	want := []int64{groupID(second), groupID(first)}
+	if got := listIDs(t, u, "/me/groups/joined"); !slices.Equal(got, want) {
+		t.Errorf("got groups %v, want %v", got, want)
+	}
+	if got := listIDs(t, u, "/me/groups/joined?page_size=1&page=2"); !slices.Equal(
+		got, want[1:]) {
+		t.Errorf("got second page %v, want %v", got, want[1:])
+	}
+}
+
+func TestDuplicateTitleModes(t *testing.T) {
+	defer func(mode string) { endpoints.DUPLICATE_TITLE_MODE = mode }(
+		endpoints.DUPLICATE_TITLE_MODE)
+	owner := signUp(t)
+	createGroup(t, owner, map[string]interface{}{"title": "Same title"})
+	create := func() *httptest.ResponseRecorder {
+		return apiRequest{
+			Method: http.MethodPost, Path: "/groups", Token: owner.Token,
+			Body: map[string]interface{}{
+				"title": "Same title", "description": "Weekly raid", "max_size": 5,
+			},
+		}.send(t)
+	}
+
+	endpoints.DUPLICATE_TITLE_MODE = "reject"
+	w := create()
+	expectStatus(t, w, http.StatusBadRequest)
+	if ids := fieldErrorIDs(t, w); len(ids["title"]) != 1 ||
+		ids["title"][0] != "duplicate_title" {
+		t.Errorf("got field errors %v, want duplicate_title", ids)
+	}
+
+	for _, tc := range []struct {
+		mode        string
+		wantWarning bool
+	}{
+		{"warn", true},
+		{"off", false},
+	} {
+		endpoints.DUPLICATE_TITLE_MODE = tc.mode
+		w := create()
+		expectStatus(t, w, http.StatusCreated)
+		var resp struct {
+			Warnings []string `json:"warnings"`
+		}
+		decode(t, w, &resp)
+		if (len(resp.Warnings) > 0) != tc.wantWarning {
+			t.Errorf("got warnings %v in %v mode", resp.Warnings, tc.mode)
+		}
+	}
+
+	// Closed groups are not duplicates.
+	endpoints.DUPLICATE_TITLE_MODE = "reject"
+	owner = signUp(t)
+	closed := createGroup(t, owner, map[string]interface{}{"title": "Same title"})
+	expectStatus(t, apiRequest{
+		Method: http.MethodPost, Path: groupPath(closed, "/close"), Token: owner.Token,
+	}.send(t), http.StatusOK)
+	expectStatus(t, create(), http.StatusCreated)
+}
+
+func TestOnlyOwnerCanDeleteGroup(t *testing.T) {
+	owner, member, outsider := signUp(t), signUp(t), signUp(t)
+	g := createGroup(t, owner, nil)
+	joinGroup(t, member, g)
+
+	for _, u := range []testUser{member, outsider} {
+		expectStatus(t, apiRequest{
+			Method: http.MethodDelete, Path: groupPath(g, ""), Token: u.Token,
+		}.send(t), http.StatusForbidden)
+	}
+	expectStatus(t, apiRequest{
+		Method: http.MethodDelete, Path: groupPath(g, ""), Token: owner.Token,
+	}.send(t), http.StatusNoContent)
+	expectStatus(t, apiRequest{
+		Method: http.MethodGet, Path: groupPath(g, ""), Token: member.Token,
+	}.send(t), http.StatusNotFound)
+}
+
+func TestJoinBlockedReason(t *testing.T) {
+	defer func(max int) { endpoints.MAX_JOINED_GROUPS = max }(endpoints.MAX_JOINED_GROUPS)
+	endpoints.MAX_JOINED_GROUPS = 1
+
+	// fill makes four users join the group so it is full at max_size 5.
+	fill := func(t *testing.T, g map[string]interface{}) {
+		for i := 0; i < 4; i++ {
+			joinGroup(t, signUp(t), g)
+		}
+	}
+
+	for _, tc := range []struct {
+		name   string
+		fields map[string]interface{}
+		// setup prepares the group and the user who views it.
+		setup func(t *testing.T, owner, viewer testUser, g map[string]interface{}) testUser
+		want  string
+		// join is the status of joining the group afterwards, or zero if
+		// it is not checked.
+		join int
+	}{
+		{
+			name: "joinable",
+			want: "", join: http.StatusOK,
+		},
+		{
+			name: "owner",
+			setup: func(t *testing.T, owner, _ testUser, _ map[string]interface{}) testUser {
+				return owner
+			},
+			want: "IS_OWNER", join: http.StatusBadRequest,
+		},
+		{
+			name: "member",
+			setup: func(t *testing.T, _, viewer testUser, g map[string]interface{}) testUser {
+				joinGroup(t, viewer, g)
+				return viewer
+			},
+			want: "ALREADY_MEMBER",
+		},
+		{
+			name: "closed",
+			setup: func(t *testing.T, owner, viewer testUser, g map[string]interface{}) testUser {
+				expectStatus(t, apiRequest{
+					Method: http.MethodPost, Path: groupPath(g, "/close"), Token: owner.Token,
+				}.send(t), http.StatusOK)
+				return viewer
+			},
+			want: "CLOSED", join: http.StatusBadRequest,
+		},
+		{
+			name: "full",
+			setup: func(t *testing.T, _, viewer testUser, g map[string]interface{}) testUser {
+				fill(t, g)
+				return viewer
+			},
+			want: "FULL", join: http.StatusBadRequest,
+		},
+		{
+			name:   "full with waitlist",
+			fields: map[string]interface{}{"waitlist": true},
+			setup: func(t *testing.T, _, viewer testUser, g map[string]interface{}) testUser {
+				fill(t, g)
+				return viewer
+			},
+			want: "", join: http.StatusAccepted,
+		},
+		{
+			name:   "full with approval",
+			fields: map[string]interface{}{"require_approval": true},
+			setup: func(t *testing.T, owner, viewer testUser, g map[string]interface{}) testUser {
+				for i := 0; i < 4; i++ {
+					u := signUp(t)
+					expectStatus(t, apiRequest{
+						Method: http.MethodPost, Path: groupPath(g, "/join"), Token: u.Token,
+					}.send(t), http.StatusAccepted)
+					expectStatus(t, apiRequest{
+						Method: http.MethodPost, Token: owner.Token,
+						Path: groupPath(g, fmt.Sprintf("/requests/%v/approve", u.ID)),
+					}.send(t), http.StatusOK)
+				}
+				return viewer
+			},
+			want: "", join: http.StatusAccepted,
+		},
+		{
+			name: "banned",
+			setup: func(t *testing.T, owner, viewer testUser, g map[string]interface{}) testUser {
+				joinGroup(t, viewer, g)
+				expectStatus(t, apiRequest{
+					Method: http.MethodPost, Path: groupPath(g, "/kick"), Token: owner.Token,
+					Body: map[string]interface{}{"id": viewer.ID, "ban": true},
+				}.send(t), http.StatusOK)
+				return viewer
+			},
+			want: "BANNED", join: http.StatusForbidden,
+		},
+		{
+			name:   "password",
+			fields: map[string]interface{}{"password": "s3cret-pass"},
+			want:   "PASSWORD_REQUIRED", join: http.StatusBadRequest,
+		},
+		{
+			name: "joined group limit",
+			setup: func(t *testing.T, _, viewer testUser, _ map[string]interface{}) testUser {
+				joinGroup(t, viewer, createGroup(t, signUp(t), nil))
+				return viewer
+			},
+			want: "JOINED_GROUP_LIMIT", join: http.StatusBadRequest,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			owner, viewer := signUp(t), signUp(t)
+			g := createGroup(t, owner, tc.fields)
+			if tc.setup != nil {
+				viewer = tc.setup(t, owner, viewer, g)
+			}
+
+			// The password unlocks the details of a private group.
+			headers := map[string]string{"X-Group-Password": "s3cret-pass"}
+			w := apiRequest{
+				Method: http.MethodGet, Path: groupPath(g, ""), Token: viewer.Token,
+				Headers: headers,
+			}.send(t)
+			expectStatus(t, w, http.StatusOK)
+			var got map[string]interface{}
+			decode(t, w, &got)
+			if got["join_blocked_reason"] != tc.want {
+				t.Errorf("got reason %q, want %q", got["join_blocked_reason"], tc.want)
+			}
+
+			// The listings set the same reason.
+			w = apiRequest{
+				Method: http.MethodGet, Path: fmt.Sprintf("/groups?ids=%v", g["id"]),
+				Token: viewer.Token,
+			}.send(t)
+			expectStatus(t, w, http.StatusOK)
+			var listed []map[string]interface{}
+			decode(t, w, &listed)
+			if len(listed) != 1 {
+				t.Fatalf("got %v listed groups, want 1", len(listed))
+			}
+			// Only the summary of a private group is listed.
+			if tc.fields["password"] == nil &&
+				listed[0]["join_blocked_reason"] != tc.want {
+				t.Errorf("got listed reason %q, want %q",
+					listed[0]["join_blocked_reason"], tc.want)
+			}
+
+			if tc.join != 0 {
+				expectStatus(t, apiRequest{
+					Method: http.MethodPost, Path: groupPath(g, "/join"),
+					Token: viewer.Token,
+				}.send(t), tc.join)
+			}
+		})
+	}
+}
+
+func TestReopenGroup(t *testing.T) {
+	owner, other := signUp(t), signUp(t)
+	g := createGroup(t, owner, nil)
+	reopen := func(u testUser) *httptest.ResponseRecorder {
+		return apiRequest{
+			Method: http.MethodPost, Path: groupPath(g, "/reopen"), Token: u.Token,
+		}.send(t)
+	}
+
+	// An open group cannot be reopened.
+	w := reopen(owner)
+	expectStatus(t, w, http.StatusBadRequest)
+	var body struct {
+		Code string `json:"code"`
+	}
+	decode(t, w, &body)
+	if body.Code != "group_not_closed" {
+		t.Errorf("got code %q, want group_not_closed", body.Code)
+	}
+
+	w = apiRequest{
+		Method: http.MethodPost, Path: groupPath(g, "/close"), Token: owner.Token,
+	}.send(t)
+	expectStatus(t, w, http.StatusOK)
+	var closed map[string]interface{}
+	decode(t, w, &closed)
+	if closed["status"] != float64(schemas.GroupStatusClosed) {
+		t.Errorf("got status %v after closing, want %v",
+			closed["status"], schemas.GroupStatusClosed)
+	}
+	expectStatus(t, reopen(other), http.StatusForbidden)
+
+	w = reopen(owner)
+	expectStatus(t, w, http.StatusOK)
+	var got map[string]interface{}
+	decode(t, w, &got)
+	if got["status"] != float64(schemas.GroupStatusOpen) {
+		t.Errorf("got status %v after reopening, want %v",
+			got["status"], schemas.GroupStatusOpen)
+	}
+	joinGroup(t, other, g)
+}
+
+func TestListGroupsAsNDJSON(t *testing.T) {
+	owner, viewer := signUp(t), signUp(t)
+	for _, title := range []string{"Foxglove raid", "Foxglove dungeon", "Foxglove arena"} {
+		createGroup(t, owner, map[string]interface{}{"title": title})
+	}
+	createGroup(t, owner, map[string]interface{}{"title": "Unrelated raid"})
+	const path = "/groups?q=foxglove"
+
+	w := apiRequest{Method: http.MethodGet, Path: path, Token: viewer.Token}.send(t)
+	expectStatus(t, w, http.StatusOK)
+	var listed []json.RawMessage
+	decode(t, w, &listed)
+
+	w = apiRequest{
+		Method: http.MethodGet, Path: path, Token: viewer.Token,
+		Headers: map[string]string{"Accept": "application/x-ndjson"},
+	}.send(t)
+	expectStatus(t, w, http.StatusOK)
+	if ct := w.Header().Get("Content-Type"); ct != "application/x-ndjson" {
+		t.Errorf("got content type %q, want application/x-ndjson", ct)
+	}
+	var lines []string
+	scanner := bufio.NewScanner(w.Body)
+	for scanner.Scan() {
+		var grp map[string]interface{}
+		if err := json.Unmarshal(scanner.Bytes(), &grp); err != nil {
+			t.Fatalf("could not decode the line %q: %v", scanner.Text(), err)
+		}
+		lines = append(lines, scanner.Text())
+	}
+
+	// Each line is the group of the JSON listing at the same position.
+	if len(lines) != 3 || len(listed) != 3 {
+		t.Fatalf("got %v lines and %v listed groups, want 3 of each",
+			len(lines), len(listed))
+	}
+	for i, line := range lines {
+		if line != string(listed[i]) {
+			t.Errorf("got line %v %s, want %s", i, line, listed[i])
+		}
+	}
+}
+
+func TestUpdateGroupKeepsOmittedFieldsAndClearsEmptyOnes(t *testing.T) {
+	owner := signUp(t)
+	startsAt := time.Now().Add(24 * time.Hour).UTC().Truncate(time.Second)
+	g := createGroup(t, owner, map[string]interface{}{
+		"title":       "Marigold raid",
+		"description": "Bring potions",
+		"starts_at":   startsAt.Format(time.RFC3339),
+	})
+	update := func(fields map[string]interface{}) map[string]interface{} {
+		t.Helper()
+		w := apiRequest{
+			Method: http.MethodPatch, Path: groupPath(g, ""), Token: owner.Token,
+			Body: fields,
+		}.send(t)
+		expectStatus(t, w, http.StatusOK)
+		var resp map[string]interface{}
+		decode(t, w, &resp)
+		return resp
+	}
+
+	// The fields left out are kept.
+	resp := update(map[string]interface{}{"title": "Marigold raid v2"})
+	if resp["title"] != "Marigold raid v2" || resp["description"] != "Bring potions" ||
+		resp["starts_at"] != startsAt.Format(time.RFC3339) {
+		t.Errorf("got %v, want the new title and the other fields kept", resp)
+	}
+
+	// An empty description and a null start time clear the fields.
+	resp = update(map[string]interface{}{"description": "", "starts_at": nil})
+	if _, ok := resp["description"]; ok {
+		t.Errorf("got description %v, want it cleared", resp["description"])
+	}
+	if _, ok := resp["starts_at"]; ok {
+		t.Errorf("got start time %v, want it cleared", resp["starts_at"])
+	}
+	if resp["title"] != "Marigold raid v2" {
+		t.Errorf("got title %v, want it kept", resp["title"])
+	}
+
+	// The title is still required.
+	w := apiRequest{
+		Method: http.MethodPatch, Path: groupPath(g, ""), Token: owner.Token,
+		Body: map[string]interface{}{"title": ""},
+	}.send(t)
+	expectStatus(t, w, http.StatusBadRequest)
+	if ids := fieldErrorIDs(t, w)["title"]; len(ids) == 0 {
+		t.Errorf("got no title errors for an empty title")
+	}
+}
+
+func TestUpdateGroupMaxSizeBelowMemberCount(t *testing.T) {
+	owner := signUp(t)
+	g := createGroup(t, owner, map[string]interface{}{"max_size": 8})
+	for i := 0; i < 5; i++ {
+		joinGroup(t, signUp(t), g)
+	}
+	update := func(size int) *httptest.ResponseRecorder {
+		return apiRequest{
+			Method: http.MethodPatch, Path: groupPath(g, ""), Token: owner.Token,
+			Body: map[string]interface{}{"max_size": size},
+		}.send(t)
+	}
+
+	// The owner and the five members are six people in the group.
+	w := update(5)
+	expectStatus(t, w, http.StatusBadRequest)
+	if ids := fieldErrorIDs(t, w)["max_size"]; !slices.Contains(ids, "below_member_count") {
+		t.Errorf("got max size errors %v, want below_member_count", ids)
+	}
+	w = update(6)
+	expectStatus(t, w, http.StatusOK)
+	var resp map[string]interface{}
+	decode(t, w, &resp)
+	if resp["max_size"] != float64(6) {
+		t.Errorf("got max size %v, want 6", resp["max_size"])
+	}
+}
+
+func TestConcurrentUpdatesConflict(t *testing.T) {
+	owner := signUp(t)
+	g := createGroup(t, owner, map[string]interface{}{"version": 42})
+	if g["version"] != float64(1) {
+		t.Fatalf("got version %v on create, want 1", g["version"])
+	}
+	update := func(title string) *httptest.ResponseRecorder {
+		return apiRequest{
+			Method: http.MethodPatch, Path: groupPath(g, ""), Token: owner.Token,
+			Body: map[string]interface{}{"title": title, "version": 1},
+		}.send(t)
+	}
+
+	// Both updates are based on version 1, so the second one is rejected.
+	w := update("First conflicting update")
+	expectStatus(t, w, http.StatusOK)
+	var resp map[string]interface{}
+	decode(t, w, &resp)
+	if resp["version"] != float64(2) {
+		t.Errorf("got version %v after the update, want 2", resp["version"])
+	}
+	w = update("Second conflicting update")
+	expectStatus(t, w, http.StatusConflict)
+	var body schemas.BodyError
+	decode(t, w, &body)
+	if body.Code != "conflict" {
+		t.Errorf("got code %q, want conflict", body.Code)
+	}
+
+	w = apiRequest{
+		Method: http.MethodGet, Path: groupPath(g, ""), Token: owner.Token,
+	}.send(t)
+	expectStatus(t, w, http.StatusOK)
+	decode(t, w, &resp)
+	if resp["title"] != "First conflicting update" || resp["version"] != float64(2) {
+		t.Errorf("got title %v and version %v, want the first update",
+			resp["title"], resp["version"])
+	}
+}
+
+func TestWhitespaceOnlyTitleIsRequired(t *testing.T) {
+	owner := signUp(t)
+	w := apiRequest{
+		Method: http.MethodPost, Path: "/groups", Token: owner.Token,
+		Body: map[string]interface{}{
+			"title": " \t ", "description": "Blank title", "max_size": 5},
+	}.send(t)
+	expectStatus(t, w, http.StatusBadRequest)
+	if ids := fieldErrorIDs(t, w)["title"]; !slices.Contains(ids, "required") {
+		t.Errorf("got title errors %v on create, want required", ids)
+	}
+
+	g := createGroup(t, owner, nil)
+	w = apiRequest{
+		Method: http.MethodPatch, Path: groupPath(g, ""), Token: owner.Token,
+		Body: map[string]interface{}{"title": "   "},
+	}.send(t)
+	expectStatus(t, w, http.StatusBadRequest)
+	if ids := fieldErrorIDs(t, w)["title"]; !slices.Contains(ids, "required") {
+		t.Errorf("got title errors %v on update, want required", ids)
+	}
+}
+
+func TestOwnedGroupLimit(t *testing.T) {
+	defer func(limit int) { endpoints.MAX_OWNED_GROUPS = limit }(endpoints.MAX_OWNED_GROUPS)
+	endpoints.MAX_OWNED_GROUPS = 2
+
+	owner := signUp(t)
+	create := func() *httptest.ResponseRecorder {
+		return apiRequest{
+			Method: http.MethodPost, Path: "/groups", Token: owner.Token,
+			Body: map[string]interface{}{
+				"title": "Juniper squad", "description": "Limit test", "max_size": 5},
+		}.send(t)
+	}
+	// The limit is only reached with the last group.
+	expectStatus(t, create(), http.StatusCreated)
+	w := create()
+	expectStatus(t, w, http.StatusCreated)
+	var second map[string]interface{}
+	decode(t, w, &second)
+
+	w = create()
+	expectStatus(t, w, http.StatusBadRequest)
+	var resp struct {
+		Code string `json:"code"`
+	}
+	decode(t, w, &resp)
+	if resp.Code != "owned_group_limit" {
+		t.Errorf("got code %q, want owned_group_limit", resp.Code)
+	}
+
+	// Closed groups do not count.
+	expectStatus(t, apiRequest{
+		Method: http.MethodPost, Path: groupPath(second, "/close"), Token: owner.Token,
+	}.send(t), http.StatusOK)
+	expectStatus(t, create(), http.StatusCreated)
+}
+
+func TestJoinedGroupLimit(t *testing.T) {
+	defer func(limit int) { endpoints.MAX_JOINED_GROUPS = limit }(endpoints.MAX_JOINED_GROUPS)
+	endpoints.MAX_JOINED_GROUPS = 2
+
+	owner, u := signUp(t), signUp(t)
+	// The groups of the user and the groups the user was put in by another
+	// owner do not count.
+	createGroup(t, u, nil)
+	injected := createGroup(t, owner, map[string]interface{}{
+		"members": []map[string]interface{}{{"id": u.ID}}})
+	if ids := listIDs(t, u, "/me/groups/joined"); slices.Contains(ids, groupID(injected)) {
+		t.Errorf("got group %v joined through the create body", groupID(injected))
+	}
+
+	first, second, third := createGroup(t, owner, nil), createGroup(t, owner, nil),
+		createGroup(t, owner, nil)
+	joinGroup(t, u, first)
+	// Joining up to the limit is allowed.
+	joinGroup(t, u, second)
+	w := apiRequest{
+		Method: http.MethodPost, Path: groupPath(third, "/join"), Token: u.Token,
+	}.send(t)
+	expectStatus(t, w, http.StatusBadRequest)
+	var resp struct {
+		Code string `json:"code"`
+	}
+	decode(t, w, &resp)
+	if resp.Code != "joined_group_limit" {
+		t.Errorf("got code %q, want joined_group_limit", resp.Code)
+	}
+
+	// Closed groups do not count.
+	expectStatus(t, apiRequest{
+		Method: http.MethodPost, Path: groupPath(first, "/close"), Token: owner.Token,
+	}.send(t), http.StatusOK)
+	joinGroup(t, u, third)
+}
+
+func TestDeletedGroupIsHiddenButKept(t *testing.T) {
+	owner, admin := signUp(t), signUp(t)
+	makeAdmin(t, admin)
+	g := createGroup(t, owner, map[string]interface{}{"title": "Hawthorn guild"})
+	expectStatus(t, apiRequest{
+		Method: http.MethodDelete, Path: groupPath(g, ""), Token: owner.Token,
+	}.send(t), http.StatusNoContent)
+
+	if ids := listGroupIDs(t, owner, "q=Hawthorn"); len(ids) != 0 {
+		t.Errorf("got groups %v listed, want the deleted group hidden", ids)
+	}
+	expectStatus(t, apiRequest{
+		Method: http.MethodGet, Path: groupPath(g, ""), Token: owner.Token,
+	}.send(t), http.StatusNotFound)
+	if ids := listIDs(t, admin, "/admin/groups?q=Hawthorn"); !slices.Equal(
+		ids, []int64{groupID(g)}) {
+		t.Errorf("got groups %v for the admin, want the deleted group", ids)
+	}
+
+	// The row is still in the table, marked as deleted.
+	sg := schemas.Group{}
+	if err := sg.InitDB(context.Background()); err != nil {
+		t.Fatalf("could not init the database: %v", err)
+	}
+	var kept schemas.Group
+	if r := sg.DB.Unscoped().First(&kept, groupID(g)); r.Error != nil {
+		t.Fatalf("could not find the deleted group: %v", r.Error)
+	}
+	if !kept.DeletedAt.Valid {
+		t.Error("got the group without a deletion time")
+	}
+}
+
+func TestListGroupsRejectsInvalidCreationTimes(t *testing.T) {
+	u := signUp(t)
+	for _, tc := range []struct {
+		query string
+		code  string
+	}{
+		{"created_after=yesterday", "invalid_query"},
+		{"created_before=2024-06-01", "invalid_query"},
+		{"created_after=2024-06-02T00:00:00Z&created_before=2024-06-01T00:00:00Z",
+			"validation_error"},
+	} {
+		w := apiRequest{
+			Method: http.MethodGet, Path: "/groups?" + tc.query, Token: u.Token,
+		}.send(t)
+		expectStatus(t, w, http.StatusBadRequest)
+		var resp struct {
+			Code string `json:"code"`
+		}
+		decode(t, w, &resp)
+		if resp.Code != tc.code {
+			t.Errorf("got code %q for %v, want %q", resp.Code, tc.query, tc.code)
+		}
+	}
+	expectStatus(t, apiRequest{
+		Method: http.MethodGet, Token: u.Token,
+		Path: "/groups?created_after=2024-06-01T00:00:00%2B02:00",
+	}.send(t), http.StatusOK)
+}
+
+func TestGroupGames(t *testing.T) {
+	defer func(games []string) { endpoints.GAMES = games }(endpoints.GAMES)
+	endpoints.GAMES = []string{"Valheim", "Dota 2"}
+
+	owner := signUp(t)
+	// Games are matched regardless of case and stored as allowed.
+	valheim := createGroup(t, owner, map[string]interface{}{
+		"title": "Wisteria vikings", "game": "valheim"})
+	if valheim["game"] != "Valheim" {
+		t.Errorf("got game %v, want Valheim", valheim["game"])
+	}
+	dota := createGroup(t, owner, map[string]interface{}{
+		"title": "Wisteria ancients", "game": "Dota 2"})
+	createGroup(t, owner, map[string]interface{}{"title": "Wisteria anything"})
+
+	for _, req := range []apiRequest{
+		{Method: http.MethodPost, Path: "/groups", Token: owner.Token,
+			Body: map[string]interface{}{
+				"title": "Wisteria blocks", "description": "Unknown game",
+				"max_size": 5, "game": "Tetris"}},
+		{Method: http.MethodPatch, Path: groupPath(dota, ""), Token: owner.Token,
+			Body: map[string]interface{}{"game": "Tetris"}},
+	} {
+		w := req.send(t)
+		expectStatus(t, w, http.StatusBadRequest)
+		if ids := fieldErrorIDs(t, w)["game"]; len(ids) != 1 || ids[0] != "one_of" {
+			t.Errorf("got game errors %v on %v, want one_of", ids, req.Method)
+		}
+	}
+
+	for game, want := range map[string][]int64{
+		"Valheim":  {groupID(valheim)},
+		"Dota%202": {groupID(dota)},
+	} {
+		if ids := listGroupIDs(t, owner, "q=Wisteria&game="+game); !slices.Equal(ids, want) {
+			t.Errorf("got groups %v for game %v, want %v", ids, game, want)
+		}
+	}
+}
+
+func TestGroupTags(t *testing.T) {
+	owner := signUp(t)
+	both := createGroup(t, owner, map[string]interface{}{
+		"title": "Primrose both", "tags": []string{" Ranked ", "voice", "RANKED"}})
+	ranked := createGroup(t, owner, map[string]interface{}{
+		"title": "Primrose ranked", "tags": []string{"ranked"}})
+	voice := createGroup(t, owner, map[string]interface{}{
+		"title": "Primrose voice", "tags": []string{"casual", "voice"}})
+
+	// Tags are stored in lowercase without duplicates.
+	if tags := fmt.Sprint(both["tags"]); tags != "[ranked voice]" {
+		t.Errorf("got tags %v, want [ranked voice]", tags)
+	}
+
+	for _, tc := range []struct {
+		tags string
+		want []int64
+	}{
+		{"ranked", []int64{groupID(both), groupID(ranked)}},
+		{"voice", []int64{groupID(both), groupID(voice)}},
+		{"Ranked,voice", []int64{groupID(both)}},
+		{"ranked,casual", []int64{}},
+	} {
+		ids := listGroupIDs(t, owner, "q=Primrose&tags="+tc.tags)
+		slices.Sort(ids)
+		if !slices.Equal(ids, tc.want) {
+			t.Errorf("got groups %v for tags %v, want %v", ids, tc.tags, tc.want)
+		}
+	}
+
+	w := apiRequest{
+		Method: http.MethodPatch, Path: groupPath(ranked, ""), Token: owner.Token,
+		Body: map[string]interface{}{"tags": []string{"a", "b", "c", "d", "e", "f"}},
+	}.send(t)
+	expectStatus(t, w, http.StatusBadRequest)
+	if ids := fieldErrorIDs(t, w)["tags"]; len(ids) != 1 {
+		t.Errorf("got tag errors %v for too many tags, want one", ids)
+	}
+}
+
+func TestGroupStartTimes(t *testing.T) {
+	owner := signUp(t)
+	base := time.Now().Add(48 * time.Hour).UTC().Truncate(time.Second)
+	early := createGroup(t, owner, map[string]interface{}{
+		"title": "Larkspur early", "starts_at": base.Format(time.RFC3339),
+		"timezone": "Europe/Paris"})
+	late := createGroup(t, owner, map[string]interface{}{
+		"title":     "Larkspur late",
+		"starts_at": base.Add(2 * time.Hour).In(time.FixedZone("", -5*60*60)).Format(time.RFC3339)})
+	createGroup(t, owner, map[string]interface{}{"title": "Larkspur anytime"})
+
+	if early["starts_at"] != base.Format(time.RFC3339) || early["timezone"] != "Europe/Paris" {
+		t.Errorf("got start time %v in %v", early["starts_at"], early["timezone"])
+	}
+	// Start times are stored in UTC.
+	if want := base.Add(2 * time.Hour).Format(time.RFC3339); late["starts_at"] != want {
+		t.Errorf("got start time %v, want %v", late["starts_at"], want)
+	}
+
+	for _, body := range []map[string]interface{}{
+		{"timezone": "Mars/Olympus"},
+		{"starts_at": time.Now().Add(-time.Hour).UTC().Format(time.RFC3339)},
+	} {
+		w := apiRequest{
+			Method: http.MethodPatch, Path: groupPath(early, ""), Token: owner.Token,
+			Body: body,
+		}.send(t)
+		expectStatus(t, w, http.StatusBadRequest)
+		if ids := fieldErrorIDs(t, w); len(ids) != 1 {
+			t.Errorf("got field errors %v for %v, want one", ids, body)
+		}
+	}
+
+	// The start of the range is included and the end is not.
+	q := func(at time.Time) string { return url.QueryEscape(at.Format(time.RFC3339)) }
+	for _, tc := range []struct {
+		query string
+		want  []int64
+	}{
+		{"starts_after=" + q(base), []int64{groupID(early), groupID(late)}},
+		{"starts_after=" + q(base.Add(time.Hour)), []int64{groupID(late)}},
+		{"starts_before=" + q(base.Add(2*time.Hour)), []int64{groupID(early)}},
+		{"starts_after=" + q(base) + "&starts_before=" + q(base.Add(time.Hour)),
+			[]int64{groupID(early)}},
+	} {
+		ids := listGroupIDs(t, owner, "q=Larkspur&"+tc.query)
+		slices.Sort(ids)
+		if !slices.Equal(ids, tc.want) {
+			t.Errorf("got groups %v for %v, want %v", ids, tc.query, tc.want)
+		}
+	}
+}
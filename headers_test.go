@@ -0,0 +1,31 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestSecurityHeaders(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		req    apiRequest
+		status int
+	}{
+		{"success", apiRequest{Method: http.MethodGet, Path: "/healthz"}, http.StatusOK},
+		{"error", apiRequest{Method: http.MethodGet, Path: "/groups"}, http.StatusUnauthorized},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			w := tc.req.send(t)
+			expectStatus(t, w, tc.status)
+			for name, want := range map[string]string{
+				"X-Content-Type-Options":    "nosniff",
+				"X-Frame-Options":           "DENY",
+				"Strict-Transport-Security": "max-age=31536000",
+			} {
+				if got := w.Header().Get(name); got != want {
+					t.Errorf("got %v %q, want %q", name, got, want)
+				}
+			}
+		})
+	}
+}
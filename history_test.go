@@ -0,0 +1,73 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+)
+
+func TestGroupSettingsHistory(t *testing.T) {
+	owner, member := signUp(t), signUp(t)
+	g := createGroup(t, owner, nil)
+	joinGroup(t, member, g)
+	update := func(fields map[string]interface{}) {
+		t.Helper()
+		expectStatus(t, apiRequest{
+			Method: http.MethodPatch, Path: groupPath(g, ""), Token: owner.Token,
+			Body: fields,
+		}.send(t), http.StatusOK)
+	}
+	update(map[string]interface{}{"title": "Raid night v2", "max_size": 8})
+	// An update that changes nothing adds no version.
+	update(map[string]interface{}{"title": "Raid night v2"})
+	expectStatus(t, apiRequest{
+		Method: http.MethodPost, Path: groupPath(g, "/close"), Token: owner.Token,
+	}.send(t), http.StatusOK)
+
+	w := apiRequest{
+		Method: http.MethodGet, Path: groupPath(g, "/history"), Token: owner.Token,
+	}.send(t)
+	expectStatus(t, w, http.StatusOK)
+	var history []struct {
+		Version int64 `json:"version"`
+		Changes map[string]struct {
+			Before interface{} `json:"before"`
+			After  interface{} `json:"after"`
+		} `json:"changes"`
+	}
+	decode(t, w, &history)
+	if len(history) != 2 {
+		t.Fatalf("got %v versions, want 2: %+v", len(history), history)
+	}
+	// The newest version comes first.
+	if history[0].Version <= history[1].Version {
+		t.Errorf("got versions %v then %v, want the newest first",
+			history[0].Version, history[1].Version)
+	}
+	for _, tc := range []struct {
+		version       int
+		field         string
+		before, after interface{}
+	}{
+		{0, "status", float64(0), float64(-100)},
+		{1, "title", "Raid night", "Raid night v2"},
+		{1, "max_size", float64(5), float64(8)},
+	} {
+		got, ok := history[tc.version].Changes[tc.field]
+		if !ok {
+			t.Errorf("got no %v change in %+v", tc.field, history[tc.version])
+			continue
+		}
+		if fmt.Sprint(got.Before, got.After) != fmt.Sprint(tc.before, tc.after) {
+			t.Errorf("got %v changed from %v to %v, want %v to %v",
+				tc.field, got.Before, got.After, tc.before, tc.after)
+		}
+	}
+	if n := len(history[0].Changes); n != 1 {
+		t.Errorf("got %v changes on close, want only the status", n)
+	}
+
+	expectStatus(t, apiRequest{
+		Method: http.MethodGet, Path: groupPath(g, "/history"), Token: member.Token,
+	}.send(t), http.StatusForbidden)
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"golang.org/x/exp/slices"
+)
+
+func TestJoinRequestWorkflow(t *testing.T) {
+	owner, approved, rejected := signUp(t), signUp(t), signUp(t)
+	g := createGroup(t, owner, map[string]interface{}{"require_approval": true})
+	join := func(u testUser) apiRequest {
+		return apiRequest{
+			Method: http.MethodPost, Path: groupPath(g, "/join"), Token: u.Token}
+	}
+	decide := func(u testUser, target testUser, action string) apiRequest {
+		return apiRequest{
+			Method: http.MethodPost, Token: u.Token,
+			Path: groupPath(g, fmt.Sprintf("/requests/%v/%v", target.ID, action)),
+		}
+	}
+
+	for _, u := range []testUser{approved, rejected} {
+		expectStatus(t, join(u).send(t), http.StatusAccepted)
+	}
+	w := join(approved).send(t)
+	expectStatus(t, w, http.StatusBadRequest)
+	var resp struct {
+		Code string `json:"code"`
+	}
+	decode(t, w, &resp)
+	if resp.Code != "join_request_exists" {
+		t.Errorf("got code %q for a second request, want join_request_exists", resp.Code)
+	}
+
+	pending := func() []int64 {
+		w := apiRequest{
+			Method: http.MethodGet, Path: groupPath(g, "/requests"), Token: owner.Token,
+		}.send(t)
+		expectStatus(t, w, http.StatusOK)
+		var requests []struct {
+			User struct {
+				ID int64 `json:"id"`
+			} `json:"user"`
+		}
+		decode(t, w, &requests)
+		ids := make([]int64, len(requests))
+		for i, r := range requests {
+			ids[i] = r.User.ID
+		}
+		return ids
+	}
+	if got := pending(); !slices.Equal(got, []int64{approved.ID, rejected.ID}) {
+		t.Errorf("got pending requests of %v, want %v and %v",
+			got, approved.ID, rejected.ID)
+	}
+	// The requests are not members until they are approved.
+	if len(memberRoles(t, owner, g)) != 0 {
+		t.Error("got members before any approval")
+	}
+
+	// Only the owner sees and decides the requests.
+	expectStatus(t, apiRequest{
+		Method: http.MethodGet, Path: groupPath(g, "/requests"), Token: approved.Token,
+	}.send(t), http.StatusForbidden)
+	expectStatus(t, decide(approved, approved, "approve").send(t), http.StatusForbidden)
+
+	expectStatus(t, decide(owner, approved, "approve").send(t), http.StatusOK)
+	expectStatus(t, decide(owner, rejected, "reject").send(t), http.StatusNoContent)
+	if got := pending(); len(got) != 0 {
+		t.Errorf("got pending requests %v after deciding all of them", got)
+	}
+	roles := memberRoles(t, owner, g)
+	if _, ok := roles[approved.ID]; !ok {
+		t.Errorf("got members %v without the approved user", roles)
+	}
+	if _, ok := roles[rejected.ID]; ok {
+		t.Errorf("got the rejected user in the members %v", roles)
+	}
+
+	// A decided request cannot be decided again.
+	expectStatus(t, decide(owner, rejected, "approve").send(t), http.StatusNotFound)
+	expectStatus(t, decide(owner, rejected, "reject").send(t), http.StatusNotFound)
+}
+
+func TestRetrieveMyGroupStatus(t *testing.T) {
+	owner, member, requester, outsider := signUp(t), signUp(t), signUp(t), signUp(t)
+	g := createGroup(t, owner, map[string]interface{}{"require_approval": true})
+	for _, u := range []testUser{member, requester} {
+		expectStatus(t, apiRequest{
+			Method: http.MethodPost, Path: groupPath(g, "/join"), Token: u.Token,
+		}.send(t), http.StatusAccepted)
+	}
+	expectStatus(t, apiRequest{
+		Method: http.MethodPost, Token: owner.Token,
+		Path: groupPath(g, fmt.Sprintf("/requests/%v/approve", member.ID)),
+	}.send(t), http.StatusOK)
+
+	type myStatus struct {
+		HasPendingInvite  bool `json:"has_pending_invite"`
+		HasPendingRequest bool `json:"has_pending_request"`
+		IsMember          bool `json:"is_member"`
+		IsOwner           bool `json:"is_owner"`
+	}
+	for _, tc := range []struct {
+		name string
+		u    testUser
+		want myStatus
+	}{
+		{"owner", owner, myStatus{IsOwner: true}},
+		{"member", member, myStatus{IsMember: true}},
+		{"pending request", requester, myStatus{HasPendingRequest: true}},
+		{"outsider", outsider, myStatus{}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			w := apiRequest{
+				Method: http.MethodGet, Path: groupPath(g, "/my-status"), Token: tc.u.Token,
+			}.send(t)
+			expectStatus(t, w, http.StatusOK)
+			var got myStatus
+			decode(t, w, &got)
+			if got != tc.want {
+				t.Errorf("got %+v, want %+v", got, tc.want)
+			}
+		})
+	}
+}
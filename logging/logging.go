@@ -0,0 +1,30 @@
+// Package logging passes the logger of a request down to the handlers and
+// the database calls so their logs carry the ID of the request.
+package logging
+
+import (
+	"context"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// contextKey is the key of the logger in the context.
+type contextKey struct{}
+
+// NewContext returns a copy of the context carrying the logger.
+func NewContext(ctx context.Context, entry *log.Entry) context.Context {
+	return context.WithValue(ctx, contextKey{}, entry)
+}
+
+// FromContext returns the logger carried by the context.
+//
+// The standard logger is returned if the context has no logger, like outside
+// of a request.
+func FromContext(ctx context.Context) *log.Entry {
+	if ctx != nil {
+		if entry, ok := ctx.Value(contextKey{}).(*log.Entry); ok {
+			return entry
+		}
+	}
+	return log.NewEntry(log.StandardLogger())
+}
@@ -0,0 +1,19 @@
+package logging
+
+import (
+	"context"
+	"testing"
+
+	log "github.com/sirupsen/logrus"
+)
+
+func TestFromContext(t *testing.T) {
+	entry := log.WithField("request_id", "abc")
+	ctx := NewContext(context.Background(), entry)
+	if got := FromContext(ctx); got != entry {
+		t.Errorf("got %v, want the logger of the context", got)
+	}
+	if got := FromContext(context.Background()); got.Logger != log.StandardLogger() {
+		t.Errorf("got %v, want the standard logger", got)
+	}
+}
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+)
+
+func TestLogsCarryRequestID(t *testing.T) {
+	u := signUp(t)
+	hook := test.NewGlobal()
+	defer log.StandardLogger().ReplaceHooks(log.LevelHooks{})
+
+	expectStatus(t, apiRequest{
+		Method: http.MethodGet, Path: "/groups", Token: u.Token,
+		Headers: map[string]string{"X-Request-ID": "list-groups-538"},
+	}.send(t), http.StatusOK)
+
+	// Both the endpoint and the database calls log with the request ID.
+	for _, msg := range []string{"Request successful", "Listed groups successfully"} {
+		found := false
+		for _, e := range hook.AllEntries() {
+			if e.Message == msg {
+				found = true
+				if e.Data["request_id"] != "list-groups-538" {
+					t.Errorf("got request ID %v in %q", e.Data["request_id"], msg)
+				}
+			}
+		}
+		if !found {
+			t.Errorf("got no %q log", msg)
+		}
+	}
+}
@@ -1,17 +1,69 @@
 package main
 
 import (
+	"context"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+	_ "time/tzdata" // Group time zones are checked even if the host has no tzdata.
+
+	"github.com/damascopaul/lfg-backend/config"
+	"github.com/damascopaul/lfg-backend/data"
 	"github.com/damascopaul/lfg-backend/endpoints"
 	"github.com/damascopaul/lfg-backend/middlewares"
+	"github.com/damascopaul/lfg-backend/schemas"
 
 	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 	log "github.com/sirupsen/logrus"
 )
 
-func GetAPI() *gin.Engine {
-	api := gin.Default()
+// GetAPI returns the routes of the server with the config applied.
+func GetAPI(cfg config.Config) *gin.Engine {
+	data.Configure(cfg)
+	endpoints.Configure(cfg)
+	api := gin.New()
+
+	// Middlewares
+	api.Use(gin.Logger())
+	api.Use(
+		middlewares.RequestID, middlewares.RecordMetrics, middlewares.Recover,
+		middlewares.SecureHeaders)
+	if len(endpoints.CORS_ALLOWED_ORIGINS) > 0 {
+		api.Use(middlewares.Cors)
+	}
+	api.Use(
+		middlewares.LimitQueryLength, middlewares.LimitBodySize,
+		middlewares.Timeout)
+	if cfg.RequireJSONAccept {
+		api.Use(middlewares.RequireJSONAccept)
+	}
 
 	// Routes
+	// Internal routes are registered outside of the private group so they
+	// never require a user token.
+	internalEndpoints := api.Group("/")
+	{
+		internalEndpoints.GET("/time", endpoints.ServerTime)
+		internalEndpoints.GET("/healthz", endpoints.Healthz)
+		internalEndpoints.GET("/readyz", endpoints.Readyz)
+		internalEndpoints.GET(
+			"/metrics", middlewares.AuthenticateMetricsRequests,
+			gin.WrapH(promhttp.Handler()))
+	}
+	adminEndpoints := api.Group("/admin")
+	adminEndpoints.Use(
+		middlewares.AuthenticateRequests, middlewares.AllowIfUserIsAdmin)
+	{
+		adminEndpoints.GET("/groups", endpoints.ListAllGroups)
+		adminEndpoints.POST(
+			"/groups/randomize-status", middlewares.AllowIfDemoMode,
+			endpoints.RandomizeGroupStatuses)
+	}
 	privateEndpoints := api.Group("/")
 	privateEndpoints.Use(middlewares.AuthenticateRequests)
 	{
@@ -19,42 +71,252 @@ func GetAPI() *gin.Engine {
 			"/groups/:id/close", middlewares.GroupObject,
 			middlewares.AllowIfUserIsOwner, middlewares.AllowIfGroupIsOpen,
 			endpoints.CloseGroup)
+		privateEndpoints.POST(
+			"/groups/:id/reopen", middlewares.GroupObject,
+			middlewares.AllowIfUserIsOwner, middlewares.AllowIfGroupIsClosed,
+			endpoints.ReopenGroup)
 		privateEndpoints.GET("/groups", endpoints.ListGroups)
+		privateEndpoints.GET("/groups/count", endpoints.CountGroups)
+		privateEndpoints.GET("/groups/trending", endpoints.ListTrendingGroups)
+		privateEndpoints.GET("/groups/timeseries", endpoints.GroupTimeseries)
+		privateEndpoints.GET(
+			"/groups/categories/stats", endpoints.GroupCategoryStats)
 		privateEndpoints.POST(
 			"/groups", middlewares.GroupRequestBody, endpoints.CreateGroup)
 		privateEndpoints.PATCH(
 			"groups/:id", middlewares.GroupObject, middlewares.AllowIfUserIsOwner,
-			middlewares.AllowIfGroupIsOpen, middlewares.GroupRequestBody,
+			middlewares.AllowIfGroupIsOpen, middlewares.GroupChangesRequestBody,
 			endpoints.UpdateGroup)
 		privateEndpoints.PATCH(
 			"groups/:id/password", middlewares.GroupObject,
 			middlewares.AllowIfUserIsOwner, middlewares.AllowIfGroupIsOpen,
 			middlewares.GroupRequestBody, endpoints.UpdateGroupPassword)
+		privateEndpoints.DELETE(
+			"groups/:id/password", middlewares.GroupObject,
+			middlewares.AllowIfUserIsOwner, middlewares.AllowIfGroupIsOpen,
+			endpoints.RemoveGroupPassword)
 		privateEndpoints.GET(
 			"/groups/:id", middlewares.GroupObject, endpoints.RetrieveGroup)
+		privateEndpoints.GET(
+			"/groups/slug/:slug", middlewares.GroupObjectBySlug,
+			endpoints.RetrieveGroup)
+		privateEndpoints.DELETE(
+			"/groups/:id", middlewares.GroupObject, middlewares.AllowIfUserIsOwner,
+			endpoints.DeleteGroup)
+		privateEndpoints.GET(
+			"/groups/:id/members", middlewares.GroupObject,
+			middlewares.AllowIfUserCanSeeGroup, endpoints.ListGroupMembers)
+		privateEndpoints.GET(
+			"/groups/:id/activity", middlewares.GroupObject,
+			middlewares.AllowIfUserIsOwnerOrMember, endpoints.ListGroupActivities)
+		privateEndpoints.GET(
+			"/groups/:id/events", middlewares.GroupObject,
+			middlewares.AllowIfUserIsOwner, endpoints.ListGroupEvents)
+		privateEndpoints.GET(
+			"/groups/:id/history", middlewares.GroupObject,
+			middlewares.AllowIfUserIsOwner, endpoints.ListGroupSettingsHistory)
 		privateEndpoints.POST(
 			"/groups/:id/join", middlewares.GroupObject,
+			middlewares.AllowIfUserIsNotOwner, middlewares.AllowIfGroupIsOpen,
+			middlewares.AllowIfUserIsNotBanned, endpoints.JoinGroup)
+		privateEndpoints.GET(
+			"/groups/:id/waitlist", middlewares.GroupObject,
+			endpoints.RetrieveWaitlistPosition)
+		privateEndpoints.DELETE(
+			"/groups/:id/waitlist", middlewares.GroupObject,
+			endpoints.LeaveWaitlist)
+		privateEndpoints.GET(
+			"/groups/:id/ws", middlewares.GroupObject,
+			middlewares.AllowIfUserIsOwnerOrMember, endpoints.WatchGroup)
+		privateEndpoints.GET(
+			"/groups/:id/my-status", middlewares.GroupObject,
+			endpoints.RetrieveMyGroupStatus)
+		privateEndpoints.GET(
+			"/groups/:id/requests", middlewares.GroupObject,
+			middlewares.AllowIfUserIsOwner, endpoints.ListJoinRequests)
+		privateEndpoints.POST(
+			"/groups/:id/requests/:uid/approve", middlewares.GroupObject,
+			middlewares.AllowIfUserIsOwner, middlewares.AllowIfGroupIsOpen,
+			middlewares.AllowIfGroupIsNotFull, endpoints.ApproveJoinRequest)
+		privateEndpoints.POST(
+			"/groups/:id/requests/:uid/reject", middlewares.GroupObject,
+			middlewares.AllowIfUserIsOwner, endpoints.RejectJoinRequest)
+		privateEndpoints.POST(
+			"/groups/:id/reserve", middlewares.GroupObject,
 			middlewares.AllowIfGroupIsNotFull, middlewares.AllowIfUserIsNotMember,
 			middlewares.AllowIfUserIsNotOwner, middlewares.AllowIfGroupIsOpen,
-			middlewares.AllowIfCorrectGroupPassword,
-			endpoints.JoinGroup)
+			middlewares.AllowIfUserIsNotBanned,
+			middlewares.AllowIfCorrectGroupPassword, endpoints.ReserveSlot)
+		privateEndpoints.POST(
+			"/groups/:id/confirm-reservation", middlewares.GroupObject,
+			middlewares.AllowIfGroupIsOpen, middlewares.AllowIfUserIsNotMember,
+			endpoints.ConfirmReservation)
+		privateEndpoints.POST(
+			"/groups/:id/cancel-reservation", middlewares.GroupObject,
+			endpoints.CancelReservation)
 		privateEndpoints.POST(
 			"/groups/:id/leave", middlewares.GroupObject,
-			middlewares.AllowIfGroupIsOpen, middlewares.AllowIfUserIsMember,
+			middlewares.AllowIfGroupIsOpen,
+			middlewares.AllowIfUserIsOwnerOrMember,
 			endpoints.LeaveGroup)
 		privateEndpoints.POST(
-			"groups/:id/kick", middlewares.UserRequestBody, middlewares.GroupObject,
-			middlewares.AllowIfGroupIsOpen, middlewares.AllowIfUserIsOwner,
+			"groups/:id/kick", middlewares.KickRequestBody, middlewares.GroupObject,
+			middlewares.AllowIfGroupIsOpen, middlewares.AllowIfUserIsOwnerOrModerator,
 			endpoints.KickFromGroup)
+		privateEndpoints.POST(
+			"/groups/:id/transfer", middlewares.UserRequestBody,
+			middlewares.GroupObject, middlewares.AllowIfUserIsOwner,
+			endpoints.TransferGroup)
+		privateEndpoints.PATCH(
+			"/groups/:id/members/:userId/role", middlewares.GroupObject,
+			middlewares.AllowIfUserIsOwner, middlewares.AllowIfGroupIsOpen,
+			middlewares.MemberRequestBody, endpoints.UpdateMemberLabel)
+		privateEndpoints.POST(
+			"/groups/:id/members/:userId/promote", middlewares.GroupObject,
+			middlewares.AllowIfUserIsOwner, middlewares.AllowIfGroupIsOpen,
+			endpoints.PromoteMember)
+		privateEndpoints.POST(
+			"/groups/:id/members/:userId/demote", middlewares.GroupObject,
+			middlewares.AllowIfUserIsOwner, middlewares.AllowIfGroupIsOpen,
+			endpoints.DemoteMember)
+		privateEndpoints.GET(
+			"/users", middlewares.LimitQueryValues("ids"), endpoints.ListUsers)
+		privateEndpoints.GET(
+			"/users/me/capabilities", endpoints.RetrieveCapabilities)
+		privateEndpoints.GET("/me", endpoints.RetrieveCurrentUser)
+		privateEndpoints.PATCH(
+			"/me", middlewares.ProfileChangesRequestBody,
+			endpoints.UpdateCurrentUser)
+		privateEndpoints.DELETE("/me", endpoints.DeleteCurrentUser)
+		privateEndpoints.POST(
+			"/me/sessions/revoke-all", endpoints.RevokeAllSessions)
+		privateEndpoints.GET("/me/groups/owned", endpoints.ListOwnedGroups)
+		privateEndpoints.GET("/me/groups/joined", endpoints.ListJoinedGroups)
+		privateEndpoints.PATCH(
+			"/me/password", middlewares.PasswordChangeRequestBody,
+			endpoints.ChangePassword)
 	}
 	api.POST("/sign-up", middlewares.UserRequestBody, endpoints.SignUp)
 	api.POST("/sign-in", middlewares.UserRequestBody, endpoints.SignIn)
+	api.GET("/users/availability", endpoints.CheckUsernameAvailability)
+	api.POST(
+		"/password-reset/request", middlewares.PasswordResetRequestBody,
+		endpoints.RequestPasswordReset)
+	api.POST(
+		"/password-reset/confirm", middlewares.PasswordResetRequestBody,
+		endpoints.ConfirmPasswordReset)
 	return api
 }
 
+// sweepReservations periodically deletes the expired slot reservations until
+// the context is done.
+func sweepReservations(ctx context.Context, interval time.Duration) {
+	r := schemas.Reservation{}
+	if err := r.InitDB(ctx); err != nil {
+		log.Errorf("Could not start the reservation sweeper. Error: %v", err)
+		return
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := r.DeleteExpired(); err != nil {
+				log.Errorf("Could not sweep the reservations. Error: %v", err)
+			}
+		}
+	}
+}
+
+// sweepStaleGroups periodically closes the groups that have been open for
+// longer than the TTL until the context is done.
+func sweepStaleGroups(ctx context.Context, interval time.Duration, ttl time.Duration) {
+	if ttl <= 0 || interval <= 0 {
+		return
+	}
+	g := schemas.Group{}
+	if err := g.InitDB(ctx); err != nil {
+		log.Errorf("Could not start the stale group sweeper. Error: %v", err)
+		return
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := g.CloseStale(ttl); err != nil {
+				log.Errorf("Could not sweep the stale groups. Error: %v", err)
+			}
+		}
+	}
+}
+
+// shutdownTimeout is how long in-flight requests have to finish once the
+// server is asked to stop.
+const shutdownTimeout = 10 * time.Second
+
+// serve handles the requests on the listener until the context is done.
+//
+// The server then stops accepting new connections and waits up to
+// shutdownTimeout for the in-flight requests to finish.
+func serve(ctx context.Context, srv *http.Server, ln net.Listener) error {
+	errs := make(chan error, 1)
+	go func() { errs <- srv.Serve(ln) }()
+	select {
+	case err := <-errs:
+		return err
+	case <-ctx.Done():
+	}
+
+	log.Info("Shutting down the server")
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	return srv.Shutdown(ctx)
+}
+
 func main() {
 	log.SetFormatter(&log.JSONFormatter{})
-	log.SetLevel(log.DebugLevel) // TODO: Should be conditional based on env.
-	api := GetAPI()
-	api.Run("localhost:8080")
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalf("Could not load the config. Error: %v", err)
+	}
+	log.SetLevel(cfg.LogLevel)
+	srv := &http.Server{Addr: cfg.Addr, Handler: GetAPI(cfg)}
+	if err := schemas.Migrate(); err != nil {
+		log.Fatalf("Could not migrate the database. Error: %v", err)
+	}
+	ln, err := net.Listen("tcp", cfg.Addr)
+	if err != nil {
+		log.Fatalf("Could not start the server. Error: %v", err)
+	}
+	ctx, stop := signal.NotifyContext(
+		context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	var sweepers sync.WaitGroup
+	sweepers.Add(2)
+	go func() {
+		defer sweepers.Done()
+		sweepReservations(ctx, time.Minute)
+	}()
+	go func() {
+		defer sweepers.Done()
+		sweepStaleGroups(ctx, cfg.StaleGroupSweepInterval, cfg.StaleGroupTTL)
+	}()
+
+	if err := serve(ctx, srv, ln); err != nil {
+		log.Errorf("Could not serve the requests. Error: %v", err)
+	}
+	// The sweepers stop with the server. The database is closed once no
+	// request or sweeper uses it anymore.
+	stop()
+	sweepers.Wait()
+	if err := data.Close(); err != nil {
+		log.Errorf("Could not close the database. Error: %v", err)
+	}
+	log.Info("Server stopped")
 }
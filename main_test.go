@@ -0,0 +1,209 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync/atomic"
+	"testing"
+
+	"github.com/damascopaul/lfg-backend/config"
+	"github.com/damascopaul/lfg-backend/schemas"
+
+	"github.com/gin-gonic/gin"
+	log "github.com/sirupsen/logrus"
+)
+
+// testPassword is the password of the users signed up by the tests.
+const testPassword = "violet-lantern-42"
+
+// api is the server the requests of the tests are sent to. The tests share
+// an in-memory database.
+var api *gin.Engine
+
+func TestMain(m *testing.M) {
+	gin.SetMode(gin.TestMode)
+	log.SetOutput(io.Discard)
+	cfg, err := config.Parse(func(key string) string {
+		return map[string]string{
+			"LFG_DB_PATH":      ":memory:",
+			"LFG_TOKEN_SECRET": "test-secret",
+		}[key]
+	})
+	if err != nil {
+		panic(err)
+	}
+	api = GetAPI(cfg)
+	if err := schemas.Migrate(); err != nil {
+		panic(err)
+	}
+	os.Exit(m.Run())
+}
+
+// apiRequest is a request sent to the server by the tests.
+type apiRequest struct {
+	Method  string
+	Path    string
+	Token   string
+	Body    interface{}
+	Headers map[string]string
+}
+
+// send sends the request to the server and returns the response.
+func (r apiRequest) send(t *testing.T) *httptest.ResponseRecorder {
+	t.Helper()
+	var body bytes.Buffer
+	if r.Body != nil {
+		if err := json.NewEncoder(&body).Encode(r.Body); err != nil {
+			t.Fatalf("could not encode the request body: %v", err)
+		}
+	}
+	req := httptest.NewRequest(r.Method, r.Path, &body)
+	req.Header.Set("Content-Type", "application/json")
+	if r.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+r.Token)
+	}
+	for k, v := range r.Headers {
+		req.Header.Set(k, v)
+	}
+	w := httptest.NewRecorder()
+	api.ServeHTTP(w, req)
+	return w
+}
+
+// decode decodes the JSON body of the response into v.
+func decode(t *testing.T, w *httptest.ResponseRecorder, v interface{}) {
+	t.Helper()
+	if err := json.Unmarshal(w.Body.Bytes(), v); err != nil {
+		t.Fatalf("could not decode the response %q: %v", w.Body.String(), err)
+	}
+}
+
+// expectStatus fails the test if the response does not have the status.
+func expectStatus(t *testing.T, w *httptest.ResponseRecorder, status int) {
+	t.Helper()
+	if w.Code != status {
+		t.Fatalf("got status %v, want %v: %s", w.Code, status, w.Body.String())
+	}
+}
+
+// userCount makes the usernames of the tests unique in the shared database.
+var userCount int64
+
+// testUser is a user signed up by the tests.
+type testUser struct {
+	ID       int64
+	Username string
+	Token    string
+}
+
+// signUp signs up a new user with a unique username.
+func signUp(t *testing.T) testUser {
+	t.Helper()
+	username := fmt.Sprintf("player%v", atomic.AddInt64(&userCount, 1))
+	w := apiRequest{
+		Method: http.MethodPost,
+		Path:   "/sign-up",
+		Body:   map[string]string{"username": username, "password": testPassword},
+	}.send(t)
+	expectStatus(t, w, http.StatusCreated)
+
+	var resp struct {
+		Token string `json:"token"`
+		User  struct {
+			ID int64 `json:"id"`
+		} `json:"user"`
+	}
+	decode(t, w, &resp)
+	return testUser{ID: resp.User.ID, Username: username, Token: resp.Token}
+}
+
+// createGroup creates a group owned by the user. The fields override the
+// defaults of the request body.
+func createGroup(
+	t *testing.T, owner testUser, fields map[string]interface{},
+) map[string]interface{} {
+	t.Helper()
+	body := map[string]interface{}{
+		"title":       "Raid night",
+		"description": "Weekly raid",
+		"max_size":    5,
+	}
+	for k, v := range fields {
+		body[k] = v
+	}
+	w := apiRequest{
+		Method: http.MethodPost, Path: "/groups", Token: owner.Token, Body: body,
+	}.send(t)
+	expectStatus(t, w, http.StatusCreated)
+
+	var g map[string]interface{}
+	decode(t, w, &g)
+	return g
+}
+
+// groupPath returns the path of the group with the suffix.
+func groupPath(g map[string]interface{}, suffix string) string {
+	return fmt.Sprintf("/groups/%v%v", g["id"], suffix)
+}
+
+// fieldErrorIDs returns the IDs of the field errors of the response by the
+// name of the field.
+func fieldErrorIDs(
+	t *testing.T, w *httptest.ResponseRecorder,
+) map[string][]string {
+	t.Helper()
+	var resp struct {
+		FieldErrors []struct {
+			Name string
+			ID   string
+		} `json:"field_errors"`
+	}
+	decode(t, w, &resp)
+	ids := map[string][]string{}
+	for _, fe := range resp.FieldErrors {
+		ids[fe.Name] = append(ids[fe.Name], fe.ID)
+	}
+	return ids
+}
+
+// joinGroup makes the user join the group.
+func joinGroup(t *testing.T, u testUser, g map[string]interface{}) {
+	t.Helper()
+	expectStatus(t, apiRequest{
+		Method: http.MethodPost, Path: groupPath(g, "/join"), Token: u.Token,
+	}.send(t), http.StatusOK)
+}
+
+// listGroupIDs lists the groups with the query string and returns their IDs
+// in order.
+func listGroupIDs(t *testing.T, u testUser, query string) []int64 {
+	t.Helper()
+	return listIDs(t, u, "/groups?"+query)
+}
+
+// listIDs returns the IDs of the entries listed at the path in order.
+func listIDs(t *testing.T, u testUser, path string) []int64 {
+	t.Helper()
+	w := apiRequest{Method: http.MethodGet, Path: path, Token: u.Token}.send(t)
+	expectStatus(t, w, http.StatusOK)
+	var entries []struct {
+		ID int64 `json:"id"`
+	}
+	decode(t, w, &entries)
+	ids := make([]int64, len(entries))
+	for i, e := range entries {
+		ids[i] = e.ID
+	}
+	return ids
+}
+
+// groupID returns the ID of the group decoded from a response.
+func groupID(g map[string]interface{}) int64 {
+	id, _ := g["id"].(float64)
+	return int64(id)
+}
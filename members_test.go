@@ -0,0 +1,359 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/damascopaul/lfg-backend/schemas"
+)
+
+func TestListGroupMembersInJoinOrder(t *testing.T) {
+	owner := signUp(t)
+	g := createGroup(t, owner, nil)
+	joiners := []testUser{signUp(t), signUp(t), signUp(t)}
+	for _, u := range joiners {
+		expectStatus(t, apiRequest{
+			Method: http.MethodPost, Path: groupPath(g, "/join"), Token: u.Token,
+		}.send(t), http.StatusOK)
+	}
+
+	w := apiRequest{
+		Method: http.MethodGet, Path: groupPath(g, "/members"), Token: owner.Token,
+	}.send(t)
+	expectStatus(t, w, http.StatusOK)
+
+	var members []struct {
+		ID       int64      `json:"id"`
+		JoinedAt *time.Time `json:"joined_at"`
+	}
+	decode(t, w, &members)
+	if len(members) != len(joiners) {
+		t.Fatalf("got %v members, want %v", len(members), len(joiners))
+	}
+	for i, m := range members {
+		if m.ID != joiners[i].ID {
+			t.Errorf("got member %v at %v, want %v", m.ID, i, joiners[i].ID)
+		}
+		if m.JoinedAt == nil {
+			t.Fatalf("got no joined_at for member %v", m.ID)
+		}
+		if i > 0 && m.JoinedAt.Before(*members[i-1].JoinedAt) {
+			t.Errorf("got member %v joined before the previous member", m.ID)
+		}
+	}
+}
+
+func TestOwnerLeavingHandsGroupToOldestMember(t *testing.T) {
+	owner, oldest, newest := signUp(t), signUp(t), signUp(t)
+	g := createGroup(t, owner, nil)
+	for _, u := range []testUser{oldest, newest} {
+		expectStatus(t, apiRequest{
+			Method: http.MethodPost, Path: groupPath(g, "/join"), Token: u.Token,
+		}.send(t), http.StatusOK)
+	}
+
+	expectStatus(t, apiRequest{
+		Method: http.MethodPost, Path: groupPath(g, "/leave"), Token: owner.Token,
+	}.send(t), http.StatusOK)
+
+	w := apiRequest{
+		Method: http.MethodGet, Path: groupPath(g, ""), Token: oldest.Token,
+	}.send(t)
+	expectStatus(t, w, http.StatusOK)
+	var resp struct {
+		OwnerID int64 `json:"owner_id"`
+	}
+	decode(t, w, &resp)
+	if resp.OwnerID != oldest.ID {
+		t.Errorf("got owner %v, want the oldest member %v", resp.OwnerID, oldest.ID)
+	}
+}
+
+func TestOwnerLeavingEmptyGroupClosesIt(t *testing.T) {
+	owner := signUp(t)
+	g := createGroup(t, owner, nil)
+
+	w := apiRequest{
+		Method: http.MethodPost, Path: groupPath(g, "/leave"), Token: owner.Token,
+	}.send(t)
+	expectStatus(t, w, http.StatusOK)
+	var resp struct {
+		OwnerID int64               `json:"owner_id"`
+		Status  schemas.GroupStatus `json:"status"`
+	}
+	decode(t, w, &resp)
+	// Nobody takes the group over so it is closed and keeps its owner.
+	if resp.Status != schemas.GroupStatusClosed || resp.OwnerID != owner.ID {
+		t.Errorf("got status %v and owner %v, want the group closed and owned by %v",
+			resp.Status, resp.OwnerID, owner.ID)
+	}
+}
+
+func TestOwnerSetsMemberLabel(t *testing.T) {
+	owner, member := signUp(t), signUp(t)
+	g := createGroup(t, owner, nil)
+	joinGroup(t, member, g)
+	rolePath := groupPath(g, fmt.Sprintf("/members/%v/role", member.ID))
+
+	expectStatus(t, apiRequest{
+		Method: http.MethodPatch, Path: rolePath, Token: owner.Token,
+		Body: map[string]string{"label": "healer"},
+	}.send(t), http.StatusOK)
+
+	w := apiRequest{
+		Method: http.MethodGet, Path: groupPath(g, "/members"), Token: owner.Token,
+	}.send(t)
+	expectStatus(t, w, http.StatusOK)
+	var members []struct {
+		ID    int64  `json:"id"`
+		Label string `json:"label"`
+	}
+	decode(t, w, &members)
+	if len(members) != 1 || members[0].Label != "healer" {
+		t.Errorf("got members %+v, want member %v labeled healer", members, member.ID)
+	}
+
+	w = apiRequest{
+		Method: http.MethodPatch, Path: rolePath, Token: owner.Token,
+		Body: map[string]string{"label": "bard"},
+	}.send(t)
+	expectStatus(t, w, http.StatusBadRequest)
+	if ids := fieldErrorIDs(t, w); len(ids["label"]) != 1 || ids["label"][0] != "one_of" {
+		t.Errorf("got field errors %v, want one_of on label", ids)
+	}
+}
+
+func TestSetMemberLabelRejections(t *testing.T) {
+	owner, member, outsider := signUp(t), signUp(t), signUp(t)
+	g := createGroup(t, owner, nil)
+	joinGroup(t, member, g)
+
+	// Only the owner can label the members.
+	w := apiRequest{
+		Method: http.MethodPatch,
+		Path:   groupPath(g, fmt.Sprintf("/members/%v/role", member.ID)),
+		Token:  member.Token,
+		Body:   map[string]string{"label": "tank"},
+	}.send(t)
+	expectStatus(t, w, http.StatusForbidden)
+
+	// Users who are not members cannot be labeled.
+	w = apiRequest{
+		Method: http.MethodPatch,
+		Path:   groupPath(g, fmt.Sprintf("/members/%v/role", outsider.ID)),
+		Token:  owner.Token,
+		Body:   map[string]string{"label": "tank"},
+	}.send(t)
+	expectStatus(t, w, http.StatusBadRequest)
+	var resp struct {
+		Code string `json:"code"`
+	}
+	decode(t, w, &resp)
+	if resp.Code != "not_member" {
+		t.Errorf("got code %q, want not_member", resp.Code)
+	}
+}
+
+func TestTransferGroupOwnership(t *testing.T) {
+	owner, member, outsider := signUp(t), signUp(t), signUp(t)
+	g := createGroup(t, owner, nil)
+	joinGroup(t, member, g)
+	transfer := func(by testUser, to testUser) *httptest.ResponseRecorder {
+		return apiRequest{
+			Method: http.MethodPost, Path: groupPath(g, "/transfer"), Token: by.Token,
+			Body: map[string]int64{"id": to.ID},
+		}.send(t)
+	}
+
+	w := transfer(owner, outsider)
+	expectStatus(t, w, http.StatusBadRequest)
+	var resp struct {
+		Code string `json:"code"`
+	}
+	decode(t, w, &resp)
+	if resp.Code != "not_member" {
+		t.Errorf("got code %q, want not_member", resp.Code)
+	}
+	expectStatus(t, transfer(member, member), http.StatusForbidden)
+
+	w = transfer(owner, member)
+	expectStatus(t, w, http.StatusOK)
+	var transferred struct {
+		OwnerID int64 `json:"owner_id"`
+	}
+	decode(t, w, &transferred)
+	if transferred.OwnerID != member.ID {
+		t.Errorf("got owner %v, want %v", transferred.OwnerID, member.ID)
+	}
+
+	// The former owner becomes a member.
+	w = apiRequest{
+		Method: http.MethodGet, Path: groupPath(g, "/members"), Token: member.Token,
+	}.send(t)
+	expectStatus(t, w, http.StatusOK)
+	var members []struct {
+		ID int64 `json:"id"`
+	}
+	decode(t, w, &members)
+	if len(members) != 1 || members[0].ID != owner.ID {
+		t.Errorf("got members %+v, want only the former owner %v", members, owner.ID)
+	}
+}
+
+// memberRoles returns the role of each member of the group.
+func memberRoles(t *testing.T, owner testUser, g map[string]interface{}) map[int64]string {
+	t.Helper()
+	w := apiRequest{
+		Method: http.MethodGet, Path: groupPath(g, "/members"), Token: owner.Token,
+	}.send(t)
+	expectStatus(t, w, http.StatusOK)
+	var members []struct {
+		ID   int64  `json:"id"`
+		Role string `json:"role"`
+	}
+	decode(t, w, &members)
+	roles := map[int64]string{}
+	for _, m := range members {
+		roles[m.ID] = m.Role
+	}
+	return roles
+}
+
+func TestPromoteAndDemoteMember(t *testing.T) {
+	owner, member, outsider := signUp(t), signUp(t), signUp(t)
+	g := createGroup(t, owner, nil)
+	joinGroup(t, member, g)
+	setRole := func(u testUser, action string, target testUser) *httptest.ResponseRecorder {
+		return apiRequest{
+			Method: http.MethodPost, Token: u.Token,
+			Path: groupPath(g, fmt.Sprintf("/members/%v/%v", target.ID, action)),
+		}.send(t)
+	}
+
+	// Only the owner can change the roles, and only of members.
+	expectStatus(t, setRole(member, "promote", member), http.StatusForbidden)
+	expectStatus(t, setRole(owner, "promote", outsider), http.StatusBadRequest)
+
+	expectStatus(t, setRole(owner, "promote", member), http.StatusOK)
+	if got := memberRoles(t, owner, g)[member.ID]; got != "moderator" {
+		t.Errorf("got role %q after promoting, want moderator", got)
+	}
+	expectStatus(t, setRole(owner, "demote", member), http.StatusOK)
+	if got := memberRoles(t, owner, g)[member.ID]; got != "member" {
+		t.Errorf("got role %q after demoting, want member", got)
+	}
+}
+
+func TestModeratorKicks(t *testing.T) {
+	owner, moderator, other, member := signUp(t), signUp(t), signUp(t), signUp(t)
+	g := createGroup(t, owner, nil)
+	for _, u := range []testUser{moderator, other, member} {
+		joinGroup(t, u, g)
+	}
+	for _, u := range []testUser{moderator, other} {
+		expectStatus(t, apiRequest{
+			Method: http.MethodPost, Token: owner.Token,
+			Path: groupPath(g, fmt.Sprintf("/members/%v/promote", u.ID)),
+		}.send(t), http.StatusOK)
+	}
+	kick := func(u, target testUser) *httptest.ResponseRecorder {
+		return apiRequest{
+			Method: http.MethodPost, Path: groupPath(g, "/kick"), Token: u.Token,
+			Body: map[string]interface{}{"id": target.ID},
+		}.send(t)
+	}
+
+	// Moderators cannot kick each other.
+	w := kick(moderator, other)
+	expectStatus(t, w, http.StatusForbidden)
+	var resp struct {
+		Code string `json:"code"`
+	}
+	decode(t, w, &resp)
+	if resp.Code != "target_is_moderator" {
+		t.Errorf("got code %q, want target_is_moderator", resp.Code)
+	}
+
+	// Regular members cannot kick.
+	expectStatus(t, kick(member, other), http.StatusForbidden)
+
+	expectStatus(t, kick(moderator, member), http.StatusOK)
+	roles := memberRoles(t, owner, g)
+	if _, ok := roles[member.ID]; ok {
+		t.Errorf("got the kicked user still in the members %v", roles)
+	}
+}
+
+func TestJoiningTwiceKeepsOneMembership(t *testing.T) {
+	owner, member := signUp(t), signUp(t)
+	g := createGroup(t, owner, nil)
+
+	// A replayed join succeeds without adding a membership.
+	for i := 0; i < 2; i++ {
+		w := apiRequest{
+			Method: http.MethodPost, Path: groupPath(g, "/join"), Token: member.Token,
+		}.send(t)
+		expectStatus(t, w, http.StatusOK)
+		var resp struct {
+			MemberCount int `json:"member_count"`
+		}
+		decode(t, w, &resp)
+		if resp.MemberCount != 1 {
+			t.Errorf("got member count %v on join %v, want 1", resp.MemberCount, i+1)
+		}
+	}
+	if roles := memberRoles(t, owner, g); len(roles) != 1 || roles[member.ID] == "" {
+		t.Errorf("got members %v, want only %v", roles, member.ID)
+	}
+}
+
+func TestMemberCountFollowsJoinsAndLeaves(t *testing.T) {
+	owner := signUp(t)
+	g := createGroup(t, owner, map[string]interface{}{"title": "Numbatnight raid"})
+	memberCounts := func() (retrieved, listed interface{}) {
+		t.Helper()
+		w := apiRequest{
+			Method: http.MethodGet, Path: groupPath(g, ""), Token: owner.Token,
+		}.send(t)
+		expectStatus(t, w, http.StatusOK)
+		var resp map[string]interface{}
+		decode(t, w, &resp)
+
+		w = apiRequest{
+			Method: http.MethodGet, Path: "/groups?q=numbatnight", Token: owner.Token,
+		}.send(t)
+		expectStatus(t, w, http.StatusOK)
+		var groups []map[string]interface{}
+		decode(t, w, &groups)
+		if len(groups) != 1 {
+			t.Fatalf("got groups %v, want only %v", groups, g["id"])
+		}
+		return resp["member_count"], groups[0]["member_count"]
+	}
+	check := func(want int) {
+		t.Helper()
+		retrieved, listed := memberCounts()
+		if retrieved != float64(want) || listed != float64(want) {
+			t.Errorf("got member count %v retrieved and %v listed, want %v",
+				retrieved, listed, want)
+		}
+	}
+
+	members := []testUser{signUp(t), signUp(t), signUp(t)}
+	for i, u := range members {
+		joinGroup(t, u, g)
+		check(i + 1)
+	}
+	for i, u := range members[:2] {
+		expectStatus(t, apiRequest{
+			Method: http.MethodPost, Path: groupPath(g, "/leave"), Token: u.Token,
+		}.send(t), http.StatusOK)
+		check(len(members) - i - 1)
+	}
+	// Joining again after leaving is counted again.
+	joinGroup(t, members[0], g)
+	check(2)
+}
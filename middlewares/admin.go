@@ -0,0 +1,59 @@
+package middlewares
+
+import (
+	"net/http"
+
+	"github.com/damascopaul/lfg-backend/endpoints"
+	"github.com/damascopaul/lfg-backend/schemas"
+
+	"github.com/gin-gonic/gin"
+	log "github.com/sirupsen/logrus"
+)
+
+// AllowIfDemoMode allows requests only if the server runs in demo mode.
+//
+// The endpoint is reported as not found otherwise.
+func AllowIfDemoMode(c *gin.Context) {
+	if !endpoints.DEMO_MODE {
+		log.WithFields(log.Fields{
+			"permission": "AllowIfDemoMode",
+			"details":    "Request denied because demo mode is disabled",
+		}).Info("Permission error")
+		endpoints.AbortWithBodyError(c, http.StatusNotFound, endpoints.BodyNotFound)
+		return
+	}
+
+	c.Next()
+}
+
+// AllowIfUserIsAdmin allows requests from admin users.
+func AllowIfUserIsAdmin(c *gin.Context) {
+	u := schemas.User{ID: c.GetInt64("user_id")}
+	if err := u.InitDB(c.Request.Context()); err != nil {
+		endpoints.AbortWithBodyError(
+			c, http.StatusInternalServerError, endpoints.BodyInternalServerError)
+		return
+	}
+	if err := u.Retrieve(); err != nil {
+		endpoints.AbortWithBodyError(
+			c, http.StatusInternalServerError, endpoints.BodyInternalServerError)
+		return
+	}
+
+	if !u.IsAdmin {
+		// Return a 403 error if the user is not an admin.
+		log.WithFields(log.Fields{
+			"permission": "AllowIfUserIsAdmin",
+			"details":    "Request denied because the user is not an admin",
+			"user_id":    u.ID,
+		}).Info("Permission error")
+		endpoints.AbortWithBodyError(
+			c, http.StatusForbidden, schemas.BodyError{
+				Code:    "not_admin",
+				Message: "User is not an admin",
+			})
+		return
+	}
+
+	c.Next()
+}
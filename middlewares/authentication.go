@@ -1,6 +1,8 @@
 package middlewares
 
 import (
+	"crypto/subtle"
+	"errors"
 	"fmt"
 	"net/http"
 	"strings"
@@ -11,24 +13,39 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v4"
 	log "github.com/sirupsen/logrus"
+	"gorm.io/gorm"
 )
 
 func parseJwt(t string) (jwt.MapClaims, error) {
+	// Tokens signed with an algorithm outside of the allowlist, including
+	// "none", are rejected before the key is looked up.
 	token, err := jwt.Parse(t, func(token *jwt.Token) (interface{}, error) {
 		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
 			log.Error("Could not parse JWT. Unexpected signing method")
 			return nil, fmt.Errorf(
 				"unexpected signing method. Method: %v", token.Header)
 		}
+		if endpoints.TOKEN_SECRET == "" {
+			return nil, errors.New("token secret is not configured")
+		}
 		return []byte(endpoints.TOKEN_SECRET), nil
-	})
-	if claims, ok := token.Claims.(jwt.MapClaims); ok && token.Valid {
-		return claims, nil
+	}, jwt.WithValidMethods(endpoints.TOKEN_ALGORITHMS))
+	if token != nil {
+		if claims, ok := token.Claims.(jwt.MapClaims); ok && token.Valid {
+			return claims, nil
+		}
 	}
 	log.Errorf("Could not parse JWT. Error: %v", err)
 	return jwt.MapClaims{}, err
 }
 
+// abortInvalidToken aborts the request with a 401 error since the token is
+// not valid.
+func abortInvalidToken(c *gin.Context) {
+	endpoints.AbortWithBodyError(c, http.StatusUnauthorized,
+		schemas.BodyError{Code: "invalid_token", Message: "Token is invalid"})
+}
+
 // AuthenticateRequests checks if the request is authorized.
 //
 // This checks the JWT in the `Authorization` header.
@@ -37,25 +54,94 @@ func AuthenticateRequests(c *gin.Context) {
 	ah := c.Request.Header.Get("Authorization")
 	if ah == "" {
 		log.Error("Could not authenticate request. Authorization header is missing")
-		c.AbortWithStatusJSON(
-			http.StatusUnauthorized,
-			schemas.BodyError{Message: "Authorization header is missing"})
+		endpoints.AbortWithBodyError(
+			c, http.StatusUnauthorized,
+			schemas.BodyError{
+				Code:    "missing_token",
+				Message: "Authorization header is missing",
+			})
+		return
+	}
+	scheme, token, found := strings.Cut(strings.TrimSpace(ah), " ")
+	token = strings.TrimSpace(token)
+	if !found || !strings.EqualFold(scheme, "Bearer") || token == "" {
+		// Return a 401 error if the header does not hold a bearer token.
+		log.Error("Could not authenticate request. Authorization header is malformed")
+		abortInvalidToken(c)
 		return
 	}
-	token := strings.Split(ah, " ")[1]
 	claims, err := parseJwt(token)
 	if err != nil {
-		if strings.Contains(err.Error(), "unexpected signing method") {
-			c.AbortWithStatusJSON(http.StatusUnauthorized,
-				schemas.BodyError{Message: "Token is invalid"})
+		var ve *jwt.ValidationError
+		if errors.As(err, &ve) {
+			// Return a 401 error if the token is malformed, has an invalid
+			// signature, or uses an algorithm that is not allowed.
+			abortInvalidToken(c)
 			return
 		} else {
-			c.AbortWithStatusJSON(
-				http.StatusInternalServerError, endpoints.BodyInternalServerError)
+			endpoints.AbortWithBodyError(
+				c, http.StatusInternalServerError, endpoints.BodyInternalServerError)
 			return
 		}
 	}
-	uid := claims["user_id"].(float64)
+	uid, ok := claims["user_id"].(float64)
+	if !ok {
+		// Return a 401 error if the token is not the token of a user.
+		log.Error("Could not authenticate request. Token has no user ID")
+		abortInvalidToken(c)
+		return
+	}
+
+	// Tokens issued before the token version was added have version zero.
+	version, _ := claims["token_version"].(float64)
+	u := schemas.User{ID: int64(uid)}
+	if err := u.InitDB(c.Request.Context()); err != nil {
+		endpoints.AbortWithBodyError(
+			c, http.StatusInternalServerError, endpoints.BodyInternalServerError)
+		return
+	}
+	if err := u.RetrieveTokenVersion(); err != nil && !errors.Is(
+		err, gorm.ErrRecordNotFound) {
+		endpoints.AbortWithBodyError(
+			c, http.StatusInternalServerError, endpoints.BodyInternalServerError)
+		return
+	} else if err != nil || int64(version) != u.TokenVersion {
+		// Return a 401 error if the tokens of the user were revoked or the
+		// user no longer exists.
+		log.Error("Could not authenticate request. Token was revoked")
+		endpoints.AbortWithBodyError(
+			c, http.StatusUnauthorized,
+			schemas.BodyError{
+				Code:    "revoked_token",
+				Message: "Token has been revoked",
+			})
+		return
+	}
+
 	c.Set("user_id", int64(uid))
 	c.Next()
 }
+
+// AuthenticateMetricsRequests checks the token of requests on the metrics.
+//
+// This is separate from the user tokens and only applies if a metrics
+// token is configured.
+func AuthenticateMetricsRequests(c *gin.Context) {
+	if endpoints.METRICS_TOKEN == "" {
+		c.Next()
+		return
+	}
+
+	ah := c.Request.Header.Get("Authorization")
+	expected := "Bearer " + endpoints.METRICS_TOKEN
+	if subtle.ConstantTimeCompare([]byte(ah), []byte(expected)) != 1 {
+		log.Error("Could not authenticate metrics request. Token is invalid")
+		endpoints.AbortWithBodyError(
+			c, http.StatusUnauthorized, schemas.BodyError{
+				Code:    "invalid_token",
+				Message: "Token is invalid",
+			})
+		return
+	}
+	c.Next()
+}
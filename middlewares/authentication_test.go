@@ -0,0 +1,74 @@
+package middlewares
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/damascopaul/lfg-backend/endpoints"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v4"
+)
+
+func TestAuthenticateRequestsRejectsMalformedHeaders(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	endpoints.TOKEN_SECRET = "test-secret"
+	noUserID, err := jwt.NewWithClaims(
+		jwt.SigningMethodHS256, jwt.MapClaims{"token_version": 0},
+	).SignedString([]byte(endpoints.TOKEN_SECRET))
+	if err != nil {
+		t.Fatalf("could not sign the token: %v", err)
+	}
+
+	r := gin.New()
+	r.GET("/", AuthenticateRequests, func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+	for _, ah := range []string{
+		"Bearer",
+		"Bearer ",
+		"Basic dXNlcjpwYXNz",
+		noUserID,
+		"Bearer not-a-token",
+		"Bearer " + noUserID,
+	} {
+		t.Run(ah, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.Header.Set("Authorization", ah)
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+			if w.Code != http.StatusUnauthorized {
+				t.Errorf("got status %v, want 401: %s", w.Code, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestParseJwtRejectsAlgorithmsOutsideAllowlist(t *testing.T) {
+	endpoints.TOKEN_SECRET = "test-secret"
+	claims := jwt.MapClaims{"user_id": 1, "token_version": 0}
+	sign := func(method jwt.SigningMethod, key interface{}) string {
+		t.Helper()
+		s, err := jwt.NewWithClaims(method, claims).SignedString(key)
+		if err != nil {
+			t.Fatalf("could not sign the token: %v", err)
+		}
+		return s
+	}
+
+	if _, err := parseJwt(sign(
+		jwt.SigningMethodHS256, []byte(endpoints.TOKEN_SECRET))); err != nil {
+		t.Fatalf("got error %v for an allowed algorithm", err)
+	}
+	for name, token := range map[string]string{
+		"HS512": sign(jwt.SigningMethodHS512, []byte(endpoints.TOKEN_SECRET)),
+		"none":  sign(jwt.SigningMethodNone, jwt.UnsafeAllowNoneSignatureType),
+	} {
+		t.Run(name, func(t *testing.T) {
+			if _, err := parseJwt(token); err == nil {
+				t.Errorf("got no error for a token signed with %v", name)
+			}
+		})
+	}
+}
@@ -0,0 +1,55 @@
+package middlewares
+
+import (
+	"mime"
+	"net/http"
+	"strings"
+
+	"github.com/damascopaul/lfg-backend/endpoints"
+	"github.com/damascopaul/lfg-backend/schemas"
+
+	"github.com/gin-gonic/gin"
+	log "github.com/sirupsen/logrus"
+)
+
+// acceptsJSON checks if a media range of the Accept header matches JSON.
+//
+// Newline delimited JSON is accepted since some listings can stream it, and
+// problem details since errors can be returned as them.
+func acceptsJSON(accept string) bool {
+	for _, r := range strings.Split(accept, ",") {
+		mt, _, err := mime.ParseMediaType(strings.TrimSpace(r))
+		if err != nil {
+			continue
+		}
+		switch mt {
+		case "application/json", "application/x-ndjson",
+			"application/problem+json", "application/*", "*/*":
+			return true
+		}
+	}
+	return false
+}
+
+// RequireJSONAccept allows requests from clients that accept JSON responses.
+//
+// A missing `Accept` header is treated as `*/*`.
+func RequireJSONAccept(c *gin.Context) {
+	accept := c.GetHeader("Accept")
+	if accept != "" && !acceptsJSON(accept) {
+		// Return a 406 error if the client does not accept JSON.
+		log.WithFields(log.Fields{
+			"details": "Request denied because the client does not accept JSON",
+			"accept":  accept,
+		}).Info("Request not acceptable")
+		endpoints.AbortWithBodyError(
+			c, http.StatusNotAcceptable,
+			schemas.BodyError{
+				Code:    "not_acceptable",
+				Message: "Responses are only available as JSON",
+			})
+		return
+	}
+
+	c.Next()
+}
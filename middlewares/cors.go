@@ -0,0 +1,51 @@
+package middlewares
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/damascopaul/lfg-backend/endpoints"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/exp/slices"
+)
+
+// allowsOrigin checks if cross-origin requests are allowed from the origin.
+func allowsOrigin(origin string) bool {
+	return slices.Contains(endpoints.CORS_ALLOWED_ORIGINS, "*") ||
+		slices.Contains(endpoints.CORS_ALLOWED_ORIGINS, origin)
+}
+
+// Cors adds the CORS headers to requests from the allowed origins.
+//
+// Preflight requests are answered right away so they never reach the
+// authentication of the private routes.
+func Cors(c *gin.Context) {
+	origin := c.GetHeader("Origin")
+	if origin != "" && allowsOrigin(origin) {
+		h := c.Writer.Header()
+		h.Set("Access-Control-Allow-Origin", origin)
+		h.Add("Vary", "Origin")
+		if endpoints.CORS_ALLOW_CREDENTIALS {
+			h.Set("Access-Control-Allow-Credentials", "true")
+		}
+		if c.Request.Method == http.MethodOptions {
+			h.Set(
+				"Access-Control-Allow-Methods",
+				strings.Join(endpoints.CORS_ALLOWED_METHODS, ", "))
+			h.Set(
+				"Access-Control-Allow-Headers",
+				strings.Join(endpoints.CORS_ALLOWED_HEADERS, ", "))
+		}
+	}
+
+	if c.Request.Method == http.MethodOptions &&
+		c.GetHeader("Access-Control-Request-Method") != "" {
+		// Return a 204 for preflight requests. Browsers block the actual
+		// request if the origin is not allowed since the headers are missing.
+		c.AbortWithStatus(http.StatusNoContent)
+		return
+	}
+
+	c.Next()
+}
@@ -0,0 +1,82 @@
+package middlewares
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/damascopaul/lfg-backend/endpoints"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestCors(t *testing.T) {
+	defer func(origins, methods, headers []string, credentials bool) {
+		endpoints.CORS_ALLOWED_ORIGINS = origins
+		endpoints.CORS_ALLOWED_METHODS = methods
+		endpoints.CORS_ALLOWED_HEADERS = headers
+		endpoints.CORS_ALLOW_CREDENTIALS = credentials
+	}(endpoints.CORS_ALLOWED_ORIGINS, endpoints.CORS_ALLOWED_METHODS,
+		endpoints.CORS_ALLOWED_HEADERS, endpoints.CORS_ALLOW_CREDENTIALS)
+	endpoints.CORS_ALLOWED_ORIGINS = []string{"https://app.example.com"}
+	endpoints.CORS_ALLOWED_METHODS = []string{"GET", "POST"}
+	endpoints.CORS_ALLOWED_HEADERS = []string{"Authorization"}
+	endpoints.CORS_ALLOW_CREDENTIALS = true
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(Cors)
+	r.Any("/", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	for _, tc := range []struct {
+		name      string
+		method    string
+		origin    string
+		preflight bool
+		// wantStatus is the status of the response and wantOrigin is its
+		// Access-Control-Allow-Origin header.
+		wantStatus  int
+		wantOrigin  string
+		wantMethods string
+	}{
+		{"allowed", http.MethodGet, "https://app.example.com", false,
+			http.StatusOK, "https://app.example.com", ""},
+		{"denied", http.MethodGet, "https://evil.example.com", false,
+			http.StatusOK, "", ""},
+		{"no origin", http.MethodGet, "", false, http.StatusOK, "", ""},
+		{"preflight", http.MethodOptions, "https://app.example.com", true,
+			http.StatusNoContent, "https://app.example.com", "GET, POST"},
+		{"denied preflight", http.MethodOptions, "https://evil.example.com", true,
+			http.StatusNoContent, "", ""},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(tc.method, "/", nil)
+			if tc.origin != "" {
+				req.Header.Set("Origin", tc.origin)
+			}
+			if tc.preflight {
+				req.Header.Set("Access-Control-Request-Method", http.MethodPost)
+			}
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			h := w.Header()
+			if w.Code != tc.wantStatus {
+				t.Errorf("got status %v, want %v", w.Code, tc.wantStatus)
+			}
+			if got := h.Get("Access-Control-Allow-Origin"); got != tc.wantOrigin {
+				t.Errorf("got allowed origin %q, want %q", got, tc.wantOrigin)
+			}
+			if got := h.Get("Access-Control-Allow-Methods"); got != tc.wantMethods {
+				t.Errorf("got allowed methods %q, want %q", got, tc.wantMethods)
+			}
+			wantCredentials := ""
+			if tc.wantOrigin != "" {
+				wantCredentials = "true"
+			}
+			if got := h.Get("Access-Control-Allow-Credentials"); got != wantCredentials {
+				t.Errorf("got allow credentials %q, want %q", got, wantCredentials)
+			}
+		})
+	}
+}
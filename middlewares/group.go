@@ -18,36 +18,57 @@ func GroupObject(c *gin.Context) {
 	// Parse the group ID from the URL parameter.
 	gid, err := strconv.ParseInt(c.Param("id"), 10, 64)
 	if err != nil {
-		// Return a 500 error if there was an error in parsing
-		// the group ID in the URL
+		// Return a 404 error if the group ID in the URL is not valid
+		// since no group can match it.
 		log.Errorf("Could not parse ID parameter from URL. Error: %v", err)
-		c.AbortWithStatusJSON(
-			http.StatusInternalServerError, endpoints.BodyInternalServerError)
+		endpoints.AbortWithBodyError(c, http.StatusNotFound, endpoints.BodyNotFound)
 		return
 	}
 
 	g := schemas.Group{}
-	if err := g.InitDB(); err != nil {
-		// Return a 500 error for any other error other than "record not found"
-		c.AbortWithStatusJSON(
-			http.StatusInternalServerError, endpoints.BodyInternalServerError)
+	if err := g.InitDB(c.Request.Context()); err != nil {
+		// Return a 500 error if the database could not be initialized
+		endpoints.AbortWithBodyError(
+			c, http.StatusInternalServerError, endpoints.BodyInternalServerError)
 		return
 	}
 
 	g.ID = gid
-	if err := g.RetrieveWithPassword(); err != nil {
+	retrieveGroupObject(c, &g, (*schemas.Group).RetrieveWithPassword)
+}
+
+// GroupObjectBySlug adds the Group entry with the slug in the URL to the
+// context.
+func GroupObjectBySlug(c *gin.Context) {
+	g := schemas.Group{}
+	if err := g.InitDB(c.Request.Context()); err != nil {
+		// Return a 500 error if the database could not be initialized
+		endpoints.AbortWithBodyError(
+			c, http.StatusInternalServerError, endpoints.BodyInternalServerError)
+		return
+	}
+
+	g.Slug = c.Param("slug")
+	retrieveGroupObject(c, &g, (*schemas.Group).RetrieveBySlug)
+}
+
+// retrieveGroupObject adds the group to the context once it is retrieved.
+func retrieveGroupObject(
+	c *gin.Context, g *schemas.Group, retrieve func(*schemas.Group) error,
+) {
+	if err := retrieve(g); err != nil {
 		if strings.Contains(err.Error(), "record not found") {
 			// Return a 404 error if the group does not exist in the database
-			c.AbortWithStatusJSON(http.StatusNotFound, endpoints.BodyNotFound)
+			endpoints.AbortWithBodyError(c, http.StatusNotFound, endpoints.BodyNotFound)
 			return
 		}
 		// Return a 500 error for any other error other than "record not found"
-		c.AbortWithStatusJSON(
-			http.StatusInternalServerError, endpoints.BodyInternalServerError)
+		endpoints.AbortWithBodyError(
+			c, http.StatusInternalServerError, endpoints.BodyInternalServerError)
 		return
 	}
 
-	c.Set("obj", g)
+	c.Set("obj", *g)
 	c.Next()
 }
 
@@ -55,11 +76,32 @@ func GroupObject(c *gin.Context) {
 func GroupRequestBody(c *gin.Context) {
 	var req schemas.Group
 	if err := c.ShouldBindWith(&req, binding.JSON); err != nil {
-		log.WithFields(log.Fields{
-			"error": err.Error(),
-		}).Error("Failed to bind JSON request body")
-		c.AbortWithStatusJSON(
-			http.StatusInternalServerError, endpoints.BodyInternalServerError)
+		endpoints.AbortWithBindError(c, err)
+		return
+	}
+
+	c.Set("req", req)
+	c.Next()
+}
+
+// GroupChangesRequestBody adds the parsed group changes request body to the
+// context.
+func GroupChangesRequestBody(c *gin.Context) {
+	var req schemas.GroupChanges
+	if err := c.ShouldBindWith(&req, binding.JSON); err != nil {
+		endpoints.AbortWithBindError(c, err)
+		return
+	}
+
+	c.Set("req", req)
+	c.Next()
+}
+
+// MemberRequestBody adds the parsed member request body to the context.
+func MemberRequestBody(c *gin.Context) {
+	var req schemas.GroupMember
+	if err := c.ShouldBindWith(&req, binding.JSON); err != nil {
+		endpoints.AbortWithBindError(c, err)
 		return
 	}
 
@@ -67,25 +109,51 @@ func GroupRequestBody(c *gin.Context) {
 	c.Next()
 }
 
+// permissionDenial describes why a permission middleware denied a request.
+type permissionDenial struct {
+	Permission string // Name of the middleware denying the request.
+	Code       string // Stable code identifying the reason of the denial.
+	Details    string
+	Status     int
+	Message    string
+}
+
+// denyPermission logs the permission denial and aborts the request.
+func denyPermission(c *gin.Context, g schemas.Group, d permissionDenial) {
+	fields := log.Fields{
+		"permission": d.Permission,
+		"code":       d.Code,
+		"details":    d.Details,
+		"group_id":   g.ID,
+		"request_id": c.GetString("request_id"),
+	}
+	if uid, ok := c.Get("user_id"); ok {
+		fields["user_id"] = uid
+	}
+	log.WithFields(fields).Info("Permission error")
+	endpoints.AbortWithBodyError(
+		c, d.Status, schemas.BodyError{Code: d.Code, Message: d.Message})
+}
+
 // AllowIfGroupIsNotFull allows requests for groups that are not yet full.
 func AllowIfGroupIsNotFull(c *gin.Context) {
 	g, ok := c.Keys["obj"].(schemas.Group)
 	if !ok {
-		c.AbortWithStatusJSON(
-			http.StatusInternalServerError, endpoints.BodyInternalServerError)
+		endpoints.AbortWithBodyError(
+			c, http.StatusInternalServerError, endpoints.BodyInternalServerError)
 		return
 	}
 
 	if g.IsFull() {
 		// Return a 400 error if the group is full
-		log.WithFields(log.Fields{
-			"permission": "AllowIfGroupIsNotFull",
-			"details":    "Request denied because the group is full",
-			"group_id":   g.ID,
-		}).Info("Permission error")
-		c.AbortWithStatusJSON(
-			http.StatusBadRequest,
-			schemas.BodyError{Message: "Group is full"})
+		denyPermission(c, g, permissionDenial{
+			Permission: "AllowIfGroupIsNotFull",
+			Code:       "group_full",
+			Details:    "Request denied because the group is full",
+			Status:     http.StatusBadRequest,
+			Message:    "Group is full",
+		})
+		return
 	}
 
 	c.Next()
@@ -95,23 +163,20 @@ func AllowIfGroupIsNotFull(c *gin.Context) {
 func AllowIfUserIsNotMember(c *gin.Context) {
 	g, ok := c.Keys["obj"].(schemas.Group)
 	if !ok {
-		c.AbortWithStatusJSON(
-			http.StatusInternalServerError, endpoints.BodyInternalServerError)
+		endpoints.AbortWithBodyError(
+			c, http.StatusInternalServerError, endpoints.BodyInternalServerError)
 		return
 	}
 
-	uid := c.GetInt64("user_id")
-	if g.IsMember(uid) {
+	if g.IsMember(c.GetInt64("user_id")) {
 		// Return a 400 error if the user is a member of the group
-		log.WithFields(log.Fields{
-			"permission": "AllowIfUserIsNotMember",
-			"details":    "Request denied because the user is a member of the group",
-			"group_id":   g.ID,
-			"user_id":    uid,
-		}).Info("Permission error")
-		c.AbortWithStatusJSON(
-			http.StatusBadRequest,
-			schemas.BodyError{Message: "User is a member of the group"})
+		denyPermission(c, g, permissionDenial{
+			Permission: "AllowIfUserIsNotMember",
+			Code:       "already_member",
+			Details:    "Request denied because the user is a member of the group",
+			Status:     http.StatusBadRequest,
+			Message:    "User is a member of the group",
+		})
 		return
 	}
 
@@ -122,23 +187,51 @@ func AllowIfUserIsNotMember(c *gin.Context) {
 func AllowIfUserIsNotOwner(c *gin.Context) {
 	g, ok := c.Keys["obj"].(schemas.Group)
 	if !ok {
-		c.AbortWithStatusJSON(
-			http.StatusInternalServerError, endpoints.BodyInternalServerError)
+		endpoints.AbortWithBodyError(
+			c, http.StatusInternalServerError, endpoints.BodyInternalServerError)
 		return
 	}
 
-	uid := c.GetInt64("user_id")
-	if g.IsOwner(uid) {
+	if g.IsOwner(c.GetInt64("user_id")) {
 		// Return a 400 error if the user is the owner of the group.
-		log.WithFields(log.Fields{
-			"permission": "AllowIfUserIsNotOwner",
-			"details":    "Request denied because the user is the owner of the group",
-			"group_id":   g.ID,
-			"user_id":    uid,
-		}).Info("Permission error")
-		c.AbortWithStatusJSON(
-			http.StatusBadRequest,
-			schemas.BodyError{Message: "User is the owner of the group"})
+		denyPermission(c, g, permissionDenial{
+			Permission: "AllowIfUserIsNotOwner",
+			Code:       "is_owner",
+			Details:    "Request denied because the user is the owner of the group",
+			Status:     http.StatusBadRequest,
+			Message:    "User is the owner of the group",
+		})
+		return
+	}
+
+	c.Next()
+}
+
+// AllowIfUserIsNotBanned allows requests on groups the user is not banned
+// from.
+func AllowIfUserIsNotBanned(c *gin.Context) {
+	g, ok := c.Keys["obj"].(schemas.Group)
+	if !ok {
+		endpoints.AbortWithBodyError(
+			c, http.StatusInternalServerError, endpoints.BodyInternalServerError)
+		return
+	}
+
+	banned, err := g.IsBanned(c.GetInt64("user_id"))
+	if err != nil {
+		endpoints.AbortWithBodyError(
+			c, http.StatusInternalServerError, endpoints.BodyInternalServerError)
+		return
+	}
+	if banned {
+		// Return a 403 error if the user was banned from the group.
+		denyPermission(c, g, permissionDenial{
+			Permission: "AllowIfUserIsNotBanned",
+			Code:       "banned",
+			Details:    "Request denied because the user is banned from the group",
+			Status:     http.StatusForbidden,
+			Message:    "User is banned from the group",
+		})
 		return
 	}
 
@@ -149,23 +242,20 @@ func AllowIfUserIsNotOwner(c *gin.Context) {
 func AllowIfUserIsOwner(c *gin.Context) {
 	g, ok := c.Keys["obj"].(schemas.Group)
 	if !ok {
-		c.AbortWithStatusJSON(
-			http.StatusInternalServerError, endpoints.BodyInternalServerError)
+		endpoints.AbortWithBodyError(
+			c, http.StatusInternalServerError, endpoints.BodyInternalServerError)
 		return
 	}
 
-	uid := c.GetInt64("user_id")
-	if !g.IsOwner(uid) {
-		// Return a 400 error if the user is the owner of the group.
-		log.WithFields(log.Fields{
-			"permission": "AllowIfUserIsOwner",
-			"details":    "Request denied because the user is not the owner of the group",
-			"group_id":   g.ID,
-			"user_id":    uid,
-		}).Info("Permission error")
-		c.AbortWithStatusJSON(
-			http.StatusBadRequest,
-			schemas.BodyError{Message: "User is not the owner of the group"})
+	if !g.IsOwner(c.GetInt64("user_id")) {
+		// Return a 403 error if the user is not the owner of the group.
+		denyPermission(c, g, permissionDenial{
+			Permission: "AllowIfUserIsOwner",
+			Code:       "not_owner",
+			Details:    "Request denied because the user is not the owner of the group",
+			Status:     http.StatusForbidden,
+			Message:    "User is not the owner of the group",
+		})
 		return
 	}
 
@@ -176,23 +266,20 @@ func AllowIfUserIsOwner(c *gin.Context) {
 func AllowIfUserIsMember(c *gin.Context) {
 	g, ok := c.Keys["obj"].(schemas.Group)
 	if !ok {
-		c.AbortWithStatusJSON(
-			http.StatusInternalServerError, endpoints.BodyInternalServerError)
+		endpoints.AbortWithBodyError(
+			c, http.StatusInternalServerError, endpoints.BodyInternalServerError)
 		return
 	}
 
-	uid := c.GetInt64("user_id")
-	if !g.IsMember(uid) {
+	if !g.IsMember(c.GetInt64("user_id")) {
 		// Return a 400 error if the user is not a member of the group
-		log.WithFields(log.Fields{
-			"permission": "AllowIfUserIsMember",
-			"details":    "Request denied because the user is not a member of the group",
-			"group_id":   g.ID,
-			"user_id":    uid,
-		}).Info("Permission error")
-		c.AbortWithStatusJSON(
-			http.StatusBadRequest,
-			schemas.BodyError{Message: "User is not a member of the group"})
+		denyPermission(c, g, permissionDenial{
+			Permission: "AllowIfUserIsMember",
+			Code:       "not_member",
+			Details:    "Request denied because the user is not a member of the group",
+			Status:     http.StatusBadRequest,
+			Message:    "User is not a member of the group",
+		})
 		return
 	}
 
@@ -203,12 +290,14 @@ func AllowIfUserIsMember(c *gin.Context) {
 func AllowIfCorrectGroupPassword(c *gin.Context) {
 	g, ok := c.Keys["obj"].(schemas.Group)
 	if !ok {
-		c.AbortWithStatusJSON(http.StatusNotFound, endpoints.BodyNotFound)
+		endpoints.AbortWithBodyError(
+			c, http.StatusInternalServerError, endpoints.BodyInternalServerError)
 		return
 	}
 
-	// No need to check if the group is not private.
-	if !g.IsPrivate() {
+	// No need to check if the group is not private or if group passwords
+	// are disabled.
+	if !g.IsPrivate() || !endpoints.GROUP_PASSWORDS_ENABLED {
 		c.Next()
 		return
 	}
@@ -222,21 +311,28 @@ func AllowIfCorrectGroupPassword(c *gin.Context) {
 		}).Error("Failed to bind JSON request body")
 		if err.Error() == "EOF" {
 			// Return a 400 error if there is no request body.
-			c.AbortWithStatusJSON(
-				http.StatusBadRequest,
-				schemas.BodyError{Message: "Group password is required"})
+			denyPermission(c, g, permissionDenial{
+				Permission: "AllowIfCorrectGroupPassword",
+				Code:       "password_required",
+				Details:    "Request denied because the group password is missing",
+				Status:     http.StatusBadRequest,
+				Message:    "Group password is required",
+			})
 			return
 		}
-		// Return a 500 error for errors other than the EOF error.
-		c.AbortWithStatusJSON(
-			http.StatusInternalServerError, endpoints.BodyInternalServerError)
+		endpoints.AbortWithBindError(c, err)
 		return
 	}
 	if err := g.ValidatePassword(req.Password); err != nil {
 		// Return a 403 error if the group password does not match
 		// the one on the request body.
-		c.AbortWithStatusJSON(
-			http.StatusForbidden, schemas.BodyError{Message: "Incorrect password"})
+		denyPermission(c, g, permissionDenial{
+			Permission: "AllowIfCorrectGroupPassword",
+			Code:       "incorrect_password",
+			Details:    "Request denied because the group password is incorrect",
+			Status:     http.StatusForbidden,
+			Message:    "Incorrect password",
+		})
 		return
 	}
 
@@ -247,21 +343,124 @@ func AllowIfCorrectGroupPassword(c *gin.Context) {
 func AllowIfGroupIsOpen(c *gin.Context) {
 	g, ok := c.Keys["obj"].(schemas.Group)
 	if !ok {
-		c.AbortWithStatusJSON(
-			http.StatusInternalServerError, endpoints.BodyInternalServerError)
+		endpoints.AbortWithBodyError(
+			c, http.StatusInternalServerError, endpoints.BodyInternalServerError)
 		return
 	}
 
 	if !g.IsOpen() {
 		// Return a 400 error if the group is not open.
-		log.WithFields(log.Fields{
-			"permission": "AllowIfUserIsMember",
-			"details":    "Request denied because the group is not open",
-			"group_id":   g.ID,
-		}).Info("Permission error")
-		c.AbortWithStatusJSON(
-			http.StatusBadRequest,
-			schemas.BodyError{Message: "Group is not open"})
+		denyPermission(c, g, permissionDenial{
+			Permission: "AllowIfGroupIsOpen",
+			Code:       "group_not_open",
+			Details:    "Request denied because the group is not open",
+			Status:     http.StatusBadRequest,
+			Message:    "Group is not open",
+		})
+		return
+	}
+
+	c.Next()
+}
+
+// AllowIfGroupIsClosed allows requests if the group is closed.
+func AllowIfGroupIsClosed(c *gin.Context) {
+	g, ok := c.Keys["obj"].(schemas.Group)
+	if !ok {
+		endpoints.AbortWithBodyError(
+			c, http.StatusInternalServerError, endpoints.BodyInternalServerError)
+		return
+	}
+
+	if g.IsOpen() {
+		// Return a 400 error if the group is not closed.
+		denyPermission(c, g, permissionDenial{
+			Permission: "AllowIfGroupIsClosed",
+			Code:       "group_not_closed",
+			Details:    "Request denied because the group is not closed",
+			Status:     http.StatusBadRequest,
+			Message:    "Group is not closed",
+		})
+		return
+	}
+
+	c.Next()
+}
+
+// AllowIfUserIsOwnerOrMember allows requests on groups where the user is
+// either the owner or a member.
+func AllowIfUserIsOwnerOrMember(c *gin.Context) {
+	g, ok := c.Keys["obj"].(schemas.Group)
+	if !ok {
+		endpoints.AbortWithBodyError(
+			c, http.StatusInternalServerError, endpoints.BodyInternalServerError)
+		return
+	}
+
+	uid := c.GetInt64("user_id")
+	if !g.IsOwner(uid) && !g.IsMember(uid) {
+		// Return a 403 error if the user is not in the group.
+		denyPermission(c, g, permissionDenial{
+			Permission: "AllowIfUserIsOwnerOrMember",
+			Code:       "not_in_group",
+			Details:    "Request denied because the user is not in the group",
+			Status:     http.StatusForbidden,
+			Message:    "User is not in the group",
+		})
+		return
+	}
+
+	c.Next()
+}
+
+// AllowIfUserCanSeeGroup allows requests on public groups, and on private
+// groups where the user is either the owner or a member.
+//
+// The X-Group-Password header is not checked since only the retrieval of the
+// group limits the wrong passwords.
+func AllowIfUserCanSeeGroup(c *gin.Context) {
+	g, ok := c.Keys["obj"].(schemas.Group)
+	if !ok {
+		endpoints.AbortWithBodyError(
+			c, http.StatusInternalServerError, endpoints.BodyInternalServerError)
+		return
+	}
+
+	if !g.IsVisibleTo(c.GetInt64("user_id")) {
+		// Return a 403 error if the user is not in the private group.
+		denyPermission(c, g, permissionDenial{
+			Permission: "AllowIfUserCanSeeGroup",
+			Code:       "not_in_group",
+			Details:    "Request denied because the user is not in the private group",
+			Status:     http.StatusForbidden,
+			Message:    "User is not in the group",
+		})
+		return
+	}
+
+	c.Next()
+}
+
+// AllowIfUserIsOwnerOrModerator allows requests on groups where the user is
+// either the owner or a moderator.
+func AllowIfUserIsOwnerOrModerator(c *gin.Context) {
+	g, ok := c.Keys["obj"].(schemas.Group)
+	if !ok {
+		endpoints.AbortWithBodyError(
+			c, http.StatusInternalServerError, endpoints.BodyInternalServerError)
+		return
+	}
+
+	uid := c.GetInt64("user_id")
+	if !g.IsOwner(uid) && !g.IsModerator(uid) {
+		// Return a 403 error if the user cannot moderate the group.
+		denyPermission(c, g, permissionDenial{
+			Permission: "AllowIfUserIsOwnerOrModerator",
+			Code:       "not_moderator",
+			Details:    "Request denied because the user is not the owner or a moderator",
+			Status:     http.StatusForbidden,
+			Message:    "User is not the owner or a moderator of the group",
+		})
 		return
 	}
 
@@ -0,0 +1,64 @@
+package middlewares
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/damascopaul/lfg-backend/schemas"
+
+	"github.com/gin-gonic/gin"
+	log "github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+)
+
+func TestPermissionDenialIsLogged(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	hook := test.NewGlobal()
+	defer log.StandardLogger().ReplaceHooks(log.LevelHooks{})
+
+	r := gin.New()
+	r.GET("/", func(c *gin.Context) {
+		c.Set("request_id", "denied-514")
+		c.Set("user_id", int64(7))
+		c.Set("obj", schemas.Group{ID: 3, OwnerID: 1})
+	}, AllowIfUserIsOwner, func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	if w.Code != http.StatusForbidden {
+		t.Fatalf("got status %v, want 403: %s", w.Code, w.Body.String())
+	}
+
+	e := hook.LastEntry()
+	if e == nil || e.Message != "Permission error" {
+		t.Fatalf("got log %v, want the permission error", e)
+	}
+	for k, want := range map[string]interface{}{
+		"permission": "AllowIfUserIsOwner",
+		"code":       "not_owner",
+		"group_id":   int64(3),
+		"user_id":    int64(7),
+		"request_id": "denied-514",
+	} {
+		if e.Data[k] != want {
+			t.Errorf("got %v %v, want %v", k, e.Data[k], want)
+		}
+	}
+}
+
+func TestGroupPermissionWithoutGroupObject(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.POST("/", func(c *gin.Context) {
+		c.Set("obj", "not a group")
+	}, AllowIfCorrectGroupPassword, func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/", nil))
+	if w.Code != http.StatusInternalServerError {
+		t.Errorf("got status %v, want 500: %s", w.Code, w.Body.String())
+	}
+}
@@ -0,0 +1,31 @@
+package middlewares
+
+import (
+	"fmt"
+
+	"github.com/damascopaul/lfg-backend/endpoints"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SecureHeaders adds the security headers to the responses.
+//
+// The headers are set before the request is handled so error responses get
+// them too. Each header can be turned off in the config.
+func SecureHeaders(c *gin.Context) {
+	h := c.Writer.Header()
+	if endpoints.NOSNIFF_HEADER {
+		h.Set("X-Content-Type-Options", "nosniff")
+	}
+	if endpoints.FRAME_DENY_HEADER {
+		h.Set("X-Frame-Options", "DENY")
+	}
+	if endpoints.HSTS_MAX_AGE > 0 {
+		hsts := fmt.Sprintf("max-age=%d", int64(endpoints.HSTS_MAX_AGE.Seconds()))
+		if endpoints.HSTS_INCLUDE_SUBDOMAINS {
+			hsts += "; includeSubDomains"
+		}
+		h.Set("Strict-Transport-Security", hsts)
+	}
+	c.Next()
+}
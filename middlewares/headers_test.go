@@ -0,0 +1,52 @@
+package middlewares
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/damascopaul/lfg-backend/endpoints"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestSecureHeadersCanBeTurnedOff(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	defer func(nosniff, frameDeny bool, hsts time.Duration, subdomains bool) {
+		endpoints.NOSNIFF_HEADER, endpoints.FRAME_DENY_HEADER = nosniff, frameDeny
+		endpoints.HSTS_MAX_AGE = hsts
+		endpoints.HSTS_INCLUDE_SUBDOMAINS = subdomains
+	}(endpoints.NOSNIFF_HEADER, endpoints.FRAME_DENY_HEADER,
+		endpoints.HSTS_MAX_AGE, endpoints.HSTS_INCLUDE_SUBDOMAINS)
+
+	r := gin.New()
+	r.GET("/", SecureHeaders, func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+	send := func() http.Header {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+		return w.Header()
+	}
+
+	endpoints.NOSNIFF_HEADER, endpoints.FRAME_DENY_HEADER = true, true
+	endpoints.HSTS_MAX_AGE = time.Hour
+	endpoints.HSTS_INCLUDE_SUBDOMAINS = true
+	h := send()
+	if got, want := h.Get("Strict-Transport-Security"),
+		"max-age=3600; includeSubDomains"; got != want {
+		t.Errorf("got Strict-Transport-Security %q, want %q", got, want)
+	}
+
+	endpoints.NOSNIFF_HEADER, endpoints.FRAME_DENY_HEADER = false, false
+	endpoints.HSTS_MAX_AGE = 0
+	h = send()
+	for _, name := range []string{
+		"X-Content-Type-Options", "X-Frame-Options", "Strict-Transport-Security",
+	} {
+		if got := h.Get(name); got != "" {
+			t.Errorf("got %v %q, want no header", name, got)
+		}
+	}
+}
@@ -0,0 +1,39 @@
+package middlewares
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestRecordMetrics(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(RecordMetrics)
+	r.GET("/groups/:id", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	count := func(route, status string) float64 {
+		return testutil.ToFloat64(
+			httpRequests.WithLabelValues(http.MethodGet, route, status))
+	}
+	send := func(path string) {
+		r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+
+	// The requests are counted under the route template, not the URL.
+	before := count("/groups/:id", "200")
+	send("/groups/1")
+	send("/groups/2")
+	if got := count("/groups/:id", "200") - before; got != 2 {
+		t.Errorf("got %v more requests on the route, want 2", got)
+	}
+
+	before = count("unmatched", "404")
+	send("/missing")
+	if got := count("unmatched", "404") - before; got != 1 {
+		t.Errorf("got %v more unmatched requests, want 1", got)
+	}
+}
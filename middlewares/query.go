@@ -0,0 +1,87 @@
+package middlewares
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/damascopaul/lfg-backend/endpoints"
+	"github.com/damascopaul/lfg-backend/schemas"
+
+	"github.com/gin-gonic/gin"
+	log "github.com/sirupsen/logrus"
+)
+
+// LimitQueryLength allows requests with a query string that is not longer
+// than MAX_QUERY_LENGTH.
+func LimitQueryLength(c *gin.Context) {
+	if n := len(c.Request.URL.RawQuery); n > endpoints.MAX_QUERY_LENGTH {
+		// Return a 414 error if the query string is too long.
+		log.WithFields(log.Fields{
+			"details": "Request denied because the query string is too long",
+			"length":  n,
+		}).Info("Request too long")
+		endpoints.AbortWithBodyError(
+			c, http.StatusRequestURITooLong,
+			schemas.BodyError{
+				Code:    "query_too_long",
+				Message: "Query string is too long",
+			})
+		return
+	}
+
+	c.Next()
+}
+
+// LimitBodySize allows requests with a body that is not larger than
+// MAX_BODY_SIZE.
+//
+// The body is cut off at the limit so reading a body without a
+// `Content-Length` header fails once it goes over the limit.
+func LimitBodySize(c *gin.Context) {
+	if c.Request.ContentLength > endpoints.MAX_BODY_SIZE {
+		// Return a 413 error if the declared body is too large.
+		endpoints.AbortBodyTooLarge(c)
+		return
+	}
+	c.Request.Body = http.MaxBytesReader(
+		c.Writer, c.Request.Body, endpoints.MAX_BODY_SIZE)
+
+	c.Next()
+}
+
+// LimitQueryValues returns a middleware allowing requests where each of the
+// params has at most MAX_QUERY_VALUES comma-separated values.
+func LimitQueryValues(params ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		for _, param := range params {
+			n := 0
+			for _, v := range c.QueryArray(param) {
+				n += strings.Count(v, ",") + 1
+			}
+			if n > endpoints.MAX_QUERY_VALUES {
+				// Return a 400 error if the param has too many values.
+				log.WithFields(log.Fields{
+					"details": "Request denied because a query parameter has too many values",
+					"param":   param,
+					"values":  n,
+				}).Info("Request too long")
+				endpoints.AbortWithBodyError(c, http.StatusBadRequest, schemas.BodyError{
+					Code:    "invalid_query",
+					Message: "Query parameters are invalid",
+					FieldErrors: []schemas.FieldError{{
+						Name:   param,
+						ID:     "too_many_values",
+						Params: []interface{}{endpoints.MAX_QUERY_VALUES},
+						Error: fmt.Sprintf(
+							"This field cannot have more than %v values",
+							endpoints.MAX_QUERY_VALUES),
+					}},
+				})
+				return
+			}
+		}
+
+		c.Next()
+	}
+}
@@ -0,0 +1,101 @@
+package middlewares
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/damascopaul/lfg-backend/endpoints"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestLimitQueryLength(t *testing.T) {
+	defer func(max int) { endpoints.MAX_QUERY_LENGTH = max }(endpoints.MAX_QUERY_LENGTH)
+	endpoints.MAX_QUERY_LENGTH = 16
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/", LimitQueryLength, func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+	for _, tc := range []struct {
+		query string
+		want  int
+	}{
+		{"", http.StatusOK},
+		{"q=" + strings.Repeat("a", 14), http.StatusOK},
+		{"q=" + strings.Repeat("a", 15), http.StatusRequestURITooLong},
+	} {
+		req := httptest.NewRequest(http.MethodGet, "/?"+tc.query, nil)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Code != tc.want {
+			t.Errorf("got status %v for %v bytes, want %v", w.Code, len(tc.query), tc.want)
+		}
+	}
+}
+
+func TestLimitQueryValues(t *testing.T) {
+	defer func(max int) { endpoints.MAX_QUERY_VALUES = max }(endpoints.MAX_QUERY_VALUES)
+	endpoints.MAX_QUERY_VALUES = 3
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/", LimitQueryValues("ids"), func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+	for _, tc := range []struct {
+		query string
+		want  int
+	}{
+		{"ids=1,2,3", http.StatusOK},
+		{"ids=1,2&ids=3", http.StatusOK},
+		{"ids=1,2,3,4", http.StatusBadRequest},
+		{"ids=1,2&ids=3,4", http.StatusBadRequest},
+		// Other params are not limited.
+		{"tags=a,b,c,d", http.StatusOK},
+	} {
+		req := httptest.NewRequest(http.MethodGet, "/?"+tc.query, nil)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Code != tc.want {
+			t.Errorf("got status %v for %v, want %v", w.Code, tc.query, tc.want)
+		}
+	}
+}
+
+func TestLimitBodySize(t *testing.T) {
+	defer func(max int64) { endpoints.MAX_BODY_SIZE = max }(endpoints.MAX_BODY_SIZE)
+	endpoints.MAX_BODY_SIZE = 64
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.POST("/", LimitBodySize, UserRequestBody, func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+	small := `{"username": "player"}`
+	large := `{"username": "` + strings.Repeat("a", 64) + `"}`
+	for _, tc := range []struct {
+		name          string
+		body          string
+		contentLength bool
+		want          int
+	}{
+		{"small body", small, true, http.StatusOK},
+		{"large body", large, true, http.StatusRequestEntityTooLarge},
+		// The body is cut off while reading it without a Content-Length.
+		{"large streamed body", large, false, http.StatusRequestEntityTooLarge},
+	} {
+		req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(tc.body))
+		if !tc.contentLength {
+			req.ContentLength = -1
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Code != tc.want {
+			t.Errorf("got status %v for the %v, want %v", w.Code, tc.name, tc.want)
+		}
+	}
+}
@@ -0,0 +1,42 @@
+package middlewares
+
+import (
+	"net/http"
+	"runtime/debug"
+
+	"github.com/damascopaul/lfg-backend/endpoints"
+
+	"github.com/gin-gonic/gin"
+	log "github.com/sirupsen/logrus"
+)
+
+// Recover turns a panic in a handler into an internal error response.
+//
+// The panic is logged with its stack trace. Nothing is sent if the handler
+// already started the response.
+func Recover(c *gin.Context) {
+	defer func() {
+		err := recover()
+		if err == nil {
+			return
+		}
+		if err == http.ErrAbortHandler {
+			// Let the server abort the response like it was asked to.
+			panic(err)
+		}
+
+		log.WithFields(log.Fields{
+			"error":      err,
+			"request_id": c.GetString("request_id"),
+			"stack":      string(debug.Stack()),
+		}).Error("Recovered from a panic")
+		if c.Writer.Written() {
+			c.Abort()
+			return
+		}
+		endpoints.AbortWithBodyError(
+			c, http.StatusInternalServerError, endpoints.BodyInternalServerError)
+	}()
+
+	c.Next()
+}
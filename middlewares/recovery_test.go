@@ -0,0 +1,43 @@
+package middlewares
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/damascopaul/lfg-backend/schemas"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestRecover(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(Recover)
+	r.GET("/panic", func(c *gin.Context) { panic("something broke") })
+	r.GET("/ok", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/panic", nil))
+	if w.Code != http.StatusInternalServerError {
+		t.Fatalf("got status %v, want 500", w.Code)
+	}
+	if ct := w.Header().Get("Content-Type"); ct != "application/json; charset=utf-8" {
+		t.Errorf("got content type %q, want JSON", ct)
+	}
+	var resp schemas.BodyError
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("could not decode %q: %v", w.Body.String(), err)
+	}
+	if resp.Code != "internal_error" {
+		t.Errorf("got code %q, want internal_error", resp.Code)
+	}
+
+	// The server keeps handling requests after the panic.
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/ok", nil))
+	if w.Code != http.StatusOK {
+		t.Errorf("got status %v after the panic, want 200", w.Code)
+	}
+}
@@ -0,0 +1,61 @@
+package middlewares
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"time"
+
+	"github.com/damascopaul/lfg-backend/logging"
+
+	"github.com/gin-gonic/gin"
+	log "github.com/sirupsen/logrus"
+)
+
+// requestIDHeader is the header carrying the ID of a request.
+const requestIDHeader = "X-Request-ID"
+
+// maxRequestIDLen is the length of the longest request ID accepted from
+// clients.
+const maxRequestIDLen = 128
+
+// newRequestID generates a random request ID.
+func newRequestID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		log.Errorf("Could not generate request ID. Error: %v", err)
+		return ""
+	}
+	return hex.EncodeToString(b)
+}
+
+// RequestID adds the ID of the request to the context and the response.
+//
+// The ID is taken from the `X-Request-ID` header or generated if the header
+// is missing. A logger carrying the ID is added to the context of the
+// request for the endpoints and the database calls. The request is logged
+// with its ID once it is handled.
+func RequestID(c *gin.Context) {
+	id := c.GetHeader(requestIDHeader)
+	if id == "" || len(id) > maxRequestIDLen {
+		id = newRequestID()
+	}
+	c.Set("request_id", id)
+	c.Header(requestIDHeader, id)
+	entry := log.WithField("request_id", id)
+	c.Request = c.Request.WithContext(
+		logging.NewContext(c.Request.Context(), entry))
+
+	start := time.Now()
+	c.Next()
+
+	fields := log.Fields{
+		"method":  c.Request.Method,
+		"path":    c.Request.URL.Path,
+		"status":  c.Writer.Status(),
+		"latency": time.Since(start).String(),
+	}
+	if uid, ok := c.Get("user_id"); ok {
+		fields["user_id"] = uid
+	}
+	entry.WithFields(fields).Info("Handled request")
+}
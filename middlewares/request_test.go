@@ -0,0 +1,47 @@
+package middlewares
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestRequestID(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	var seen string
+	r.GET("/", RequestID, func(c *gin.Context) {
+		seen = c.GetString("request_id")
+		c.Status(http.StatusOK)
+	})
+	send := func(id string) string {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		if id != "" {
+			req.Header.Set("X-Request-ID", id)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		got := w.Header().Get("X-Request-ID")
+		if got != seen {
+			t.Errorf("got ID %q in the response and %q in the context", got, seen)
+		}
+		return got
+	}
+
+	if got := send("client-id-1"); got != "client-id-1" {
+		t.Errorf("got ID %q, want the ID of the client", got)
+	}
+
+	// An ID is generated if the header is missing or too long, and each
+	// request gets its own.
+	first, second := send(""), send("")
+	if len(first) != 32 || first == second {
+		t.Errorf("got generated IDs %q and %q, want two different IDs", first, second)
+	}
+	if got := send(strings.Repeat("a", maxRequestIDLen+1)); len(got) != 32 {
+		t.Errorf("got ID %q for an over-long header, want a generated one", got)
+	}
+}
@@ -0,0 +1,100 @@
+package middlewares
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/damascopaul/lfg-backend/endpoints"
+	"github.com/damascopaul/lfg-backend/schemas"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/render"
+	log "github.com/sirupsen/logrus"
+)
+
+// bodyTimeout is the response body of a request that timed out.
+var bodyTimeout = schemas.BodyError{
+	Code:    "timeout",
+	Message: "The request took too long to complete",
+}
+
+// timeoutWriter turns a response started after the deadline, like the
+// internal error caused by a canceled query, into a timeout error.
+type timeoutWriter struct {
+	gin.ResponseWriter
+	c        *gin.Context
+	ctx      context.Context
+	timedOut bool
+}
+
+// WriteHeader replaces the status with a 503 if the deadline passed before
+// the response was started. A response already being sent is left as is.
+func (w *timeoutWriter) WriteHeader(code int) {
+	if !w.ResponseWriter.Written() &&
+		errors.Is(w.ctx.Err(), context.DeadlineExceeded) {
+		w.timedOut = true
+		code = http.StatusServiceUnavailable
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *timeoutWriter) Write(b []byte) (int, error) {
+	if !w.timedOut {
+		return w.ResponseWriter.Write(b)
+	}
+	// The body of the late response is replaced once.
+	if err := w.writeTimeoutBody(); err != nil {
+		return 0, err
+	}
+	return len(b), nil
+}
+
+// writeTimeoutBody writes the timeout error if nothing was written yet.
+func (w *timeoutWriter) writeTimeoutBody() error {
+	if w.ResponseWriter.Written() {
+		return nil
+	}
+	body := endpoints.ErrorBody(w.c, http.StatusServiceUnavailable, bodyTimeout)
+	return (render.JSON{Data: body}).Render(w.ResponseWriter)
+}
+
+// Timeout cancels the database queries of a request that takes longer than
+// REQUEST_TIMEOUT.
+//
+// The request fails with a 503 error if its response is started after the
+// deadline, whether a query was canceled or the handler was slow otherwise.
+// The handler still runs to the end, so a handler that does not use the
+// context of the request is only cut short in its response. WebSocket
+// requests are not limited since they stay open.
+func Timeout(c *gin.Context) {
+	upgrade := strings.EqualFold(c.GetHeader("Upgrade"), "websocket")
+	if endpoints.REQUEST_TIMEOUT <= 0 || upgrade {
+		c.Next()
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(
+		c.Request.Context(), endpoints.REQUEST_TIMEOUT)
+	defer cancel()
+	c.Request = c.Request.WithContext(ctx)
+	w := &timeoutWriter{ResponseWriter: c.Writer, c: c, ctx: ctx}
+	c.Writer = w
+
+	c.Next()
+
+	if !w.ResponseWriter.Written() && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		// The handler only set a status, or nothing, after the deadline.
+		w.WriteHeader(http.StatusServiceUnavailable)
+		if err := w.writeTimeoutBody(); err != nil {
+			log.Errorf("Could not write the timeout error. Error: %v", err)
+		}
+	}
+	if w.timedOut {
+		log.WithFields(log.Fields{
+			"details": "Request canceled because it took too long",
+			"timeout": endpoints.REQUEST_TIMEOUT.String(),
+		}).Warn("Request timed out")
+	}
+}
@@ -0,0 +1,68 @@
+package middlewares
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/damascopaul/lfg-backend/endpoints"
+	"github.com/damascopaul/lfg-backend/schemas"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestTimeout(t *testing.T) {
+	defer func(timeout time.Duration) { endpoints.REQUEST_TIMEOUT = timeout }(
+		endpoints.REQUEST_TIMEOUT)
+	endpoints.REQUEST_TIMEOUT = 50 * time.Millisecond
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(Timeout)
+	r.GET("/fast", func(c *gin.Context) { c.JSON(http.StatusOK, gin.H{}) })
+	// A slow handler that does not use the context of the request.
+	r.GET("/slow", func(c *gin.Context) {
+		time.Sleep(100 * time.Millisecond)
+		c.JSON(http.StatusOK, gin.H{})
+	})
+	r.GET("/slow-no-content", func(c *gin.Context) {
+		time.Sleep(100 * time.Millisecond)
+		c.Status(http.StatusNoContent)
+	})
+	// A query canceled at the deadline fails with an internal error.
+	r.GET("/query", func(c *gin.Context) {
+		<-c.Request.Context().Done()
+		endpoints.AbortWithBodyError(
+			c, http.StatusInternalServerError, endpoints.BodyInternalServerError)
+	})
+
+	for _, tc := range []struct {
+		path string
+		want int
+	}{
+		{"/fast", http.StatusOK},
+		{"/slow", http.StatusServiceUnavailable},
+		{"/slow-no-content", http.StatusServiceUnavailable},
+		{"/query", http.StatusServiceUnavailable},
+	} {
+		t.Run(tc.path, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tc.path, nil))
+			if w.Code != tc.want {
+				t.Fatalf("got status %v, want %v", w.Code, tc.want)
+			}
+			if tc.want != http.StatusServiceUnavailable {
+				return
+			}
+			var resp schemas.BodyError
+			if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("could not decode %q: %v", w.Body.String(), err)
+			}
+			if resp.Code != "timeout" {
+				t.Errorf("got code %q, want timeout", resp.Code)
+			}
+		})
+	}
+}
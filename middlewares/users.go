@@ -1,25 +1,66 @@
 package middlewares
 
 import (
-	"net/http"
-
 	"github.com/damascopaul/lfg-backend/endpoints"
 	"github.com/damascopaul/lfg-backend/schemas"
 
 	"github.com/gin-gonic/gin"
 	"github.com/gin-gonic/gin/binding"
-	log "github.com/sirupsen/logrus"
 )
 
 // UserRequestBody adds the parsed request body to the context.
 func UserRequestBody(c *gin.Context) {
 	var req schemas.User
 	if err := c.ShouldBindWith(&req, binding.JSON); err != nil {
-		log.WithFields(log.Fields{
-			"error": err.Error(),
-		}).Error("Failed to bind JSON request body")
-		c.AbortWithStatusJSON(
-			http.StatusInternalServerError, endpoints.BodyInternalServerError)
+		endpoints.AbortWithBindError(c, err)
+		return
+	}
+
+	c.Set("req", req)
+	c.Next()
+}
+
+// KickRequestBody adds the parsed kick request body to the context.
+func KickRequestBody(c *gin.Context) {
+	var req schemas.Kick
+	if err := c.ShouldBindWith(&req, binding.JSON); err != nil {
+		endpoints.AbortWithBindError(c, err)
+		return
+	}
+
+	c.Set("req", req)
+	c.Next()
+}
+
+// ProfileChangesRequestBody adds the parsed profile changes request body to the context.
+func ProfileChangesRequestBody(c *gin.Context) {
+	var req schemas.ProfileChanges
+	if err := c.ShouldBindWith(&req, binding.JSON); err != nil {
+		endpoints.AbortWithBindError(c, err)
+		return
+	}
+
+	c.Set("req", req)
+	c.Next()
+}
+
+// PasswordChangeRequestBody adds the parsed password change request body to the context.
+func PasswordChangeRequestBody(c *gin.Context) {
+	var req schemas.PasswordChange
+	if err := c.ShouldBindWith(&req, binding.JSON); err != nil {
+		endpoints.AbortWithBindError(c, err)
+		return
+	}
+
+	c.Set("req", req)
+	c.Next()
+}
+
+// PasswordResetRequestBody adds the parsed password reset request body to the context.
+func PasswordResetRequestBody(c *gin.Context) {
+	var req schemas.PasswordReset
+	if err := c.ShouldBindWith(&req, binding.JSON); err != nil {
+		endpoints.AbortWithBindError(c, err)
 		return
 	}
 
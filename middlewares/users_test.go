@@ -0,0 +1,63 @@
+package middlewares
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/damascopaul/lfg-backend/schemas"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestUserRequestBodyBindErrors(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	var bound schemas.User
+	r.POST("/", UserRequestBody, func(c *gin.Context) {
+		bound, _ = c.Keys["req"].(schemas.User)
+		c.Status(http.StatusOK)
+	})
+	for _, tc := range []struct {
+		name   string
+		body   string
+		status int
+		code   string
+	}{
+		{"empty body", "", http.StatusBadRequest, "empty_body"},
+		{"invalid JSON", `{"username": "player"`, http.StatusBadRequest, "malformed_json"},
+		{"not JSON", `username=player`, http.StatusBadRequest, "malformed_json"},
+		{"wrong type", `{"username": 42}`, http.StatusBadRequest, "invalid_type"},
+		{"valid body", `{"username": "player", "password": "s3cret-pass"}`,
+			http.StatusOK, ""},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(tc.body))
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+			if w.Code != tc.status {
+				t.Fatalf("got status %v, want %v: %s", w.Code, tc.status, w.Body.String())
+			}
+			if tc.status == http.StatusOK {
+				if bound.Username != "player" || bound.Password != "s3cret-pass" {
+					t.Errorf("got user %+v bound", bound)
+				}
+				return
+			}
+			var resp schemas.BodyError
+			if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("could not decode %q: %v", w.Body.String(), err)
+			}
+			if resp.Code != tc.code {
+				t.Errorf("got code %q, want %q", resp.Code, tc.code)
+			}
+			if tc.code == "invalid_type" &&
+				(len(resp.FieldErrors) != 1 || resp.FieldErrors[0].Name != "username") {
+				t.Errorf("got field errors %+v, want one on username", resp.FieldErrors)
+			}
+		})
+	}
+}
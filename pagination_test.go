@@ -0,0 +1,51 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/damascopaul/lfg-backend/endpoints"
+	"github.com/damascopaul/lfg-backend/schemas"
+)
+
+func TestListingsUseTheirOwnPageLimits(t *testing.T) {
+	defer func(groups, members, activity schemas.PageLimits) {
+		endpoints.GROUPS_PAGE_LIMITS = groups
+		endpoints.MEMBERS_PAGE_LIMITS = members
+		endpoints.ACTIVITY_PAGE_LIMITS = activity
+	}(endpoints.GROUPS_PAGE_LIMITS, endpoints.MEMBERS_PAGE_LIMITS,
+		endpoints.ACTIVITY_PAGE_LIMITS)
+	endpoints.GROUPS_PAGE_LIMITS = schemas.PageLimits{Default: 3, Max: 4}
+	endpoints.MEMBERS_PAGE_LIMITS = schemas.PageLimits{Default: 1, Max: 2}
+	endpoints.ACTIVITY_PAGE_LIMITS = schemas.PageLimits{Default: 2, Max: 3}
+
+	owner := signUp(t)
+	var g map[string]interface{}
+	for i := 0; i < 5; i++ {
+		g = createGroup(t, owner, nil)
+	}
+	for i := 0; i < 4; i++ {
+		joinGroup(t, signUp(t), g)
+	}
+
+	for _, tc := range []struct {
+		path        string
+		def, capped int
+	}{
+		{"/me/groups/owned", 3, 4},
+		{groupPath(g, "/members"), 1, 2},
+		{groupPath(g, "/activity"), 2, 3},
+	} {
+		t.Run(tc.path, func(t *testing.T) {
+			if got := len(listIDs(t, owner, tc.path)); got != tc.def {
+				t.Errorf("got %v entries by default, want %v", got, tc.def)
+			}
+			// A page size beyond the cap is clamped.
+			path := fmt.Sprintf("%v?page_size=100", tc.path)
+			if got := len(listIDs(t, owner, path)); got != tc.capped {
+				t.Errorf("got %v entries for a page size of 100, want %v",
+					got, tc.capped)
+			}
+		})
+	}
+}
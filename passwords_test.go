@@ -0,0 +1,80 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/damascopaul/lfg-backend/endpoints"
+)
+
+func TestRemoveGroupPasswordMakesGroupPublic(t *testing.T) {
+	owner, outsider := signUp(t), signUp(t)
+	g := createGroup(t, owner, map[string]interface{}{"password": "s3cret-pass"})
+	join := apiRequest{
+		Method: http.MethodPost, Path: groupPath(g, "/join"), Token: outsider.Token,
+	}
+	if w := join.send(t); w.Code == http.StatusOK {
+		t.Fatalf("got status %v joining a private group without a password", w.Code)
+	}
+
+	w := apiRequest{
+		Method: http.MethodDelete, Path: groupPath(g, "/password"), Token: owner.Token,
+	}.send(t)
+	expectStatus(t, w, http.StatusOK)
+	var resp map[string]interface{}
+	decode(t, w, &resp)
+	if resp["private"] != false || resp["visibility"] != "public" {
+		t.Errorf("got private %v and visibility %v, want a public group",
+			resp["private"], resp["visibility"])
+	}
+	if _, ok := resp["password"]; ok {
+		t.Errorf("got the password in the response: %v", resp)
+	}
+
+	expectStatus(t, join.send(t), http.StatusOK)
+
+	// Removing the password again is not an error.
+	expectStatus(t, apiRequest{
+		Method: http.MethodDelete, Path: groupPath(g, "/password"), Token: owner.Token,
+	}.send(t), http.StatusOK)
+}
+
+func TestGroupPasswordsDisabled(t *testing.T) {
+	owner, outsider := signUp(t), signUp(t)
+	// The group is made private before the passwords are disabled.
+	private := createGroup(t, owner, map[string]interface{}{"password": "s3cret-pass"})
+	public := createGroup(t, owner, nil)
+
+	defer func(enabled bool) {
+		endpoints.GROUP_PASSWORDS_ENABLED = enabled
+	}(endpoints.GROUP_PASSWORDS_ENABLED)
+	endpoints.GROUP_PASSWORDS_ENABLED = false
+
+	for name, req := range map[string]apiRequest{
+		"create": {
+			Method: http.MethodPost, Path: "/groups", Token: owner.Token,
+			Body: map[string]interface{}{
+				"title": "Raid night", "description": "Weekly raid", "max_size": 5,
+				"password": "s3cret-pass"},
+		},
+		"set": {
+			Method: http.MethodPatch, Path: groupPath(public, "/password"),
+			Token: owner.Token, Body: map[string]string{"password": "s3cret-pass"},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			w := req.send(t)
+			expectStatus(t, w, http.StatusBadRequest)
+			ids := fieldErrorIDs(t, w)["password"]
+			if len(ids) != 1 || ids[0] != "group_passwords_disabled" {
+				t.Errorf("got password errors %v, want [group_passwords_disabled]", ids)
+			}
+		})
+	}
+
+	// The groups that are already private need the approval of the owner
+	// instead of the password.
+	expectStatus(t, apiRequest{
+		Method: http.MethodPost, Path: groupPath(private, "/join"), Token: outsider.Token,
+	}.send(t), http.StatusAccepted)
+}
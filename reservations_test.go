@@ -0,0 +1,76 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/damascopaul/lfg-backend/schemas"
+)
+
+func TestReservedSlotCountsTowardCapacity(t *testing.T) {
+	owner, holder, late := signUp(t), signUp(t), signUp(t)
+	g := createGroup(t, owner, nil)
+	for i := 0; i < 3; i++ {
+		joinGroup(t, signUp(t), g)
+	}
+
+	w := apiRequest{
+		Method: http.MethodPost, Path: groupPath(g, "/reserve"), Token: holder.Token,
+	}.send(t)
+	expectStatus(t, w, http.StatusCreated)
+	var r struct {
+		ExpiresAt time.Time `json:"expires_at"`
+	}
+	decode(t, w, &r)
+	if !r.ExpiresAt.After(time.Now()) {
+		t.Errorf("got expires_at %v, want a time in the future", r.ExpiresAt)
+	}
+
+	// The last slot is held so the group is full for the others.
+	expectStatus(t, apiRequest{
+		Method: http.MethodPost, Path: groupPath(g, "/join"), Token: late.Token,
+	}.send(t), http.StatusBadRequest)
+
+	w = apiRequest{
+		Method: http.MethodPost, Path: groupPath(g, "/confirm-reservation"),
+		Token: holder.Token,
+	}.send(t)
+	expectStatus(t, w, http.StatusOK)
+	var resp struct {
+		MemberCount   int `json:"member_count"`
+		ReservedSlots int `json:"reserved_slots"`
+	}
+	decode(t, w, &resp)
+	if resp.MemberCount != 4 || resp.ReservedSlots != 0 {
+		t.Errorf("got %+v, want 4 members and no reserved slot", resp)
+	}
+}
+
+func TestExpiredReservationReleasesSlot(t *testing.T) {
+	owner, holder, late := signUp(t), signUp(t), signUp(t)
+	g := createGroup(t, owner, nil)
+	for i := 0; i < 3; i++ {
+		joinGroup(t, signUp(t), g)
+	}
+	expectStatus(t, apiRequest{
+		Method: http.MethodPost, Path: groupPath(g, "/reserve"), Token: holder.Token,
+	}.send(t), http.StatusCreated)
+
+	r := schemas.Reservation{}
+	if err := r.InitDB(context.Background()); err != nil {
+		t.Fatalf("could not init the database: %v", err)
+	}
+	if res := r.DB.Model(&schemas.Reservation{}).Where(
+		"group_id = ? AND user_id = ?", groupID(g), holder.ID,
+	).Update("expires_at", time.Now().Add(-time.Minute).UTC()); res.Error != nil {
+		t.Fatalf("could not expire the reservation: %v", res.Error)
+	}
+
+	expectStatus(t, apiRequest{
+		Method: http.MethodPost, Path: groupPath(g, "/confirm-reservation"),
+		Token: holder.Token,
+	}.send(t), http.StatusNotFound)
+	joinGroup(t, late, g)
+}
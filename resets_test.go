@@ -0,0 +1,40 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/damascopaul/lfg-backend/endpoints"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+)
+
+func TestRequestPasswordResetLogsTokenOnlyInDevMode(t *testing.T) {
+	u := signUp(t)
+	level := log.GetLevel()
+	log.SetLevel(log.DebugLevel)
+	defer log.SetLevel(level)
+
+	for _, devMode := range []bool{false, true} {
+		hook := test.NewGlobal()
+		endpoints.DEV_MODE = devMode
+		expectStatus(t, apiRequest{
+			Method: http.MethodPost, Path: "/password-reset/request",
+			Body: map[string]string{"username": u.Username},
+		}.send(t), http.StatusOK)
+
+		logged := false
+		for _, e := range hook.AllEntries() {
+			if _, ok := e.Data["token"]; ok {
+				logged = true
+			}
+		}
+		if logged != devMode {
+			t.Errorf("got token logged %v in dev mode %v", logged, devMode)
+		}
+		hook.Reset()
+		log.StandardLogger().ReplaceHooks(log.LevelHooks{})
+	}
+	endpoints.DEV_MODE = false
+}
@@ -0,0 +1,70 @@
+package schemas
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Types of group activities.
+const (
+	ActivityCreated     = "created"
+	ActivityUpdated     = "updated"
+	ActivityJoined      = "joined"
+	ActivityApproved    = "approved"
+	ActivityLeft        = "left"
+	ActivityKicked      = "kicked"
+	ActivityClosed      = "closed"
+	ActivityReopened    = "reopened"
+	ActivityTransferred = "transferred"
+	ActivityDeleted     = "deleted"
+)
+
+// staleGroupReason is the reason of the closed activities of the groups
+// closed by the server since they were open for too long.
+const staleGroupReason = "The group was open for too long"
+
+// GroupActivity is an entry in the activity feed of a group.
+type GroupActivity struct {
+	ID      int64  `json:"id" gorm:"primaryKey"`
+	GroupID int64  `json:"group_id" gorm:"not null;index"`
+	Type    string `json:"type" gorm:"not null"`
+	// ActorID is the user who did the activity. It is zero for the
+	// activities of the server, like closing stale groups.
+	ActorID int64 `json:"-" gorm:"not null"`
+	Actor   *User `json:"actor" gorm:"foreignKey:ActorID"`
+	// TargetID is the user affected by the activity, if any.
+	TargetID  *int64    `json:"-"`
+	Target    *User     `json:"target,omitempty" gorm:"foreignKey:TargetID"`
+	Reason    string    `json:"reason,omitempty"` // Why the owner or a moderator did it.
+	CreatedAt time.Time `json:"created_at" gorm:"autoCreateTime"`
+
+	DB *gorm.DB `json:"-" gorm:"-"`
+}
+
+// Create adds the activity to the feed of the group.
+func (a *GroupActivity) Create() error {
+	r := a.DB.Omit("Actor", "Target").Create(&a)
+	if r.Error != nil {
+		dbLog(a.DB).Errorf("Could not create group activity. Error: %v", r.Error)
+		return r.Error
+	}
+	dbLog(a.DB).Info("Created group activity successfully")
+	return nil
+}
+
+// ListActivities retrieves a page of the activity feed of the group.
+//
+// The newest activities come first.
+func (g *Group) ListActivities(p Pagination) ([]GroupActivity, error) {
+	activities := []GroupActivity{}
+	r := p.apply(g.DB.Where("group_id = ?", g.ID)).Order(
+		"created_at DESC, id DESC").Preload("Actor", preloadUser).Preload(
+		"Target", preloadUser).Find(&activities)
+	if r.Error != nil {
+		dbLog(g.DB).Errorf("Could not list group activities. Error: %v", r.Error)
+		return activities, r.Error
+	}
+	dbLog(g.DB).Info("Listed the group activities successfully")
+	return activities, nil
+}
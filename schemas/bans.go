@@ -0,0 +1,52 @@
+package schemas
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// GroupBan keeps a user who was kicked from a group from joining it again.
+type GroupBan struct {
+	GroupID   int64     `json:"group_id" gorm:"primaryKey;autoIncrement:false"`
+	UserID    int64     `json:"user_id" gorm:"primaryKey;autoIncrement:false"`
+	CreatedAt time.Time `json:"created_at" gorm:"autoCreateTime"`
+
+	DB *gorm.DB `json:"-" gorm:"-"`
+}
+
+// Ban keeps the user from joining the group again.
+//
+// Banning a user who is already banned is not an error.
+func (g *Group) Ban(uid int64) error {
+	r := g.DB.Clauses(clause.OnConflict{DoNothing: true}).Create(
+		&GroupBan{GroupID: g.ID, UserID: uid})
+	if r.Error != nil {
+		dbLog(g.DB).Errorf("Could not ban user. Error: %v", r.Error)
+		return r.Error
+	}
+	dbLog(g.DB).Info("Banned the user successfully")
+	return nil
+}
+
+// IsBanned checks if the user is banned from the group.
+func (g *Group) IsBanned(uid int64) (bool, error) {
+	var count int64
+	r := g.DB.Model(&GroupBan{}).Where(
+		"group_id = ? AND user_id = ?", g.ID, uid).Limit(1).Count(&count)
+	if r.Error != nil {
+		dbLog(g.DB).Errorf("Could not check the ban. Error: %v", r.Error)
+	}
+	return count > 0, r.Error
+}
+
+// ListBannedGroupIDs lists the IDs of the groups the user is banned from.
+func (u *User) ListBannedGroupIDs() ([]int64, error) {
+	ids := []int64{}
+	r := u.DB.Model(&GroupBan{}).Where("user_id = ?", u.ID).Pluck("group_id", &ids)
+	if r.Error != nil {
+		dbLog(u.DB).Errorf("Could not list the bans. Error: %v", r.Error)
+	}
+	return ids, r.Error
+}
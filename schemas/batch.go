@@ -0,0 +1,85 @@
+package schemas
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ParseGroupIDs reads the comma separated group IDs of a batch request.
+//
+// Duplicate IDs are only kept once. A ValidationError is returned if an ID
+// is not a number or if there are more than max IDs.
+func ParseGroupIDs(s string, max int) ([]int64, error) {
+	var ids []int64
+	seen := map[int64]bool{}
+	for _, part := range strings.Split(s, ",") {
+		if part = strings.TrimSpace(part); part == "" {
+			continue
+		}
+		id, err := strconv.ParseInt(part, 10, 64)
+		if err != nil || id < 1 {
+			return nil, &ValidationError{
+				Message: "The query parameters contain errors",
+				Errors: []FieldError{{
+					Name:  "ids",
+					ID:    "unsupported_value",
+					Error: "This field has an unsupported value",
+				}},
+			}
+		}
+		if !seen[id] {
+			seen[id] = true
+			ids = append(ids, id)
+		}
+	}
+	if len(ids) > max {
+		return nil, &ValidationError{
+			Message: "The query parameters contain errors",
+			Errors: []FieldError{{
+				Name:   "ids",
+				ID:     "too_many_values",
+				Params: []interface{}{max},
+				Error: fmt.Sprintf(
+					"This field cannot have more than %v values", max),
+			}},
+		}
+	}
+	return ids, nil
+}
+
+// ListByIDs retrieves the groups with the IDs in the order of the IDs.
+//
+// The IDs without a group are skipped. The passwords are not loaded so the
+// groups cannot be unlocked with a password. The member lists are only
+// loaded if members is true.
+func (g *Group) ListByIDs(ids []int64, members bool) ([]Group, error) {
+	found := []Group{}
+	if len(ids) == 0 {
+		return found, nil
+	}
+	r := preloadMembers(g.DB, members).Select(listFields).Where(
+		"id IN ?", ids).Find(&found)
+	if r.Error != nil {
+		dbLog(g.DB).Errorf("Could not list groups by ID. Error: %v", r.Error)
+		return found, r.Error
+	}
+
+	byID := make(map[int64]Group, len(found))
+	for _, grp := range found {
+		byID[grp.ID] = grp
+	}
+	groups := make([]Group, 0, len(found))
+	for _, id := range ids {
+		if grp, ok := byID[id]; ok {
+			groups = append(groups, grp)
+		}
+	}
+	dbLog(g.DB).Info("Listed groups by ID successfully")
+
+	refs := make([]*Group, len(groups))
+	for i := range groups {
+		refs[i] = &groups[i]
+	}
+	return groups, loadGroupDetails(g.DB, refs)
+}
@@ -0,0 +1,112 @@
+package schemas
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// cursorSort is the only sort key of the cursor pagination.
+//
+// The newest groups come first so groups created while paging do not shift
+// the pages.
+const cursorSort = "-created_at"
+
+// groupCursor is the position of the last group of a page.
+type groupCursor struct {
+	CreatedAt time.Time
+	ID        int64
+}
+
+// errInvalidCursor is returned when a cursor cannot be decoded.
+var errInvalidCursor = errors.New("invalid cursor")
+
+// encode returns the opaque form of the cursor sent to the clients.
+func (gc groupCursor) encode() string {
+	s := fmt.Sprintf("%d.%d", gc.CreatedAt.UnixNano(), gc.ID)
+	return base64.RawURLEncoding.EncodeToString([]byte(s))
+}
+
+// decodeGroupCursor parses a cursor sent by a client.
+//
+// An empty cursor is the start of the listing and returns nil.
+func decodeGroupCursor(s string) (*groupCursor, error) {
+	if s == "" {
+		return nil, nil
+	}
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, errInvalidCursor
+	}
+	var nanos, id int64
+	if n, err := fmt.Sscanf(string(b), "%d.%d", &nanos, &id); err != nil || n != 2 {
+		return nil, errInvalidCursor
+	}
+	return &groupCursor{CreatedAt: time.Unix(0, nanos).UTC(), ID: id}, nil
+}
+
+// validateCursor returns the field errors of the cursor pagination.
+func (f *GroupFilters) validateCursor() []FieldError {
+	if !f.UseCursor {
+		return nil
+	}
+	var errors []FieldError
+	if _, err := decodeGroupCursor(f.Cursor); err != nil {
+		errors = append(errors, FieldError{
+			Name:  "cursor",
+			ID:    "invalid_cursor",
+			Error: "This field is not a valid cursor",
+		})
+	}
+	if f.Sort != "" && f.Sort != cursorSort {
+		errors = append(errors, FieldError{
+			Name:   "sort",
+			ID:     "cursor_sort",
+			Params: []interface{}{cursorSort},
+			Error:  "Only " + cursorSort + " is supported with a cursor",
+		})
+	}
+	return errors
+}
+
+// ListPage gets the page of groups after the cursor of the filters.
+//
+// The cursor of the next page is empty if there are no more groups.
+func (g *Group) ListPage(f GroupFilters) ([]Group, string, error) {
+	groups := []Group{}
+	after, err := decodeGroupCursor(f.Cursor)
+	if err != nil {
+		return groups, "", err
+	}
+	f.Pagination.clamp()
+
+	db := f.apply(g.DB.Model(&Group{}))
+	if after != nil {
+		db = db.Where(
+			"(julianday(created_at) < julianday(?) OR "+
+				"(julianday(created_at) = julianday(?) AND id < ?))",
+			after.CreatedAt, after.CreatedAt, after.ID)
+	}
+	// One more group is loaded to know if there is a next page.
+	db = db.Order(groupSortOrders[cursorSort]).Limit(f.PageSize + 1)
+	r := preloadMembers(db, f.ExpandMembers).Select(listFields).Find(&groups)
+	if r.Error != nil {
+		dbLog(g.DB).Errorf("Could not list group page. Error: %v", r.Error)
+		return groups, "", r.Error
+	}
+	dbLog(g.DB).Info("Listed group page successfully")
+
+	next := ""
+	if len(groups) > f.PageSize {
+		groups = groups[:f.PageSize]
+		last := groups[len(groups)-1]
+		next = groupCursor{CreatedAt: last.CreatedAt, ID: last.ID}.encode()
+	}
+
+	refs := make([]*Group, len(groups))
+	for i := range groups {
+		refs[i] = &groups[i]
+	}
+	return groups, next, loadGroupDetails(g.DB, refs)
+}
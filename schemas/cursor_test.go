@@ -0,0 +1,148 @@
+package schemas
+
+import (
+	"testing"
+	"time"
+)
+
+// createTestGroupsAt creates a group for each of the creation times. The
+// times are stored with their own offset like rows written by servers in
+// other time zones.
+func createTestGroupsAt(t *testing.T, owner User, times []time.Time) []Group {
+	t.Helper()
+	groups := make([]Group, len(times))
+	for i, at := range times {
+		groups[i] = createTestGroup(t, owner, "Timed raid")
+		if r := groups[i].DB.Exec(
+			"UPDATE groups SET created_at = ? WHERE id = ?",
+			at.Format("2006-01-02 15:04:05.999999999-07:00"),
+			groups[i].ID); r.Error != nil {
+			t.Fatalf("could not set the creation time: %v", r.Error)
+		}
+	}
+	return groups
+}
+
+func TestListPageOrdersMixedTimeZones(t *testing.T) {
+	owner := createTestUser(t)
+	base := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	groups := createTestGroupsAt(t, owner, []time.Time{
+		base.In(time.FixedZone("JST", 9*60*60)),
+		base.Add(time.Hour),
+		base.Add(2 * time.Hour).In(time.FixedZone("EST", -5*60*60)),
+	})
+
+	var got []int64
+	f := GroupFilters{
+		OwnerID: owner.ID, UseCursor: true,
+		Pagination: Pagination{PageSize: 1},
+	}
+	for {
+		page, next, err := groups[0].ListPage(f)
+		if err != nil {
+			t.Fatalf("could not list the page: %v", err)
+		}
+		for _, g := range page {
+			got = append(got, g.ID)
+		}
+		if next == "" {
+			break
+		}
+		f.Cursor = next
+	}
+
+	want := []int64{groups[2].ID, groups[1].ID, groups[0].ID}
+	if len(got) != len(want) {
+		t.Fatalf("got groups %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("got groups %v, want %v", got, want)
+		}
+	}
+}
+
+func TestListFiltersCreationTimeAcrossTimeZones(t *testing.T) {
+	owner := createTestUser(t)
+	base := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	groups := createTestGroupsAt(t, owner, []time.Time{
+		base.In(time.FixedZone("JST", 9*60*60)),
+		base.Add(2 * time.Hour).In(time.FixedZone("EST", -5*60*60)),
+	})
+
+	after := base.Add(time.Hour)
+	listed, err := groups[0].List(
+		GroupFilters{OwnerID: owner.ID, CreatedAfter: &after})
+	if err != nil {
+		t.Fatalf("could not list the groups: %v", err)
+	}
+	if len(listed) != 1 || listed[0].ID != groups[1].ID {
+		t.Errorf("got %v groups, want only group %v", len(listed), groups[1].ID)
+	}
+}
+
+func TestListFiltersCreationTimeRange(t *testing.T) {
+	owner := createTestUser(t)
+	base := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	groups := createTestGroupsAt(t, owner, []time.Time{
+		base, base.Add(time.Hour), base.Add(2 * time.Hour)})
+
+	// The start of the range is included and the end is not.
+	after, before := base.Add(time.Hour), base.Add(2*time.Hour)
+	listed, err := groups[0].List(GroupFilters{
+		OwnerID: owner.ID, CreatedAfter: &after, CreatedBefore: &before})
+	if err != nil {
+		t.Fatalf("could not list the groups: %v", err)
+	}
+	if len(listed) != 1 || listed[0].ID != groups[1].ID {
+		t.Errorf("got %v groups, want only group %v", len(listed), groups[1].ID)
+	}
+}
+
+func TestListPageIsStableWhileGroupsAreAdded(t *testing.T) {
+	owner := createTestUser(t)
+	base := time.Date(2024, 7, 1, 12, 0, 0, 0, time.UTC)
+	// Two of the groups share a creation time.
+	groups := createTestGroupsAt(t, owner, []time.Time{
+		base, base.Add(time.Hour), base.Add(time.Hour), base.Add(2 * time.Hour),
+		base.Add(3 * time.Hour),
+	})
+
+	f := GroupFilters{
+		OwnerID: owner.ID, UseCursor: true,
+		Pagination: Pagination{PageSize: 2},
+	}
+	seen := map[int64]bool{}
+	var got []int64
+	for i := 0; ; i++ {
+		page, next, err := groups[0].ListPage(f)
+		if err != nil {
+			t.Fatalf("could not list the page: %v", err)
+		}
+		for _, g := range page {
+			if seen[g.ID] {
+				t.Errorf("got group %v on more than one page", g.ID)
+			}
+			seen[g.ID] = true
+			got = append(got, g.ID)
+		}
+		if next == "" {
+			break
+		}
+		f.Cursor = next
+		// Newer groups added between the pages come before the cursor, so
+		// they do not shift the next pages.
+		createTestGroupsAt(t, owner, []time.Time{base.Add(time.Duration(10+i) * time.Hour)})
+	}
+
+	// The groups with the same creation time are ordered by ID.
+	want := []int64{groups[4].ID, groups[3].ID, groups[2].ID, groups[1].ID, groups[0].ID}
+	if len(got) != len(want) {
+		t.Fatalf("got groups %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("got groups %v, want %v", got, want)
+		}
+	}
+}
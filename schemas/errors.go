@@ -1,13 +1,60 @@
 package schemas
 
+import (
+	"fmt"
+	"strings"
+)
+
 type BodyError struct {
+	// Code is a stable identifier of the error for clients. Message can
+	// change since it is meant for humans.
+	Code        string       `json:"code,omitempty"`
 	Message     string       `json:"message,omitempty"`
 	FieldErrors []FieldError `json:"field_errors,omitempty"`
+	// Params are the values formatted into the message.
+	Params []interface{} `json:"-"`
+}
+
+// Problem is an error response body in the RFC 7807 problem details format.
+//
+// The code and the field errors of the BodyError are kept as extension
+// members.
+type Problem struct {
+	Type        string       `json:"type"`
+	Title       string       `json:"title"`
+	Status      int          `json:"status"`
+	Detail      string       `json:"detail,omitempty"`
+	Instance    string       `json:"instance,omitempty"`
+	Code        string       `json:"code,omitempty"`
+	FieldErrors []FieldError `json:"field_errors,omitempty"`
 }
 
+// FieldError is an error in the value of a field.
+//
+// ID is a stable identifier of the error. Error is the message in English,
+// or in the language of the client once the response is localized. Params
+// are the values formatted into the message.
 type FieldError struct {
-	Name  string
-	Error string
+	Name   string
+	ID     string
+	Error  string
+	Params []interface{} `json:"-"`
+}
+
+// oneOfError returns the field error of a value that is not one of the
+// allowed values.
+func oneOfError[T any](name string, allowed []T) FieldError {
+	values := make([]string, len(allowed))
+	for i, v := range allowed {
+		values[i] = fmt.Sprint(v)
+	}
+	list := strings.Join(values, ", ")
+	return FieldError{
+		Name:   name,
+		ID:     "one_of",
+		Params: []interface{}{list},
+		Error:  fmt.Sprintf("The value should be one of: %v", list),
+	}
 }
 
 type ValidationError struct {
@@ -0,0 +1,60 @@
+package schemas
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Types of group events.
+const (
+	EventCreated  = "created"
+	EventUpdated  = "updated"
+	EventJoined   = "joined"
+	EventLeft     = "left"
+	EventKicked   = "kicked"
+	EventClosed   = "closed"
+	EventReopened = "reopened"
+)
+
+// GroupEvent is an entry in the audit log of a group.
+//
+// Unlike the activity feed, which members can read, the audit log is only
+// shown to the owner.
+type GroupEvent struct {
+	ID      int64  `json:"id" gorm:"primaryKey"`
+	GroupID int64  `json:"group_id" gorm:"not null;index"`
+	Type    string `json:"type" gorm:"not null"`
+	// ActorID is the user who caused the event. It is zero for the events of
+	// the server, like closing stale groups.
+	ActorID   int64     `json:"actor_id" gorm:"not null"`
+	CreatedAt time.Time `json:"created_at" gorm:"autoCreateTime"`
+
+	DB *gorm.DB `json:"-" gorm:"-"`
+}
+
+// Create adds the event to the audit log of the group.
+func (e *GroupEvent) Create() error {
+	r := e.DB.Create(&e)
+	if r.Error != nil {
+		dbLog(e.DB).Errorf("Could not create group event. Error: %v", r.Error)
+		return r.Error
+	}
+	dbLog(e.DB).Info("Created group event successfully")
+	return nil
+}
+
+// ListEvents retrieves a page of the audit log of the group.
+//
+// The newest events come first.
+func (g *Group) ListEvents(p Pagination) ([]GroupEvent, error) {
+	events := []GroupEvent{}
+	r := p.apply(g.DB.Where("group_id = ?", g.ID)).Order(
+		"created_at DESC, id DESC").Find(&events)
+	if r.Error != nil {
+		dbLog(g.DB).Errorf("Could not list group events. Error: %v", r.Error)
+		return events, r.Error
+	}
+	dbLog(g.DB).Info("Listed the group events successfully")
+	return events, nil
+}
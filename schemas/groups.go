@@ -1,9 +1,14 @@
 package schemas
 
 import (
+	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"math/rand"
+	"strings"
 	"time"
+	"unicode/utf8"
 
 	"github.com/damascopaul/lfg-backend/data"
 
@@ -12,29 +17,406 @@ import (
 	"gorm.io/gorm"
 )
 
+// GroupStatus is the state of a group.
+type GroupStatus int16
+
+const (
+	GroupStatusOpen   GroupStatus = 0
+	GroupStatusClosed GroupStatus = -100
+)
+
+// GroupStatuses are the valid statuses of a group.
+var GroupStatuses = []GroupStatus{GroupStatusOpen, GroupStatusClosed}
+
+// IsValid checks if the status is one of the known group statuses.
+func (s GroupStatus) IsValid() bool {
+	return slices.Contains(GroupStatuses, s)
+}
+
+// GroupVisibility is who can find a group.
+type GroupVisibility string
+
+const (
+	// GroupVisibilityPublic groups are listed to everyone.
+	GroupVisibilityPublic GroupVisibility = "public"
+	// GroupVisibilityUnlisted groups are not listed but anyone with the link
+	// can join.
+	GroupVisibilityUnlisted GroupVisibility = "unlisted"
+	// GroupVisibilityPrivate groups need a password to join.
+	GroupVisibilityPrivate GroupVisibility = "private"
+)
+
+// GroupVisibilities are the valid visibilities of a group.
+var GroupVisibilities = []GroupVisibility{
+	GroupVisibilityPublic, GroupVisibilityUnlisted, GroupVisibilityPrivate}
+
+// IsValid checks if the visibility is one of the known group visibilities.
+func (v GroupVisibility) IsValid() bool {
+	return slices.Contains(GroupVisibilities, v)
+}
+
+// validateVisibility returns the field errors of a visibility value.
+func validateVisibility(v GroupVisibility) []FieldError {
+	if !v.IsValid() {
+		return []FieldError{oneOfError("visibility", GroupVisibilities)}
+	}
+	return nil
+}
+
+var (
+	// ErrGroupNotOpen is returned when joining a group that is not open.
+	ErrGroupNotOpen = errors.New("group is not open")
+	// ErrGroupFull is returned when joining a group that is full.
+	ErrGroupFull = errors.New("group is full")
+	// ErrAlreadyMember is returned when the user is already a member.
+	ErrAlreadyMember = errors.New("user is already a member")
+	// ErrNotMember is returned when the user is not a member.
+	ErrNotMember = errors.New("user is not a member")
+	// ErrGroupPasswordRequired is returned when joining a private group
+	// without a password.
+	ErrGroupPasswordRequired = errors.New("group password is required")
+	// ErrIncorrectGroupPassword is returned when the group password is wrong.
+	ErrIncorrectGroupPassword = errors.New("incorrect group password")
+	// ErrGroupConflict is returned when updating a group that was changed
+	// since it was retrieved.
+	ErrGroupConflict = errors.New("group was changed by another request")
+)
+
 type Group struct {
-	ID          int64     `json:"id,omitempty" gorm:"primaryKey"`
-	Title       string    `json:"title,omitempty" gorm:"not null"`
-	Description string    `json:"description,omitempty"`
-	Status      int16     `json:"status" gorm:"default:0"`
-	Password    string    `json:"password,omitempty"`
-	MaxSize     int16     `json:"max_size,omitempty" gorm:"default:5"`
-	CreatedAt   time.Time `json:"created_at,omitempty" gorm:"autoCreateTime"`
-	OwnerID     int64     `json:"owner_id" gorm:"not null"`
-	Members     []User    `json:"members" gorm:"many2many:joined_groups"`
+	ID              int64       `json:"id,omitempty" gorm:"primaryKey"`
+	Title           string      `json:"title,omitempty" gorm:"not null"`
+	Description     string      `json:"description,omitempty"`
+	Status          GroupStatus `json:"status" gorm:"default:0"`
+	Password        string      `json:"password,omitempty"`
+	MaxSize         int16       `json:"max_size,omitempty" gorm:"default:5"`
+	CreatedAt       time.Time   `json:"created_at,omitempty" gorm:"autoCreateTime"`
+	OwnerID         int64       `json:"owner_id" gorm:"not null"`
+	Views           int64       `json:"views" gorm:"not null;default:0"`
+	RequireApproval *bool       `json:"require_approval" gorm:"not null;default:false"`
+	Waitlist        *bool       `json:"waitlist" gorm:"not null;default:false"`
+	Category        string      `json:"category,omitempty" gorm:"not null;default:'';index"`
+	Game            string      `json:"game,omitempty" gorm:"not null;default:'';index"`
+	StartsAt        *time.Time  `json:"starts_at,omitempty" gorm:"index"`
+	Timezone        string      `json:"timezone,omitempty" gorm:"not null;default:''"` // IANA name
+	Members         []User      `json:"members" gorm:"many2many:joined_groups"`
+	Tags            []Tag       `json:"tags,omitempty" gorm:"many2many:group_tags"`
+	// Visibility is who can find the group. Private groups have a password.
+	Visibility GroupVisibility `json:"visibility" gorm:"not null;default:'public';index"`
+	// Slug is the URL-safe name of the group made from its title when the
+	// group is created. It does not change with the title so links keep
+	// working.
+	Slug string `json:"slug" gorm:"not null;default:'';uniqueIndex:idx_groups_slug,where:slug <> ''"`
+	// Version is incremented on every update of the group so an update
+	// based on an older version can be rejected.
+	Version int64 `json:"version" gorm:"not null;default:1"`
+	// DeletedAt is set when the group is deleted. Deleted groups are left
+	// out of the queries unless they are asked for.
+	DeletedAt gorm.DeletedAt `json:"deleted_at,omitempty" gorm:"index"`
+
+	// Private is true if a password is required to join the group.
+	Private bool `json:"private" gorm:"->;-:migration"`
+	// JoinBlockedReason is why the authenticated user cannot join the group.
+	//
+	// It is empty if the user can join the group.
+	JoinBlockedReason string `json:"join_blocked_reason" gorm:"-"`
+	// MemberCount is the number of members counted in the database so it
+	// does not depend on how the members are preloaded. It is returned in
+	// place of the member list unless the members are expanded.
+	//
+	// The owner takes a slot of the group without being a member, so the
+	// group is full once MemberCount and ReservedSlots reach MaxSize-1.
+	MemberCount int16 `json:"member_count" gorm:"-"`
+	// ReservedSlots is the number of slots held by active reservations.
+	ReservedSlots int16 `json:"reserved_slots" gorm:"-"`
+	// Warnings are non-blocking issues found while handling the request.
+	Warnings []string `json:"warnings,omitempty" gorm:"-"`
 
 	DB *gorm.DB `json:"-" gorm:"-"`
 }
 
+// OptionalTime is a time on a request body that can be left out or cleared.
+//
+// Set is false if the field is left out of the request. Time is nil if the
+// field is null.
+type OptionalTime struct {
+	Set  bool
+	Time *time.Time
+}
+
+// UnmarshalJSON reads the time, or clears it if the value is null.
+func (t *OptionalTime) UnmarshalJSON(b []byte) error {
+	t.Set = true
+	return json.Unmarshal(b, &t.Time)
+}
+
+// GroupChanges is the request body for updating the details of a group.
+//
+// Fields left out of the request are nil and are not changed. An explicit
+// empty value clears the field, or null for the start time.
+type GroupChanges struct {
+	Title           *string      `json:"title"`
+	Description     *string      `json:"description"`
+	MaxSize         *int16       `json:"max_size"`
+	RequireApproval *bool        `json:"require_approval"`
+	Waitlist        *bool        `json:"waitlist"`
+	Category        *string      `json:"category"`
+	Game            *string      `json:"game"`
+	Tags            *[]Tag       `json:"tags"`
+	StartsAt        OptionalTime `json:"starts_at"`
+	Timezone        *string      `json:"timezone"`
+	// Visibility can only be changed to public or unlisted. Groups are
+	// made private by setting a password.
+	Visibility *GroupVisibility `json:"visibility"`
+	// Version is the version of the group the changes are based on. The
+	// changes are rejected if the group has been updated since.
+	Version *int64 `json:"version"`
+}
+
+// GroupFilters are the query parameters used to filter the group listing.
+//
+// There is no region filter since groups are not tied to a region.
+type GroupFilters struct {
+	Pagination
+	Query    string `form:"q"`
+	Sort     string `form:"sort"`
+	Game     string `form:"game"`     // Only lists the groups of the game if set.
+	Category string `form:"category"` // Only lists the groups of the category if set.
+	// Joinable only lists the open groups with a free slot if true, and the
+	// other groups if false. Slots held by active reservations are taken.
+	Joinable *bool `form:"joinable"`
+	// Status only lists the groups with the status if set.
+	Status *GroupStatus `form:"status"`
+	// Tags only lists the groups with all the tags if set. The tags are
+	// separated by commas.
+	Tags string `form:"tags"`
+	// Visibility lists the public or the private groups. The public groups
+	// are listed if it is not set. Unlisted groups are never listed.
+	Visibility GroupVisibility `form:"visibility"`
+
+	// CreatedAfter only lists the groups created at or after the time.
+	CreatedAfter *time.Time `form:"created_after" time_format:"2006-01-02T15:04:05Z07:00"`
+	// CreatedBefore only lists the groups created before the time.
+	CreatedBefore *time.Time `form:"created_before" time_format:"2006-01-02T15:04:05Z07:00"`
+	// StartsAfter only lists the groups starting at or after the time.
+	StartsAfter *time.Time `form:"starts_after" time_format:"2006-01-02T15:04:05Z07:00"`
+	// StartsBefore only lists the groups starting before the time.
+	StartsBefore *time.Time `form:"starts_before" time_format:"2006-01-02T15:04:05Z07:00"`
+
+	OwnerID  int64 `form:"-"` // Only lists the groups of the owner if set.
+	MemberID int64 `form:"-"` // Only lists the groups of the member if set.
+
+	// IncludeDeleted lists the deleted groups too if set.
+	IncludeDeleted bool `form:"-"`
+	// AnyVisibility lists the groups regardless of their visibility if set.
+	AnyVisibility bool `form:"-"`
+
+	// Cursor is where the page starts when paging with a cursor. It is
+	// empty on the first page.
+	Cursor string `form:"cursor"`
+	// UseCursor pages with the cursor instead of the page number if set.
+	UseCursor bool `form:"-"`
+	// ExpandMembers loads the member list of the groups if set. Only the
+	// member count is loaded otherwise.
+	ExpandMembers bool `form:"-"`
+}
+
+// groupSortOrders maps the supported sort keys to their ORDER BY clause.
+var groupSortOrders = map[string]string{
+	"created_at":  "julianday(created_at), id",
+	"-created_at": "julianday(created_at) DESC, id DESC",
+	"title":       "title, id",
+	"-title":      "title DESC, id",
+	"max_size":    "max_size, id",
+	"-max_size":   "max_size DESC, id",
+	"popular":     "views DESC, id DESC",
+}
+
+// defaultGroupSort lists the newest groups first.
+const defaultGroupSort = "-created_at"
+
+// Validate checks if the filters are valid.
+func (f *GroupFilters) Validate() error {
+	var errors []FieldError
+	if _, ok := groupSortOrders[f.Sort]; f.Sort != "" && !ok {
+		// Add a field error if the sort key is not supported
+		errors = append(
+			errors,
+			FieldError{
+				Name:  "sort",
+				ID:    "unsupported_value",
+				Error: "This field has an unsupported value",
+			})
+	}
+	if f.Visibility != "" && f.Visibility != GroupVisibilityPublic &&
+		f.Visibility != GroupVisibilityPrivate {
+		// Add a field error if the groups with the visibility are not listed
+		errors = append(
+			errors,
+			FieldError{
+				Name:  "visibility",
+				ID:    "unsupported_value",
+				Error: "This field has an unsupported value",
+			})
+	}
+	if f.Status != nil && !f.Status.IsValid() {
+		// Add a field error if the status is not a known group status
+		errors = append(
+			errors,
+			FieldError{
+				Name:  "status",
+				ID:    "unsupported_value",
+				Error: "This field has an unsupported value",
+			})
+	}
+	errors = append(errors, validateTimeRange(
+		f.CreatedAfter, f.CreatedBefore, "created_after", "created_before")...)
+	errors = append(errors, validateTimeRange(
+		f.StartsAfter, f.StartsBefore, "starts_after", "starts_before")...)
+	errors = append(errors, f.validateCursor()...)
+
+	if len(errors) > 0 {
+		log.WithFields(log.Fields{"model": "GroupFilters"}).Warn("Query is invalid")
+		return &ValidationError{
+			Message: "The query parameters contain errors",
+			Errors:  errors,
+		}
+	}
+	return nil
+}
+
+// validateTimeRange returns the field errors of a time range filter.
+func validateTimeRange(after, before *time.Time, afterName, beforeName string) []FieldError {
+	if after != nil && before != nil && !before.After(*after) {
+		// Add a field error if the range is empty
+		return []FieldError{{
+			Name:   beforeName,
+			ID:     "later_than",
+			Params: []interface{}{afterName},
+			Error:  fmt.Sprintf("This field should be later than %v", afterName),
+		}}
+	}
+	return nil
+}
+
+// order returns the ORDER BY clause of the sort key.
+//
+// A full-text search without a sort key is ordered by relevance.
+func (f *GroupFilters) order() string {
+	if f.Sort == "" && strings.TrimSpace(f.Query) != "" && groupSearchEnabled() {
+		return "fts.rank, groups.id DESC"
+	}
+	if o, ok := groupSortOrders[f.Sort]; ok {
+		return o
+	}
+	return groupSortOrders[defaultGroupSort]
+}
+
+// listsAnyVisibility checks if the groups are listed regardless of their
+// visibility.
+//
+// The groups of the owner and the member are all listed since they can
+// already see them.
+func (f *GroupFilters) listsAnyVisibility() bool {
+	return f.AnyVisibility || f.OwnerID != 0 || f.MemberID != 0
+}
+
+// likeEscaper escapes the LIKE wildcards of a search term.
+var likeEscaper = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+
+// apply adds the filters to the query.
+func (f *GroupFilters) apply(db *gorm.DB) *gorm.DB {
+	if f.IncludeDeleted {
+		db = db.Unscoped()
+	}
+	if f.OwnerID != 0 {
+		db = db.Where("owner_id = ?", f.OwnerID)
+	}
+	if f.MemberID != 0 {
+		db = db.Where(
+			"id IN (SELECT group_id FROM joined_groups WHERE user_id = ?)",
+			f.MemberID)
+	}
+	if !f.listsAnyVisibility() {
+		visibility := f.Visibility
+		if visibility == "" {
+			visibility = GroupVisibilityPublic
+		}
+		db = db.Where("visibility = ?", visibility)
+	}
+	if f.Status != nil {
+		db = db.Where("status = ?", *f.Status)
+	}
+	if game := strings.TrimSpace(f.Game); game != "" {
+		db = db.Where("LOWER(game) = ?", strings.ToLower(game))
+	}
+	if category := normalizeCategory(f.Category); category != "" {
+		db = db.Where("category = ?", category)
+	}
+	if f.Joinable != nil {
+		joinable := "status = ? AND max_size - 1 > " +
+			"(SELECT COUNT(*) FROM joined_groups WHERE group_id = groups.id) + " +
+			"(SELECT COUNT(*) FROM reservations WHERE group_id = groups.id " +
+			"AND julianday(expires_at) > julianday(?))"
+		if *f.Joinable {
+			db = db.Where(joinable, GroupStatusOpen, time.Now())
+		} else {
+			db = db.Where("NOT ("+joinable+")", GroupStatusOpen, time.Now())
+		}
+	}
+	for _, name := range strings.Split(f.Tags, ",") {
+		if name = normalizeTag(name); name != "" {
+			tagged := tagFilter(db.Session(&gorm.Session{NewDB: true}), name)
+			db = db.Where("id IN (?)", tagged)
+		}
+	}
+	if f.CreatedAfter != nil {
+		db = db.Where(
+			"julianday(created_at) >= julianday(?)", f.CreatedAfter.UTC())
+	}
+	if f.CreatedBefore != nil {
+		db = db.Where(
+			"julianday(created_at) < julianday(?)", f.CreatedBefore.UTC())
+	}
+	if f.StartsAfter != nil {
+		db = db.Where(
+			"julianday(starts_at) >= julianday(?)", f.StartsAfter.UTC())
+	}
+	if f.StartsBefore != nil {
+		db = db.Where(
+			"julianday(starts_at) < julianday(?)", f.StartsBefore.UTC())
+	}
+	if q := strings.TrimSpace(f.Query); q != "" && groupSearchEnabled() {
+		// The rank of the match is used to order the results.
+		db = db.Joins(
+			"JOIN (SELECT rowid, rank FROM groups_fts WHERE groups_fts MATCH ?) "+
+				"AS fts ON fts.rowid = groups.id", ftsQuery(q))
+	} else if q != "" {
+		pattern := "%" + likeEscaper.Replace(strings.ToLower(q)) + "%"
+		db = db.Where(
+			`(LOWER(title) LIKE ? ESCAPE '\' OR LOWER(description) LIKE ? ESCAPE '\')`,
+			pattern, pattern)
+	}
+	return db
+}
+
 func (g *Group) memberIndex(uid int64) int {
 	return slices.IndexFunc(g.Members, func(m User) bool {
 		return m.ID == uid
 	})
 }
 
+// IsModerator checks if the user is a moderator of the group.
+func (g *Group) IsModerator(uid int64) bool {
+	i := g.memberIndex(uid)
+	return i != -1 && g.Members[i].Role == RoleModerator
+}
+
 // IsFull checks if the group is full.
+//
+// Slots held by active reservations count as taken. The members are counted
+// in the database instead of using the preloaded members.
 func (g *Group) IsFull() bool {
-	return g.MaxSize-1 == int16(len(g.Members))
+	return g.MaxSize-1 <= g.MemberCount+g.ReservedSlots
 }
 
 // IsMember checks if the user is a member of the group.
@@ -46,7 +428,7 @@ func (g *Group) IsMember(uid int64) bool {
 
 // IsOpen checks if the group is open.
 func (g *Group) IsOpen() bool {
-	return g.Status == 0
+	return g.Status == GroupStatusOpen
 }
 
 // IsOwner checks if the user is the owner of the group.
@@ -54,81 +436,400 @@ func (g *Group) IsOwner(uid int64) bool {
 	return g.OwnerID == uid
 }
 
+// RequiresApproval checks if joining the group needs the approval of the owner.
+func (g *Group) RequiresApproval() bool {
+	return g.RequireApproval != nil && *g.RequireApproval
+}
+
+// HasWaitlist checks if users can wait for a slot when the group is full.
+func (g *Group) HasWaitlist() bool {
+	return g.Waitlist != nil && *g.Waitlist
+}
+
+// SetPassword sets the password of the group.
+//
+// Setting a password makes the group private. Removing it makes a private
+// group public.
+func (g *Group) SetPassword(pw string) {
+	g.Password = pw
+	if pw != "" {
+		g.Visibility = GroupVisibilityPrivate
+	} else if g.Visibility == GroupVisibilityPrivate {
+		g.Visibility = GroupVisibilityPublic
+	}
+}
+
+// validateGroupPassword returns the field errors of the password of a new
+// group. Only private groups have a password and they need one.
+func validateGroupPassword(g *Group) []FieldError {
+	if g.Visibility == GroupVisibilityPrivate && !g.IsPrivate() {
+		return []FieldError{{
+			Name:  "password",
+			ID:    "required",
+			Error: "This field is required",
+		}}
+	} else if g.Visibility != GroupVisibilityPrivate && g.IsPrivate() {
+		return []FieldError{{
+			Name:  "password",
+			ID:    "private_only",
+			Error: "This field is only allowed on private groups",
+		}}
+	}
+	return nil
+}
+
 // IsPrivate checks if the group is private.
 func (g *Group) IsPrivate() bool {
 	return g.Password != ""
 }
 
+// IsVisibleTo checks if the user can see the details of the group without
+// its password.
+//
+// Private groups are only visible to the owner and the members.
+func (g *Group) IsVisibleTo(uid int64) bool {
+	private := g.Private || g.IsPrivate()
+	return !private || g.IsOwner(uid) || g.IsMember(uid)
+}
+
+// Summary returns the details of the group shown to users who cannot see
+// the rest of the details.
+func (g *Group) Summary() GroupSummary {
+	return GroupSummary{
+		ID:      g.ID,
+		Title:   g.Title,
+		OwnerID: g.OwnerID,
+		Private: g.Private || g.IsPrivate(),
+	}
+}
+
+// Values of JoinBlockedReason.
+const (
+	JoinBlockedIsOwner          = "IS_OWNER"
+	JoinBlockedAlreadyMember    = "ALREADY_MEMBER"
+	JoinBlockedClosed           = "CLOSED"
+	JoinBlockedFull             = "FULL"
+	JoinBlockedPasswordRequired = "PASSWORD_REQUIRED"
+	JoinBlockedBanned           = "BANNED"
+	JoinBlockedJoinedGroupLimit = "JOINED_GROUP_LIMIT"
+)
+
 // ValidatePassword validates the group password
 func (g *Group) ValidatePassword(pw string) error {
 	if g.Password != pw {
 		// Return an error if the password does not match.
-		log.Error("Password for group is invalid")
+		dbLog(g.DB).Error("Password for group is invalid")
 		return errors.New("incorrect group password")
 	}
 	return nil
 }
 
-// ValidateForCreate checks if the group is a valid new entry.
-func (g *Group) ValidateForCreate() error {
-	const FieldIsReqMsg string = "This field is required"
-	var errors []FieldError
+// normalizeCategory returns the form of the category stored in the database.
+//
+// Categories are case-insensitive so they are stored in lowercase.
+func normalizeCategory(category string) string {
+	return strings.ToLower(strings.TrimSpace(category))
+}
 
+// validateCategory returns the field errors of a category value.
+//
+// A category is optional.
+func validateCategory(category string) []FieldError {
+	const maxCategoryLen int = 30
+	if utf8.RuneCountInString(category) > maxCategoryLen {
+		return []FieldError{{
+			Name:   "category",
+			ID:     "too_long",
+			Params: []interface{}{maxCategoryLen},
+			Error: fmt.Sprintf(
+				"This field cannot be more than %v characters long", maxCategoryLen),
+		}}
+	}
+	return nil
+}
+
+// validateGame returns the field errors of a game value.
+//
+// A game is optional. Whether the game is allowed is checked separately
+// since the allowed games are configured on the server.
+func validateGame(game string) []FieldError {
+	const maxGameLen int = 50
+	if utf8.RuneCountInString(game) > maxGameLen {
+		return []FieldError{{
+			Name:   "game",
+			ID:     "too_long",
+			Params: []interface{}{maxGameLen},
+			Error: fmt.Sprintf(
+				"This field cannot be more than %v characters long", maxGameLen),
+		}}
+	}
+	return nil
+}
+
+// validateStartsAt returns the field errors of a start time value.
+//
+// A start time is optional but it cannot be in the past.
+func validateStartsAt(startsAt *time.Time) []FieldError {
+	if startsAt != nil && startsAt.Before(time.Now()) {
+		return []FieldError{{
+			Name:  "starts_at",
+			ID:    "in_the_past",
+			Error: "This field cannot be in the past",
+		}}
+	}
+	return nil
+}
+
+// validateTimezone returns the field errors of a time zone value.
+//
+// A time zone is optional. It should be a name from the IANA time zone
+// database.
+func validateTimezone(tz string) []FieldError {
+	if tz == "" {
+		return nil
+	}
+	if _, err := time.LoadLocation(tz); err != nil || tz == "Local" {
+		return []FieldError{{
+			Name:  "timezone",
+			ID:    "invalid_timezone",
+			Error: "This field should be an IANA time zone like Europe/Paris",
+		}}
+	}
+	return nil
+}
+
+// validateTitle returns the field errors of a title value.
+func validateTitle(title string) []FieldError {
 	const maxTitleLen int = 50
-	if g.Title == "" {
+	if title == "" {
 		// Add a field error if the `title` field is empty
-		errors = append(
-			errors,
-			FieldError{
-				Name:  "title",
-				Error: FieldIsReqMsg,
-			})
-	} else if len(g.Title) > maxTitleLen {
+		return []FieldError{{
+			Name: "title", ID: "required", Error: "This field is required"}}
+	} else if utf8.RuneCountInString(title) > maxTitleLen {
 		// Add a field error if the `title` length is greater than 50
-		errors = append(
-			errors,
-			FieldError{
-				Name: "title",
-				Error: fmt.Sprintf(
-					"This field cannot be more than %v characters long", maxTitleLen),
-			})
+		return []FieldError{{
+			Name:   "title",
+			ID:     "too_long",
+			Params: []interface{}{maxTitleLen},
+			Error: fmt.Sprintf(
+				"This field cannot be more than %v characters long", maxTitleLen),
+		}}
 	}
+	return nil
+}
 
+// validateDescription returns the field errors of a description value.
+//
+// An empty description is valid here since it is only required on create.
+func validateDescription(desc string) []FieldError {
 	const maxDescLen int = 200
-	if g.Description == "" {
-		// Add a field error if the `description` field is empty
-		errors = append(
-			errors,
-			FieldError{
-				Name:  "description",
-				Error: FieldIsReqMsg,
-			})
-	} else if len(g.Description) > maxDescLen {
+	if utf8.RuneCountInString(desc) > maxDescLen {
 		// Add a field error if the `description` length is greater than 200
-		errors = append(
-			errors,
-			FieldError{
-				Name: "description",
-				Error: fmt.Sprintf(
-					"This field cannot be more than %v characters long", maxDescLen),
-			})
+		return []FieldError{{
+			Name:   "description",
+			ID:     "too_long",
+			Params: []interface{}{maxDescLen},
+			Error: fmt.Sprintf(
+				"This field cannot be more than %v characters long", maxDescLen),
+		}}
 	}
+	return nil
+}
 
+// validateMaxSize returns the field errors of a max size value.
+func validateMaxSize(size int16) []FieldError {
 	const (
 		minSize int16 = 5
 		maxSize int16 = 200
 	)
-	if g.MaxSize < minSize || g.MaxSize > maxSize {
+	if size < minSize || size > maxSize {
+		return []FieldError{{
+			Name:   "max_size",
+			ID:     "value_range",
+			Params: []interface{}{minSize, maxSize},
+			Error: fmt.Sprintf(
+				"The value should range from %v to %v", minSize, maxSize),
+		}}
+	}
+	return nil
+}
+
+// Validate checks if the changes to the group are valid.
+//
+// Only the fields set on the request are checked. The title is trimmed like
+// on create. The max size cannot be
+// less than the number of people already in the group, owner included.
+func (ch *GroupChanges) Validate(g *Group) error {
+	var errors []FieldError
+	if ch.Title != nil {
+		*ch.Title = strings.TrimSpace(*ch.Title)
+		errors = append(errors, validateTitle(*ch.Title)...)
+	}
+	if ch.Description != nil {
+		errors = append(errors, validateDescription(*ch.Description)...)
+	}
+	if ch.Category != nil {
+		errors = append(errors, validateCategory(normalizeCategory(*ch.Category))...)
+	}
+	if ch.Game != nil {
+		*ch.Game = strings.TrimSpace(*ch.Game)
+		errors = append(errors, validateGame(*ch.Game)...)
+	}
+	if ch.Tags != nil {
+		*ch.Tags = normalizeTags(*ch.Tags)
+		errors = append(errors, validateTags(*ch.Tags)...)
+	}
+	errors = append(errors, validateStartsAt(ch.StartsAt.Time)...)
+	if ch.Timezone != nil {
+		*ch.Timezone = strings.TrimSpace(*ch.Timezone)
+		errors = append(errors, validateTimezone(*ch.Timezone)...)
+	}
+	if ch.Visibility != nil {
+		if visErrors := validateVisibility(*ch.Visibility); len(visErrors) > 0 {
+			errors = append(errors, visErrors...)
+		} else if *ch.Visibility == GroupVisibilityPrivate &&
+			g.Visibility != GroupVisibilityPrivate {
+			// Add a field error since a private group needs a password
+			errors = append(
+				errors,
+				FieldError{
+					Name:  "visibility",
+					ID:    "private_needs_password",
+					Error: "Set the group password to make the group private",
+				})
+		}
+	}
+	if ch.MaxSize != nil {
+		if sizeErrors := validateMaxSize(*ch.MaxSize); len(sizeErrors) > 0 {
+			errors = append(errors, sizeErrors...)
+		} else if *ch.MaxSize < g.MemberCount+1 {
+			// Add a field error if the group would be over capacity
+			errors = append(
+				errors,
+				FieldError{
+					Name:   "max_size",
+					ID:     "below_member_count",
+					Params: []interface{}{g.MemberCount + 1},
+					Error: fmt.Sprintf(
+						"The value cannot be less than the %v people in the group",
+						g.MemberCount+1),
+				})
+		}
+	}
+
+	if len(errors) > 0 {
+		log.WithFields(
+			log.Fields{"model": "GroupChanges"}).Warn("Request body is invalid")
+		return &ValidationError{
+			Message: "The group changes are not valid",
+			Errors:  errors,
+		}
+	}
+	return nil
+}
+
+// Apply sets the fields of the group that are set on the request.
+func (ch *GroupChanges) Apply(g *Group) {
+	if ch.Title != nil {
+		g.Title = *ch.Title
+	}
+	if ch.Description != nil {
+		g.Description = *ch.Description
+	}
+	if ch.MaxSize != nil {
+		g.MaxSize = *ch.MaxSize
+	}
+	if ch.RequireApproval != nil {
+		g.RequireApproval = ch.RequireApproval
+	}
+	if ch.Waitlist != nil {
+		g.Waitlist = ch.Waitlist
+	}
+	if ch.Category != nil {
+		g.Category = *ch.Category
+	}
+	if ch.Game != nil {
+		g.Game = *ch.Game
+	}
+	if ch.Tags != nil {
+		g.Tags = *ch.Tags
+	}
+	if ch.StartsAt.Set {
+		g.StartsAt = nil
+		if ch.StartsAt.Time != nil {
+			startsAt := ch.StartsAt.Time.UTC()
+			g.StartsAt = &startsAt
+		}
+	}
+	if ch.Timezone != nil {
+		g.Timezone = *ch.Timezone
+	}
+	if ch.Visibility != nil && *ch.Visibility != g.Visibility {
+		// Only private groups have a password. Validate rejects making a
+		// group private here.
+		g.Visibility = *ch.Visibility
+		g.Password = ""
+	}
+	if ch.Version != nil {
+		g.Version = *ch.Version
+	}
+}
+
+// ValidateForCreate checks if the group is a valid new entry.
+//
+// The surrounding whitespace of the title and the game is trimmed first so
+// a whitespace-only title is treated as empty.
+func (g *Group) ValidateForCreate() error {
+	g.Title = strings.TrimSpace(g.Title)
+	const FieldIsReqMsg string = "This field is required"
+	var errors []FieldError
+
+	errors = append(errors, validateTitle(g.Title)...)
+	if g.Description == "" {
+		// Add a field error if the `description` field is empty
 		errors = append(
 			errors,
 			FieldError{
-				Name: "max_size",
-				Error: fmt.Sprintf(
-					"The value should range from %v to %v", minSize, maxSize),
+				Name:  "description",
+				ID:    "required",
+				Error: FieldIsReqMsg,
 			})
 	}
+	errors = append(errors, validateDescription(g.Description)...)
+
+	errors = append(errors, validateCategory(normalizeCategory(g.Category))...)
+
+	g.Game = strings.TrimSpace(g.Game)
+	errors = append(errors, validateGame(g.Game)...)
+
+	g.Tags = normalizeTags(g.Tags)
+	errors = append(errors, validateTags(g.Tags)...)
 
-	log.Info("Validated new group request")
+	errors = append(errors, validateStartsAt(g.StartsAt)...)
+	g.Timezone = strings.TrimSpace(g.Timezone)
+	errors = append(errors, validateTimezone(g.Timezone)...)
+
+	errors = append(errors, validateMaxSize(g.MaxSize)...)
+
+	if g.Visibility == "" {
+		// Groups with a password were private before the visibility was
+		// added, so the visibility defaults to it.
+		g.Visibility = GroupVisibilityPublic
+		if g.IsPrivate() {
+			g.Visibility = GroupVisibilityPrivate
+		}
+	}
+	errors = append(errors, validateVisibility(g.Visibility)...)
+	errors = append(errors, validateGroupPassword(g)...)
+
+	if g.Status != GroupStatusOpen {
+		// Add a field error if the `status` is not open since the groups are
+		// only closed after they are created
+		errors = append(
+			errors, oneOfError("status", []GroupStatus{GroupStatusOpen}))
+	}
+
+	dbLog(g.DB).Info("Validated new group request")
 	if len(errors) > 0 {
 		return &ValidationError{
 			Message: "The new group is not valid",
@@ -138,75 +839,340 @@ func (g *Group) ValidateForCreate() error {
 	return nil
 }
 
+// privateColumn selects whether the group has a password without the password.
+const privateColumn = "COALESCE(password, '') <> '' AS private"
+
 func preloadUser(db *gorm.DB) *gorm.DB {
-	return db.Select("id", "username", "created_at")
+	return db.Select("id", "username", "display_name", "created_at")
+}
+
+// preloadMembers preloads the member list of the groups if it is expanded.
+func preloadMembers(db *gorm.DB, expand bool) *gorm.DB {
+	if !expand {
+		return db
+	}
+	return db.Preload("Members", preloadUser)
 }
 
 func retrieveGroup(g *Group, fields []string) error {
 	r := g.DB.Model(&g).Preload(
 		"Members", preloadUser).Select(fields).First(&g, g.ID)
 	if r.Error != nil {
-		log.Fatalf("Could not retrieve group. Error: %v", r.Error.Error())
-	} else {
-		log.Info("Retrieved group successfully")
+		dbLog(g.DB).Errorf("Could not retrieve group. Error: %v", r.Error.Error())
+		return r.Error
 	}
-	return r.Error
+	dbLog(g.DB).Info("Retrieved group successfully")
+	return loadGroupDetails(g.DB, []*Group{g})
+}
+
+// loadGroupDetails loads the details of the groups that are not columns.
+func loadGroupDetails(db *gorm.DB, groups []*Group) error {
+	if err := loadMemberInfo(db, groups); err != nil {
+		return err
+	}
+	if err := loadMemberCounts(db, groups); err != nil {
+		return err
+	}
+	if err := loadTags(db, groups); err != nil {
+		return err
+	}
+	return loadReservedSlots(db, groups)
 }
 
 // InitDB initializes the database object
-func (g *Group) InitDB() error {
+//
+// The queries of the group are canceled once the context is done.
+func (g *Group) InitDB(ctx context.Context) error {
 	db, err := data.CreateConnection()
 	if err != nil {
 		return err
 	}
-	g.DB = db
-	g.Migrate()
-	log.WithFields(log.Fields{"model": "Group"}).Info("Initialized database")
+	g.DB = db.WithContext(ctx)
+	dbLog(g.DB).WithFields(log.Fields{"model": "Group"}).Info("Initialized database")
 	return nil
 }
 
+// backfillVisibility makes the groups with a password private.
+//
+// Groups created before the visibility was added are public by default
+// even if they have a password. New groups cannot be public with a password
+// so the groups only need to be fixed once.
+func backfillVisibility(db *gorm.DB) error {
+	return db.Model(&Group{}).Unscoped().Where(
+		"visibility <> ? AND COALESCE(password, '') <> ''",
+		GroupVisibilityPrivate).Update("visibility", GroupVisibilityPrivate).Error
+}
+
 // Creates the group table based on the struct model
 func (g *Group) Migrate() error {
-	if err := g.DB.AutoMigrate(&g); err != nil {
-		log.WithFields(
+	if err := g.DB.SetupJoinTable(&Group{}, "Members", &GroupMember{}); err != nil {
+		dbLog(g.DB).WithFields(
+			log.Fields{"model": "Group"}).Fatal("Failed to set up join table")
+		return err
+	}
+	// The users side of the members is set up as well since the join table
+	// would otherwise be created without the columns of GroupMember.
+	if err := g.DB.SetupJoinTable(&User{}, "JoinedGroups", &GroupMember{}); err != nil {
+		dbLog(g.DB).WithFields(
+			log.Fields{"model": "Group"}).Fatal("Failed to set up join table")
+		return err
+	}
+	if err := g.DB.SetupJoinTable(&Group{}, "Tags", &GroupTag{}); err != nil {
+		dbLog(g.DB).WithFields(
+			log.Fields{"model": "Group"}).Fatal("Failed to set up join table")
+		return err
+	}
+	if err := g.DB.AutoMigrate(
+		&g, &GroupMember{}, &Tag{}, &Reservation{}, &GroupActivity{},
+		&JoinRequest{}, &WaitlistEntry{}, &GroupSettingsChange{},
+		&GroupViewDay{}, &GroupEvent{}, &GroupBan{}); err != nil {
+		dbLog(g.DB).WithFields(
 			log.Fields{"model": "Group"}).Fatal("Failed to auto migrate model")
 		return err
 	}
-	log.WithFields(log.Fields{"model": "Group"}).Info("Auto migrated model")
+	if err := backfillVisibility(g.DB); err != nil {
+		dbLog(g.DB).WithFields(
+			log.Fields{"model": "Group"}).Fatal("Failed to backfill visibility")
+		return err
+	}
+	if err := backfillSlugs(g.DB); err != nil {
+		dbLog(g.DB).WithFields(
+			log.Fields{"model": "Group"}).Fatal("Failed to backfill slugs")
+		return err
+	}
+	if err := migrateGroupSearch(g.DB); err != nil {
+		dbLog(g.DB).WithFields(
+			log.Fields{"model": "Group"}).Fatal("Failed to set up group search")
+		return err
+	}
+	dbLog(g.DB).WithFields(log.Fields{"model": "Group"}).Info("Auto migrated model")
+	return nil
+}
+
+// BeforeSave normalizes the category and the start time of the group before
+// saving it.
+func (g *Group) BeforeSave(tx *gorm.DB) error {
+	g.Category = normalizeCategory(g.Category)
+	if g.StartsAt != nil {
+		// Start times are stored in UTC so they can be compared.
+		startsAt := g.StartsAt.UTC()
+		g.StartsAt = &startsAt
+	}
 	return nil
 }
 
 // Create adds a new group entry to the database.
+//
+// The fields kept by the server, like the views and the version, are reset
+// so they cannot be set with the request body. Members are only added by
+// joining the group. The tags of the group are created if they do not exist
+// yet.
 func (g *Group) Create() error {
-	r := g.DB.Create(&g)
-	if r.Error != nil {
-		log.Fatalf("Could not create group. Error: %v", r.Error.Error())
+	g.ID, g.Views, g.Version = 0, 0, 1
+	g.CreatedAt, g.DeletedAt = time.Time{}, gorm.DeletedAt{}
+	g.Members = nil
+	err := g.DB.Transaction(func(tx *gorm.DB) error {
+		if err := createWithUniqueSlug(tx, g); err != nil {
+			return err
+		}
+		return saveGroupTags(tx, g)
+	})
+	if err != nil {
+		dbLog(g.DB).Errorf("Could not create group. Error: %v", err.Error())
 	} else {
-		log.Info("Created group successfully")
+		g.Private = g.IsPrivate()
+		dbLog(g.DB).Info("Created group successfully")
 	}
-	return r.Error
+	return err
 }
 
-// List gets all of the group entries from the database.
-func (g *Group) List() ([]Group, error) {
+// CloseStale closes the open groups that have been open longer than the TTL.
+//
+// The age of a group with a start time is counted from its start time so
+// groups planned in advance are not closed before they start. A closed
+// activity and a closed event without an actor are recorded for each closed
+// group.
+func (g *Group) CloseStale(ttl time.Duration) (int64, error) {
+	cutoff := time.Now().Add(-ttl).UTC()
+	var ids []int64
+	err := g.DB.Transaction(func(tx *gorm.DB) error {
+		r := tx.Model(&Group{}).Where(
+			"status = ? AND "+
+				"julianday(COALESCE(starts_at, created_at)) < julianday(?)",
+			GroupStatusOpen, cutoff).Pluck("id", &ids)
+		if r.Error != nil || len(ids) == 0 {
+			return r.Error
+		}
+		r = tx.Model(&Group{}).Where("id IN ?", ids).Updates(
+			map[string]interface{}{
+				"status": GroupStatusClosed, "version": gorm.Expr("version + 1"),
+			})
+		if r.Error != nil {
+			return r.Error
+		}
+		activities := make([]GroupActivity, len(ids))
+		for i, id := range ids {
+			activities[i] = GroupActivity{
+				GroupID: id, Type: ActivityClosed, Reason: staleGroupReason}
+		}
+		if r := tx.Omit("Actor", "Target").Create(&activities); r.Error != nil {
+			return r.Error
+		}
+		events := make([]GroupEvent, len(ids))
+		for i, id := range ids {
+			events[i] = GroupEvent{GroupID: id, Type: EventClosed}
+		}
+		return tx.Create(&events).Error
+	})
+	if err != nil {
+		dbLog(g.DB).Errorf("Could not close stale groups. Error: %v", err)
+		return 0, err
+	}
+	dbLog(g.DB).WithFields(log.Fields{
+		"count": len(ids),
+	}).Info("Closed stale groups")
+	return int64(len(ids)), nil
+}
+
+// HasOpenDuplicate checks if the owner has another open group with the title.
+func (g *Group) HasOpenDuplicate() (bool, error) {
+	var count int64
+	r := g.DB.Model(&Group{}).Where(
+		"owner_id = ? AND status = ? AND title = ? AND id <> ?",
+		g.OwnerID, GroupStatusOpen, g.Title, g.ID).Count(&count)
+	if r.Error != nil {
+		dbLog(g.DB).Errorf("Could not check for duplicate groups. Error: %v", r.Error)
+		return false, r.Error
+	}
+	return count > 0, nil
+}
+
+// listFields are the fields of the groups included in listings.
+var listFields = []string{
+	"id", "title", "description", "status",
+	"max_size", "created_at", "owner_id", "views", "require_approval",
+	"waitlist", "category", "game", "starts_at", "timezone", "visibility",
+	"slug", "version", "deleted_at", privateColumn,
+}
+
+// streamBatchSize is the number of groups loaded at a time by Stream.
+const streamBatchSize = 100
+
+// List gets the group entries that match the filters from the database.
+func (g *Group) List(f GroupFilters) ([]Group, error) {
 	groups := []Group{}
-	r := g.DB.Model(&g).Preload("Members", preloadUser).Select(
-		"id", "title", "description", "status",
-		"max_size", "created_at", "owner_id",
-	).Find(&groups)
+	db := f.Pagination.apply(f.apply(g.DB.Model(&g)))
+	r := preloadMembers(db.Order(f.order()), f.ExpandMembers).Select(
+		listFields).Find(&groups)
 	if r.Error != nil {
-		log.Fatalf("Could not list group. Error: %v", r.Error.Error())
+		dbLog(g.DB).Errorf("Could not list group. Error: %v", r.Error.Error())
+		return groups, r.Error
+	}
+	dbLog(g.DB).Info("Listed groups successfully")
+
+	refs := make([]*Group, len(groups))
+	for i := range groups {
+		refs[i] = &groups[i]
+	}
+	return groups, loadGroupDetails(g.DB, refs)
+}
+
+// Stream calls fn with each group that matches the filters in order.
+//
+// The IDs of the groups are read from a cursor and the groups are loaded in
+// batches so the listing is never fully loaded in memory. The pagination of
+// the filters is ignored.
+func (g *Group) Stream(f GroupFilters, fn func(Group) error) error {
+	rows, err := f.apply(g.DB.Model(&Group{})).Order(f.order()).Select(
+		"id").Rows()
+	if err != nil {
+		dbLog(g.DB).Errorf("Could not stream groups. Error: %v", err)
+		return err
+	}
+	defer rows.Close()
+
+	ids := make([]int64, 0, streamBatchSize)
+	flush := func() error {
+		if len(ids) == 0 {
+			return nil
+		}
+		groups := []Group{}
+		db := g.DB
+		if f.IncludeDeleted {
+			db = db.Unscoped()
+		}
+		r := preloadMembers(db, f.ExpandMembers).Select(listFields).Find(
+			&groups, ids)
+		if r.Error != nil {
+			return r.Error
+		}
+		byID := make(map[int64]*Group, len(groups))
+		refs := make([]*Group, len(groups))
+		for i := range groups {
+			byID[groups[i].ID] = &groups[i]
+			refs[i] = &groups[i]
+		}
+		if err := loadGroupDetails(g.DB, refs); err != nil {
+			return err
+		}
+		// Keep the order of the cursor. Groups deleted in the meantime
+		// are skipped.
+		for _, id := range ids {
+			if grp, ok := byID[id]; ok {
+				if err := fn(*grp); err != nil {
+					return err
+				}
+			}
+		}
+		ids = ids[:0]
+		return nil
+	}
+
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			dbLog(g.DB).Errorf("Could not stream groups. Error: %v", err)
+			return err
+		}
+		ids = append(ids, id)
+		if len(ids) == streamBatchSize {
+			if err := flush(); err != nil {
+				dbLog(g.DB).Errorf("Could not stream groups. Error: %v", err)
+				return err
+			}
+		}
+	}
+	if err := rows.Err(); err != nil {
+		dbLog(g.DB).Errorf("Could not stream groups. Error: %v", err)
+		return err
+	}
+	if err := flush(); err != nil {
+		dbLog(g.DB).Errorf("Could not stream groups. Error: %v", err)
+		return err
+	}
+	dbLog(g.DB).Info("Streamed groups successfully")
+	return nil
+}
+
+// Count counts the group entries that match the filters in the database.
+func (g *Group) Count(f GroupFilters) (int64, error) {
+	var count int64
+	r := f.apply(g.DB.Model(&Group{})).Count(&count)
+	if r.Error != nil {
+		dbLog(g.DB).Errorf("Could not count groups. Error: %v", r.Error.Error())
 	} else {
-		log.Info("Listed groups successfully")
+		dbLog(g.DB).Info("Counted groups successfully")
 	}
-	return groups, r.Error
+	return count, r.Error
 }
 
 // Retrieve retrieves the group details from the database given its database ID.
 func (g *Group) Retrieve() error {
 	fields := []string{
 		"id", "title", "description",
-		"status", "max_size", "created_at", "owner_id",
+		"status", "max_size", "created_at", "owner_id", "views",
+		"require_approval", "waitlist", "category", "game", "starts_at",
+		"timezone", "visibility", "slug", "version", privateColumn,
 	}
 	return retrieveGroup(g, fields)
 }
@@ -217,28 +1183,361 @@ func (g *Group) Retrieve() error {
 func (g *Group) RetrieveWithPassword() error {
 	fields := []string{
 		"id", "title", "description", "password",
-		"status", "max_size", "created_at", "owner_id",
+		"status", "max_size", "created_at", "owner_id", "views",
+		"require_approval", "waitlist", "category", "game", "starts_at",
+		"timezone", "visibility", "slug", "version", privateColumn,
 	}
 	return retrieveGroup(g, fields)
 }
 
 // Update updates a group entry.
+//
+// The view counter is left as is since it is only changed by IncrementViews.
+// The members are left as is as well.
+//
+// Changed settings are added to the settings history of the group in the
+// same transaction.
+//
+// The update only succeeds if the version of the group in the database is
+// still the version of g. ErrGroupConflict is returned otherwise.
 func (g *Group) Update() error {
-	r := g.DB.Save(&g)
+	version := g.Version
+	g.Version++
+	err := g.DB.Transaction(func(tx *gorm.DB) error {
+		before := Group{}
+		if r := tx.Select(
+			"id", "title", "description", "password", "status", "max_size",
+			"category", "game", "starts_at", "timezone", "visibility",
+		).First(&before, g.ID); r.Error != nil {
+			return r.Error
+		}
+		// Members are only changed by Join and RemoveMember. The columns are
+		// selected so Save does not insert the group if no row matches.
+		r := tx.Select("*").Omit("views", "Members", "Tags").Where(
+			"version = ?", version).Save(&g)
+		if r.Error != nil {
+			return r.Error
+		}
+		if r.RowsAffected == 0 {
+			return ErrGroupConflict
+		}
+		// The tags are only saved if they were loaded or set.
+		if g.Tags != nil {
+			if err := saveGroupTags(tx, g); err != nil {
+				return err
+			}
+		}
+		return recordSettingsChange(tx, before, *g)
+	})
+	if err != nil {
+		g.Version = version
+		dbLog(g.DB).Errorf("Could not update group. Error: %v", err.Error())
+	} else {
+		g.Private = g.IsPrivate()
+		dbLog(g.DB).Info("Updated the group successfully")
+	}
+	return err
+}
+
+// Delete soft deletes the group.
+//
+// The group is hidden from the listing and cannot be retrieved anymore but
+// its members and history are kept.
+func (g *Group) Delete() error {
+	r := g.DB.Delete(&Group{}, g.ID)
 	if r.Error != nil {
-		log.Fatalf("Could not update group. Error: %v", r.Error.Error())
+		dbLog(g.DB).Errorf("Could not delete group. Error: %v", r.Error)
+		return r.Error
+	}
+	dbLog(g.DB).Info("Deleted the group successfully")
+	return nil
+}
+
+// TransferOwnership makes a member the owner of the group.
+//
+// The new owner is removed from the members and the former owner becomes a
+// member in the same transaction.
+func (g *Group) TransferOwnership(uid int64) error {
+	formerOwnerID := g.OwnerID
+	err := g.DB.Transaction(func(tx *gorm.DB) error {
+		if r := tx.Model(&Group{}).Where("id = ?", g.ID).Updates(
+			map[string]interface{}{
+				"owner_id": uid, "version": gorm.Expr("version + 1"),
+			}); r.Error != nil {
+			return r.Error
+		}
+		if r := tx.Where("group_id = ? AND user_id = ?", g.ID, uid).Delete(
+			&GroupMember{}); r.Error != nil {
+			return r.Error
+		}
+		return tx.Create(
+			&GroupMember{GroupID: g.ID, UserID: formerOwnerID}).Error
+	})
+	if err != nil {
+		dbLog(g.DB).Errorf("Could not transfer group ownership. Error: %v", err)
+		return err
+	}
+	g.OwnerID = uid
+	g.Version++
+	dbLog(g.DB).Info("Transferred the group ownership successfully")
+	return nil
+}
+
+// RemoveOwner removes the owner from the group.
+//
+// The ownership goes to the member who joined first. The group is closed
+// instead if it has no members. It returns the ID of the new owner, or
+// zero if the group was closed.
+func (g *Group) RemoveOwner() (int64, error) {
+	var newOwnerID int64
+	err := g.DB.Transaction(func(tx *gorm.DB) error {
+		// Members without a join time joined before it was recorded so
+		// they sort first. The rowid orders them in the order they joined.
+		var m GroupMember
+		r := tx.Where("group_id = ?", g.ID).Order(
+			"joined_at, rowid").Limit(1).Find(&m)
+		if r.Error != nil {
+			return r.Error
+		}
+		if r.RowsAffected == 0 {
+			return tx.Model(&Group{}).Where("id = ?", g.ID).Updates(
+				map[string]interface{}{
+					"status":  GroupStatusClosed,
+					"version": gorm.Expr("version + 1"),
+				}).Error
+		}
+
+		newOwnerID = m.UserID
+		if r := tx.Model(&Group{}).Where("id = ?", g.ID).Updates(
+			map[string]interface{}{
+				"owner_id": newOwnerID, "version": gorm.Expr("version + 1"),
+			}); r.Error != nil {
+			return r.Error
+		}
+		return tx.Where("group_id = ? AND user_id = ?", g.ID, newOwnerID).Delete(
+			&GroupMember{}).Error
+	})
+	if err != nil {
+		dbLog(g.DB).Errorf("Could not remove the group owner. Error: %v", err)
+		return 0, err
+	}
+	if newOwnerID == 0 {
+		g.Status = GroupStatusClosed
 	} else {
-		log.Info("Updated the group successfully")
+		g.OwnerID = newOwnerID
+	}
+	g.Version++
+	dbLog(g.DB).Info("Removed the group owner successfully")
+	return newOwnerID, nil
+}
+
+// IncrementViews adds one to the view counter of the group.
+//
+// The counter is incremented in a single UPDATE statement so concurrent
+// views are not lost. The view is added to the views of the day as well
+// for the trending groups.
+func (g *Group) IncrementViews() error {
+	err := g.DB.Transaction(func(tx *gorm.DB) error {
+		r := tx.Model(&Group{}).Where("id = ?", g.ID).UpdateColumn(
+			"views", gorm.Expr("views + ?", 1))
+		if r.Error != nil {
+			return r.Error
+		}
+		return countViewDay(tx, g.ID, time.Now())
+	})
+	if err != nil {
+		dbLog(g.DB).Errorf("Could not increment group views. Error: %v", err)
+		return err
 	}
-	return r.Error
+	g.Views++
+	dbLog(g.DB).Info("Incremented the group views successfully")
+	return nil
+}
+
+// RandomizeStatuses opens and closes all the groups at random.
+//
+// closedPercent is the percentage of the groups that are closed. It is meant
+// for seeding demo data only.
+func (g *Group) RandomizeStatuses(closedPercent int) (StatusDistribution, error) {
+	var dist StatusDistribution
+	err := g.DB.Transaction(func(tx *gorm.DB) error {
+		var ids []int64
+		if r := tx.Model(&Group{}).Pluck("id", &ids); r.Error != nil {
+			return r.Error
+		}
+
+		rnd := rand.New(rand.NewSource(time.Now().UnixNano()))
+		rnd.Shuffle(len(ids), func(i, j int) { ids[i], ids[j] = ids[j], ids[i] })
+		closed := len(ids) * closedPercent / 100
+		if closed < 0 {
+			closed = 0
+		} else if closed > len(ids) {
+			closed = len(ids)
+		}
+
+		if closed > 0 {
+			if r := tx.Model(&Group{}).Where("id IN ?", ids[:closed]).Update(
+				"status", GroupStatusClosed); r.Error != nil {
+				return r.Error
+			}
+		}
+		if closed < len(ids) {
+			if r := tx.Model(&Group{}).Where("id IN ?", ids[closed:]).Update(
+				"status", GroupStatusOpen); r.Error != nil {
+				return r.Error
+			}
+		}
+		dist.Closed = int64(closed)
+		dist.Open = int64(len(ids) - closed)
+		return nil
+	})
+	if err != nil {
+		dbLog(g.DB).Errorf("Could not randomize group statuses. Error: %v", err)
+		return dist, err
+	}
+	dbLog(g.DB).Info("Randomized the group statuses successfully")
+	return dist, nil
+}
+
+// Join adds the user as a member of the group.
+//
+// The status, password, and capacity of the group are checked in the same
+// transaction as the insert so changes made after the group was retrieved
+// are taken into account.
+func (g *Group) Join(uid int64, pw string) error {
+	err := g.DB.Transaction(func(tx *gorm.DB) error {
+		// Insert first so the transaction holds the write lock while the
+		// group is checked.
+		err := tx.Create(&GroupMember{GroupID: g.ID, UserID: uid}).Error
+		if err != nil && strings.Contains(err.Error(), "UNIQUE constraint failed") {
+			return ErrAlreadyMember
+		} else if err != nil {
+			return err
+		}
+
+		cur := Group{}
+		if r := tx.Select(
+			"id", "status", "password", "max_size").First(&cur, g.ID); r.Error != nil {
+			return r.Error
+		}
+		if !cur.IsOpen() {
+			return ErrGroupNotOpen
+		}
+		if cur.IsPrivate() && pw == "" {
+			return ErrGroupPasswordRequired
+		} else if cur.IsPrivate() && cur.ValidatePassword(pw) != nil {
+			return ErrIncorrectGroupPassword
+		}
+
+		var members, reserved int64
+		if r := tx.Model(&GroupMember{}).Where(
+			"group_id = ?", g.ID).Count(&members); r.Error != nil {
+			return r.Error
+		}
+		// The reservation of the user does not take an extra slot.
+		if r := tx.Model(&Reservation{}).Where(
+			"group_id = ? AND user_id <> ? AND "+
+				"julianday(expires_at) > julianday(?)",
+			g.ID, uid, time.Now()).Count(&reserved); r.Error != nil {
+			return r.Error
+		}
+		if members+reserved > int64(cur.MaxSize-1) {
+			return ErrGroupFull
+		}
+		return tx.Where("group_id = ? AND user_id = ?", g.ID, uid).Delete(
+			&WaitlistEntry{}).Error
+	})
+	if err != nil {
+		dbLog(g.DB).Errorf("Could not join group. Error: %v", err)
+		return err
+	}
+	g.MemberCount++
+	dbLog(g.DB).Info("Joined the group successfully")
+	return nil
 }
 
 // RemoveMember removes a user from the group.
+//
+// The membership and the waitlist entry of the user are removed in a single
+// transaction.
 func (g *Group) RemoveMember(u User) error {
-	if err := g.DB.Model(&g).Association("Members").Delete(u); err != nil {
-		log.Fatalf("Could not remove group member. Error: %v", err)
+	err := g.DB.Transaction(func(tx *gorm.DB) error {
+		r := tx.Where("group_id = ? AND user_id = ?", g.ID, u.ID).Delete(
+			&GroupMember{})
+		if r.Error != nil {
+			return r.Error
+		} else if r.RowsAffected == 0 {
+			return ErrNotMember
+		}
+		return tx.Where("group_id = ? AND user_id = ?", g.ID, u.ID).Delete(
+			&WaitlistEntry{}).Error
+	})
+	if err != nil {
+		dbLog(g.DB).Errorf("Could not remove group member. Error: %v", err)
 		return err
 	}
-	log.Info("Removed the member from the group successfully")
+
+	if i := g.memberIndex(u.ID); i != -1 {
+		g.Members = slices.Delete(g.Members, i, i+1)
+	}
+	g.MemberCount--
+	dbLog(g.DB).Info("Removed the member from the group successfully")
+	return nil
+}
+
+// ListMembers retrieves a page of the members of the group.
+//
+// The members are listed in the order they joined the group.
+//
+// Only the fields selected by preloadUser are included with the labels and roles.
+func (g *Group) ListMembers(p Pagination) ([]User, error) {
+	users := []User{}
+	db := p.apply(preloadUser(g.DB.Model(&User{})))
+	r := db.Joins(
+		"JOIN joined_groups ON joined_groups.user_id = users.id"+
+			" AND joined_groups.group_id = ?", g.ID,
+	).Order("joined_groups.joined_at, joined_groups.rowid").Find(&users)
+	if r.Error != nil {
+		dbLog(g.DB).Errorf("Could not list group members. Error: %v", r.Error)
+		return users, r.Error
+	}
+
+	page := Group{ID: g.ID, Members: users}
+	if err := loadMemberInfo(g.DB, []*Group{&page}); err != nil {
+		return users, err
+	}
+	dbLog(g.DB).Info("Listed the group members successfully")
+	return users, nil
+}
+
+// SetMemberLabel sets the label of a member of the group.
+func (g *Group) SetMemberLabel(uid int64, label string) error {
+	r := g.DB.Model(&GroupMember{}).Where(
+		"group_id = ? AND user_id = ?", g.ID, uid).Update("label", label)
+	if r.Error != nil {
+		dbLog(g.DB).Errorf("Could not set member label. Error: %v", r.Error)
+		return r.Error
+	}
+
+	if i := g.memberIndex(uid); i != -1 {
+		g.Members[i].Label = label
+	}
+	dbLog(g.DB).Info("Set the member label successfully")
+	return nil
+}
+
+// SetMemberRole sets the role of a member of the group.
+func (g *Group) SetMemberRole(uid int64, role string) error {
+	r := g.DB.Model(&GroupMember{}).Where(
+		"group_id = ? AND user_id = ?", g.ID, uid).Update("role", role)
+	if r.Error != nil {
+		dbLog(g.DB).Errorf("Could not set member role. Error: %v", r.Error)
+		return r.Error
+	}
+
+	if i := g.memberIndex(uid); i != -1 {
+		g.Members[i].Role = role
+	}
+	dbLog(g.DB).Info("Set the member role successfully")
 	return nil
 }
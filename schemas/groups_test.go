@@ -0,0 +1,313 @@
+package schemas
+
+import (
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+func TestCloseStaleRecordsClosedActivities(t *testing.T) {
+	owner := createTestUser(t)
+	stale := createTestGroup(t, owner, "Stale raid")
+	fresh := createTestGroup(t, owner, "Fresh raid")
+	if r := stale.DB.Model(&Group{}).Where("id = ?", stale.ID).Update(
+		"created_at", time.Now().Add(-2*time.Hour)); r.Error != nil {
+		t.Fatalf("could not age the group: %v", r.Error)
+	}
+
+	closed, err := stale.CloseStale(time.Hour)
+	if err != nil {
+		t.Fatalf("could not close the stale groups: %v", err)
+	}
+	if closed == 0 {
+		t.Error("got no closed groups")
+	}
+
+	for _, tc := range []struct {
+		g          Group
+		wantStatus GroupStatus
+		wantClosed int
+	}{
+		{stale, GroupStatusClosed, 1},
+		{fresh, GroupStatusOpen, 0},
+	} {
+		if err := tc.g.Retrieve(); err != nil {
+			t.Fatalf("could not retrieve the group: %v", err)
+		}
+		if tc.g.Status != tc.wantStatus {
+			t.Errorf("got status %v, want %v", tc.g.Status, tc.wantStatus)
+		}
+		activities, err := tc.g.ListActivities(Pagination{Page: 1, PageSize: 10})
+		if err != nil {
+			t.Fatalf("could not list the activities: %v", err)
+		}
+		closedCount := 0
+		for _, a := range activities {
+			if a.Type == ActivityClosed {
+				closedCount++
+			}
+		}
+		if closedCount != tc.wantClosed {
+			t.Errorf("got %v closed activities, want %v",
+				closedCount, tc.wantClosed)
+		}
+	}
+}
+
+func TestRandomizeStatusesClosesThePercentage(t *testing.T) {
+	owner := createTestUser(t)
+	for i := 0; i < 8; i++ {
+		createTestGroup(t, owner, "Randomized raid")
+	}
+
+	g := Group{DB: owner.DB}
+	dist, err := g.RandomizeStatuses(25)
+	if err != nil {
+		t.Fatalf("could not randomize the statuses: %v", err)
+	}
+	var total, closed int64
+	g.DB.Model(&Group{}).Count(&total)
+	g.DB.Model(&Group{}).Where("status = ?", GroupStatusClosed).Count(&closed)
+	if dist.Open+dist.Closed != total {
+		t.Errorf("got %v open and %v closed groups, want %v in total",
+			dist.Open, dist.Closed, total)
+	}
+	if dist.Closed != total*25/100 || closed != dist.Closed {
+		t.Errorf("got %v closed groups and %v in the table, want %v",
+			dist.Closed, closed, total*25/100)
+	}
+}
+
+func TestIsOpen(t *testing.T) {
+	for _, tc := range []struct {
+		status GroupStatus
+		want   bool
+	}{
+		{GroupStatusOpen, true},
+		{GroupStatusClosed, false},
+		{GroupStatus(1), false},
+	} {
+		g := Group{Status: tc.status}
+		if got := g.IsOpen(); got != tc.want {
+			t.Errorf("got IsOpen %v for status %v, want %v", got, tc.status, tc.want)
+		}
+	}
+}
+
+func TestListFullnessDoesNotDependOnMemberPreload(t *testing.T) {
+	owner := createTestUser(t)
+	full := createTestGroup(t, owner, "Full raid")
+	open := createTestGroup(t, owner, "Open raid")
+	members := make([]User, 4)
+	for i := range members {
+		members[i] = createTestUser(t)
+		if err := full.Join(members[i].ID, ""); err != nil {
+			t.Fatalf("could not join the group: %v", err)
+		}
+	}
+	if err := open.Join(members[0].ID, ""); err != nil {
+		t.Fatalf("could not join the group: %v", err)
+	}
+
+	list := func(t *testing.T, f GroupFilters) []Group {
+		t.Helper()
+		f.OwnerID = owner.ID
+		groups, err := full.List(f)
+		if err != nil {
+			t.Fatalf("could not list the groups: %v", err)
+		}
+		return groups
+	}
+	ids := func(groups []Group) []int64 {
+		ids := make([]int64, len(groups))
+		for i, g := range groups {
+			ids[i] = g.ID
+		}
+		return ids
+	}
+
+	yes, no := true, false
+	checkIDs(t, ids(list(t, GroupFilters{Joinable: &yes})), []int64{open.ID})
+	checkIDs(t, ids(list(t, GroupFilters{Joinable: &no})), []int64{full.ID})
+
+	// The member list is only loaded if it is expanded.
+	groups := list(t, GroupFilters{MemberID: members[3].ID})
+	checkIDs(t, ids(groups), []int64{full.ID})
+	g := groups[0]
+	if g.MemberCount != 4 || !g.IsFull() || g.Members != nil {
+		t.Errorf("got %v members, full %v and member list %v, want 4, true and none",
+			g.MemberCount, g.IsFull(), g.Members)
+	}
+	groups = list(t, GroupFilters{MemberID: members[3].ID, ExpandMembers: true})
+	checkIDs(t, ids(groups), []int64{full.ID})
+	g = groups[0]
+	for _, m := range members {
+		if !g.IsMember(m.ID) {
+			t.Errorf("got user %v missing from the listed members", m.ID)
+		}
+	}
+}
+
+func TestJoinTakesTheLastSlotOnce(t *testing.T) {
+	owner := createTestUser(t)
+	g := createTestGroup(t, owner, "Last slot raid")
+	for i := 0; i < 3; i++ {
+		if err := g.Join(createTestUser(t).ID, ""); err != nil {
+			t.Fatalf("could not join the group: %v", err)
+		}
+	}
+
+	users := make([]User, 5)
+	for i := range users {
+		users[i] = createTestUser(t)
+	}
+	errs := make([]error, len(users))
+	var wg sync.WaitGroup
+	for i := range users {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			// Each request has its own copy of the group.
+			cp := g
+			errs[i] = cp.Join(users[i].ID, "")
+		}(i)
+	}
+	wg.Wait()
+
+	joined := 0
+	for _, err := range errs {
+		if err == nil {
+			joined++
+		} else if !errors.Is(err, ErrGroupFull) {
+			t.Errorf("got error %v, want %v", err, ErrGroupFull)
+		}
+	}
+	var members int64
+	g.DB.Model(&GroupMember{}).Where("group_id = ?", g.ID).Count(&members)
+	if joined != 1 || members != 4 {
+		t.Errorf("got %v joins and %v members, want 1 and 4", joined, members)
+	}
+}
+
+func TestJoinChecksTheCurrentPassword(t *testing.T) {
+	owner, u := createTestUser(t), createTestUser(t)
+	g := createTestGroup(t, owner, "Rekeyed raid")
+	g.SetPassword("old-secret")
+	if err := g.Update(); err != nil {
+		t.Fatalf("could not set the password: %v", err)
+	}
+	// The password changes after the group was retrieved for the join.
+	stale := g
+	if r := g.DB.Model(&Group{}).Where("id = ?", g.ID).Update(
+		"password", "new-secret"); r.Error != nil {
+		t.Fatalf("could not change the password: %v", r.Error)
+	}
+
+	if err := stale.Join(u.ID, "old-secret"); !errors.Is(err, ErrIncorrectGroupPassword) {
+		t.Errorf("got error %v with the old password, want %v",
+			err, ErrIncorrectGroupPassword)
+	}
+	var members int64
+	g.DB.Model(&GroupMember{}).Where("group_id = ?", g.ID).Count(&members)
+	if members != 0 {
+		t.Errorf("got %v members after the failed join, want none", members)
+	}
+	if err := stale.Join(u.ID, "new-secret"); err != nil {
+		t.Errorf("could not join with the new password: %v", err)
+	}
+}
+
+func TestFailedJoinIsRolledBack(t *testing.T) {
+	owner, u := createTestUser(t), createTestUser(t)
+	for _, tc := range []struct {
+		name string
+		// setup makes the join fail after the member is inserted.
+		setup func(g *Group)
+		want  error
+	}{
+		{"closed", func(g *Group) { g.Status = GroupStatusClosed }, ErrGroupNotOpen},
+		{"full", func(g *Group) {
+			for i := 0; i < 4; i++ {
+				if err := g.Join(createTestUser(t).ID, ""); err != nil {
+					t.Fatalf("could not join the group: %v", err)
+				}
+			}
+		}, ErrGroupFull},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			g := createTestGroup(t, owner, "Rollback raid")
+			tc.setup(&g)
+			if err := g.Update(); err != nil {
+				t.Fatalf("could not update the group: %v", err)
+			}
+			before := g.MemberCount
+
+			if err := g.Join(u.ID, ""); !errors.Is(err, tc.want) {
+				t.Fatalf("got error %v, want %v", err, tc.want)
+			}
+			var n int64
+			g.DB.Model(&GroupMember{}).Where(
+				"group_id = ? AND user_id = ?", g.ID, u.ID).Count(&n)
+			if n != 0 || g.MemberCount != before {
+				t.Errorf("got %v memberships and a count of %v, want none and %v",
+					n, g.MemberCount, before)
+			}
+		})
+	}
+}
+
+func TestFailedLeaveIsRolledBack(t *testing.T) {
+	owner, u := createTestUser(t), createTestUser(t)
+	g := createTestGroup(t, owner, "Rollback raid")
+	if err := g.Join(u.ID, ""); err != nil {
+		t.Fatalf("could not join the group: %v", err)
+	}
+
+	// The removal of the waitlist entry fails after the membership is
+	// deleted.
+	const callback = "test:fail_waitlist_delete"
+	if err := g.DB.Callback().Delete().Before("gorm:delete").Register(
+		callback, func(tx *gorm.DB) {
+			if tx.Statement.Table == "waitlist_entries" {
+				tx.AddError(errors.New("waitlist is unavailable"))
+			}
+		}); err != nil {
+		t.Fatalf("could not register the callback: %v", err)
+	}
+	defer g.DB.Callback().Delete().Remove(callback)
+
+	if err := g.RemoveMember(u); err == nil {
+		t.Fatal("got no error removing the member")
+	}
+	var n int64
+	g.DB.Model(&GroupMember{}).Where(
+		"group_id = ? AND user_id = ?", g.ID, u.ID).Count(&n)
+	if n != 1 {
+		t.Errorf("got %v memberships after the failed leave, want 1", n)
+	}
+}
+
+func TestLengthsCountCharacters(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		errors []FieldError
+		valid  bool
+	}{
+		// Each of these characters takes more than one byte.
+		{"title at the limit", validateTitle(strings.Repeat("é", 50)), true},
+		{"title over the limit", validateTitle(strings.Repeat("é", 51)), false},
+		{"description at the limit", validateDescription(strings.Repeat("日", 200)), true},
+		{"description over the limit", validateDescription(strings.Repeat("日", 201)), false},
+		{"category at the limit", validateCategory(strings.Repeat("ß", 30)), true},
+		{"short password", validatePassword("password", "日本語パスワ"), false},
+		{"password at the limit", validatePassword("password", strings.Repeat("🔑", 200)), true},
+	} {
+		if valid := len(tc.errors) == 0; valid != tc.valid {
+			t.Errorf("%v: got errors %+v, want valid %v", tc.name, tc.errors, tc.valid)
+		}
+	}
+}
@@ -0,0 +1,103 @@
+package schemas
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// SettingChange is the value of a group setting before and after a change.
+type SettingChange struct {
+	Before interface{} `json:"before"`
+	After  interface{} `json:"after"`
+}
+
+// GroupSettingsChange is a version of the settings of a group.
+type GroupSettingsChange struct {
+	ID      int64 `json:"-" gorm:"primaryKey"`
+	GroupID int64 `json:"group_id" gorm:"not null;uniqueIndex:idx_settings_version"`
+	Version int64 `json:"version" gorm:"not null;uniqueIndex:idx_settings_version"`
+	// Changes are the changed settings keyed by their JSON field name.
+	Changes   map[string]SettingChange `json:"changes" gorm:"serializer:json"`
+	CreatedAt time.Time                `json:"created_at" gorm:"autoCreateTime"`
+}
+
+// settingsDiff returns the settings that differ between two versions of
+// the group.
+//
+// Passwords are never stored. Only whether the group is private is compared.
+func settingsDiff(before Group, after Group) map[string]SettingChange {
+	changes := map[string]SettingChange{}
+	if before.Title != after.Title {
+		changes["title"] = SettingChange{before.Title, after.Title}
+	}
+	if before.Description != after.Description {
+		changes["description"] = SettingChange{before.Description, after.Description}
+	}
+	if before.MaxSize != after.MaxSize {
+		changes["max_size"] = SettingChange{before.MaxSize, after.MaxSize}
+	}
+	if before.Category != after.Category {
+		changes["category"] = SettingChange{before.Category, after.Category}
+	}
+	if before.Game != after.Game {
+		changes["game"] = SettingChange{before.Game, after.Game}
+	}
+	if !timesEqual(before.StartsAt, after.StartsAt) {
+		changes["starts_at"] = SettingChange{before.StartsAt, after.StartsAt}
+	}
+	if before.Timezone != after.Timezone {
+		changes["timezone"] = SettingChange{before.Timezone, after.Timezone}
+	}
+	if before.Status != after.Status {
+		changes["status"] = SettingChange{before.Status, after.Status}
+	}
+	if before.Visibility != after.Visibility {
+		changes["visibility"] = SettingChange{before.Visibility, after.Visibility}
+	}
+	if before.IsPrivate() != after.IsPrivate() {
+		changes["private"] = SettingChange{before.IsPrivate(), after.IsPrivate()}
+	}
+	return changes
+}
+
+// recordSettingsChange adds a new version to the settings history of the
+// group if any of its settings changed.
+func recordSettingsChange(tx *gorm.DB, before Group, after Group) error {
+	changes := settingsDiff(before, after)
+	if len(changes) == 0 {
+		return nil
+	}
+
+	var version int64
+	if r := tx.Model(&GroupSettingsChange{}).Where(
+		"group_id = ?", after.ID).Select("COALESCE(MAX(version), 0)").Scan(
+		&version); r.Error != nil {
+		return r.Error
+	}
+	return tx.Create(&GroupSettingsChange{
+		GroupID: after.ID, Version: version + 1, Changes: changes}).Error
+}
+
+// ListSettingsHistory retrieves a page of the settings history of the group.
+//
+// The newest versions come first.
+func (g *Group) ListSettingsHistory(p Pagination) ([]GroupSettingsChange, error) {
+	history := []GroupSettingsChange{}
+	r := p.apply(g.DB.Where("group_id = ?", g.ID)).Order(
+		"version DESC").Find(&history)
+	if r.Error != nil {
+		dbLog(g.DB).Errorf("Could not list group settings history. Error: %v", r.Error)
+		return history, r.Error
+	}
+	dbLog(g.DB).Info("Listed the group settings history successfully")
+	return history, nil
+}
+
+// timesEqual checks if two optional times are the same instant.
+func timesEqual(a, b *time.Time) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Equal(*b)
+}
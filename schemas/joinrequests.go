@@ -0,0 +1,104 @@
+package schemas
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrJoinRequestExists is returned when the user already asked to join.
+	ErrJoinRequestExists = errors.New("user already has a pending join request")
+	// ErrJoinRequestNotFound is returned when there is no pending join request.
+	ErrJoinRequestNotFound = errors.New("join request not found")
+)
+
+// JoinRequest is a pending request of a user to join a group that requires
+// the approval of the owner.
+type JoinRequest struct {
+	GroupID   int64     `json:"group_id" gorm:"primaryKey"`
+	UserID    int64     `json:"-" gorm:"primaryKey"`
+	User      *User     `json:"user" gorm:"foreignKey:UserID"`
+	CreatedAt time.Time `json:"created_at" gorm:"autoCreateTime"`
+
+	DB *gorm.DB `json:"-" gorm:"-"`
+}
+
+// Create adds the pending join request of the user.
+func (jr *JoinRequest) Create() error {
+	err := jr.DB.Omit("User").Create(&jr).Error
+	if err != nil && strings.Contains(err.Error(), "UNIQUE constraint failed") {
+		err = ErrJoinRequestExists
+	}
+	if err != nil {
+		dbLog(jr.DB).Errorf("Could not create join request. Error: %v", err)
+		return err
+	}
+	dbLog(jr.DB).Info("Created join request successfully")
+	return nil
+}
+
+// Exists checks if the user has a pending join request for the group.
+func (jr *JoinRequest) Exists() (bool, error) {
+	var count int64
+	r := jr.DB.Model(&JoinRequest{}).Where(
+		"group_id = ? AND user_id = ?", jr.GroupID, jr.UserID).Count(&count)
+	if r.Error != nil {
+		dbLog(jr.DB).Errorf("Could not check for join request. Error: %v", r.Error)
+		return false, r.Error
+	}
+	return count > 0, nil
+}
+
+// Approve converts the pending join request of the user into a membership.
+func (jr *JoinRequest) Approve() error {
+	err := jr.DB.Transaction(func(tx *gorm.DB) error {
+		res := tx.Where(
+			"group_id = ? AND user_id = ?", jr.GroupID, jr.UserID,
+		).Delete(&JoinRequest{})
+		if res.Error != nil {
+			return res.Error
+		} else if res.RowsAffected == 0 {
+			return ErrJoinRequestNotFound
+		}
+		return tx.Create(&GroupMember{GroupID: jr.GroupID, UserID: jr.UserID}).Error
+	})
+	if err != nil {
+		dbLog(jr.DB).Errorf("Could not approve join request. Error: %v", err)
+		return err
+	}
+	dbLog(jr.DB).Info("Approved join request successfully")
+	return nil
+}
+
+// Reject discards the pending join request of the user.
+func (jr *JoinRequest) Reject() error {
+	res := jr.DB.Where(
+		"group_id = ? AND user_id = ?", jr.GroupID, jr.UserID,
+	).Delete(&JoinRequest{})
+	if res.Error != nil {
+		dbLog(jr.DB).Errorf("Could not reject join request. Error: %v", res.Error)
+		return res.Error
+	} else if res.RowsAffected == 0 {
+		return ErrJoinRequestNotFound
+	}
+	dbLog(jr.DB).Info("Rejected join request successfully")
+	return nil
+}
+
+// ListJoinRequests retrieves the pending join requests of the group.
+//
+// The oldest requests come first.
+func (g *Group) ListJoinRequests() ([]JoinRequest, error) {
+	requests := []JoinRequest{}
+	r := g.DB.Where("group_id = ?", g.ID).Order("created_at, user_id").Preload(
+		"User", preloadUser).Find(&requests)
+	if r.Error != nil {
+		dbLog(g.DB).Errorf("Could not list join requests. Error: %v", r.Error)
+		return requests, r.Error
+	}
+	dbLog(g.DB).Info("Listed the join requests successfully")
+	return requests, nil
+}
@@ -0,0 +1,19 @@
+package schemas
+
+import (
+	"context"
+
+	"github.com/damascopaul/lfg-backend/logging"
+
+	log "github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+// dbLog returns the logger of the request the database object was created
+// for, so the logs carry the ID of the request.
+func dbLog(db *gorm.DB) *log.Entry {
+	if db == nil || db.Statement == nil {
+		return logging.FromContext(context.Background())
+	}
+	return logging.FromContext(db.Statement.Context)
+}
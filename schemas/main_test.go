@@ -0,0 +1,63 @@
+package schemas
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"sync/atomic"
+	"testing"
+
+	"github.com/damascopaul/lfg-backend/config"
+	"github.com/damascopaul/lfg-backend/data"
+
+	log "github.com/sirupsen/logrus"
+)
+
+func TestMain(m *testing.M) {
+	log.SetOutput(io.Discard)
+	data.Configure(config.Config{DBPath: ":memory:"})
+	if err := Migrate(); err != nil {
+		panic(err)
+	}
+	os.Exit(m.Run())
+}
+
+// userCount makes the usernames of the tests unique in the shared database.
+var userCount int64
+
+// createTestUser adds a user with a unique username to the database.
+func createTestUser(t *testing.T) User {
+	t.Helper()
+	u := User{
+		Username: fmt.Sprintf("player%v", atomic.AddInt64(&userCount, 1)),
+		Password: "violet-lantern-42",
+	}
+	if err := u.InitDB(context.Background()); err != nil {
+		t.Fatalf("could not init the database: %v", err)
+	}
+	if err := u.Create(); err != nil {
+		t.Fatalf("could not create the user: %v", err)
+	}
+	return u
+}
+
+// createTestGroup adds an open group owned by the user to the database.
+func createTestGroup(t *testing.T, owner User, title string) Group {
+	t.Helper()
+	g := Group{
+		Title:       title,
+		Description: "Weekly raid",
+		MaxSize:     5,
+		OwnerID:     owner.ID,
+		Status:      GroupStatusOpen,
+		Visibility:  GroupVisibilityPublic,
+	}
+	if err := g.InitDB(context.Background()); err != nil {
+		t.Fatalf("could not init the database: %v", err)
+	}
+	if err := g.Create(); err != nil {
+		t.Fatalf("could not create the group: %v", err)
+	}
+	return g
+}
@@ -0,0 +1,159 @@
+package schemas
+
+import (
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	log "github.com/sirupsen/logrus"
+	"golang.org/x/exp/slices"
+	"gorm.io/gorm"
+)
+
+// MemberLabels are the labels an owner can assign to a member.
+var MemberLabels = []string{"leader", "tank", "healer", "support", "dps"}
+
+// Roles of the members of a group.
+//
+// The owner of a group is not a member so it has no member role.
+const (
+	RoleMember    = "member"
+	RoleModerator = "moderator"
+)
+
+// GroupMember is the join model between a group and its members.
+type GroupMember struct {
+	GroupID int64  `json:"group_id" gorm:"primaryKey"`
+	UserID  int64  `json:"user_id" gorm:"primaryKey"`
+	Label   string `json:"label"`
+	Role    string `json:"role" gorm:"not null;default:member"`
+	// JoinedAt is when the user became a member. It is nil for members who
+	// joined before the time was recorded.
+	JoinedAt *time.Time `json:"joined_at,omitempty" gorm:"autoCreateTime"`
+}
+
+// TableName keeps the join table name used by the many2many associations.
+func (GroupMember) TableName() string {
+	return "joined_groups"
+}
+
+// ValidateLabel checks if the label is one of the allowed member labels.
+//
+// An empty label is valid and clears the label of the member.
+func (m *GroupMember) ValidateLabel() error {
+	if m.Label == "" || slices.Contains(MemberLabels, m.Label) {
+		return nil
+	}
+	log.WithFields(log.Fields{"model": "GroupMember"}).Warn("Request body is invalid")
+	return &ValidationError{
+		Message: "The request body contains errors",
+		Errors:  []FieldError{oneOfError("label", MemberLabels)},
+	}
+}
+
+// Kick is the request body for removing a member from a group.
+type Kick struct {
+	UserID int64  `json:"id"`
+	Reason string `json:"reason"`
+	// Ban keeps the user from joining the group again.
+	Ban bool `json:"ban"`
+}
+
+// Validate checks if the kick is valid.
+//
+// The reason is optional. Its surrounding whitespace is trimmed first.
+func (k *Kick) Validate() error {
+	const maxReasonLen int = 200
+	k.Reason = strings.TrimSpace(k.Reason)
+	if utf8.RuneCountInString(k.Reason) <= maxReasonLen {
+		return nil
+	}
+	log.WithFields(log.Fields{"model": "Kick"}).Warn("Request body is invalid")
+	return &ValidationError{
+		Message: "The request body contains errors",
+		Errors: []FieldError{{
+			Name:   "reason",
+			ID:     "too_long",
+			Params: []interface{}{maxReasonLen},
+			Error: fmt.Sprintf(
+				"This field cannot be more than %v characters long", maxReasonLen),
+		}},
+	}
+}
+
+// loadMemberInfo sets the label, the role, and the join time of each member
+// of the groups.
+func loadMemberInfo(db *gorm.DB, groups []*Group) error {
+	// Only the groups with a loaded member list need the member info.
+	ids := make([]int64, 0, len(groups))
+	for _, g := range groups {
+		if len(g.Members) > 0 {
+			ids = append(ids, g.ID)
+		}
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+
+	var members []GroupMember
+	r := db.Where("group_id IN ?", ids).Find(&members)
+	if r.Error != nil {
+		dbLog(db).Errorf("Could not load member info. Error: %v", r.Error)
+		return r.Error
+	}
+
+	info := make(map[[2]int64]GroupMember, len(members))
+	for _, m := range members {
+		info[[2]int64{m.GroupID, m.UserID}] = m
+	}
+	for _, g := range groups {
+		for i, m := range g.Members {
+			gm := info[[2]int64{g.ID, m.ID}]
+			g.Members[i].Label = gm.Label
+			g.Members[i].Role = gm.Role
+			g.Members[i].JoinedAt = gm.JoinedAt
+		}
+	}
+	return nil
+}
+
+// loadMemberCounts sets the number of members of the groups.
+func loadMemberCounts(db *gorm.DB, groups []*Group) error {
+	ids := make([]int64, len(groups))
+	for i, g := range groups {
+		ids[i] = g.ID
+	}
+
+	var counts []struct {
+		GroupID int64
+		Count   int16
+	}
+	r := db.Model(&GroupMember{}).Select("group_id, COUNT(*) AS count").Where(
+		"group_id IN ?", ids).Group("group_id").Scan(&counts)
+	if r.Error != nil {
+		dbLog(db).Errorf("Could not load member counts. Error: %v", r.Error)
+		return r.Error
+	}
+
+	members := make(map[int64]int16, len(counts))
+	for _, c := range counts {
+		members[c.GroupID] = c.Count
+	}
+	for _, g := range groups {
+		g.MemberCount = members[g.ID]
+	}
+	return nil
+}
+
+// HasMember checks if the user is a member of the group without loading the
+// member list.
+func (g *Group) HasMember(uid int64) (bool, error) {
+	var count int64
+	r := g.DB.Model(&GroupMember{}).Where(
+		"group_id = ? AND user_id = ?", g.ID, uid).Limit(1).Count(&count)
+	if r.Error != nil {
+		dbLog(g.DB).Errorf("Could not check the membership. Error: %v", r.Error)
+	}
+	return count > 0, r.Error
+}
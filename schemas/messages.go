@@ -0,0 +1,132 @@
+package schemas
+
+import "fmt"
+
+// DefaultLanguage is the language of the messages written in the code.
+const DefaultLanguage = "en"
+
+// messageCatalog are the translations of the messages of a language.
+type messageCatalog struct {
+	// Errors are the messages of the error responses keyed by error code.
+	Errors map[string]string
+	// Fields are the messages of the field errors keyed by field error ID.
+	Fields map[string]string
+}
+
+// catalogs are the translations keyed by language. The messages can have
+// the same verbs as the English messages they translate.
+//
+// English has no catalog since the English messages are the ones in the
+// code.
+var catalogs = map[string]messageCatalog{
+	"es": {
+		Errors: map[string]string{
+			"account_locked":           "Demasiados inicios de sesión fallidos. Inténtalo más tarde.",
+			"already_member":           "El usuario es miembro del grupo",
+			"already_owner":            "El usuario ya es el propietario",
+			"already_waitlisted":       "El usuario ya está en la lista de espera",
+			"approval_required":        "El grupo requiere aprobación para unirse",
+			"body_too_large":           "El cuerpo de la solicitud es demasiado grande",
+			"conflict":                 "Otra solicitud cambió el grupo",
+			"duplicate_title":          "El nuevo grupo no es válido",
+			"email_taken":              "El correo electrónico ya está en uso.",
+			"empty_body":               "El cuerpo de la solicitud está vacío",
+			"group_full":               "El grupo está lleno",
+			"group_not_closed":         "El grupo no está cerrado",
+			"group_not_open":           "El grupo no está abierto",
+			"group_passwords_disabled": "La solicitud contiene errores",
+			"incorrect_password":       "Contraseña incorrecta",
+			"internal_error":           "Ocurrió un error interno en el servidor",
+			"invalid_credentials":      "El usuario o la contraseña no son válidos.",
+			"invalid_query":            "Los parámetros de la consulta no son válidos",
+			"invalid_reset_token":      "El token de restablecimiento no es válido o ha caducado",
+			"invalid_time":             "Las horas del cuerpo de la solicitud deben estar en el formato RFC 3339",
+			"invalid_token":            "El token no es válido",
+			"invalid_type":             "La solicitud contiene errores",
+			"is_owner":                 "El usuario es el propietario del grupo",
+			"join_request_exists":      "El usuario ya tiene una solicitud para unirse pendiente",
+			"joined_group_limit":       "El usuario no puede ser miembro de más de %v grupos abiertos",
+			"malformed_json":           "El cuerpo de la solicitud no es JSON válido",
+			"missing_token":            "Falta la cabecera Authorization",
+			"not_acceptable":           "Las respuestas solo están disponibles en JSON",
+			"not_admin":                "El usuario no es administrador",
+			"not_found":                "No se encontró el recurso solicitado",
+			"not_in_group":             "El usuario no está en el grupo",
+			"not_member":               "El usuario no es miembro del grupo",
+			"not_moderator":            "El usuario no es el propietario ni un moderador del grupo",
+			"not_owner":                "El usuario no es el propietario del grupo",
+			"owned_group_limit":        "El usuario no puede tener más de %v grupos abiertos",
+			"password_required":        "Se requiere la contraseña del grupo",
+			"query_too_long":           "La cadena de consulta es demasiado larga",
+			"reservation_exists":       "El usuario ya tiene una reserva",
+			"revoked_token":            "El token fue revocado",
+			"target_is_moderator":      "Los moderadores solo pueden expulsar a miembros normales",
+			"timeout":                  "La solicitud tardó demasiado en completarse",
+			"too_many_attempts":        "Demasiados intentos fallidos. Inténtalo más tarde.",
+			"unknown_game":             "La solicitud contiene errores",
+			"username_taken":           "El usuario ya existe.",
+			"validation_error":         "La solicitud contiene errores",
+		},
+		Fields: map[string]string{
+			"below_member_count":       "El valor no puede ser menor que las %v personas del grupo",
+			"common_password":          "Esta contraseña es demasiado común",
+			"cursor_sort":              "Solo se admite %v con un cursor",
+			"duplicate_title":          "Ya tienes un grupo abierto con este título",
+			"group_passwords_disabled": "Las contraseñas de grupo están desactivadas",
+			"in_the_past":              "Este campo no puede estar en el pasado",
+			"incorrect_password":       "La contraseña es incorrecta",
+			"invalid_cursor":           "Este campo no es un cursor válido",
+			"invalid_email":            "Este campo debe ser un correo electrónico válido",
+			"invalid_time":             "Este campo debe ser una marca de tiempo RFC3339",
+			"invalid_timezone":         "Este campo debe ser una zona horaria IANA como Europe/Paris",
+			"invalid_type":             "Este campo debe ser de tipo %v",
+			"later_than":               "Este campo debe ser posterior a %v",
+			"length_range":             "Este campo debe tener entre %v y %v caracteres",
+			"one_of":                   "El valor debe ser uno de: %v",
+			"required":                 "Este campo es obligatorio",
+			"private_needs_password":   "Define la contraseña del grupo para hacerlo privado",
+			"private_only":             "Este campo solo se permite en grupos privados",
+			"reserved_username":        "Este nombre de usuario está reservado",
+			"same_as_username":         "Este campo no puede ser igual al nombre de usuario",
+			"tag_length":               "Cada etiqueta debe tener entre 1 y %v caracteres",
+			"too_long":                 "Este campo no puede tener más de %v caracteres",
+			"too_many_tags":            "No puede haber más de %v etiquetas",
+			"too_many_values":          "Este campo no puede tener más de %v valores",
+			"unsupported_value":        "Este campo tiene un valor no admitido",
+			"username_characters":      "Este campo solo puede contener letras, números, guiones bajos, puntos y guiones",
+			"value_range":              "El valor debe estar entre %v y %v",
+			"window_too_long":          "El intervalo no puede ser mayor de %v días",
+		},
+	},
+}
+
+// IsSupportedLanguage checks if the messages can be returned in the
+// language.
+func IsSupportedLanguage(lang string) bool {
+	_, ok := catalogs[lang]
+	return ok || lang == DefaultLanguage
+}
+
+// Localize returns the error with its messages in the language.
+//
+// Messages without a translation are left in English.
+func (e BodyError) Localize(lang string) BodyError {
+	catalog, ok := catalogs[lang]
+	if !ok {
+		return e
+	}
+	if msg, ok := catalog.Errors[e.Code]; ok {
+		e.Message = fmt.Sprintf(msg, e.Params...)
+	}
+	if len(e.FieldErrors) > 0 {
+		fieldErrors := make([]FieldError, len(e.FieldErrors))
+		for i, fe := range e.FieldErrors {
+			if msg, ok := catalog.Fields[fe.ID]; ok {
+				fe.Error = fmt.Sprintf(msg, fe.Params...)
+			}
+			fieldErrors[i] = fe
+		}
+		e.FieldErrors = fieldErrors
+	}
+	return e
+}
@@ -0,0 +1,30 @@
+package schemas
+
+import (
+	"github.com/damascopaul/lfg-backend/data"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Migrate creates and updates the tables of all the models.
+//
+// It is run once at startup, before the server handles any request, so the
+// connections of the requests do not migrate the database.
+func Migrate() error {
+	db, err := data.CreateConnection()
+	if err != nil {
+		return err
+	}
+	for _, migrate := range []func() error{
+		(&User{DB: db}).Migrate,
+		(&Group{DB: db}).Migrate,
+		(&Reservation{DB: db}).Migrate,
+		(&PasswordReset{DB: db}).Migrate,
+	} {
+		if err := migrate(); err != nil {
+			return err
+		}
+	}
+	log.Info("Migrated the database successfully")
+	return nil
+}
@@ -0,0 +1,56 @@
+package schemas
+
+import "gorm.io/gorm"
+
+const (
+	defaultPageSize int = 20
+	maxPageSize     int = 100
+)
+
+// PageLimits are the page sizes allowed on a listing.
+//
+// Zero values fall back to the defaults shared by all listings.
+type PageLimits struct {
+	Default int
+	Max     int
+}
+
+// Pagination are the query parameters used to page through a listing.
+//
+// Pages start at 1. Out of range values are clamped instead of rejected.
+type Pagination struct {
+	Page     int `form:"page"`
+	PageSize int `form:"page_size"`
+
+	// Limits are the page sizes allowed on the listing.
+	Limits PageLimits `form:"-"`
+}
+
+// clamp keeps the page and page size within their allowed range.
+func (p *Pagination) clamp() {
+	def, max := p.Limits.Default, p.Limits.Max
+	if max < 1 {
+		max = maxPageSize
+	}
+	if def < 1 {
+		def = defaultPageSize
+	}
+	if def > max {
+		def = max
+	}
+
+	if p.Page < 1 {
+		p.Page = 1
+	}
+	if p.PageSize < 1 {
+		p.PageSize = def
+	} else if p.PageSize > max {
+		p.PageSize = max
+	}
+}
+
+// apply limits the query to the requested page.
+func (p Pagination) apply(db *gorm.DB) *gorm.DB {
+	p.clamp()
+	return db.Offset((p.Page - 1) * p.PageSize).Limit(p.PageSize)
+}
@@ -0,0 +1,34 @@
+package schemas
+
+import "strings"
+
+// commonPasswords are passwords long enough to be valid that are too easy
+// to guess. They are in lowercase.
+var commonPasswords = map[string]bool{
+	"password": true, "password1": true, "password12": true,
+	"password123": true, "password1234": true, "passw0rd": true,
+	"p@ssw0rd": true, "p@ssword": true, "12345678": true, "123456789": true,
+	"1234567890": true, "0123456789": true, "87654321": true,
+	"11111111": true, "00000000": true, "12341234": true, "11223344": true,
+	"123123123": true, "1q2w3e4r": true, "1q2w3e4r5t": true,
+	"qwertyuiop": true, "qwerty123": true, "qwerty12": true,
+	"qwertyui": true, "asdfghjk": true, "asdfghjkl": true, "zxcvbnm1": true,
+	"1qaz2wsx": true, "qazwsxedc": true, "abcd1234": true,
+	"abcdefgh": true, "abc12345": true, "abc123456": true, "iloveyou": true,
+	"iloveyou1": true, "sunshine": true, "princess": true, "football": true,
+	"baseball": true, "basketball": true, "superman": true, "batman123": true,
+	"starwars": true, "trustno1": true, "whatever": true, "letmein1": true,
+	"letmein123": true, "welcome1": true, "welcome123": true,
+	"changeme": true, "changeme1": true, "computer": true, "internet": true,
+	"michelle": true, "jennifer": true, "charlie1": true, "master123": true,
+	"mustang1": true, "shadow123": true, "dragon123": true,
+	"monkey123": true, "minecraft": true, "pokemon1": true, "gamer123": true,
+	"admin123": true, "administrator": true, "loveyou1": true,
+	"secret123": true, "test1234": true, "testing123": true,
+}
+
+// isCommonPassword checks if the password is in the list of common
+// passwords, ignoring the case.
+func isCommonPassword(pw string) bool {
+	return commonPasswords[strings.ToLower(pw)]
+}
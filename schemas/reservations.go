@@ -0,0 +1,167 @@
+package schemas
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/damascopaul/lfg-backend/data"
+
+	log "github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+// reservationTTL is how long a reserved slot is held before it expires.
+const reservationTTL = 5 * time.Minute
+
+var (
+	// ErrReservationExists is returned when the user already holds a slot.
+	ErrReservationExists = errors.New("user already has a reservation")
+	// ErrReservationNotFound is returned when there is no active reservation.
+	ErrReservationNotFound = errors.New("reservation not found or expired")
+)
+
+// Reservation holds a slot in a group for a user until it expires.
+type Reservation struct {
+	ID        int64     `json:"-" gorm:"primaryKey"`
+	GroupID   int64     `json:"group_id" gorm:"not null;uniqueIndex:idx_reservation"`
+	UserID    int64     `json:"user_id" gorm:"not null;uniqueIndex:idx_reservation"`
+	ExpiresAt time.Time `json:"expires_at" gorm:"not null;index"`
+	CreatedAt time.Time `json:"created_at" gorm:"autoCreateTime"`
+
+	DB *gorm.DB `json:"-" gorm:"-"`
+}
+
+// InitDB initializes the database object
+//
+// The queries of the reservation are canceled once the context is done.
+func (r *Reservation) InitDB(ctx context.Context) error {
+	db, err := data.CreateConnection()
+	if err != nil {
+		return err
+	}
+	r.DB = db.WithContext(ctx)
+	dbLog(r.DB).WithFields(log.Fields{"model": "Reservation"}).Info("Initialized database")
+	return nil
+}
+
+// Migrate creates the reservation table based on the struct model
+func (r *Reservation) Migrate() error {
+	if err := r.DB.AutoMigrate(&Reservation{}); err != nil {
+		dbLog(r.DB).WithFields(
+			log.Fields{"model": "Reservation"}).Fatal("Failed to auto migrate model")
+		return err
+	}
+	dbLog(r.DB).WithFields(log.Fields{"model": "Reservation"}).Info("Auto migrated model")
+	return nil
+}
+
+// Create reserves a slot in the group for the user.
+//
+// An expired reservation of the user is replaced by the new one.
+func (r *Reservation) Create() error {
+	r.ExpiresAt = time.Now().Add(reservationTTL).UTC()
+	err := r.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where(
+			"group_id = ? AND user_id = ? AND "+
+				"julianday(expires_at) <= julianday(?)",
+			r.GroupID, r.UserID, time.Now()).Delete(&Reservation{}).Error; err != nil {
+			return err
+		}
+		err := tx.Create(&r).Error
+		if err != nil && strings.Contains(err.Error(), "UNIQUE constraint failed") {
+			return ErrReservationExists
+		}
+		return err
+	})
+	if err != nil {
+		dbLog(r.DB).Errorf("Could not create reservation. Error: %v", err)
+		return err
+	}
+	dbLog(r.DB).Info("Created reservation successfully")
+	return nil
+}
+
+// Confirm converts the active reservation of the user into a membership.
+func (r *Reservation) Confirm() error {
+	err := r.DB.Transaction(func(tx *gorm.DB) error {
+		res := tx.Where(
+			"group_id = ? AND user_id = ? AND "+
+				"julianday(expires_at) > julianday(?)",
+			r.GroupID, r.UserID, time.Now()).Delete(&Reservation{})
+		if res.Error != nil {
+			return res.Error
+		} else if res.RowsAffected == 0 {
+			return ErrReservationNotFound
+		}
+		return tx.Create(&GroupMember{GroupID: r.GroupID, UserID: r.UserID}).Error
+	})
+	if err != nil {
+		dbLog(r.DB).Errorf("Could not confirm reservation. Error: %v", err)
+		return err
+	}
+	dbLog(r.DB).Info("Confirmed reservation successfully")
+	return nil
+}
+
+// Cancel removes the active reservation of the user.
+func (r *Reservation) Cancel() error {
+	res := r.DB.Where(
+		"group_id = ? AND user_id = ? AND "+
+			"julianday(expires_at) > julianday(?)",
+		r.GroupID, r.UserID, time.Now()).Delete(&Reservation{})
+	if res.Error != nil {
+		dbLog(r.DB).Errorf("Could not cancel reservation. Error: %v", res.Error)
+		return res.Error
+	} else if res.RowsAffected == 0 {
+		return ErrReservationNotFound
+	}
+	dbLog(r.DB).Info("Cancelled reservation successfully")
+	return nil
+}
+
+// DeleteExpired removes the reservations that have expired.
+func (r *Reservation) DeleteExpired() (int64, error) {
+	res := r.DB.Where(
+		"julianday(expires_at) <= julianday(?)", time.Now()).Delete(
+		&Reservation{})
+	if res.Error != nil {
+		dbLog(r.DB).Errorf("Could not delete expired reservations. Error: %v", res.Error)
+		return 0, res.Error
+	}
+	dbLog(r.DB).WithFields(log.Fields{
+		"count": res.RowsAffected,
+	}).Info("Deleted expired reservations")
+	return res.RowsAffected, nil
+}
+
+// loadReservedSlots sets the number of active reservations of the groups.
+func loadReservedSlots(db *gorm.DB, groups []*Group) error {
+	ids := make([]int64, len(groups))
+	for i, g := range groups {
+		ids[i] = g.ID
+	}
+
+	var counts []struct {
+		GroupID int64
+		Count   int16
+	}
+	r := db.Model(&Reservation{}).Select("group_id, COUNT(*) AS count").Where(
+		"group_id IN ? AND julianday(expires_at) > julianday(?)", ids,
+		time.Now(),
+	).Group("group_id").Scan(&counts)
+	if r.Error != nil {
+		dbLog(db).Errorf("Could not load reserved slots. Error: %v", r.Error)
+		return r.Error
+	}
+
+	reserved := make(map[int64]int16, len(counts))
+	for _, c := range counts {
+		reserved[c.GroupID] = c.Count
+	}
+	for _, g := range groups {
+		g.ReservedSlots = reserved[g.ID]
+	}
+	return nil
+}
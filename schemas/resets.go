@@ -0,0 +1,159 @@
+package schemas
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"time"
+
+	"github.com/damascopaul/lfg-backend/data"
+
+	log "github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+// passwordResetTTL is how long a reset token can be used after it is issued.
+const passwordResetTTL = 30 * time.Minute
+
+// ErrInvalidResetToken is returned when a reset token is unknown, expired,
+// or already used.
+var ErrInvalidResetToken = errors.New("reset token is invalid or expired")
+
+type PasswordReset struct {
+	ID        int64      `json:"-" gorm:"primaryKey"`
+	UserID    int64      `json:"-" gorm:"not null"`
+	TokenHash string     `json:"-" gorm:"uniqueIndex;not null"`
+	ExpiresAt time.Time  `json:"-" gorm:"not null"`
+	UsedAt    *time.Time `json:"-"`
+	CreatedAt time.Time  `json:"-" gorm:"autoCreateTime"`
+
+	Username    string `json:"username" gorm:"-"`
+	Token       string `json:"token" gorm:"-"`
+	NewPassword string `json:"new_password" gorm:"-"`
+
+	DB *gorm.DB `json:"-" gorm:"-"`
+}
+
+func hashResetToken(t string) string {
+	sum := sha256.Sum256([]byte(t))
+	return hex.EncodeToString(sum[:])
+}
+
+// ValidateForConfirm checks if the reset request is valid for confirmation.
+func (p *PasswordReset) ValidateForConfirm() error {
+	var errors []FieldError
+	if p.Token == "" {
+		// Add a field error if the `token` field is empty
+		errors = append(
+			errors,
+			FieldError{
+				Name:  "token",
+				ID:    "required",
+				Error: "This field is required",
+			})
+	}
+	errors = append(errors, validatePassword("new_password", p.NewPassword)...)
+
+	if len(errors) > 0 {
+		dbLog(p.DB).WithFields(
+			log.Fields{"model": "PasswordReset"}).Warn("Request body is invalid")
+		return &ValidationError{
+			Message: "The request body contains errors",
+			Errors:  errors,
+		}
+	}
+	return nil
+}
+
+// InitDB initializes the database object
+//
+// The queries of the password reset are canceled once the context is done.
+func (p *PasswordReset) InitDB(ctx context.Context) error {
+	db, err := data.CreateConnection()
+	if err != nil {
+		return err
+	}
+	p.DB = db.WithContext(ctx)
+	dbLog(p.DB).WithFields(
+		log.Fields{"model": "PasswordReset"}).Info("Initialized database")
+	return nil
+}
+
+// Migrate creates the password reset table based on the struct model
+func (p *PasswordReset) Migrate() error {
+	if err := p.DB.AutoMigrate(&p); err != nil {
+		dbLog(p.DB).WithFields(
+			log.Fields{"model": "PasswordReset"}).Fatal("Failed to auto migrate model")
+		return err
+	}
+	dbLog(p.DB).WithFields(
+		log.Fields{"model": "PasswordReset"}).Info("Auto migrated model")
+	return nil
+}
+
+// Create issues a new reset token for the user.
+//
+// Only the hash of the token is stored. The raw token is set on the struct
+// so it can be delivered to the user.
+func (p *PasswordReset) Create() error {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		dbLog(p.DB).Errorf("Could not generate reset token. Error: %v", err)
+		return err
+	}
+	p.Token = hex.EncodeToString(b)
+	p.TokenHash = hashResetToken(p.Token)
+	p.ExpiresAt = time.Now().Add(passwordResetTTL).UTC()
+
+	r := p.DB.Create(&p)
+	if r.Error != nil {
+		dbLog(p.DB).Errorf("Could not create password reset. Error: %v", r.Error)
+	} else {
+		dbLog(p.DB).Info("Created password reset successfully")
+	}
+	return r.Error
+}
+
+// Confirm sets the new password of the user that owns the reset token.
+//
+// The token is marked as used in the same transaction so it cannot be
+// reused.
+func (p *PasswordReset) Confirm() error {
+	hashedPw, err := hashPassword(p.NewPassword)
+	if err != nil {
+		return err
+	}
+
+	err = p.DB.Transaction(func(tx *gorm.DB) error {
+		now := time.Now().UTC()
+		r := tx.Where(
+			"token_hash = ? AND used_at IS NULL AND "+
+				"julianday(expires_at) > julianday(?)",
+			hashResetToken(p.Token), now).First(&p)
+		if errors.Is(r.Error, gorm.ErrRecordNotFound) {
+			return ErrInvalidResetToken
+		} else if r.Error != nil {
+			return r.Error
+		}
+
+		// Only one request can mark the token as used.
+		r = tx.Model(&PasswordReset{}).Where(
+			"id = ? AND used_at IS NULL", p.ID).Update("used_at", now)
+		if r.Error != nil {
+			return r.Error
+		} else if r.RowsAffected == 0 {
+			return ErrInvalidResetToken
+		}
+
+		return tx.Model(&User{}).Where("id = ?", p.UserID).Update(
+			"password", hashedPw).Error
+	})
+	if err != nil {
+		dbLog(p.DB).Errorf("Could not confirm password reset. Error: %v", err)
+		return err
+	}
+	dbLog(p.DB).Info("Confirmed password reset successfully")
+	return nil
+}
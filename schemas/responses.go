@@ -0,0 +1,77 @@
+package schemas
+
+import "time"
+
+// CountResponse is the response body of endpoints returning a count.
+type CountResponse struct {
+	Count int64 `json:"count"`
+}
+
+// MessageResponse is the response body of endpoints returning only a message.
+type MessageResponse struct {
+	Message string `json:"message"`
+}
+
+// TimeResponse is the response body of the server time endpoint.
+type TimeResponse struct {
+	Time time.Time `json:"time"`
+	Unix int64     `json:"unix"`
+}
+
+// AvailabilityResponse is the response body of the username availability
+// endpoint.
+type AvailabilityResponse struct {
+	Username  string `json:"username"`
+	Available bool   `json:"available"`
+}
+
+// GroupPage is the response body of the group listing when paging with a
+// cursor.
+type GroupPage struct {
+	// Groups are either groups or the summaries of the private groups the
+	// user cannot see.
+	Groups []interface{} `json:"groups"`
+	// NextCursor is the cursor of the next page. It is empty on the last
+	// page.
+	NextCursor string `json:"next_cursor"`
+}
+
+// GroupSummary is the response body of a private group retrieved by a user
+// who cannot see its details.
+type GroupSummary struct {
+	ID      int64  `json:"id"`
+	Title   string `json:"title"`
+	OwnerID int64  `json:"owner_id"`
+	Private bool   `json:"private"`
+}
+
+// StatusDistribution is the number of groups per status.
+type StatusDistribution struct {
+	Open   int64 `json:"open"`
+	Closed int64 `json:"closed"`
+}
+
+// MyGroupStatus is the relation of the authenticated user with a group.
+type MyGroupStatus struct {
+	// HasPendingInvite is always false since groups have no invites yet.
+	HasPendingInvite  bool `json:"has_pending_invite"`
+	HasPendingRequest bool `json:"has_pending_request"`
+	IsMember          bool `json:"is_member"`
+	IsOwner           bool `json:"is_owner"`
+}
+
+// StatusResponse is the response body of the health check endpoints.
+type StatusResponse struct {
+	Status string `json:"status"`
+}
+
+// GroupUpdate is the message pushed to the clients watching a group.
+type GroupUpdate struct {
+	// Type is one of the types of group activities.
+	Type    string `json:"type"`
+	GroupID int64  `json:"group_id"`
+	ActorID int64  `json:"actor_id"`
+	// TargetID is the user affected by the update, if any.
+	TargetID *int64    `json:"target_id,omitempty"`
+	At       time.Time `json:"at"`
+}
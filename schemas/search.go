@@ -0,0 +1,97 @@
+package schemas
+
+import (
+	"strings"
+	"sync/atomic"
+
+	"gorm.io/gorm"
+)
+
+// groupSearchTable is the FTS5 table indexing the title and the
+// description of the groups.
+const groupSearchTable = "groups_fts"
+
+// ftsUnavailable is set once the SQLite driver is found to be built without
+// FTS5. It cannot change while the server runs.
+var ftsUnavailable atomic.Bool
+
+// groupSearchStatements create the search table and the triggers keeping it
+// in sync with the groups table.
+//
+// Triggers are used instead of model hooks so updates made with plain
+// UPDATE statements are indexed too.
+var groupSearchStatements = []string{
+	`CREATE VIRTUAL TABLE IF NOT EXISTS groups_fts USING fts5(
+		title, description, content='groups', content_rowid='id')`,
+	`CREATE TRIGGER IF NOT EXISTS groups_fts_insert AFTER INSERT ON groups BEGIN
+		INSERT INTO groups_fts(rowid, title, description)
+		VALUES (new.id, new.title, new.description);
+	END`,
+	`CREATE TRIGGER IF NOT EXISTS groups_fts_delete AFTER DELETE ON groups BEGIN
+		INSERT INTO groups_fts(groups_fts, rowid, title, description)
+		VALUES ('delete', old.id, old.title, old.description);
+	END`,
+	`CREATE TRIGGER IF NOT EXISTS groups_fts_update
+	AFTER UPDATE OF title, description ON groups BEGIN
+		INSERT INTO groups_fts(groups_fts, rowid, title, description)
+		VALUES ('delete', old.id, old.title, old.description);
+		INSERT INTO groups_fts(rowid, title, description)
+		VALUES (new.id, new.title, new.description);
+	END`,
+	// Indexes the groups created before the search table.
+	`INSERT INTO groups_fts(groups_fts) VALUES ('rebuild')`,
+}
+
+// migrateGroupSearch creates the full-text search table of the groups if
+// the driver supports FTS5.
+//
+// The search falls back to LIKE otherwise.
+func migrateGroupSearch(db *gorm.DB) error {
+	if ftsUnavailable.Load() || db.Migrator().HasTable(groupSearchTable) {
+		return nil
+	}
+	if !supportsFTS5(db) {
+		ftsUnavailable.Store(true)
+		dbLog(db).Warn("SQLite is built without FTS5. Searching groups with LIKE")
+		return nil
+	}
+	return db.Transaction(func(tx *gorm.DB) error {
+		for _, stmt := range groupSearchStatements {
+			if err := tx.Exec(stmt).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// supportsFTS5 checks if the SQLite driver is built with FTS5.
+//
+// The compile options are checked instead of creating the search table so
+// a driver without FTS5 does not log a failed statement.
+func supportsFTS5(db *gorm.DB) bool {
+	var enabled bool
+	r := db.Raw("SELECT sqlite_compileoption_used('ENABLE_FTS5')").Scan(&enabled)
+	if r.Error != nil {
+		dbLog(db).Errorf("Could not check for FTS5. Error: %v", r.Error)
+		return false
+	}
+	return enabled
+}
+
+// groupSearchEnabled checks if the groups are searched with FTS5.
+func groupSearchEnabled() bool {
+	return !ftsUnavailable.Load()
+}
+
+// ftsQuery turns a search term into an FTS5 query.
+//
+// Each word is quoted so the FTS5 syntax is not interpreted, and matches
+// the words starting with it. All the words have to match.
+func ftsQuery(q string) string {
+	words := strings.Fields(q)
+	for i, w := range words {
+		words[i] = `"` + strings.ReplaceAll(w, `"`, `""`) + `"*`
+	}
+	return strings.Join(words, " ")
+}
@@ -0,0 +1,72 @@
+package schemas
+
+import (
+	"context"
+	"testing"
+)
+
+func TestSearchGroups(t *testing.T) {
+	owner := createTestUser(t)
+	create := func(title, description string) int64 {
+		t.Helper()
+		g := createTestGroup(t, owner, title)
+		if r := g.DB.Model(&g).Update("description", description); r.Error != nil {
+			t.Fatalf("could not update the description: %v", r.Error)
+		}
+		return g.ID
+	}
+	// The groups are created from the best match to the worst so the
+	// newest groups are not ranked first by their age.
+	strong := create(
+		"Quokkaquest night", "Quokkaquest every week, quokkaquest pros only")
+	weak := create("Weekend raid", "Casual runs, maybe some quokkaquest later")
+	inner := create("Megaquokkaquest", "Weekly raid")
+
+	search := func(t *testing.T) []int64 {
+		t.Helper()
+		g := Group{}
+		if err := g.InitDB(context.Background()); err != nil {
+			t.Fatalf("could not init the database: %v", err)
+		}
+		groups, err := g.List(GroupFilters{Query: "quokkaquest"})
+		if err != nil {
+			t.Fatalf("could not search the groups: %v", err)
+		}
+		ids := make([]int64, len(groups))
+		for i, grp := range groups {
+			ids[i] = grp.ID
+		}
+		return ids
+	}
+
+	t.Run("full-text", func(t *testing.T) {
+		if !groupSearchEnabled() {
+			t.Skip("SQLite is built without FTS5. Run with -tags sqlite_fts5")
+		}
+		// The groups are ranked by relevance and only whole words, or the
+		// words starting with the term, match.
+		checkIDs(t, search(t), []int64{strong, weak})
+	})
+
+	t.Run("substring", func(t *testing.T) {
+		defer func(unavailable bool) { ftsUnavailable.Store(unavailable) }(
+			ftsUnavailable.Load())
+		ftsUnavailable.Store(true)
+
+		// Any group containing the term matches, newest first.
+		checkIDs(t, search(t), []int64{inner, weak, strong})
+	})
+}
+
+// checkIDs fails the test if the IDs are not the same in the same order.
+func checkIDs(t *testing.T, got, want []int64) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Fatalf("got IDs %v, want %v", got, want)
+	}
+	for i := range got {
+		if got[i] != want[i] {
+			t.Fatalf("got IDs %v, want %v", got, want)
+		}
+	}
+}
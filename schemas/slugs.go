@@ -0,0 +1,157 @@
+package schemas
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+
+	log "github.com/sirupsen/logrus"
+	"golang.org/x/text/unicode/norm"
+	"gorm.io/gorm"
+)
+
+// maxSlugLen is the length of the longest slug without its suffix.
+const maxSlugLen int = 50
+
+// defaultSlug is the slug of the groups whose title has no letters or
+// digits that can be used in a URL.
+const defaultSlug = "group"
+
+// slugify returns the URL-safe form of the title.
+//
+// Accents are removed and only ASCII letters and digits are kept. The other
+// characters are replaced with a single hyphen.
+func slugify(title string) string {
+	var b strings.Builder
+	hyphen := false
+	for _, r := range norm.NFD.String(strings.ToLower(title)) {
+		if unicode.Is(unicode.Mn, r) {
+			continue
+		} else if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') {
+			if hyphen && b.Len() > 0 {
+				b.WriteByte('-')
+			}
+			b.WriteRune(r)
+			hyphen = false
+		} else {
+			hyphen = true
+		}
+	}
+	slug := b.String()
+	if len(slug) > maxSlugLen {
+		slug = strings.TrimRight(slug[:maxSlugLen], "-")
+	}
+	if slug == "" {
+		return defaultSlug
+	}
+	return slug
+}
+
+// maxSlugAttempts is the number of slugs tried when creating a group before
+// giving up.
+const maxSlugAttempts int = 5
+
+// takenSlugs returns the slugs of the groups that start like the slug.
+//
+// The slugs of the deleted groups stay taken since they can be restored.
+func takenSlugs(tx *gorm.DB, base string) (map[string]bool, error) {
+	var slugs []string
+	r := tx.Unscoped().Model(&Group{}).Where(
+		"slug = ? OR slug LIKE ?", base, base+"-%").Pluck("slug", &slugs)
+	if r.Error != nil {
+		return nil, r.Error
+	}
+	taken := make(map[string]bool, len(slugs))
+	for _, s := range slugs {
+		taken[s] = true
+	}
+	return taken, nil
+}
+
+// nextSlug returns the first slug that is not taken.
+//
+// A number is added to the slug if it is taken, starting from 2.
+func nextSlug(base string, taken map[string]bool) string {
+	slug := base
+	for n := 2; taken[slug]; n++ {
+		slug = fmt.Sprintf("%v-%v", base, n)
+	}
+	return slug
+}
+
+// uniqueSlug returns a slug for the title that no other group has.
+func uniqueSlug(tx *gorm.DB, title string) (string, error) {
+	base := slugify(title)
+	taken, err := takenSlugs(tx, base)
+	if err != nil {
+		return "", err
+	}
+	return nextSlug(base, taken), nil
+}
+
+// isSlugTaken checks if the error is caused by a slug another group has.
+func isSlugTaken(err error) bool {
+	return err != nil && strings.Contains(
+		err.Error(), "UNIQUE constraint failed: groups.slug")
+}
+
+// createWithUniqueSlug adds the group with a slug that no other group has.
+//
+// Another group can take the slug between the check and the insert. The
+// next slug is tried if it does.
+func createWithUniqueSlug(tx *gorm.DB, g *Group) error {
+	base := slugify(g.Title)
+	taken, err := takenSlugs(tx, base)
+	if err != nil {
+		return err
+	}
+	for attempt := 1; ; attempt++ {
+		g.Slug = nextSlug(base, taken)
+		err := tx.Omit("Tags", "Members").Create(g).Error
+		if !isSlugTaken(err) || attempt == maxSlugAttempts {
+			return err
+		}
+		taken[g.Slug] = true
+	}
+}
+
+// backfillSlugs sets the slugs of the groups created before slugs were
+// added.
+func backfillSlugs(db *gorm.DB) error {
+	var groups []Group
+	r := db.Unscoped().Select("id", "title").Where("slug = ''").Order(
+		"id").Find(&groups)
+	if r.Error != nil {
+		return r.Error
+	}
+	for _, g := range groups {
+		err := db.Transaction(func(tx *gorm.DB) error {
+			slug, err := uniqueSlug(tx, g.Title)
+			if err != nil {
+				return err
+			}
+			return tx.Unscoped().Model(&Group{}).Where("id = ?", g.ID).Update(
+				"slug", slug).Error
+		})
+		if err != nil {
+			return err
+		}
+	}
+	if len(groups) > 0 {
+		dbLog(db).WithFields(log.Fields{
+			"model": "Group", "groups": len(groups)}).Info("Backfilled slugs")
+	}
+	return nil
+}
+
+// RetrieveBySlug returns the group details from the database given its slug.
+//
+// The returned Group includes the password value like RetrieveWithPassword.
+func (g *Group) RetrieveBySlug() error {
+	r := g.DB.Model(&Group{}).Select("id").Where("slug = ?", g.Slug).Take(&g.ID)
+	if r.Error != nil {
+		dbLog(g.DB).Errorf("Could not find group by slug. Error: %v", r.Error)
+		return r.Error
+	}
+	return g.RetrieveWithPassword()
+}
@@ -0,0 +1,77 @@
+package schemas
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"gorm.io/gorm"
+)
+
+func TestSlugify(t *testing.T) {
+	for _, tc := range []struct {
+		title string
+		want  string
+	}{
+		{"Raid Night", "raid-night"},
+		{"  Élan -- Vital!  ", "elan-vital"},
+		{"Señor's 2nd run", "senor-s-2nd-run"},
+		{"!!!", defaultSlug},
+		{"日本語", defaultSlug},
+		{strings.Repeat("a", 49) + " b", strings.Repeat("a", 49)},
+	} {
+		if got := slugify(tc.title); got != tc.want {
+			t.Errorf("slugify(%q) = %q, want %q", tc.title, got, tc.want)
+		}
+	}
+}
+
+func TestCreateNumbersTakenSlugs(t *testing.T) {
+	owner := createTestUser(t)
+	want := []string{"slug-collision", "slug-collision-2", "slug-collision-3"}
+	for _, slug := range want {
+		if g := createTestGroup(t, owner, "Slug collision"); g.Slug != slug {
+			t.Errorf("got slug %q, want %q", g.Slug, slug)
+		}
+	}
+}
+
+func TestCreateRetriesSlugTakenMeanwhile(t *testing.T) {
+	owner := createTestUser(t)
+	g := Group{
+		Title: "Slug race", Description: "Weekly raid", MaxSize: 5,
+		OwnerID: owner.ID, Visibility: GroupVisibilityPublic,
+	}
+	if err := g.InitDB(context.Background()); err != nil {
+		t.Fatalf("could not init the database: %v", err)
+	}
+
+	// Another group takes the slug between the check and the insert.
+	stolen := false
+	const name = "test:take_slug"
+	err := g.DB.Callback().Create().Before("gorm:create").Register(
+		name, func(tx *gorm.DB) {
+			if stolen || tx.Statement.Table != "groups" {
+				return
+			}
+			stolen = true
+			tx.Session(&gorm.Session{NewDB: true}).Exec(
+				"INSERT INTO groups (title, description, owner_id, slug) "+
+					"VALUES (?, ?, ?, ?)",
+				"Slug race", "Weekly raid", owner.ID, "slug-race")
+		})
+	if err != nil {
+		t.Fatalf("could not register the callback: %v", err)
+	}
+	defer g.DB.Callback().Create().Remove(name)
+
+	if err := g.Create(); err != nil {
+		t.Fatalf("could not create the group: %v", err)
+	}
+	if !stolen {
+		t.Fatal("the slug was not taken")
+	}
+	if g.Slug != "slug-race-2" {
+		t.Errorf("got slug %q, want slug-race-2", g.Slug)
+	}
+}
@@ -0,0 +1,159 @@
+package schemas
+
+import (
+	"fmt"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// timeseriesBuckets maps the supported bucket sizes to their date format.
+var timeseriesBuckets = map[string]string{
+	"day":   "%Y-%m-%d",
+	"month": "%Y-%m",
+}
+
+// maxTimeseriesWindow is the longest time window that can be requested.
+const maxTimeseriesWindow = 366 * 24 * time.Hour
+
+// GroupTimeseriesQuery are the query parameters of the group timeseries.
+type GroupTimeseriesQuery struct {
+	From   string `form:"from"`
+	To     string `form:"to"`
+	Bucket string `form:"bucket"`
+
+	from time.Time
+	to   time.Time
+}
+
+// TimeseriesBucket is the number of entries created within a time bucket.
+type TimeseriesBucket struct {
+	Bucket string `json:"bucket"`
+	Count  int64  `json:"count"`
+}
+
+// Validate checks if the time window and bucket size are valid.
+//
+// The `from` and `to` values are RFC3339 timestamps. The window includes
+// `from` and excludes `to`.
+func (q *GroupTimeseriesQuery) Validate() error {
+	const FieldIsReqMsg string = "This field is required"
+	const InvalidTimeMsg string = "This field should be an RFC3339 timestamp"
+	var errors []FieldError
+
+	var err error
+	if q.From == "" {
+		errors = append(errors, FieldError{
+			Name: "from", ID: "required", Error: FieldIsReqMsg})
+	} else if q.from, err = time.Parse(time.RFC3339, q.From); err != nil {
+		errors = append(errors, FieldError{
+			Name: "from", ID: "invalid_time", Error: InvalidTimeMsg})
+	}
+	if q.To == "" {
+		errors = append(errors, FieldError{
+			Name: "to", ID: "required", Error: FieldIsReqMsg})
+	} else if q.to, err = time.Parse(time.RFC3339, q.To); err != nil {
+		errors = append(errors, FieldError{
+			Name: "to", ID: "invalid_time", Error: InvalidTimeMsg})
+	}
+	if len(errors) == 0 {
+		if !q.from.Before(q.to) {
+			// Add a field error if the window is empty
+			errors = append(
+				errors,
+				FieldError{
+					Name:   "to",
+					ID:     "later_than",
+					Params: []interface{}{"from"},
+					Error:  "This field should be after `from`",
+				})
+		} else if q.to.Sub(q.from) > maxTimeseriesWindow {
+			// Add a field error if the window is too long
+			errors = append(
+				errors,
+				FieldError{
+					Name:   "to",
+					ID:     "window_too_long",
+					Params: []interface{}{int64(maxTimeseriesWindow / (24 * time.Hour))},
+					Error: fmt.Sprintf(
+						"The window cannot be longer than %v days",
+						int64(maxTimeseriesWindow/(24*time.Hour))),
+				})
+		}
+	}
+
+	if q.Bucket == "" {
+		q.Bucket = "day"
+	}
+	if _, ok := timeseriesBuckets[q.Bucket]; !ok {
+		errors = append(
+			errors,
+			FieldError{
+				Name:   "bucket",
+				ID:     "one_of",
+				Params: []interface{}{"day, month"},
+				Error:  "The value should be one of: day, month",
+			})
+	}
+
+	if len(errors) > 0 {
+		log.WithFields(
+			log.Fields{"model": "GroupTimeseriesQuery"}).Warn("Query is invalid")
+		return &ValidationError{
+			Message: "The query parameters contain errors",
+			Errors:  errors,
+		}
+	}
+	return nil
+}
+
+// Timeseries counts the groups created per time bucket within the window.
+//
+// Buckets without any group are not included.
+func (g *Group) Timeseries(q GroupTimeseriesQuery) ([]TimeseriesBucket, error) {
+	buckets := []TimeseriesBucket{}
+	r := g.DB.Model(&Group{}).Select(
+		"strftime(?, created_at) AS bucket, COUNT(*) AS count",
+		timeseriesBuckets[q.Bucket],
+	).Where(
+		"julianday(created_at) >= julianday(?) AND "+
+			"julianday(created_at) < julianday(?)", q.from.UTC(), q.to.UTC(),
+	).Group("bucket").Order("bucket").Scan(&buckets)
+	if r.Error != nil {
+		dbLog(g.DB).Errorf("Could not compute group timeseries. Error: %v", r.Error)
+	} else {
+		dbLog(g.DB).Info("Computed group timeseries successfully")
+	}
+	return buckets, r.Error
+}
+
+// CategoryStats are the aggregates of the open groups of a category.
+type CategoryStats struct {
+	Category   string `json:"category"`
+	OpenGroups int64  `json:"open_groups"`
+	// OpenSlots is the number of members the open groups can still take.
+	OpenSlots int64 `json:"open_slots"`
+}
+
+// CategoryStats computes the aggregates of the open groups per category.
+//
+// Groups without a category are not included.
+func (g *Group) CategoryStats() ([]CategoryStats, error) {
+	stats := []CategoryStats{}
+	members := g.DB.Model(&GroupMember{}).Select(
+		"group_id, COUNT(*) AS count").Group("group_id")
+	r := g.DB.Model(&Group{}).Select(
+		"groups.category AS category, COUNT(*) AS open_groups, "+
+			"SUM(MAX(groups.max_size - 1 - COALESCE(m.count, 0), 0)) AS open_slots",
+	).Joins(
+		"LEFT JOIN (?) AS m ON m.group_id = groups.id", members,
+	).Where(
+		"groups.status = ? AND groups.category <> ''", GroupStatusOpen,
+	).Group("groups.category").Order("groups.category").Scan(&stats)
+	if r.Error != nil {
+		dbLog(g.DB).Errorf("Could not compute category stats. Error: %v", r.Error)
+	} else {
+		dbLog(g.DB).Info("Computed category stats successfully")
+	}
+	return stats, r.Error
+}
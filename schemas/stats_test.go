@@ -0,0 +1,93 @@
+package schemas
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"golang.org/x/exp/slices"
+)
+
+func TestTimeseriesCountsGroupsPerDay(t *testing.T) {
+	owner := createTestUser(t)
+	for _, created := range []string{
+		"1999-03-01T00:00:00Z",
+		"1999-03-01T23:59:59Z",
+		"1999-03-03T12:00:00Z",
+		"1999-03-04T00:00:00Z", // Outside of the window.
+	} {
+		g := createTestGroup(t, owner, "Timeseries")
+		at, _ := time.Parse(time.RFC3339, created)
+		if r := g.DB.Model(&Group{}).Where("id = ?", g.ID).Update(
+			"created_at", at); r.Error != nil {
+			t.Fatalf("could not set the creation time: %v", r.Error)
+		}
+	}
+
+	q := GroupTimeseriesQuery{
+		From: "1999-03-01T00:00:00Z", To: "1999-03-04T00:00:00Z",
+	}
+	if err := q.Validate(); err != nil {
+		t.Fatalf("got error %v for a valid query", err)
+	}
+	g := Group{}
+	if err := g.InitDB(context.Background()); err != nil {
+		t.Fatalf("could not init the database: %v", err)
+	}
+	buckets, err := g.Timeseries(q)
+	if err != nil {
+		t.Fatalf("could not compute the timeseries: %v", err)
+	}
+	want := []TimeseriesBucket{
+		{Bucket: "1999-03-01", Count: 2},
+		{Bucket: "1999-03-03", Count: 1},
+	}
+	if !slices.Equal(buckets, want) {
+		t.Errorf("got buckets %v, want %v", buckets, want)
+	}
+}
+
+func TestCategoryStatsAggregatesOpenGroups(t *testing.T) {
+	owner := createTestUser(t)
+	create := func(category string, members int, status GroupStatus) Group {
+		t.Helper()
+		g := createTestGroup(t, owner, "Category raid")
+		for i := 0; i < members; i++ {
+			if err := g.Join(createTestUser(t).ID, ""); err != nil {
+				t.Fatalf("could not join the group: %v", err)
+			}
+		}
+		if r := g.DB.Model(&g).Updates(map[string]interface{}{
+			"category": category, "status": status}); r.Error != nil {
+			t.Fatalf("could not update the group: %v", r.Error)
+		}
+		return g
+	}
+	create("heliotrope", 2, GroupStatusOpen)
+	create("heliotrope", 0, GroupStatusOpen)
+	create("heliotrope", 1, GroupStatusClosed)
+	create("verbena", 4, GroupStatusOpen)
+	deleted := create("verbena", 0, GroupStatusOpen)
+	if err := deleted.Delete(); err != nil {
+		t.Fatalf("could not delete the group: %v", err)
+	}
+
+	g := Group{DB: owner.DB}
+	stats, err := g.CategoryStats()
+	if err != nil {
+		t.Fatalf("could not compute the stats: %v", err)
+	}
+	got := map[string]CategoryStats{}
+	for _, s := range stats {
+		got[s.Category] = s
+	}
+	// Groups of 5 have 4 slots besides the owner.
+	for _, want := range []CategoryStats{
+		{Category: "heliotrope", OpenGroups: 2, OpenSlots: 2 + 4},
+		{Category: "verbena", OpenGroups: 1, OpenSlots: 0},
+	} {
+		if got[want.Category] != want {
+			t.Errorf("got %+v, want %+v", got[want.Category], want)
+		}
+	}
+}
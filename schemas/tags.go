@@ -0,0 +1,154 @@
+package schemas
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"gorm.io/gorm"
+)
+
+// Limits of the tags of a group.
+const (
+	maxGroupTags int = 5
+	maxTagLen    int = 20
+)
+
+// Tag is a label used to find groups.
+//
+// A tag is written as its name in JSON.
+type Tag struct {
+	ID   int64  `gorm:"primaryKey"`
+	Name string `gorm:"not null;uniqueIndex"`
+}
+
+// GroupTag is the join model between a group and its tags.
+type GroupTag struct {
+	GroupID int64 `gorm:"primaryKey"`
+	TagID   int64 `gorm:"primaryKey"`
+}
+
+// MarshalJSON writes the tag as its name.
+func (t Tag) MarshalJSON() ([]byte, error) {
+	return json.Marshal(t.Name)
+}
+
+// UnmarshalJSON reads the tag from its name.
+func (t *Tag) UnmarshalJSON(b []byte) error {
+	return json.Unmarshal(b, &t.Name)
+}
+
+// normalizeTag returns the form of the tag stored in the database.
+//
+// Tags are case-insensitive so they are stored in lowercase.
+func normalizeTag(name string) string {
+	return strings.ToLower(strings.TrimSpace(name))
+}
+
+// normalizeTags normalizes the names of the tags and removes the duplicates.
+//
+// The result is never nil so an empty list of tags clears the tags.
+func normalizeTags(tags []Tag) []Tag {
+	normalized := make([]Tag, 0, len(tags))
+	seen := make(map[string]bool, len(tags))
+	for _, t := range tags {
+		name := normalizeTag(t.Name)
+		if seen[name] {
+			continue
+		}
+		seen[name] = true
+		normalized = append(normalized, Tag{Name: name})
+	}
+	return normalized
+}
+
+// validateTags returns the field errors of the tags of a group.
+func validateTags(tags []Tag) []FieldError {
+	if len(tags) > maxGroupTags {
+		return []FieldError{{
+			Name:   "tags",
+			ID:     "too_many_tags",
+			Params: []interface{}{maxGroupTags},
+			Error:  fmt.Sprintf("There cannot be more than %v tags", maxGroupTags),
+		}}
+	}
+	for _, t := range tags {
+		if t.Name == "" || utf8.RuneCountInString(t.Name) > maxTagLen {
+			return []FieldError{{
+				Name:   "tags",
+				ID:     "tag_length",
+				Params: []interface{}{maxTagLen},
+				Error: fmt.Sprintf(
+					"Each tag should be 1 to %v characters long", maxTagLen),
+			}}
+		}
+	}
+	return nil
+}
+
+// resolveTags sets the IDs of the tags, creating the tags that are new.
+func resolveTags(tx *gorm.DB, tags []Tag) error {
+	for i := range tags {
+		if r := tx.Where(Tag{Name: tags[i].Name}).FirstOrCreate(
+			&tags[i]); r.Error != nil {
+			return r.Error
+		}
+	}
+	return nil
+}
+
+// tagFilter returns the condition matching the groups with the tag.
+func tagFilter(db *gorm.DB, name string) *gorm.DB {
+	return db.Model(&GroupTag{}).Select("group_tags.group_id").Joins(
+		"JOIN tags ON tags.id = group_tags.tag_id").Where("tags.name = ?", name)
+}
+
+// loadTags sets the tags of the groups.
+func loadTags(db *gorm.DB, groups []*Group) error {
+	ids := make([]int64, len(groups))
+	for i, g := range groups {
+		ids[i] = g.ID
+	}
+
+	var rows []struct {
+		GroupID int64
+		TagID   int64
+		Name    string
+	}
+	r := db.Model(&GroupTag{}).Select(
+		"group_tags.group_id, group_tags.tag_id, tags.name").Joins(
+		"JOIN tags ON tags.id = group_tags.tag_id").Where(
+		"group_tags.group_id IN ?", ids).Order("tags.name").Scan(&rows)
+	if r.Error != nil {
+		dbLog(db).Errorf("Could not load tags. Error: %v", r.Error)
+		return r.Error
+	}
+
+	tags := make(map[int64][]Tag, len(groups))
+	for _, row := range rows {
+		tags[row.GroupID] = append(tags[row.GroupID], Tag{ID: row.TagID, Name: row.Name})
+	}
+	for _, g := range groups {
+		g.Tags = tags[g.ID]
+	}
+	return nil
+}
+
+// saveGroupTags replaces the tags of the group with the tags set on it.
+func saveGroupTags(tx *gorm.DB, g *Group) error {
+	if err := resolveTags(tx, g.Tags); err != nil {
+		return err
+	}
+	if r := tx.Where("group_id = ?", g.ID).Delete(&GroupTag{}); r.Error != nil {
+		return r.Error
+	}
+	if len(g.Tags) == 0 {
+		return nil
+	}
+	rows := make([]GroupTag, len(g.Tags))
+	for i, t := range g.Tags {
+		rows[i] = GroupTag{GroupID: g.ID, TagID: t.ID}
+	}
+	return tx.Create(&rows).Error
+}
@@ -0,0 +1,147 @@
+package schemas
+
+import (
+	"math"
+	"sort"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	// trendingWindow is how far back the views and joins of a group count
+	// toward its trending score.
+	trendingWindow = 7 * 24 * time.Hour
+	// trendingJoinWeight is the number of views a join is worth.
+	trendingJoinWeight = 5
+	// trendingGravity is how fast the score of a group decays with its age.
+	trendingGravity = 0.8
+)
+
+// viewDayLayout is the format of the days of the daily view counts.
+const viewDayLayout = "2006-01-02"
+
+// GroupViewDay is the number of views of a group on a day in UTC.
+type GroupViewDay struct {
+	GroupID int64  `gorm:"primaryKey"`
+	Day     string `gorm:"primaryKey"`
+	Views   int64  `gorm:"not null;default:0"`
+}
+
+// TrendingQuery are the query parameters of the trending groups.
+type TrendingQuery struct {
+	Limit int `form:"limit"`
+
+	// Limits are the number of groups allowed in the response.
+	Limits PageLimits `form:"-"`
+	// ExpandMembers loads the member list of the groups if set.
+	ExpandMembers bool `form:"-"`
+}
+
+// clamp keeps the limit within its allowed range.
+func (q *TrendingQuery) clamp() {
+	p := Pagination{PageSize: q.Limit, Limits: q.Limits}
+	p.clamp()
+	q.Limit = p.PageSize
+}
+
+// TrendingScore ranks a group by its recent views and joins.
+//
+// A quiet group still scores above zero so it is ranked by its age. The
+// score decays with the age of the group so newer groups rank higher than
+// older groups with the same activity.
+func TrendingScore(recentViews, recentJoins int64, age time.Duration) float64 {
+	activity := 1 + float64(recentViews) + trendingJoinWeight*float64(recentJoins)
+	hours := math.Max(age.Hours(), 0)
+	return activity / math.Pow(hours+2, trendingGravity)
+}
+
+// countViewDay adds a view of the group to the view count of the day.
+func countViewDay(tx *gorm.DB, groupID int64, at time.Time) error {
+	return tx.Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "group_id"}, {Name: "day"}},
+		DoUpdates: clause.Assignments(map[string]interface{}{
+			"views": gorm.Expr("group_view_days.views + 1"),
+		}),
+	}).Create(&GroupViewDay{
+		GroupID: groupID, Day: at.UTC().Format(viewDayLayout), Views: 1,
+	}).Error
+}
+
+// ListTrending gets the open groups with the highest trending score.
+//
+// The scores of all the open groups are computed on each call, which is
+// fine for the number of open groups at a time.
+func (g *Group) ListTrending(q TrendingQuery) ([]Group, error) {
+	q.clamp()
+	now := time.Now().UTC()
+	cutoff := now.Add(-trendingWindow)
+
+	views := g.DB.Model(&GroupViewDay{}).Select(
+		"group_id, SUM(views) AS views").Where(
+		"day >= ?", cutoff.Format(viewDayLayout)).Group("group_id")
+	joins := g.DB.Model(&GroupMember{}).Select(
+		"group_id, COUNT(*) AS joins").Where(
+		"julianday(joined_at) >= julianday(?)", cutoff).Group("group_id")
+	var stats []struct {
+		ID        int64
+		CreatedAt time.Time
+		Views     int64
+		Joins     int64
+	}
+	r := g.DB.Model(&Group{}).Select(
+		"groups.id, groups.created_at, "+
+			"COALESCE(v.views, 0) AS views, COALESCE(j.joins, 0) AS joins",
+	).Joins(
+		"LEFT JOIN (?) AS v ON v.group_id = groups.id", views,
+	).Joins(
+		"LEFT JOIN (?) AS j ON j.group_id = groups.id", joins,
+	).Where(
+		"groups.status = ? AND groups.visibility = ?",
+		GroupStatusOpen, GroupVisibilityPublic,
+	).Scan(&stats)
+	if r.Error != nil {
+		dbLog(g.DB).Errorf("Could not score trending groups. Error: %v", r.Error)
+		return []Group{}, r.Error
+	}
+
+	scores := make(map[int64]float64, len(stats))
+	ids := make([]int64, len(stats))
+	for i, s := range stats {
+		scores[s.ID] = TrendingScore(s.Views, s.Joins, now.Sub(s.CreatedAt))
+		ids[i] = s.ID
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		if scores[ids[i]] != scores[ids[j]] {
+			return scores[ids[i]] > scores[ids[j]]
+		}
+		return ids[i] > ids[j]
+	})
+	if len(ids) > q.Limit {
+		ids = ids[:q.Limit]
+	}
+
+	groups := []Group{}
+	if len(ids) == 0 {
+		return groups, nil
+	}
+	r = preloadMembers(g.DB, q.ExpandMembers).Select(listFields).Find(
+		&groups, ids)
+	if r.Error != nil {
+		dbLog(g.DB).Errorf("Could not list trending groups. Error: %v", r.Error)
+		return groups, r.Error
+	}
+	sort.Slice(groups, func(i, j int) bool {
+		return scores[groups[i].ID] > scores[groups[j].ID] ||
+			scores[groups[i].ID] == scores[groups[j].ID] &&
+				groups[i].ID > groups[j].ID
+	})
+	dbLog(g.DB).Info("Listed trending groups successfully")
+
+	refs := make([]*Group, len(groups))
+	for i := range groups {
+		refs[i] = &groups[i]
+	}
+	return groups, loadGroupDetails(g.DB, refs)
+}
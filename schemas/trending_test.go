@@ -0,0 +1,39 @@
+package schemas
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTrendingScore(t *testing.T) {
+	for _, tc := range []struct {
+		name          string
+		higher, lower float64
+	}{
+		{
+			"more views",
+			TrendingScore(10, 0, time.Hour),
+			TrendingScore(0, 0, time.Hour),
+		},
+		{
+			"a join is worth more than a view",
+			TrendingScore(0, 1, time.Hour),
+			TrendingScore(1, 0, time.Hour),
+		},
+		{
+			"newer with the same activity",
+			TrendingScore(5, 1, time.Hour),
+			TrendingScore(5, 1, 48*time.Hour),
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if tc.higher <= tc.lower {
+				t.Errorf("got %v, want it above %v", tc.higher, tc.lower)
+			}
+		})
+	}
+
+	if score := TrendingScore(0, 0, 1000*time.Hour); score <= 0 {
+		t.Errorf("got score %v for a quiet old group, want it above zero", score)
+	}
+}
@@ -1,8 +1,13 @@
 package schemas
 
 import (
+	"context"
 	"fmt"
+	"net/mail"
+	"regexp"
+	"strings"
 	"time"
+	"unicode/utf8"
 
 	"github.com/damascopaul/lfg-backend/data"
 
@@ -12,12 +17,32 @@ import (
 )
 
 type User struct {
-	ID           int64     `json:"id" gorm:"primaryKey"`
-	Username     string    `json:"username" gorm:"unique"`
-	Password     string    `json:"password,omitempty"`
-	CreatedAt    time.Time `json:"created_at" gorm:"autoCreateTime"`
-	MyGroups     []Group   `json:"-" gorm:"foreignKey:OwnerID"`
-	JoinedGroups []Group   `json:"-" gorm:"many2many:joined_groups"`
+	ID int64 `json:"id" gorm:"primaryKey"`
+	// Username is unique regardless of its case. The lookups match the
+	// lowercase username so the index on it is used.
+	Username    string `json:"username" gorm:"unique;uniqueIndex:idx_users_username_lower,expression:LOWER(username)"`
+	Password    string `json:"password,omitempty"`
+	DisplayName string `json:"display_name,omitempty"`
+	Bio         string `json:"bio,omitempty"`
+	// Email is optional. It can be used instead of the username to sign in.
+	Email     *string   `json:"email,omitempty" gorm:"uniqueIndex"`
+	CreatedAt time.Time `json:"created_at" gorm:"autoCreateTime"`
+	IsAdmin   bool      `json:"-" gorm:"not null;default:false"`
+	// TokenVersion is embedded in the tokens of the user. Tokens with an
+	// older version are rejected.
+	TokenVersion int64 `json:"-" gorm:"not null;default:0"`
+	// FailedSignIns is the number of consecutive failed sign ins.
+	FailedSignIns int `json:"-" gorm:"not null;default:0"`
+	// LockedUntil is when the user can sign in again after too many failed
+	// sign ins.
+	LockedUntil  *time.Time `json:"-"`
+	MyGroups     []Group    `json:"-" gorm:"foreignKey:OwnerID"`
+	JoinedGroups []Group    `json:"-" gorm:"many2many:joined_groups"`
+	Label        string     `json:"label,omitempty" gorm:"-"`
+	Role         string     `json:"role,omitempty" gorm:"-"`
+	JoinedAt     *time.Time `json:"joined_at,omitempty" gorm:"-"`
+	// Identifier is the username or the email used to sign in.
+	Identifier string `json:"identifier,omitempty" gorm:"-"`
 
 	DB *gorm.DB `json:"-" gorm:"-"`
 }
@@ -27,103 +52,375 @@ type TokenResponse struct {
 	User  User   `json:"user"`
 }
 
-// ValidateForSignUp checks if the user struct is valid for sign up.
-func (u *User) ValidateForSignUp() error {
+// Capabilities summarizes what the user is allowed to do.
+type Capabilities struct {
+	IsAdmin        bool `json:"is_admin"`
+	CanCreateGroup bool `json:"can_create_group"`
+	// RemainingGroupQuota is null if the user has no group limit.
+	RemainingGroupQuota *int64 `json:"remaining_group_quota"`
+}
+
+// ProfileChanges is the request body for updating the profile of a user.
+//
+// Fields left out of the request are nil and are not changed. The username
+// cannot be changed.
+type ProfileChanges struct {
+	DisplayName *string `json:"display_name"`
+	Bio         *string `json:"bio"`
+}
+
+// PasswordChange is the request body for changing the password of a user.
+type PasswordChange struct {
+	CurrentPassword string `json:"current_password"`
+	NewPassword     string `json:"new_password"`
+}
+
+// usernamePattern is the set of characters allowed in a username.
+var usernamePattern = regexp.MustCompile(`^[a-zA-Z0-9_.-]+$`)
+
+// normalizeUsername returns the form of the username stored in the database.
+//
+// Usernames are case-insensitive so they are stored in lowercase. The
+// surrounding whitespace is not part of the username.
+func normalizeUsername(username string) string {
+	return strings.ToLower(strings.TrimSpace(username))
+}
+
+// validateUsername returns the field errors of a username value.
+func validateUsername(username string) []FieldError {
 	const FieldIsReqMsg string = "This field is required"
 	const (
+		minUsernameLen int = 3
 		maxUsernameLen int = 50
-		minPasswordLen int = 8
-		maxPasswordLen int = 200
 	)
 	var errors []FieldError
-	if u.Username == "" {
+	if username == "" {
 		// Add a field error if the `username` field is empty
 		errors = append(
 			errors,
 			FieldError{
 				Name:  "username",
+				ID:    "required",
 				Error: FieldIsReqMsg,
 			})
-	} else if len(u.Username) > maxUsernameLen {
-		// Add a field error if the `username` has more than 50 characters
+	} else if n := utf8.RuneCountInString(username); n < minUsernameLen || n > maxUsernameLen {
+		// Add a field error if the `username` is too short or too long
 		errors = append(
 			errors,
 			FieldError{
-				Name: "username",
+				Name:   "username",
+				ID:     "length_range",
+				Params: []interface{}{minUsernameLen, maxUsernameLen},
 				Error: fmt.Sprintf(
-					"This field cannot be more than %v characters long", maxUsernameLen),
+					"This field has to be %v to %v characters long",
+					minUsernameLen, maxUsernameLen),
 			})
+	} else if !usernamePattern.MatchString(username) {
+		// Add a field error if the `username` has characters that are not allowed
+		errors = append(
+			errors,
+			FieldError{
+				Name: "username",
+				ID:   "username_characters",
+				Error: "This field can only contain letters, numbers, " +
+					"underscores, periods, and hyphens",
+			})
+	}
+	return errors
+}
+
+// SignUpRules are the configurable checks of the details used to sign up.
+type SignUpRules struct {
+	// ReservedUsernames are the usernames that cannot be claimed. They are
+	// compared with the normalized username.
+	ReservedUsernames []string
+	// BlockCommonPasswords rejects common passwords and passwords that are
+	// the same as the username.
+	BlockCommonPasswords bool
+}
+
+// validateReservedUsername returns the field errors of a username that is
+// reserved.
+func (r SignUpRules) validateReservedUsername(username string) []FieldError {
+	username = normalizeUsername(username)
+	for _, reserved := range r.ReservedUsernames {
+		if normalizeUsername(reserved) == username {
+			return []FieldError{{
+				Name:  "username",
+				ID:    "reserved_username",
+				Error: "This username is reserved",
+			}}
+		}
+	}
+	return nil
+}
+
+// validateWeakPassword returns the field errors of a password that is easy
+// to guess.
+func (r SignUpRules) validateWeakPassword(username, pw string) []FieldError {
+	if !r.BlockCommonPasswords {
+		return nil
+	}
+	if strings.EqualFold(pw, username) {
+		return []FieldError{{
+			Name:  "password",
+			ID:    "same_as_username",
+			Error: "This field cannot be the same as the username",
+		}}
+	}
+	if isCommonPassword(pw) {
+		return []FieldError{{
+			Name:  "password",
+			ID:    "common_password",
+			Error: "This password is too common",
+		}}
+	}
+	return nil
+}
+
+// normalizeEmail returns the form of the email stored in the database.
+func normalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+// validateEmail returns the field errors of an email value.
+func validateEmail(email string) []FieldError {
+	const maxEmailLen int = 254
+	addr, err := mail.ParseAddress(email)
+	if err != nil || addr.Address != email {
+		// Add a field error if the `email` is not a plain email address
+		return []FieldError{{
+			Name:  "email",
+			ID:    "invalid_email",
+			Error: "This field has to be a valid email address",
+		}}
+	} else if utf8.RuneCountInString(email) > maxEmailLen {
+		// Add a field error if the `email` is too long
+		return []FieldError{{
+			Name:   "email",
+			ID:     "too_long",
+			Params: []interface{}{maxEmailLen},
+			Error: fmt.Sprintf(
+				"This field cannot be more than %v characters long", maxEmailLen),
+		}}
 	}
-	// TODO: Add more robust validation for username
+	return nil
+}
 
-	if u.Password == "" {
-		// Add a field error if the `description` field is empty
+// validatePassword returns the field errors of a password value.
+func validatePassword(name string, pw string) []FieldError {
+	const FieldIsReqMsg string = "This field is required"
+	const (
+		minPasswordLen int = 8
+		maxPasswordLen int = 200
+	)
+	var errors []FieldError
+	if pw == "" {
+		// Add a field error if the password field is empty
 		errors = append(
 			errors,
 			FieldError{
-				Name:  "password",
+				Name:  name,
+				ID:    "required",
 				Error: FieldIsReqMsg,
 			})
-	} else if len(u.Password) < minPasswordLen ||
-		len(u.Password) > maxPasswordLen {
-		// Add a field error if the `password` has more than 8 characters
+	} else if n := utf8.RuneCountInString(pw); n < minPasswordLen || n > maxPasswordLen {
+		// Add a field error if the password is too short or too long
 		errors = append(
 			errors,
 			FieldError{
-				Name: "password",
+				Name:   name,
+				ID:     "length_range",
+				Params: []interface{}{minPasswordLen, maxPasswordLen},
 				Error: fmt.Sprintf(
 					"This field has to be %v to %v characters long",
-					minPasswordLen, maxUsernameLen),
+					minPasswordLen, maxPasswordLen),
 			})
 	}
-	// TODO: Add more robust validation for password
+	return errors
+}
+
+// hashPassword hashes a password for storing in the database.
+func hashPassword(pw string) (string, error) {
+	hashedPw, err := bcrypt.GenerateFromPassword([]byte(pw), bcrypt.MinCost)
+	if err != nil {
+		log.WithFields(log.Fields{
+			"error": err.Error(),
+		}).Error("Could not hash user password")
+		return "", err
+	}
+	log.Debug("Hashed user password")
+	return string(hashedPw), nil
+}
+
+// validateMaxLength returns the field errors of a value with a length limit.
+func validateMaxLength(name string, value string, maxLen int) []FieldError {
+	if utf8.RuneCountInString(value) > maxLen {
+		return []FieldError{{
+			Name:   name,
+			ID:     "too_long",
+			Params: []interface{}{maxLen},
+			Error: fmt.Sprintf(
+				"This field cannot be more than %v characters long", maxLen),
+		}}
+	}
+	return nil
+}
+
+// Validate checks if the profile changes are valid.
+//
+// The surrounding whitespace of the fields is trimmed first.
+func (p *ProfileChanges) Validate() error {
+	const (
+		maxDisplayNameLen int = 50
+		maxBioLen         int = 300
+	)
+	var errors []FieldError
+	if p.DisplayName != nil {
+		*p.DisplayName = strings.TrimSpace(*p.DisplayName)
+		errors = append(errors, validateMaxLength(
+			"display_name", *p.DisplayName, maxDisplayNameLen)...)
+	}
+	if p.Bio != nil {
+		*p.Bio = strings.TrimSpace(*p.Bio)
+		errors = append(errors, validateMaxLength("bio", *p.Bio, maxBioLen)...)
+	}
+
+	if len(errors) > 0 {
+		log.WithFields(
+			log.Fields{"model": "ProfileChanges"}).Warn("Request body is invalid")
+		return &ValidationError{
+			Message: "The request body contains errors",
+			Errors:  errors,
+		}
+	}
+	return nil
+}
+
+// Validate checks if the new password is valid.
+func (p *PasswordChange) Validate() error {
+	var errors []FieldError
+	if p.CurrentPassword == "" {
+		// Add a field error if the `current_password` field is empty
+		errors = append(
+			errors,
+			FieldError{
+				Name:  "current_password",
+				ID:    "required",
+				Error: "This field is required",
+			})
+	}
+	errors = append(errors, validatePassword("new_password", p.NewPassword)...)
 
 	if len(errors) > 0 {
-		log.WithFields(log.Fields{"model": "User"}).Warn("Request body is invalid")
+		log.WithFields(
+			log.Fields{"model": "PasswordChange"}).Warn("Request body is invalid")
 		return &ValidationError{
 			Message: "The request body contains errors",
 			Errors:  errors,
 		}
 	}
-	log.WithFields(log.Fields{"model": "User"}).Info("Request body is valid")
+	return nil
+}
+
+// ValidateForSignUp checks if the user struct is valid for sign up.
+//
+// The surrounding whitespace of the username is trimmed first so a
+// whitespace-only username is treated as empty.
+func (u *User) ValidateForSignUp(rules SignUpRules) error {
+	u.Username = strings.TrimSpace(u.Username)
+	var errors []FieldError
+	errors = append(errors, validateUsername(u.Username)...)
+	if len(errors) == 0 {
+		errors = append(errors, rules.validateReservedUsername(u.Username)...)
+	}
+	if pwErrors := validatePassword("password", u.Password); len(pwErrors) > 0 {
+		errors = append(errors, pwErrors...)
+	} else {
+		errors = append(
+			errors, rules.validateWeakPassword(u.Username, u.Password)...)
+	}
+	if u.Email != nil {
+		email := normalizeEmail(*u.Email)
+		if email == "" {
+			// An empty email is the same as no email.
+			u.Email = nil
+		} else {
+			u.Email = &email
+			errors = append(errors, validateEmail(email)...)
+		}
+	}
+
+	if len(errors) > 0 {
+		dbLog(u.DB).WithFields(log.Fields{"model": "User"}).Warn("Request body is invalid")
+		return &ValidationError{
+			Message: "The request body contains errors",
+			Errors:  errors,
+		}
+	}
+	dbLog(u.DB).WithFields(log.Fields{"model": "User"}).Info("Request body is valid")
+	return nil
+}
+
+// ValidateUsername checks if the username of the user is valid.
+func (u *User) ValidateUsername(rules SignUpRules) error {
+	u.Username = strings.TrimSpace(u.Username)
+	errors := validateUsername(u.Username)
+	if len(errors) == 0 {
+		errors = rules.validateReservedUsername(u.Username)
+	}
+	if len(errors) > 0 {
+		dbLog(u.DB).WithFields(log.Fields{"model": "User"}).Warn("Username is invalid")
+		return &ValidationError{
+			Message: "The username is not valid",
+			Errors:  errors,
+		}
+	}
 	return nil
 }
 
 // InitDB initializes the database object
-func (u *User) InitDB() error {
+//
+// The queries of the user are canceled once the context is done.
+func (u *User) InitDB(ctx context.Context) error {
 	db, err := data.CreateConnection()
 	if err != nil {
 		return err
 	}
-	u.DB = db
-	u.Migrate()
-	log.WithFields(log.Fields{"model": "User"}).Info("Initialized database")
+	u.DB = db.WithContext(ctx)
+	dbLog(u.DB).WithFields(log.Fields{"model": "User"}).Info("Initialized database")
 	return nil
 }
 
 // Migrate creates the user table based on the struct model
 func (u *User) Migrate() error {
+	if err := u.DB.SetupJoinTable(&User{}, "JoinedGroups", &GroupMember{}); err != nil {
+		dbLog(u.DB).WithFields(
+			log.Fields{"model": "User"}).Fatal("Failed to set up join table")
+		return err
+	}
 	if err := u.DB.AutoMigrate(&u); err != nil {
-		log.WithFields(
+		dbLog(u.DB).WithFields(
 			log.Fields{"model": "User"}).Fatal("Failed to auto migrate model")
 		return err
 	}
-	log.WithFields(log.Fields{"model": "User"}).Info("Auto migrated model")
+	dbLog(u.DB).WithFields(log.Fields{"model": "User"}).Info("Auto migrated model")
 	return nil
 }
 
-// BeforeCreate hashes the password of the user before adding it to the DB.
+// BeforeCreate normalizes the username and the email and hashes the password
+// of the user before adding it to the DB.
 func (u *User) BeforeCreate(tx *gorm.DB) error {
-	hashedPw, err := bcrypt.GenerateFromPassword(
-		[]byte(u.Password), bcrypt.MinCost)
+	u.Username = normalizeUsername(u.Username)
+	if u.Email != nil {
+		email := normalizeEmail(*u.Email)
+		u.Email = &email
+	}
+	hashedPw, err := hashPassword(u.Password)
 	if err != nil {
-		log.WithFields(log.Fields{
-			"error": err.Error(),
-		}).Error("Could not hash user password")
 		return err
 	}
-	u.Password = string(hashedPw)
-	log.Debug("Hashed user password")
+	u.Password = hashedPw
 	return nil
 }
 
@@ -131,31 +428,331 @@ func (u *User) BeforeCreate(tx *gorm.DB) error {
 func (u *User) Create() error {
 	r := u.DB.Create(&u)
 	if r.Error != nil {
-		log.Fatalf("Could not create user. Error: %v", r.Error.Error())
+		dbLog(u.DB).Errorf("Could not create user. Error: %v", r.Error.Error())
 	} else {
-		log.Info("Created user successfully")
+		dbLog(u.DB).Info("Created user successfully")
 	}
 	return r.Error
 }
 
+// Delete removes the user from the database.
+//
+// Groups owned by the user are deleted as well since a group cannot exist
+// without its owner. The memberships of the user and of the owned groups are
+// removed in the same transaction.
+func (u *User) Delete() error {
+	err := u.DB.Transaction(func(tx *gorm.DB) error {
+		// Deleted groups are removed too since they cannot be restored
+		// without their owner.
+		owned := tx.Unscoped().Model(&Group{}).Select("id").Where(
+			"owner_id = ?", u.ID)
+		if r := tx.Where("group_id IN (?) OR user_id = ?", owned, u.ID).Delete(
+			&GroupMember{}); r.Error != nil {
+			return r.Error
+		}
+		if r := tx.Where("group_id IN (?)", owned).Delete(
+			&GroupActivity{}); r.Error != nil {
+			return r.Error
+		}
+		if r := tx.Where("group_id IN (?)", owned).Delete(
+			&GroupEvent{}); r.Error != nil {
+			return r.Error
+		}
+		if r := tx.Where("group_id IN (?) OR user_id = ?", owned, u.ID).Delete(
+			&JoinRequest{}); r.Error != nil {
+			return r.Error
+		}
+		if r := tx.Where("group_id IN (?) OR user_id = ?", owned, u.ID).Delete(
+			&WaitlistEntry{}); r.Error != nil {
+			return r.Error
+		}
+		if r := tx.Where("group_id IN (?) OR user_id = ?", owned, u.ID).Delete(
+			&GroupBan{}); r.Error != nil {
+			return r.Error
+		}
+		if r := tx.Where("group_id IN (?)", owned).Delete(
+			&GroupSettingsChange{}); r.Error != nil {
+			return r.Error
+		}
+		if r := tx.Where("group_id IN (?)", owned).Delete(
+			&GroupTag{}); r.Error != nil {
+			return r.Error
+		}
+		if r := tx.Where("group_id IN (?)", owned).Delete(
+			&GroupViewDay{}); r.Error != nil {
+			return r.Error
+		}
+		if r := tx.Unscoped().Where("owner_id = ?", u.ID).Delete(
+			&Group{}); r.Error != nil {
+			return r.Error
+		}
+		return tx.Delete(&User{}, u.ID).Error
+	})
+	if err != nil {
+		dbLog(u.DB).Errorf("Could not delete user. Error: %v", err)
+		return err
+	}
+	dbLog(u.DB).Info("Deleted the user successfully")
+	return nil
+}
+
 // Retrieve retrieves the user details given its database ID.
 func (u *User) Retrieve() error {
-	r := u.DB.Select("id", "username", "created_at").First(&u, u.ID)
+	r := u.DB.Select(
+		"id", "username", "display_name", "bio", "email", "created_at",
+		"is_admin").First(&u, u.ID)
 	if r.Error != nil {
-		log.Fatalf("Could not retrieve user. Error: %v", r.Error)
+		dbLog(u.DB).Errorf("Could not retrieve user. Error: %v", r.Error)
 	} else {
-		log.Info("Retrieved the user successfully")
+		dbLog(u.DB).Info("Retrieved the user successfully")
 	}
 	return r.Error
 }
 
+// ListByIDs retrieves the details of the users with the given database IDs.
+//
+// IDs that do not match any user are omitted from the result.
+func (u *User) ListByIDs(ids []int64) ([]User, error) {
+	users := []User{}
+	if len(ids) == 0 {
+		return users, nil
+	}
+	r := u.DB.Select("id", "username", "display_name", "created_at").Where(
+		"id IN ?", ids).Find(&users)
+	if r.Error != nil {
+		dbLog(u.DB).Errorf("Could not list users. Error: %v", r.Error)
+	} else {
+		dbLog(u.DB).Info("Listed users successfully")
+	}
+	return users, r.Error
+}
+
+// CountOpenOwnedGroups counts the open groups owned by the user.
+func (u *User) CountOpenOwnedGroups() (int64, error) {
+	var count int64
+	r := u.DB.Model(&Group{}).Where(
+		"owner_id = ? AND status = ?", u.ID, GroupStatusOpen).Count(&count)
+	if r.Error != nil {
+		dbLog(u.DB).Errorf("Could not count owned groups. Error: %v", r.Error)
+	}
+	return count, r.Error
+}
+
+// CountOpenJoinedGroups counts the open groups the user is a member of.
+func (u *User) CountOpenJoinedGroups() (int64, error) {
+	var count int64
+	r := u.DB.Model(&GroupMember{}).Joins(
+		"JOIN groups ON groups.id = joined_groups.group_id").Where(
+		"joined_groups.user_id = ? AND groups.status = ? AND "+
+			"groups.deleted_at IS NULL",
+		u.ID, GroupStatusOpen).Count(&count)
+	if r.Error != nil {
+		dbLog(u.DB).Errorf("Could not count joined groups. Error: %v", r.Error)
+	}
+	return count, r.Error
+}
+
+// ListJoinedGroupIDs lists the IDs of the groups the user is a member of.
+func (u *User) ListJoinedGroupIDs() ([]int64, error) {
+	ids := []int64{}
+	r := u.DB.Model(&GroupMember{}).Where("user_id = ?", u.ID).Pluck("group_id", &ids)
+	if r.Error != nil {
+		dbLog(u.DB).Errorf("Could not list joined groups. Error: %v", r.Error)
+	}
+	return ids, r.Error
+}
+
+// Capabilities computes what the user is allowed to do.
+//
+// maxOwnedGroups is the number of open groups a user can own, or zero if
+// there is no limit.
+func (u *User) Capabilities(maxOwnedGroups int) (Capabilities, error) {
+	caps := Capabilities{IsAdmin: u.IsAdmin, CanCreateGroup: true}
+	if u.IsAdmin || maxOwnedGroups <= 0 {
+		return caps, nil
+	}
+
+	owned, err := u.CountOpenOwnedGroups()
+	if err != nil {
+		return caps, err
+	}
+	remaining := int64(maxOwnedGroups) - owned
+	if remaining < 0 {
+		remaining = 0
+	}
+	caps.RemainingGroupQuota = &remaining
+	caps.CanCreateGroup = remaining > 0
+	return caps, nil
+}
+
+// RetrieveWithPassword retrieves the user details including the password hash.
+func (u *User) RetrieveWithPassword() error {
+	r := u.DB.First(&u, u.ID)
+	if r.Error != nil {
+		dbLog(u.DB).Errorf("Could not retrieve user. Error: %v", r.Error)
+	} else {
+		dbLog(u.DB).Info("Retrieved the user successfully")
+	}
+	return r.Error
+}
+
+// UpdateProfile saves the profile fields set on the changes.
+func (u *User) UpdateProfile(p ProfileChanges) error {
+	changes := map[string]interface{}{}
+	if p.DisplayName != nil {
+		changes["display_name"] = *p.DisplayName
+		u.DisplayName = *p.DisplayName
+	}
+	if p.Bio != nil {
+		changes["bio"] = *p.Bio
+		u.Bio = *p.Bio
+	}
+	if len(changes) == 0 {
+		return nil
+	}
+
+	r := u.DB.Model(&User{}).Where("id = ?", u.ID).UpdateColumns(changes)
+	if r.Error != nil {
+		dbLog(u.DB).Errorf("Could not update user profile. Error: %v", r.Error)
+		return r.Error
+	}
+	dbLog(u.DB).Info("Updated the user profile successfully")
+	return nil
+}
+
+// UpdatePassword hashes and saves a new password for the user.
+func (u *User) UpdatePassword(pw string) error {
+	hashedPw, err := hashPassword(pw)
+	if err != nil {
+		return err
+	}
+	r := u.DB.Model(&u).Update("password", hashedPw)
+	if r.Error != nil {
+		dbLog(u.DB).Errorf("Could not update user password. Error: %v", r.Error)
+		return r.Error
+	}
+	u.Password = hashedPw
+	dbLog(u.DB).Info("Updated the user password successfully")
+	return nil
+}
+
+// IsUsernameAvailable checks if no user has the username yet.
+//
+// The username is normalized the same way as on sign up.
+func (u *User) IsUsernameAvailable() (bool, error) {
+	var count int64
+	r := u.DB.Model(&User{}).Where(
+		"LOWER(username) = ?", normalizeUsername(u.Username)).Count(&count)
+	if r.Error != nil {
+		dbLog(u.DB).Errorf("Could not check username availability. Error: %v", r.Error)
+		return false, r.Error
+	}
+	return count == 0, nil
+}
+
 // RetrieveUserByUsername retrieves a user details given its username.
 func (u *User) RetrieveByUsername() error {
-	r := u.DB.Where("username = ?", u.Username).First(&u)
+	// Usernames are stored in lowercase. LOWER also matches accounts created
+	// before usernames were normalized.
+	r := u.DB.Where(
+		"LOWER(username) = ?", normalizeUsername(u.Username)).First(&u)
+	if r.Error != nil {
+		dbLog(u.DB).Errorf("Could not retrieve user by username. Error: %v", r.Error)
+	} else {
+		dbLog(u.DB).Info("Retrieved the user successfully")
+	}
+	return r.Error
+}
+
+// RetrieveByIdentifier retrieves a user details given its username or email.
+//
+// Usernames cannot contain an `@` so an identifier cannot match the username
+// of a user and the email of another.
+func (u *User) RetrieveByIdentifier() error {
+	r := u.DB.Where(
+		"LOWER(username) = ? OR email = ?",
+		normalizeUsername(u.Identifier), normalizeEmail(u.Identifier)).First(&u)
 	if r.Error != nil {
-		log.Errorf("Could not retrieve user by username. Error: %v", r.Error)
+		dbLog(u.DB).Errorf("Could not retrieve user by identifier. Error: %v", r.Error)
 	} else {
-		log.Info("Retrieved the user successfully")
+		dbLog(u.DB).Info("Retrieved the user successfully")
 	}
 	return r.Error
 }
+
+// IsLocked checks if the user cannot sign in because of failed sign ins.
+func (u *User) IsLocked() bool {
+	return u.LockedUntil != nil && u.LockedUntil.After(time.Now())
+}
+
+// RecordFailedSignIn counts a failed sign in of the user.
+//
+// The user is locked out for the lockout duration once maxAttempts
+// consecutive sign ins failed. The counter then starts over.
+func (u *User) RecordFailedSignIn(maxAttempts int, lockout time.Duration) error {
+	err := u.DB.Transaction(func(tx *gorm.DB) error {
+		if r := tx.Model(&User{}).Where("id = ?", u.ID).UpdateColumn(
+			"failed_sign_ins", gorm.Expr("failed_sign_ins + 1")); r.Error != nil {
+			return r.Error
+		}
+		if r := tx.Model(&User{}).Select("failed_sign_ins").Where(
+			"id = ?", u.ID).Scan(&u.FailedSignIns); r.Error != nil {
+			return r.Error
+		}
+		if u.FailedSignIns < maxAttempts {
+			return nil
+		}
+
+		until := time.Now().Add(lockout).UTC()
+		u.FailedSignIns = 0
+		u.LockedUntil = &until
+		return tx.Model(&User{}).Where("id = ?", u.ID).UpdateColumns(
+			map[string]interface{}{"failed_sign_ins": 0, "locked_until": until}).Error
+	})
+	if err != nil {
+		dbLog(u.DB).Errorf("Could not record failed sign in. Error: %v", err)
+		return err
+	}
+	if u.IsLocked() {
+		dbLog(u.DB).WithFields(log.Fields{"user_id": u.ID}).Warn("Locked the user out")
+	}
+	return nil
+}
+
+// ResetFailedSignIns clears the failed sign ins of the user.
+func (u *User) ResetFailedSignIns() error {
+	if u.FailedSignIns == 0 && u.LockedUntil == nil {
+		return nil
+	}
+	r := u.DB.Model(&User{}).Where("id = ?", u.ID).UpdateColumns(
+		map[string]interface{}{"failed_sign_ins": 0, "locked_until": nil})
+	if r.Error != nil {
+		dbLog(u.DB).Errorf("Could not reset failed sign ins. Error: %v", r.Error)
+		return r.Error
+	}
+	u.FailedSignIns = 0
+	u.LockedUntil = nil
+	return nil
+}
+
+// RetrieveTokenVersion retrieves the current token version of the user.
+func (u *User) RetrieveTokenVersion() error {
+	r := u.DB.Select("id", "token_version").First(&u, u.ID)
+	if r.Error != nil {
+		dbLog(u.DB).Errorf("Could not retrieve token version. Error: %v", r.Error)
+	}
+	return r.Error
+}
+
+// RevokeTokens invalidates all the tokens issued to the user so far.
+func (u *User) RevokeTokens() error {
+	r := u.DB.Model(&User{}).Where("id = ?", u.ID).UpdateColumn(
+		"token_version", gorm.Expr("token_version + 1"))
+	if r.Error != nil {
+		dbLog(u.DB).Errorf("Could not revoke user tokens. Error: %v", r.Error)
+		return r.Error
+	}
+	u.TokenVersion++
+	dbLog(u.DB).Info("Revoked the user tokens successfully")
+	return nil
+}
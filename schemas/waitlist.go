@@ -0,0 +1,128 @@
+package schemas
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrWaitlistEntryExists is returned when the user is already waitlisted.
+	ErrWaitlistEntryExists = errors.New("user is already on the waitlist")
+	// ErrWaitlistEntryNotFound is returned when the user is not waitlisted.
+	ErrWaitlistEntryNotFound = errors.New("user is not on the waitlist")
+)
+
+// WaitlistEntry is a user waiting for a slot in a full group.
+type WaitlistEntry struct {
+	ID        int64     `json:"-" gorm:"primaryKey"`
+	GroupID   int64     `json:"group_id" gorm:"not null;uniqueIndex:idx_waitlist_entry"`
+	UserID    int64     `json:"user_id" gorm:"not null;uniqueIndex:idx_waitlist_entry"`
+	CreatedAt time.Time `json:"created_at" gorm:"autoCreateTime"`
+	// Position is the place of the user in the waitlist starting from 1.
+	Position int64 `json:"position" gorm:"-"`
+
+	DB *gorm.DB `json:"-" gorm:"-"`
+}
+
+// Create adds the user to the end of the waitlist.
+func (w *WaitlistEntry) Create() error {
+	err := w.DB.Create(&w).Error
+	if err != nil && strings.Contains(err.Error(), "UNIQUE constraint failed") {
+		err = ErrWaitlistEntryExists
+	}
+	if err != nil {
+		dbLog(w.DB).Errorf("Could not create waitlist entry. Error: %v", err)
+		return err
+	}
+	dbLog(w.DB).Info("Created waitlist entry successfully")
+	return w.loadPosition()
+}
+
+// Retrieve retrieves the waitlist entry of the user and its position.
+func (w *WaitlistEntry) Retrieve() error {
+	r := w.DB.Where(
+		"group_id = ? AND user_id = ?", w.GroupID, w.UserID).Limit(1).Find(&w)
+	if r.Error != nil {
+		dbLog(w.DB).Errorf("Could not retrieve waitlist entry. Error: %v", r.Error)
+		return r.Error
+	} else if r.RowsAffected == 0 {
+		return ErrWaitlistEntryNotFound
+	}
+	return w.loadPosition()
+}
+
+// Delete removes the user from the waitlist.
+func (w *WaitlistEntry) Delete() error {
+	r := w.DB.Where(
+		"group_id = ? AND user_id = ?", w.GroupID, w.UserID,
+	).Delete(&WaitlistEntry{})
+	if r.Error != nil {
+		dbLog(w.DB).Errorf("Could not delete waitlist entry. Error: %v", r.Error)
+		return r.Error
+	} else if r.RowsAffected == 0 {
+		return ErrWaitlistEntryNotFound
+	}
+	dbLog(w.DB).Info("Deleted waitlist entry successfully")
+	return nil
+}
+
+// loadPosition sets the position of the entry in the waitlist.
+func (w *WaitlistEntry) loadPosition() error {
+	r := w.DB.Model(&WaitlistEntry{}).Where(
+		"group_id = ? AND id <= ?", w.GroupID, w.ID).Count(&w.Position)
+	if r.Error != nil {
+		dbLog(w.DB).Errorf("Could not load waitlist position. Error: %v", r.Error)
+	}
+	return r.Error
+}
+
+// PromoteFromWaitlist adds the earliest waitlisted user as a member.
+//
+// It returns the ID of the promoted user, or zero if nobody was promoted
+// because the waitlist is empty or the group is not open or still full.
+func (g *Group) PromoteFromWaitlist() (int64, error) {
+	var uid int64
+	err := g.DB.Transaction(func(tx *gorm.DB) error {
+		var next WaitlistEntry
+		// Users who joined in another way are skipped.
+		r := tx.Where(
+			"group_id = ? AND user_id NOT IN (?)", g.ID,
+			tx.Model(&GroupMember{}).Select("user_id").Where("group_id = ?", g.ID),
+		).Order("id").Limit(1).Find(&next)
+		if r.Error != nil || r.RowsAffected == 0 {
+			return r.Error
+		}
+
+		cur := Group{}
+		if r := tx.Select("id", "status", "max_size").First(&cur, g.ID); r.Error != nil {
+			return r.Error
+		}
+		if err := loadGroupDetails(tx, []*Group{&cur}); err != nil {
+			return err
+		}
+		if !cur.IsOpen() || cur.IsFull() {
+			return nil
+		}
+
+		if r := tx.Delete(&next); r.Error != nil {
+			return r.Error
+		}
+		if r := tx.Create(
+			&GroupMember{GroupID: g.ID, UserID: next.UserID}); r.Error != nil {
+			return r.Error
+		}
+		uid = next.UserID
+		return nil
+	})
+	if err != nil {
+		dbLog(g.DB).Errorf("Could not promote from the waitlist. Error: %v", err)
+		return 0, err
+	}
+	if uid != 0 {
+		dbLog(g.DB).Info("Promoted the user from the waitlist successfully")
+	}
+	return uid, nil
+}
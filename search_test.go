@@ -0,0 +1,86 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"golang.org/x/exp/slices"
+)
+
+func TestSearchGroupsByTitleAndDescription(t *testing.T) {
+	owner := signUp(t)
+	byTitle := createGroup(t, owner, map[string]interface{}{
+		"title": "Marigold speedruns",
+	})
+	byDescription := createGroup(t, owner, map[string]interface{}{
+		"description": "Chill marigold farming",
+	})
+	other := createGroup(t, owner, nil)
+
+	ids := listGroupIDs(t, owner, "q=marigold&page_size=100")
+	for _, g := range []map[string]interface{}{byTitle, byDescription} {
+		if !slices.Contains(ids, groupID(g)) {
+			t.Errorf("group %v is not in the results %v", g["id"], ids)
+		}
+	}
+	if slices.Contains(ids, groupID(other)) {
+		t.Errorf("group %v without the query is in the results %v", other["id"], ids)
+	}
+
+	if ids := listGroupIDs(t, owner, "q=MARIGOLD+SPEEDRUNS"); len(ids) != 1 ||
+		ids[0] != groupID(byTitle) {
+		t.Errorf("got results %v, want only %v", ids, byTitle["id"])
+	}
+	if ids := listGroupIDs(t, owner, "q=nomatchwhatsoever"); len(ids) != 0 {
+		t.Errorf("got results %v, want none", ids)
+	}
+}
+
+func TestListGroupsSortOrders(t *testing.T) {
+	owner, viewer := signUp(t), signUp(t)
+	var ids []int64
+	for _, fields := range []map[string]interface{}{
+		{"title": "Bluebell B", "max_size": 8},
+		{"title": "Bluebell C", "max_size": 5},
+		{"title": "Bluebell A", "max_size": 12},
+	} {
+		ids = append(ids, groupID(createGroup(t, owner, fields)))
+	}
+	// The views of the owner are not counted.
+	for i := 0; i < 2; i++ {
+		expectStatus(t, apiRequest{
+			Method: http.MethodGet, Path: fmt.Sprintf("/groups/%v", ids[1]),
+			Token: viewer.Token,
+		}.send(t), http.StatusOK)
+	}
+
+	for _, tc := range []struct {
+		sort string
+		want []int64
+	}{
+		{"", []int64{ids[2], ids[1], ids[0]}},
+		{"created_at", []int64{ids[0], ids[1], ids[2]}},
+		{"-created_at", []int64{ids[2], ids[1], ids[0]}},
+		{"title", []int64{ids[2], ids[0], ids[1]}},
+		{"-title", []int64{ids[1], ids[0], ids[2]}},
+		{"max_size", []int64{ids[1], ids[0], ids[2]}},
+		{"-max_size", []int64{ids[2], ids[0], ids[1]}},
+		{"popular", []int64{ids[1], ids[2], ids[0]}},
+	} {
+		t.Run(tc.sort, func(t *testing.T) {
+			got := listGroupIDs(t, owner, "q=bluebell&sort="+tc.sort)
+			if !slices.Equal(got, tc.want) {
+				t.Errorf("got order %v, want %v", got, tc.want)
+			}
+		})
+	}
+
+	w := apiRequest{
+		Method: http.MethodGet, Path: "/groups?sort=members", Token: owner.Token,
+	}.send(t)
+	expectStatus(t, w, http.StatusBadRequest)
+	if ids := fieldErrorIDs(t, w); len(ids["sort"]) != 1 {
+		t.Errorf("got field errors %v, want one on sort", ids)
+	}
+}
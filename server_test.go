@@ -0,0 +1,113 @@
+package main
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/damascopaul/lfg-backend/schemas"
+)
+
+func TestServeFinishesInFlightRequestsOnShutdown(t *testing.T) {
+	started, release := make(chan struct{}), make(chan struct{})
+	srv := &http.Server{Handler: http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			close(started)
+			<-release
+			io.WriteString(w, "done")
+		})}
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("could not listen: %v", err)
+	}
+	addr := "http://" + ln.Addr().String()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	stopped := make(chan error, 1)
+	go func() { stopped <- serve(ctx, srv, ln) }()
+
+	type result struct {
+		body string
+		err  error
+	}
+	inFlight := make(chan result, 1)
+	go func() {
+		resp, err := http.Get(addr)
+		if err != nil {
+			inFlight <- result{err: err}
+			return
+		}
+		defer resp.Body.Close()
+		b, err := io.ReadAll(resp.Body)
+		inFlight <- result{string(b), err}
+	}()
+	<-started
+
+	cancel()
+	// New connections are refused once the listener is closed.
+	deadline := time.Now().Add(time.Second)
+	for {
+		conn, err := net.Dial("tcp", ln.Addr().String())
+		if err != nil {
+			break
+		}
+		conn.Close()
+		if time.Now().After(deadline) {
+			t.Fatal("got new connections accepted after the shutdown")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	close(release)
+	if r := <-inFlight; r.err != nil || r.body != "done" {
+		t.Errorf("got %q and error %v for the in-flight request, want done", r.body, r.err)
+	}
+	if err := <-stopped; err != nil {
+		t.Errorf("got error %v stopping the server", err)
+	}
+}
+
+func TestSweepStaleGroupsStopsOnShutdown(t *testing.T) {
+	owner := signUp(t)
+	stale := createGroup(t, owner, nil)
+	g := schemas.Group{}
+	if err := g.InitDB(context.Background()); err != nil {
+		t.Fatalf("could not init the database: %v", err)
+	}
+	if r := g.DB.Model(&schemas.Group{}).Where("id = ?", groupID(stale)).Update(
+		"created_at", time.Now().Add(-1000*time.Hour).UTC()); r.Error != nil {
+		t.Fatalf("could not age the group: %v", r.Error)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		sweepStaleGroups(ctx, 10*time.Millisecond, 999*time.Hour)
+	}()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		closed := schemas.Group{ID: groupID(stale), DB: g.DB}
+		if err := closed.Retrieve(); err != nil {
+			t.Fatalf("could not retrieve the group: %v", err)
+		}
+		if !closed.IsOpen() {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("got the stale group still open")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	cancel()
+	select {
+	case <-stopped:
+	case <-time.After(5 * time.Second):
+		t.Fatal("got the sweeper still running after the shutdown")
+	}
+}
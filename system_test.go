@@ -0,0 +1,61 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/damascopaul/lfg-backend/endpoints"
+	"github.com/damascopaul/lfg-backend/schemas"
+)
+
+func TestServerTime(t *testing.T) {
+	before := time.Now().Add(-time.Second)
+	w := apiRequest{Method: http.MethodGet, Path: "/time"}.send(t)
+	expectStatus(t, w, http.StatusOK)
+	after := time.Now().Add(time.Second)
+
+	var resp schemas.TimeResponse
+	decode(t, w, &resp)
+	if resp.Time.Before(before) || resp.Time.After(after) {
+		t.Errorf("got time %v, want it between %v and %v", resp.Time, before, after)
+	}
+	if resp.Time.Location() != time.UTC {
+		t.Errorf("got time zone %v, want UTC", resp.Time.Location())
+	}
+	if resp.Unix != resp.Time.Unix() {
+		t.Errorf("got unix %v, want %v", resp.Unix, resp.Time.Unix())
+	}
+}
+
+func TestInternalRoutesDoNotRequireUserToken(t *testing.T) {
+	for _, path := range []string{"/time", "/healthz", "/readyz", "/metrics"} {
+		t.Run(path, func(t *testing.T) {
+			expectStatus(t, apiRequest{Method: http.MethodGet, Path: path}.send(t),
+				http.StatusOK)
+		})
+	}
+}
+
+func TestMetricsToken(t *testing.T) {
+	defer func(token string) { endpoints.METRICS_TOKEN = token }(
+		endpoints.METRICS_TOKEN)
+	endpoints.METRICS_TOKEN = "scrape-token"
+	u := signUp(t)
+
+	for _, tc := range []struct {
+		name  string
+		token string
+		want  int
+	}{
+		{"no token", "", http.StatusUnauthorized},
+		{"user token", u.Token, http.StatusUnauthorized},
+		{"metrics token", "scrape-token", http.StatusOK},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			expectStatus(t, apiRequest{
+				Method: http.MethodGet, Path: "/metrics", Token: tc.token,
+			}.send(t), tc.want)
+		})
+	}
+}
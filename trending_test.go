@@ -0,0 +1,46 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestTrendingGroupsRankActiveAboveQuiet(t *testing.T) {
+	owner, viewer := signUp(t), signUp(t)
+	// The active group is created first so it is not ranked higher for
+	// being newer.
+	active := createGroup(t, owner, map[string]interface{}{"title": "Busy raid"})
+	quiet := createGroup(t, owner, map[string]interface{}{"title": "Quiet raid"})
+
+	for i := 0; i < 3; i++ {
+		expectStatus(t, apiRequest{
+			Method: http.MethodGet, Path: groupPath(active, ""), Token: viewer.Token,
+		}.send(t), http.StatusOK)
+	}
+	for i := 0; i < 2; i++ {
+		expectStatus(t, apiRequest{
+			Method: http.MethodPost, Path: groupPath(active, "/join"),
+			Token: signUp(t).Token,
+		}.send(t), http.StatusOK)
+	}
+
+	w := apiRequest{
+		Method: http.MethodGet, Path: "/groups/trending?limit=50", Token: viewer.Token,
+	}.send(t)
+	expectStatus(t, w, http.StatusOK)
+	var groups []map[string]interface{}
+	decode(t, w, &groups)
+
+	rank := map[interface{}]int{}
+	for i, g := range groups {
+		rank[g["id"]] = i + 1
+	}
+	activeRank, quietRank := rank[active["id"]], rank[quiet["id"]]
+	if activeRank == 0 || quietRank == 0 {
+		t.Fatalf("got ranks %v and %v, want both groups listed", activeRank, quietRank)
+	}
+	if activeRank > quietRank {
+		t.Errorf("got the active group at %v below the quiet group at %v",
+			activeRank, quietRank)
+	}
+}
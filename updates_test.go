@@ -0,0 +1,104 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/damascopaul/lfg-backend/schemas"
+
+	"github.com/gorilla/websocket"
+)
+
+func TestWatchGroupIsOnlyForOwnerAndMembers(t *testing.T) {
+	owner, member, outsider := signUp(t), signUp(t), signUp(t)
+	g := createGroup(t, owner, nil)
+	expectStatus(t, apiRequest{
+		Method: http.MethodPost, Path: groupPath(g, "/join"), Token: member.Token,
+	}.send(t), http.StatusOK)
+
+	srv := httptest.NewServer(api)
+	defer srv.Close()
+	url := "ws" + strings.TrimPrefix(srv.URL, "http") + groupPath(g, "/ws")
+
+	for _, tc := range []struct {
+		name   string
+		user   testUser
+		status int
+	}{
+		{"owner", owner, http.StatusSwitchingProtocols},
+		{"member", member, http.StatusSwitchingProtocols},
+		{"outsider", outsider, http.StatusForbidden},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			conn, resp, err := websocket.DefaultDialer.Dial(url, http.Header{
+				"Authorization": {"Bearer " + tc.user.Token}})
+			if conn != nil {
+				conn.Close()
+			}
+			if resp == nil {
+				t.Fatalf("got no response: %v", err)
+			}
+			if resp.StatusCode != tc.status {
+				t.Errorf("got status %v, want %v", resp.StatusCode, tc.status)
+			}
+		})
+	}
+}
+
+func TestWatchGroupSendsUpdates(t *testing.T) {
+	owner, member, joiner := signUp(t), signUp(t), signUp(t)
+	g := createGroup(t, owner, nil)
+	joinGroup(t, member, g)
+
+	srv := httptest.NewServer(api)
+	defer srv.Close()
+	url := "ws" + strings.TrimPrefix(srv.URL, "http") + groupPath(g, "/ws")
+	dial := func(u testUser) *websocket.Conn {
+		conn, _, err := websocket.DefaultDialer.Dial(url, http.Header{
+			"Authorization": {"Bearer " + u.Token}})
+		if err != nil {
+			t.Fatalf("could not watch the group: %v", err)
+		}
+		conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+		return conn
+	}
+	next := func(conn *websocket.Conn) schemas.GroupUpdate {
+		var u schemas.GroupUpdate
+		if err := conn.ReadJSON(&u); err != nil {
+			t.Fatalf("could not read an update: %v", err)
+		}
+		return u
+	}
+	ownerConn, memberConn := dial(owner), dial(member)
+	defer ownerConn.Close()
+	defer memberConn.Close()
+
+	joinGroup(t, joiner, g)
+	for _, conn := range []*websocket.Conn{ownerConn, memberConn} {
+		u := next(conn)
+		if u.Type != schemas.ActivityJoined || u.GroupID != groupID(g) ||
+			u.ActorID != joiner.ID {
+			t.Errorf("got update %+v, want the join of %v", u, joiner.ID)
+		}
+	}
+
+	// The kicked member gets the kick and then the connection is closed.
+	expectStatus(t, apiRequest{
+		Method: http.MethodPost, Path: groupPath(g, "/kick"), Token: owner.Token,
+		Body: map[string]interface{}{"id": member.ID},
+	}.send(t), http.StatusOK)
+	if u := next(memberConn); u.Type != schemas.ActivityKicked {
+		t.Errorf("got update %+v, want the kick", u)
+	}
+	if _, _, err := memberConn.ReadMessage(); !websocket.IsCloseError(
+		err, websocket.ClosePolicyViolation) {
+		t.Errorf("got %v after the kick, want the connection closed", err)
+	}
+	if u := next(ownerConn); u.Type != schemas.ActivityKicked ||
+		u.TargetID == nil || *u.TargetID != member.ID {
+		t.Errorf("got update %+v, want the kick of %v", u, member.ID)
+	}
+}
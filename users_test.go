@@ -0,0 +1,548 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/damascopaul/lfg-backend/endpoints"
+	"github.com/damascopaul/lfg-backend/schemas"
+)
+
+func TestSignUpWithReservedUsername(t *testing.T) {
+	for _, tc := range []struct {
+		username     string
+		wantReserved bool
+	}{
+		{"admin", true},
+		{"ADMIN", true},
+		{"  Support  ", true},
+		{"Root", true},
+		{"api", true},
+		{"admiral", false},
+		{"support-fan", false},
+	} {
+		t.Run(tc.username, func(t *testing.T) {
+			username := tc.username
+			if !tc.wantReserved {
+				// The allowed names are made unique in the shared database.
+				username = fmt.Sprintf("%v%v", username, atomic.AddInt64(&userCount, 1))
+			}
+			w := apiRequest{
+				Method: http.MethodPost,
+				Path:   "/sign-up",
+				Body:   map[string]string{"username": username, "password": testPassword},
+			}.send(t)
+			if !tc.wantReserved {
+				expectStatus(t, w, http.StatusCreated)
+				return
+			}
+			expectStatus(t, w, http.StatusBadRequest)
+			ids := fieldErrorIDs(t, w)["username"]
+			if len(ids) != 1 || ids[0] != "reserved_username" {
+				t.Errorf("got username errors %v, want [reserved_username]", ids)
+			}
+		})
+	}
+}
+
+func TestSignUpWithWeakPassword(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		password func(username string) string
+		wantID   string
+	}{
+		{"common", func(string) string { return "Password123" }, "common_password"},
+		{"same as username", func(u string) string { return u }, "same_as_username"},
+		{"strong", func(string) string { return testPassword }, ""},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			// The username is long enough to be a valid password.
+			username := fmt.Sprintf("longplayer%v", atomic.AddInt64(&userCount, 1))
+			w := apiRequest{
+				Method: http.MethodPost,
+				Path:   "/sign-up",
+				Body: map[string]string{
+					"username": username, "password": tc.password(username)},
+			}.send(t)
+			if tc.wantID == "" {
+				expectStatus(t, w, http.StatusCreated)
+				return
+			}
+			expectStatus(t, w, http.StatusBadRequest)
+			ids := fieldErrorIDs(t, w)["password"]
+			if len(ids) != 1 || ids[0] != tc.wantID {
+				t.Errorf("got password errors %v, want [%v]", ids, tc.wantID)
+			}
+		})
+	}
+}
+
+func TestSignUpWithUsernameTakenInAnotherCase(t *testing.T) {
+	// The username is stored as is, like the usernames stored before they
+	// were normalized.
+	legacy := fmt.Sprintf("LegacyPlayer%v", atomic.AddInt64(&userCount, 1))
+	u := schemas.User{}
+	if err := u.InitDB(context.Background()); err != nil {
+		t.Fatalf("could not init the database: %v", err)
+	}
+	if r := u.DB.Exec(
+		"INSERT INTO users (username, password) VALUES (?, ?)", legacy, "x",
+	); r.Error != nil {
+		t.Fatalf("could not add the user: %v", r.Error)
+	}
+
+	w := apiRequest{
+		Method: http.MethodPost,
+		Path:   "/sign-up",
+		Body: map[string]string{
+			"username": strings.ToLower(legacy), "password": testPassword},
+	}.send(t)
+	expectStatus(t, w, http.StatusBadRequest)
+	var resp struct {
+		Code string `json:"code"`
+	}
+	decode(t, w, &resp)
+	if resp.Code != "username_taken" {
+		t.Errorf("got code %q, want username_taken", resp.Code)
+	}
+}
+
+func TestListUsersByIDs(t *testing.T) {
+	a, b := signUp(t), signUp(t)
+	const missing = 1 << 40
+
+	w := apiRequest{
+		Method: http.MethodGet,
+		Path:   fmt.Sprintf("/users?ids=%v,%v,%v", a.ID, missing, b.ID),
+		Token:  a.Token,
+	}.send(t)
+	expectStatus(t, w, http.StatusOK)
+	var users []map[string]interface{}
+	decode(t, w, &users)
+	got := map[string]bool{}
+	for _, u := range users {
+		got[fmt.Sprint(u["username"])] = true
+		if _, ok := u["password"]; ok {
+			t.Errorf("got the password of user %v", u["id"])
+		}
+	}
+	if len(users) != 2 || !got[a.Username] || !got[b.Username] {
+		t.Errorf("got users %v, want %v and %v", users, a.Username, b.Username)
+	}
+
+	for _, ids := range []string{"1,abc", "1.5", "-"} {
+		w := apiRequest{
+			Method: http.MethodGet, Path: "/users?ids=" + ids, Token: a.Token,
+		}.send(t)
+		expectStatus(t, w, http.StatusBadRequest)
+	}
+}
+
+func TestRetrieveCurrentUser(t *testing.T) {
+	u := signUp(t)
+
+	w := apiRequest{Method: http.MethodGet, Path: "/me", Token: u.Token}.send(t)
+	expectStatus(t, w, http.StatusOK)
+	var resp map[string]interface{}
+	decode(t, w, &resp)
+	if resp["id"] != float64(u.ID) || resp["username"] != u.Username {
+		t.Errorf("got user %v, want %v %v", resp, u.ID, u.Username)
+	}
+	if _, ok := resp["password"]; ok {
+		t.Errorf("got the password in %v", resp)
+	}
+
+	expectStatus(t, apiRequest{Method: http.MethodGet, Path: "/me"}.send(t),
+		http.StatusUnauthorized)
+}
+
+func TestUpdateCurrentUserProfile(t *testing.T) {
+	u := signUp(t)
+	update := func(fields map[string]interface{}) *httptest.ResponseRecorder {
+		return apiRequest{
+			Method: http.MethodPatch, Path: "/me", Token: u.Token, Body: fields,
+		}.send(t)
+	}
+	me := func() map[string]interface{} {
+		t.Helper()
+		w := apiRequest{Method: http.MethodGet, Path: "/me", Token: u.Token}.send(t)
+		expectStatus(t, w, http.StatusOK)
+		var resp map[string]interface{}
+		decode(t, w, &resp)
+		return resp
+	}
+
+	// The username cannot be changed here.
+	expectStatus(t, update(map[string]interface{}{
+		"display_name": "  Night Owl  ", "username": "renamed"}), http.StatusOK)
+	resp := me()
+	if resp["display_name"] != "Night Owl" || resp["username"] != u.Username {
+		t.Errorf("got %v, want the trimmed display name and the same username", resp)
+	}
+	// Updating the bio keeps the display name.
+	expectStatus(t, update(map[string]interface{}{"bio": "Tank main"}), http.StatusOK)
+	resp = me()
+	if resp["bio"] != "Tank main" || resp["display_name"] != "Night Owl" {
+		t.Errorf("got %v, want the new bio and the display name kept", resp)
+	}
+
+	w := update(map[string]interface{}{
+		"display_name": strings.Repeat("a", 51), "bio": strings.Repeat("b", 301)})
+	expectStatus(t, w, http.StatusBadRequest)
+	ids := fieldErrorIDs(t, w)
+	for _, name := range []string{"display_name", "bio"} {
+		if len(ids[name]) != 1 || ids[name][0] != "too_long" {
+			t.Errorf("got %v errors %v, want too_long", name, ids[name])
+		}
+	}
+	if resp = me(); resp["bio"] != "Tank main" {
+		t.Errorf("got bio %v after a rejected update", resp["bio"])
+	}
+}
+
+func TestDeleteCurrentUserRemovesOwnedGroups(t *testing.T) {
+	u, member, other := signUp(t), signUp(t), signUp(t)
+	owned := createGroup(t, u, nil)
+	joinGroup(t, member, owned)
+	joined := createGroup(t, other, nil)
+	joinGroup(t, u, joined)
+
+	expectStatus(t, apiRequest{
+		Method: http.MethodDelete, Path: "/me", Token: u.Token,
+	}.send(t), http.StatusNoContent)
+
+	expectStatus(t, apiRequest{
+		Method: http.MethodGet, Path: groupPath(owned, ""), Token: member.Token,
+	}.send(t), http.StatusNotFound)
+	w := apiRequest{
+		Method: http.MethodGet, Path: groupPath(joined, "/members"),
+		Token: other.Token,
+	}.send(t)
+	expectStatus(t, w, http.StatusOK)
+	var members []map[string]interface{}
+	decode(t, w, &members)
+	if len(members) != 0 {
+		t.Errorf("got members %v, want none", members)
+	}
+
+	if w := (apiRequest{
+		Method: http.MethodGet, Path: "/me", Token: u.Token,
+	}.send(t)); w.Code == http.StatusOK {
+		t.Errorf("got status 200 with the token of the deleted user")
+	}
+	expectStatus(t, apiRequest{
+		Method: http.MethodPost, Path: "/sign-in",
+		Body: map[string]string{"username": u.Username, "password": testPassword},
+	}.send(t), http.StatusUnauthorized)
+}
+
+func TestChangePassword(t *testing.T) {
+	u := signUp(t)
+	const newPassword = "amber-compass-77"
+	change := func(current, next string) *httptest.ResponseRecorder {
+		return apiRequest{
+			Method: http.MethodPatch, Path: "/me/password", Token: u.Token,
+			Body: map[string]string{
+				"current_password": current, "new_password": next,
+			},
+		}.send(t)
+	}
+	signIn := func(password string) int {
+		return apiRequest{
+			Method: http.MethodPost, Path: "/sign-in",
+			Body: map[string]string{"username": u.Username, "password": password},
+		}.send(t).Code
+	}
+
+	w := change("wrong-password-1", newPassword)
+	expectStatus(t, w, http.StatusBadRequest)
+	if ids := fieldErrorIDs(t, w); len(ids["current_password"]) != 1 ||
+		ids["current_password"][0] != "incorrect_password" {
+		t.Errorf("got field errors %v, want incorrect_password", ids)
+	}
+
+	w = change(testPassword, "short")
+	expectStatus(t, w, http.StatusBadRequest)
+	if ids := fieldErrorIDs(t, w); len(ids["new_password"]) == 0 {
+		t.Errorf("got field errors %v, want one on new_password", ids)
+	}
+
+	expectStatus(t, change(testPassword, newPassword), http.StatusOK)
+	if code := signIn(testPassword); code != http.StatusUnauthorized {
+		t.Errorf("got status %v signing in with the old password, want 401", code)
+	}
+	if code := signIn(newPassword); code != http.StatusCreated {
+		t.Errorf("got status %v signing in with the new password, want 201", code)
+	}
+}
+
+func TestSignUpWithDisallowedUsernameCharacters(t *testing.T) {
+	for _, username := range []string{
+		"has space", "emoji🎮", "semi;colon", "slash/name", "at@sign", "ünïcode",
+	} {
+		t.Run(username, func(t *testing.T) {
+			w := apiRequest{
+				Method: http.MethodPost,
+				Path:   "/sign-up",
+				Body:   map[string]string{"username": username, "password": testPassword},
+			}.send(t)
+			expectStatus(t, w, http.StatusBadRequest)
+			if ids := fieldErrorIDs(t, w); len(ids["username"]) != 1 ||
+				ids["username"][0] != "username_characters" {
+				t.Errorf("got field errors %v, want username_characters", ids)
+			}
+		})
+	}
+
+	// Letters, numbers, underscores, periods, and hyphens are allowed.
+	username := fmt.Sprintf("Ok_name.%v-x", atomic.AddInt64(&userCount, 1))
+	expectStatus(t, apiRequest{
+		Method: http.MethodPost,
+		Path:   "/sign-up",
+		Body:   map[string]string{"username": username, "password": testPassword},
+	}.send(t), http.StatusCreated)
+}
+
+func TestSignUpWithWhitespaceOnlyUsername(t *testing.T) {
+	for _, username := range []string{"   ", "\t\n"} {
+		w := apiRequest{
+			Method: http.MethodPost,
+			Path:   "/sign-up",
+			Body:   map[string]string{"username": username, "password": testPassword},
+		}.send(t)
+		expectStatus(t, w, http.StatusBadRequest)
+		if ids := fieldErrorIDs(t, w); len(ids["username"]) != 1 ||
+			ids["username"][0] != "required" {
+			t.Errorf("got field errors %v for %q, want required", ids, username)
+		}
+	}
+}
+
+func TestCheckUsernameAvailability(t *testing.T) {
+	taken := signUp(t)
+	free := fmt.Sprintf("free%v", atomic.AddInt64(&userCount, 1))
+	for _, tc := range []struct {
+		username  string
+		status    int
+		available bool
+	}{
+		{taken.Username, http.StatusOK, false},
+		// Usernames are compared without case.
+		{strings.ToUpper(taken.Username), http.StatusOK, false},
+		{free, http.StatusOK, true},
+		{"ab", http.StatusBadRequest, false},
+		{"admin", http.StatusBadRequest, false},
+	} {
+		t.Run(tc.username, func(t *testing.T) {
+			w := apiRequest{
+				Method: http.MethodGet, Path: "/users/availability?username=" + tc.username,
+			}.send(t)
+			expectStatus(t, w, tc.status)
+			if tc.status != http.StatusOK {
+				return
+			}
+			var resp schemas.AvailabilityResponse
+			decode(t, w, &resp)
+			if resp.Available != tc.available {
+				t.Errorf("got available %v, want %v", resp.Available, tc.available)
+			}
+		})
+	}
+}
+
+func TestSignInByUsernameOrEmail(t *testing.T) {
+	n := atomic.AddInt64(&userCount, 1)
+	username := fmt.Sprintf("mailer%v", n)
+	expectStatus(t, apiRequest{
+		Method: http.MethodPost,
+		Path:   "/sign-up",
+		Body: map[string]string{
+			"username": username, "password": testPassword,
+			"email": fmt.Sprintf("Mailer%v@Example.com", n)},
+	}.send(t), http.StatusCreated)
+
+	for _, tc := range []struct {
+		name   string
+		body   map[string]string
+		status int
+	}{
+		{"username", map[string]string{"identifier": username}, http.StatusCreated},
+		{"email", map[string]string{
+			"identifier": fmt.Sprintf("mailer%v@example.com", n)}, http.StatusCreated},
+		{"username field", map[string]string{"username": username}, http.StatusCreated},
+		{"unknown identifier", map[string]string{
+			"identifier": fmt.Sprintf("nobody%v@example.com", n)}, http.StatusUnauthorized},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.body["password"] = testPassword
+			w := apiRequest{Method: http.MethodPost, Path: "/sign-in", Body: tc.body}.send(t)
+			expectStatus(t, w, tc.status)
+			var resp struct {
+				Code  string `json:"code"`
+				Token string `json:"token"`
+			}
+			decode(t, w, &resp)
+			if tc.status == http.StatusCreated && resp.Token == "" {
+				t.Error("got no token")
+			}
+			// An unknown identifier gets the same error as a wrong password.
+			if tc.status == http.StatusUnauthorized && resp.Code != "invalid_credentials" {
+				t.Errorf("got code %q, want invalid_credentials", resp.Code)
+			}
+		})
+	}
+}
+
+func TestSignInLockout(t *testing.T) {
+	defer func(attempts int, lockout time.Duration) {
+		endpoints.SIGN_IN_MAX_ATTEMPTS, endpoints.SIGN_IN_LOCKOUT = attempts, lockout
+	}(endpoints.SIGN_IN_MAX_ATTEMPTS, endpoints.SIGN_IN_LOCKOUT)
+	endpoints.SIGN_IN_MAX_ATTEMPTS, endpoints.SIGN_IN_LOCKOUT = 3, 500*time.Millisecond
+
+	u := signUp(t)
+	signIn := func(password string) *httptest.ResponseRecorder {
+		return apiRequest{
+			Method: http.MethodPost, Path: "/sign-in",
+			Body: map[string]string{"identifier": u.Username, "password": password},
+		}.send(t)
+	}
+
+	for i := 0; i < 3; i++ {
+		expectStatus(t, signIn("wrong-pass"), http.StatusUnauthorized)
+	}
+	// The right password is refused too while the user is locked out.
+	w := signIn(testPassword)
+	expectStatus(t, w, http.StatusTooManyRequests)
+	if w.Header().Get("Retry-After") != "1" {
+		t.Errorf("got Retry-After %q, want 1", w.Header().Get("Retry-After"))
+	}
+	var resp struct {
+		Code string `json:"code"`
+	}
+	decode(t, w, &resp)
+	if resp.Code != "account_locked" {
+		t.Errorf("got code %q, want account_locked", resp.Code)
+	}
+
+	time.Sleep(endpoints.SIGN_IN_LOCKOUT)
+	expectStatus(t, signIn(testPassword), http.StatusCreated)
+	// The count started over, so failing again does not lock the user out.
+	expectStatus(t, signIn("wrong-pass"), http.StatusUnauthorized)
+	expectStatus(t, signIn(testPassword), http.StatusCreated)
+}
+
+func TestTokensUseTheConfiguredSecret(t *testing.T) {
+	defer func(secret string) { endpoints.TOKEN_SECRET = secret }(endpoints.TOKEN_SECRET)
+	u := signUp(t)
+	me := func(token string) int {
+		return apiRequest{Method: http.MethodGet, Path: "/me", Token: token}.send(t).Code
+	}
+	if got := me(u.Token); got != http.StatusOK {
+		t.Fatalf("got status %v with the token of the configured secret", got)
+	}
+
+	// A token signed with another secret is refused, and new tokens are
+	// signed with the secret now configured.
+	endpoints.TOKEN_SECRET = "rotated-secret"
+	if got := me(u.Token); got != http.StatusUnauthorized {
+		t.Errorf("got status %v with the token of the old secret, want 401", got)
+	}
+	w := apiRequest{
+		Method: http.MethodPost, Path: "/sign-in",
+		Body: map[string]string{"identifier": u.Username, "password": testPassword},
+	}.send(t)
+	expectStatus(t, w, http.StatusCreated)
+	var resp struct {
+		Token string `json:"token"`
+	}
+	decode(t, w, &resp)
+	if got := me(resp.Token); got != http.StatusOK {
+		t.Errorf("got status %v with the token of the new secret, want 200", got)
+	}
+
+	// No token is accepted without a secret.
+	endpoints.TOKEN_SECRET = ""
+	if got := me(resp.Token); got != http.StatusUnauthorized {
+		t.Errorf("got status %v without a secret, want 401", got)
+	}
+}
+
+func TestRevokeAllSessions(t *testing.T) {
+	u := signUp(t)
+	signIn := func() string {
+		t.Helper()
+		w := apiRequest{
+			Method: http.MethodPost, Path: "/sign-in",
+			Body: map[string]string{"identifier": u.Username, "password": testPassword},
+		}.send(t)
+		expectStatus(t, w, http.StatusCreated)
+		var resp struct {
+			Token string `json:"token"`
+		}
+		decode(t, w, &resp)
+		return resp.Token
+	}
+	me := func(token string) *httptest.ResponseRecorder {
+		return apiRequest{Method: http.MethodGet, Path: "/me", Token: token}.send(t)
+	}
+	other := signIn()
+
+	expectStatus(t, apiRequest{
+		Method: http.MethodPost, Path: "/me/sessions/revoke-all", Token: u.Token,
+	}.send(t), http.StatusNoContent)
+
+	// Every token issued before is refused.
+	for _, token := range []string{u.Token, other} {
+		w := me(token)
+		expectStatus(t, w, http.StatusUnauthorized)
+		var resp struct {
+			Code string `json:"code"`
+		}
+		decode(t, w, &resp)
+		if resp.Code != "revoked_token" {
+			t.Errorf("got code %q, want revoked_token", resp.Code)
+		}
+	}
+	expectStatus(t, me(signIn()), http.StatusOK)
+}
+
+func TestRetrieveCapabilities(t *testing.T) {
+	u := signUp(t)
+	retrieve := func() schemas.Capabilities {
+		t.Helper()
+		w := apiRequest{
+			Method: http.MethodGet, Path: "/users/me/capabilities", Token: u.Token,
+		}.send(t)
+		expectStatus(t, w, http.StatusOK)
+		var caps schemas.Capabilities
+		decode(t, w, &caps)
+		return caps
+	}
+
+	caps := retrieve()
+	if caps.IsAdmin || !caps.CanCreateGroup || caps.RemainingGroupQuota != nil {
+		t.Errorf("got %+v without a group limit", caps)
+	}
+
+	defer func(limit int) { endpoints.MAX_OWNED_GROUPS = limit }(
+		endpoints.MAX_OWNED_GROUPS)
+	endpoints.MAX_OWNED_GROUPS = 2
+	createGroup(t, u, nil)
+	caps = retrieve()
+	if !caps.CanCreateGroup || caps.RemainingGroupQuota == nil ||
+		*caps.RemainingGroupQuota != 1 {
+		t.Errorf("got %+v with one group left", caps)
+	}
+	createGroup(t, u, map[string]interface{}{"title": "Second raid"})
+	caps = retrieve()
+	if caps.CanCreateGroup || caps.RemainingGroupQuota == nil ||
+		*caps.RemainingGroupQuota != 0 {
+		t.Errorf("got %+v with no group left", caps)
+	}
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+)
+
+// waitlistPosition returns the position of the user in the waitlist of the
+// group, or zero if the user is not waitlisted.
+func waitlistPosition(t *testing.T, u testUser, g map[string]interface{}) int64 {
+	t.Helper()
+	w := apiRequest{
+		Method: http.MethodGet, Path: groupPath(g, "/waitlist"), Token: u.Token,
+	}.send(t)
+	if w.Code == http.StatusNotFound {
+		return 0
+	}
+	expectStatus(t, w, http.StatusOK)
+	var entry struct {
+		Position int64 `json:"position"`
+	}
+	decode(t, w, &entry)
+	return entry.Position
+}
+
+func TestWaitlistPromotesWhenASlotFrees(t *testing.T) {
+	owner, first, second := signUp(t), signUp(t), signUp(t)
+	g := createGroup(t, owner, map[string]interface{}{"waitlist": true})
+	members := make([]testUser, 4)
+	for i := range members {
+		members[i] = signUp(t)
+		joinGroup(t, members[i], g)
+	}
+
+	// Joining the full group waitlists the users in order.
+	for i, u := range []testUser{first, second} {
+		w := apiRequest{
+			Method: http.MethodPost, Path: groupPath(g, "/join"), Token: u.Token,
+		}.send(t)
+		expectStatus(t, w, http.StatusAccepted)
+		var entry struct {
+			Position int64 `json:"position"`
+		}
+		decode(t, w, &entry)
+		if entry.Position != int64(i+1) {
+			t.Errorf("got position %v, want %v", entry.Position, i+1)
+		}
+	}
+	expectStatus(t, apiRequest{
+		Method: http.MethodPost, Path: groupPath(g, "/join"), Token: first.Token,
+	}.send(t), http.StatusBadRequest)
+
+	// The first waitlisted user takes the slot of the member who leaves.
+	expectStatus(t, apiRequest{
+		Method: http.MethodPost, Path: groupPath(g, "/leave"), Token: members[0].Token,
+	}.send(t), http.StatusOK)
+	roles := memberRoles(t, owner, g)
+	if _, ok := roles[first.ID]; !ok || len(roles) != 4 {
+		t.Errorf("got members %v, want 4 with the first waitlisted user", roles)
+	}
+	if got := waitlistPosition(t, first, g); got != 0 {
+		t.Errorf("got the promoted user at position %v of the waitlist", got)
+	}
+	if got := waitlistPosition(t, second, g); got != 1 {
+		t.Errorf("got position %v after the promotion, want 1", got)
+	}
+
+	expectStatus(t, apiRequest{
+		Method: http.MethodDelete, Path: groupPath(g, "/waitlist"), Token: second.Token,
+	}.send(t), http.StatusNoContent)
+	if got := waitlistPosition(t, second, g); got != 0 {
+		t.Errorf("got position %v after leaving the waitlist", got)
+	}
+}